@@ -14,6 +14,7 @@ require (
 	github.com/cncf/xds/go v0.0.0-20230105202645-06c439db220b
 	github.com/dchest/siphash v1.2.1
 	github.com/envoyproxy/go-control-plane v0.10.3
+	github.com/fsnotify/fsnotify v1.4.9
 	github.com/ghodss/yaml v1.0.0
 	github.com/go-chi/chi v4.1.0+incompatible
 	github.com/go-resty/resty/v2 v2.6.0
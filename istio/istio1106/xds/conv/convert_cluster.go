@@ -174,6 +174,7 @@ func convertHashPolicy(hashPolicy []*envoy_config_route_v3.RouteAction_HashPolic
 				Header: &v2.HeaderHashPolicy{
 					Key: header.HeaderName,
 				},
+				Terminal: p.Terminal,
 			})
 
 			continue
@@ -188,6 +189,7 @@ func convertHashPolicy(hashPolicy []*envoy_config_route_v3.RouteAction_HashPolic
 						Duration: ConvertDuration(cookieConfig.Ttl),
 					},
 				},
+				Terminal: p.Terminal,
 			})
 
 			continue
@@ -196,6 +198,7 @@ func convertHashPolicy(hashPolicy []*envoy_config_route_v3.RouteAction_HashPolic
 		if ip := p.GetConnectionProperties(); ip != nil {
 			hpReturn = append(hpReturn, v2.HashPolicy{
 				SourceIP: &v2.SourceIPHashPolicy{},
+				Terminal: p.Terminal,
 			})
 
 			continue
@@ -19,12 +19,14 @@ package conv
 
 import (
 	"net/http"
+	"regexp"
 	"strings"
 
 	_ "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/http/router/v3" // some config contains this protobuf, mosn does not parse it yet.
 
 	envoy_config_core_v3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
 	envoy_config_route_v3 "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+	envoy_type_matcher_v3 "github.com/envoyproxy/go-control-plane/envoy/type/matcher/v3"
 	"github.com/envoyproxy/go-control-plane/pkg/wellknown"
 	"github.com/golang/protobuf/ptypes/any"
 	"mosn.io/api"
@@ -158,7 +160,8 @@ func convertRouteMatch(xdsRouteMatch *envoy_config_route_v3.RouteMatch) v2.Route
 		Path:   xdsRouteMatch.GetPath(),
 		//CaseSensitive: xdsRouteMatch.GetCaseSensitive().GetValue(),
 		//Runtime:       convertRuntime(xdsRouteMatch.GetRuntime()),
-		Headers: convertHeaders(xdsRouteMatch.GetHeaders()),
+		Headers:         convertHeaders(xdsRouteMatch.GetHeaders()),
+		QueryParameters: convertQueryParameters(xdsRouteMatch.GetQueryParameters()),
 	}
 	if xdsRouteMatch.GetSafeRegex() != nil {
 		rm.Regex = xdsRouteMatch.GetSafeRegex().Regex
@@ -172,15 +175,33 @@ func convertHeaders(xdsHeaders []*envoy_config_route_v3.HeaderMatcher) []v2.Head
 	}
 	headerMatchers := make([]v2.HeaderMatcher, 0, len(xdsHeaders))
 	for _, xdsHeader := range xdsHeaders {
-		headerMatcher := v2.HeaderMatcher{}
-		if xdsHeader.GetSafeRegexMatch() != nil && xdsHeader.GetSafeRegexMatch().Regex != "" {
-			headerMatcher.Name = xdsHeader.GetName()
-			headerMatcher.Value = xdsHeader.GetSafeRegexMatch().Regex
+		headerMatcher := v2.HeaderMatcher{
+			Name:        xdsHeader.GetName(),
+			InvertMatch: xdsHeader.GetInvertMatch(),
+		}
+		switch spec := xdsHeader.GetHeaderMatchSpecifier().(type) {
+		case *envoy_config_route_v3.HeaderMatcher_SafeRegexMatch:
+			headerMatcher.Value = spec.SafeRegexMatch.GetRegex()
+			headerMatcher.Regex = headerMatcher.Value != ""
+		case *envoy_config_route_v3.HeaderMatcher_RangeMatch:
+			headerMatcher.RangeMatch = &v2.Int64Range{
+				Start: spec.RangeMatch.GetStart(),
+				End:   spec.RangeMatch.GetEnd(),
+			}
+		case *envoy_config_route_v3.HeaderMatcher_PresentMatch:
+			present := spec.PresentMatch
+			headerMatcher.PresentMatch = &present
+		case *envoy_config_route_v3.HeaderMatcher_PrefixMatch:
+			headerMatcher.PrefixMatch = spec.PrefixMatch
+		case *envoy_config_route_v3.HeaderMatcher_SuffixMatch:
+			headerMatcher.SuffixMatch = spec.SuffixMatch
+		case *envoy_config_route_v3.HeaderMatcher_ContainsMatch:
+			headerMatcher.Value = regexp.QuoteMeta(spec.ContainsMatch)
 			headerMatcher.Regex = true
-		} else {
-			headerMatcher.Name = xdsHeader.GetName()
+		case *envoy_config_route_v3.HeaderMatcher_StringMatch:
+			convertStringMatch(spec.StringMatch, &headerMatcher)
+		default:
 			headerMatcher.Value = xdsHeader.GetExactMatch()
-			headerMatcher.Regex = false
 		}
 
 		// as pseudo headers not support when Http1.x upgrade to Http2, change pseudo headers to normal headers
@@ -193,6 +214,46 @@ func convertHeaders(xdsHeaders []*envoy_config_route_v3.HeaderMatcher) []v2.Head
 	return headerMatchers
 }
 
+func convertQueryParameters(xdsParams []*envoy_config_route_v3.QueryParameterMatcher) []v2.HeaderMatcher {
+	if xdsParams == nil {
+		return nil
+	}
+	paramMatchers := make([]v2.HeaderMatcher, 0, len(xdsParams))
+	for _, xdsParam := range xdsParams {
+		paramMatcher := v2.HeaderMatcher{
+			Name: xdsParam.GetName(),
+		}
+		switch spec := xdsParam.GetQueryParameterMatchSpecifier().(type) {
+		case *envoy_config_route_v3.QueryParameterMatcher_PresentMatch:
+			present := spec.PresentMatch
+			paramMatcher.PresentMatch = &present
+		case *envoy_config_route_v3.QueryParameterMatcher_StringMatch:
+			convertStringMatch(spec.StringMatch, &paramMatcher)
+		}
+		paramMatchers = append(paramMatchers, paramMatcher)
+	}
+	return paramMatchers
+}
+
+// convertStringMatch converts a StringMatcher to the value match of a v2.HeaderMatcher.
+// ignore case is not supported
+func convertStringMatch(sm *envoy_type_matcher_v3.StringMatcher, matcher *v2.HeaderMatcher) {
+	switch spec := sm.GetMatchPattern().(type) {
+	case *envoy_type_matcher_v3.StringMatcher_Exact:
+		matcher.Value = spec.Exact
+	case *envoy_type_matcher_v3.StringMatcher_Prefix:
+		matcher.PrefixMatch = spec.Prefix
+	case *envoy_type_matcher_v3.StringMatcher_Suffix:
+		matcher.SuffixMatch = spec.Suffix
+	case *envoy_type_matcher_v3.StringMatcher_SafeRegex:
+		matcher.Value = spec.SafeRegex.GetRegex()
+		matcher.Regex = true
+	case *envoy_type_matcher_v3.StringMatcher_Contains:
+		matcher.Value = regexp.QuoteMeta(spec.Contains)
+		matcher.Regex = true
+	}
+}
+
 func convertMeta(xdsMeta *envoy_config_core_v3.Metadata) api.Metadata {
 	if xdsMeta == nil {
 		return nil
@@ -428,6 +428,68 @@ func Test_convertHeaders(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "match modes",
+			args: args{
+				xdsHeaders: []*envoy_config_route_v3.HeaderMatcher{
+					{
+						Name: "prefix",
+						HeaderMatchSpecifier: &envoy_config_route_v3.HeaderMatcher_PrefixMatch{
+							PrefixMatch: "v1.",
+						},
+					},
+					{
+						Name: "suffix",
+						HeaderMatchSpecifier: &envoy_config_route_v3.HeaderMatcher_SuffixMatch{
+							SuffixMatch: ".com",
+						},
+						InvertMatch: true,
+					},
+					{
+						Name: "present",
+						HeaderMatchSpecifier: &envoy_config_route_v3.HeaderMatcher_PresentMatch{
+							PresentMatch: true,
+						},
+					},
+					{
+						Name: "range",
+						HeaderMatchSpecifier: &envoy_config_route_v3.HeaderMatcher_RangeMatch{
+							RangeMatch: &envoy_type_v3.Int64Range{Start: 1, End: 10},
+						},
+					},
+					{
+						Name: "contains",
+						HeaderMatchSpecifier: &envoy_config_route_v3.HeaderMatcher_ContainsMatch{
+							ContainsMatch: "a.b",
+						},
+					},
+				},
+			},
+			want: []v2.HeaderMatcher{
+				{
+					Name:        "prefix",
+					PrefixMatch: "v1.",
+				},
+				{
+					Name:        "suffix",
+					SuffixMatch: ".com",
+					InvertMatch: true,
+				},
+				{
+					Name:         "present",
+					PresentMatch: NewBool(true),
+				},
+				{
+					Name:       "range",
+					RangeMatch: &v2.Int64Range{Start: 1, End: 10},
+				},
+				{
+					Name:  "contains",
+					Value: `a\.b`,
+					Regex: true,
+				},
+			},
+		},
 	}
 
 	for _, tt := range tests {
@@ -439,6 +501,44 @@ func Test_convertHeaders(t *testing.T) {
 	}
 }
 
+func Test_convertQueryParameters(t *testing.T) {
+	got := convertQueryParameters([]*envoy_config_route_v3.QueryParameterMatcher{
+		{
+			Name: "version",
+			QueryParameterMatchSpecifier: &envoy_config_route_v3.QueryParameterMatcher_StringMatch{
+				StringMatch: &envoy_type_matcher_v3.StringMatcher{
+					MatchPattern: &envoy_type_matcher_v3.StringMatcher_Exact{
+						Exact: "beta",
+					},
+				},
+			},
+		},
+		{
+			Name: "debug",
+			QueryParameterMatchSpecifier: &envoy_config_route_v3.QueryParameterMatcher_PresentMatch{
+				PresentMatch: true,
+			},
+		},
+	})
+	want := []v2.HeaderMatcher{
+		{
+			Name:  "version",
+			Value: "beta",
+		},
+		{
+			Name:         "debug",
+			PresentMatch: NewBool(true),
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("convertQueryParameters() = %v, want %v", got, want)
+	}
+}
+
+func NewBool(val bool) *bool {
+	return &val
+}
+
 func NewBoolValue(val bool) *wrappers.BoolValue {
 	return &wrappers.BoolValue{
 		Value: val,
@@ -853,6 +953,30 @@ func Test_convertHashPolicy(t *testing.T) {
 	if !assert.NotNilf(t, hp[0].SourceIP, "hashPolicy SourceIP field should not be nil") {
 		t.FailNow()
 	}
+
+	xdsHashPolicy = []*envoy_config_route_v3.RouteAction_HashPolicy{
+		{
+			PolicySpecifier: &envoy_config_route_v3.RouteAction_HashPolicy_Header_{
+				Header: &envoy_config_route_v3.RouteAction_HashPolicy_Header{
+					HeaderName: "header_name",
+				},
+			},
+			Terminal: true,
+		},
+		{
+			PolicySpecifier: &envoy_config_route_v3.RouteAction_HashPolicy_ConnectionProperties_{
+				ConnectionProperties: &envoy_config_route_v3.RouteAction_HashPolicy_ConnectionProperties{
+					SourceIp: true,
+				},
+			},
+		},
+	}
+	hp = convertHashPolicy(xdsHashPolicy)
+	if !assert.Lenf(t, hp, 2, "hashPolicy should have 2 policies") {
+		t.FailNow()
+	}
+	assert.Truef(t, hp[0].Terminal, "hashPolicy Terminal field should be true")
+	assert.Falsef(t, hp[1].Terminal, "hashPolicy Terminal field should be false")
 }
 
 // Test stream filters convert for envoy.gzip
@@ -29,6 +29,7 @@ import (
 
 	"mosn.io/mosn/pkg/configmanager"
 	"mosn.io/mosn/pkg/metrics"
+	"mosn.io/mosn/pkg/router"
 )
 
 func TestKnownFeatures(t *testing.T) {
@@ -127,6 +128,44 @@ func TestStatsDumpWithParameters(t *testing.T) {
 }
 
 // Common Invalid Case
+func TestRuntimeFraction(t *testing.T) {
+	defer router.RemoveRuntimeFraction("canary")
+	// set
+	r := httptest.NewRequest("POST", "http://127.0.0.1/api/v1/runtime_fraction", bytes.NewBufferString(`{"runtime_key":"canary","value":30}`))
+	w := httptest.NewRecorder()
+	RuntimeFraction(w, r)
+	if w.Result().StatusCode != http.StatusOK {
+		t.Fatalf("set runtime fraction response status got %d", w.Result().StatusCode)
+	}
+	// invalid value
+	r = httptest.NewRequest("POST", "http://127.0.0.1/api/v1/runtime_fraction", bytes.NewBufferString(`{"runtime_key":"canary","value":101}`))
+	w = httptest.NewRecorder()
+	RuntimeFraction(w, r)
+	if w.Result().StatusCode != http.StatusBadRequest {
+		t.Fatalf("set invalid runtime fraction response status got %d", w.Result().StatusCode)
+	}
+	// list
+	r = httptest.NewRequest("GET", "http://127.0.0.1/api/v1/runtime_fraction", nil)
+	w = httptest.NewRecorder()
+	RuntimeFraction(w, r)
+	b, _ := ioutil.ReadAll(w.Body)
+	m := map[string]uint32{}
+	json.Unmarshal(b, &m)
+	if len(m) != 1 || m["canary"] != 30 {
+		t.Fatalf("list runtime fraction got %s", string(b))
+	}
+	// remove
+	r = httptest.NewRequest("DELETE", "http://127.0.0.1/api/v1/runtime_fraction?runtime_key=canary", nil)
+	w = httptest.NewRecorder()
+	RuntimeFraction(w, r)
+	if w.Result().StatusCode != http.StatusOK {
+		t.Fatalf("remove runtime fraction response status got %d", w.Result().StatusCode)
+	}
+	if len(router.GetRuntimeFractions()) != 0 {
+		t.Fatalf("runtime fraction is not removed")
+	}
+}
+
 func TestInvalidCommon(t *testing.T) {
 	teasCases := []struct {
 		Method             string
@@ -218,6 +257,18 @@ func TestInvalidCommon(t *testing.T) {
 			ExpectedStatusCode: http.StatusBadRequest,
 			Func:               GetEnv,
 		},
+		{
+			Method:             "PUT",
+			Url:                "http://127.0.0.1/api/v1/runtime_fraction",
+			ExpectedStatusCode: http.StatusMethodNotAllowed,
+			Func:               RuntimeFraction,
+		},
+		{
+			Method:             "DELETE",
+			Url:                "http://127.0.0.1/api/v1/runtime_fraction",
+			ExpectedStatusCode: http.StatusBadRequest,
+			Func:               RuntimeFraction,
+		},
 	}
 	for idx, tc := range teasCases {
 		r := httptest.NewRequest(tc.Method, tc.Url, nil)
@@ -34,6 +34,7 @@ import (
 	"mosn.io/mosn/pkg/metrics"
 	"mosn.io/mosn/pkg/metrics/sink/console"
 	"mosn.io/mosn/pkg/plugin"
+	"mosn.io/mosn/pkg/router"
 	"mosn.io/mosn/pkg/stagemanager"
 	"mosn.io/mosn/pkg/types"
 )
@@ -383,3 +384,58 @@ func GetEnv(w http.ResponseWriter, r *http.Request) {
 	data, _ := json.MarshalIndent(results, "", " ")
 	w.Write(data)
 }
+
+type RuntimeFractionData struct {
+	RuntimeKey string `json:"runtime_key"`
+	Value      uint32 `json:"value"`
+}
+
+// RuntimeFraction lists, sets or removes the runtime overrides of route runtime fractions
+// GET: list the overrides
+// POST: set an override, post data: {"runtime_key": "key", "value": 10}
+// DELETE: remove an override, query: ?runtime_key=key
+func RuntimeFraction(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		data, _ := json.MarshalIndent(router.GetRuntimeFractions(), "", " ")
+		w.Write(data)
+	case http.MethodPost:
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			log.DefaultLogger.Alertf(types.ErrorKeyAdmin, "api: %s, error: read body failed, %v", "runtime fraction", err)
+			w.WriteHeader(http.StatusBadRequest)
+			msg := fmt.Sprintf(errMsgFmt, "read body error")
+			fmt.Fprint(w, msg)
+			return
+		}
+		data := &RuntimeFractionData{}
+		if err = json.Unmarshal(body, data); err == nil {
+			if err = router.SetRuntimeFraction(data.RuntimeKey, data.Value); err == nil {
+				log.DefaultLogger.Infof("[admin api] [runtime fraction] set runtime fraction %s as %d", data.RuntimeKey, data.Value)
+				w.WriteHeader(http.StatusOK)
+				fmt.Fprint(w, "set runtime fraction success\n")
+				return
+			}
+		}
+		log.DefaultLogger.Alertf(types.ErrorKeyAdmin, "api: %s, set runtime fraction failed with bad request data: %s, error: %v", "runtime fraction", string(body), err)
+		w.WriteHeader(http.StatusBadRequest)
+		msg := fmt.Sprintf(errMsgFmt, "set runtime fraction failed")
+		fmt.Fprint(w, msg)
+	case http.MethodDelete:
+		r.ParseForm()
+		key := r.FormValue("runtime_key")
+		if key == "" {
+			log.DefaultLogger.Alertf(types.ErrorKeyAdmin, "api: %s no runtime key", "runtime fraction")
+			w.WriteHeader(http.StatusBadRequest)
+			msg := fmt.Sprintf(errMsgFmt, "no runtime key")
+			fmt.Fprint(w, msg)
+			return
+		}
+		router.RemoveRuntimeFraction(key)
+		log.DefaultLogger.Infof("[admin api] [runtime fraction] remove runtime fraction %s", key)
+		fmt.Fprint(w, "remove runtime fraction success\n")
+	default:
+		log.DefaultLogger.Alertf(types.ErrorKeyAdmin, "api: %s, error: invalid method: %s", "runtime fraction", r.Method)
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
@@ -53,19 +53,20 @@ func DeleteRegisteredAdminHandler(pattern string) {
 func init() {
 	// default admin api
 	apiHandlerStore = map[string]*APIHandler{
-		"/api/v1/version":         NewAPIHandler(OutputVersion),
-		"/api/v1/config_dump":     NewAPIHandler(ConfigDump),
-		"/api/v1/stats":           NewAPIHandler(StatsDump),
-		"/api/v1/stats_glob":      NewAPIHandler(StatsDumpProxyTotal),
-		"/api/v1/update_loglevel": NewAPIHandler(UpdateLogLevel),
-		"/api/v1/get_loglevel":    NewAPIHandler(GetLoggerInfo),
-		"/api/v1/enable_log":      NewAPIHandler(EnableLogger),
-		"/api/v1/disable_log":     NewAPIHandler(DisableLogger),
-		"/api/v1/states":          NewAPIHandler(GetState),
-		"/api/v1/plugin":          NewAPIHandler(PluginApi),
-		"/api/v1/features":        NewAPIHandler(KnownFeatures),
-		"/api/v1/env":             NewAPIHandler(GetEnv),
-		"/":                       NewAPIHandler(Help),
+		"/api/v1/version":          NewAPIHandler(OutputVersion),
+		"/api/v1/config_dump":      NewAPIHandler(ConfigDump),
+		"/api/v1/stats":            NewAPIHandler(StatsDump),
+		"/api/v1/stats_glob":       NewAPIHandler(StatsDumpProxyTotal),
+		"/api/v1/update_loglevel":  NewAPIHandler(UpdateLogLevel),
+		"/api/v1/get_loglevel":     NewAPIHandler(GetLoggerInfo),
+		"/api/v1/enable_log":       NewAPIHandler(EnableLogger),
+		"/api/v1/disable_log":      NewAPIHandler(DisableLogger),
+		"/api/v1/states":           NewAPIHandler(GetState),
+		"/api/v1/plugin":           NewAPIHandler(PluginApi),
+		"/api/v1/features":         NewAPIHandler(KnownFeatures),
+		"/api/v1/env":              NewAPIHandler(GetEnv),
+		"/api/v1/runtime_fraction": NewAPIHandler(RuntimeFraction),
+		"/":                        NewAPIHandler(Help),
 	}
 }
 
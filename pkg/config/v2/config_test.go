@@ -306,6 +306,73 @@ func TestSlowStartConfigParse(t *testing.T) {
 	assert.Zero(t, clusters[1].SlowStart.MinWeightPercent)
 }
 
+func TestOutlierDetectionConfigParse(t *testing.T) {
+	mosnConfig := `{
+		"cluster_manager": {
+			"clusters": [
+				{
+					"name": "cluster0",
+					"outlier_detection": {
+						"consecutive_5xx": 5,
+						"consecutive_connect_failure": 3,
+						"interval": "5s",
+						"base_ejection_time": "30s",
+						"max_ejection_percent": 50,
+						"success_rate_stdev_factor": 1.9
+					}
+				},
+				{
+					"name": "cluster1"
+				}
+			]
+		}
+	}`
+	testConfig := &MOSNConfig{}
+	if err := json.Unmarshal([]byte(mosnConfig), testConfig); err != nil {
+		t.Fatal(err)
+	}
+	// verify
+	clusters := testConfig.ClusterManager.Clusters
+	od := clusters[0].OutlierDetection
+	assert.NotNil(t, od)
+	assert.Equal(t, uint32(5), od.Consecutive5xx)
+	assert.Equal(t, uint32(0), od.ConsecutiveGatewayFailure)
+	assert.Equal(t, uint32(3), od.ConsecutiveConnectFailure)
+	assert.Equal(t, 5*time.Second, od.Interval.Duration)
+	assert.Equal(t, 30*time.Second, od.BaseEjectionTime.Duration)
+	assert.Nil(t, od.MaxEjectionTime)
+	assert.Equal(t, uint32(50), od.MaxEjectionPercent)
+	assert.Equal(t, 1.9, od.SuccessRateStdevFactor)
+	assert.Nil(t, clusters[1].OutlierDetection)
+}
+
+func TestHostPriorityConfigParse(t *testing.T) {
+	mosnConfig := `{
+		"cluster_manager": {
+			"clusters": [
+				{
+					"name": "cluster0",
+					"lbconfig": {
+						"overprovisioning_factor": 200
+					},
+					"hosts": [
+						{"address": "127.0.0.1:8080"},
+						{"address": "127.0.0.2:8080", "priority": 1}
+					]
+				}
+			]
+		}
+	}`
+	testConfig := &MOSNConfig{}
+	if err := json.Unmarshal([]byte(mosnConfig), testConfig); err != nil {
+		t.Fatal(err)
+	}
+	cluster := testConfig.ClusterManager.Clusters[0]
+	assert.Equal(t, uint32(200), cluster.LbConfig.OverprovisioningFactor)
+	assert.Equal(t, uint32(0), cluster.Hosts[0].Priority)
+	assert.Equal(t, uint32(1), cluster.Hosts[1].Priority)
+}
+
 var _iterJson = jsoniter.ConfigCompatibleWithStandardLibrary
 
 // test for config unmarshal with json-iterator and json (std lib)
@@ -59,7 +59,9 @@ type StreamDSL struct {
 
 // Listener Filter's Type
 const (
-	ORIGINALDST_LISTENER_FILTER = "original_dst"
+	ORIGINALDST_LISTENER_FILTER    = "original_dst"
+	PROXY_PROTOCOL_LISTENER_FILTER = "proxy_protocol"
+	TLS_INSPECTOR_LISTENER_FILTER  = "tls_inspector"
 )
 
 type FaultToleranceFilterConfig struct {
@@ -384,6 +384,67 @@ func TestRetryPolicyUnmarshal(t *testing.T) {
 	}
 }
 
+func TestRetryPolicyConditions(t *testing.T) {
+	cfgStr := `{
+		"retry_on": ["5xx", "connect-failure"],
+		"num_retries": 2,
+		"retry_budget": {
+			"budget_percent": 30,
+			"min_retry_concurrency": 5
+		},
+		"retry_back_off": {
+			"base_interval": "10ms",
+			"max_interval": "1s"
+		},
+		"retry_host_predicate": [
+			{"name": "previous_hosts"},
+			{"name": "previous_localities", "metadata_keys": ["zone"]}
+		],
+		"host_selection_retry_max_attempts": 3,
+		"hedge_on_per_try_timeout": true
+	}`
+	p := &RetryPolicy{}
+	if err := json.Unmarshal([]byte(cfgStr), p); err != nil {
+		t.Fatal(err)
+	}
+	if !(p.RetryOn &&
+		len(p.RetryOnConditions) == 2 &&
+		p.RetryOnConditions[0] == RetryOn5xx &&
+		p.RetryOnConditions[1] == RetryOnConnectFailure &&
+		p.NumRetries == 2 &&
+		p.RetryBudget.BudgetPercent == 30 &&
+		p.RetryBudget.MinRetryConcurrency == 5 &&
+		p.RetryBackOff.BaseInterval.Duration == 10*time.Millisecond &&
+		p.RetryBackOff.MaxInterval.Duration == time.Second &&
+		len(p.RetryHostPredicates) == 2 &&
+		p.RetryHostPredicates[0].Name == RetryHostPreviousHosts &&
+		p.RetryHostPredicates[1].Name == RetryHostPreviousLocalities &&
+		reflect.DeepEqual(p.RetryHostPredicates[1].MetadataKeys, []string{"zone"}) &&
+		p.HostSelectionRetryMaxAttempts == 3 &&
+		p.HedgeOnPerTryTimeout) {
+		t.Errorf("unmarshal unexpected %v", p)
+	}
+	b, err := json.Marshal(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	np := &RetryPolicy{}
+	if err := json.Unmarshal(b, np); err != nil {
+		t.Fatal(err)
+	}
+	if !(np.RetryOn && len(np.RetryOnConditions) == 2 && np.RetryBudget != nil && np.RetryBackOff != nil) {
+		t.Errorf("marshal and unmarshal not equal: %s", string(b))
+	}
+	// invalid retry_on
+	if err := json.Unmarshal([]byte(`{"retry_on": 1}`), &RetryPolicy{}); err == nil {
+		t.Error("expected an error for invalid retry_on")
+	}
+	// unknown retry_on condition
+	if err := json.Unmarshal([]byte(`{"retry_on": ["5xx", "connect_failure"]}`), &RetryPolicy{}); err == nil {
+		t.Error("expected an error for unknown retry_on condition")
+	}
+}
+
 func TestCircuitBreakersMarshal(t *testing.T) {
 	cb := &CircuitBreakers{
 		Thresholds: []Thresholds{
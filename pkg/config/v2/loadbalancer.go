@@ -29,18 +29,53 @@ type LbConfig struct {
 	// The larger the active request bias is, the more aggressively active requests
 	// will lower the effective weight when all host weights are not equal.
 	ActiveRequestBias float64 `json:"active_request_bias,omitempty"`
+
+	// The overprovisioning factor in percent when hosts have different priorities,
+	// a priority level receives all the traffic while its healthy hosts ratio multiply
+	// the factor is not less than 100%. The default value is 140.
+	OverprovisioningFactor uint32 `json:"overprovisioning_factor,omitempty"`
+
+	// The metadata keys that identify the locality of a host, such as ["region", "zone"].
+	// The locality aware load balancing is enabled if it is not empty.
+	LocalityKeys []string `json:"locality_keys,omitempty"`
+	// The locality of the local node, keyed by the locality keys.
+	// If it is not configured, the service labels and the pod labels are used.
+	LocalLocality map[string]string `json:"local_locality,omitempty"`
+	// The locality aware load balancing is disabled if the hosts number is less than it.
+	// The default value is 6.
+	LocalityMinClusterSize uint32 `json:"locality_min_cluster_size,omitempty"`
+	// The cluster of the local service instances, its healthy hosts in each locality are compared
+	// with the healthy upstream hosts to decide how many requests stay in the local locality.
+	// If it is not configured, the instances are expected to be distributed as the upstream hosts.
+	LocalClusterName string `json:"local_cluster_name,omitempty"`
+
+	// The min and max entries number of the ring in LB_RING_HASH.
+	// The default values are 1024 and 8M.
+	MinimumRingSize uint64 `json:"minimum_ring_size,omitempty"`
+	MaximumRingSize uint64 `json:"maximum_ring_size,omitempty"`
+	// The hash balance factor in percent for the consistent hashing with bounded loads.
+	// A host is skipped if its active requests are more than the factor multiply the average
+	// active requests of all the hosts. It should be greater than 100, 0 means unbounded.
+	HashBalanceFactor uint32 `json:"hash_balance_factor,omitempty"`
 }
 
+// HashPolicy generates the hash for the hash based load balancers.
+// If several hash policies are configured, the hashes are combined in order,
+// and the policies after a Terminal policy that generates a hash are skipped.
 type HashPolicy struct {
 	Header   *HeaderHashPolicy   `json:"header,omitempty"`
 	Cookie   *CookieHashPolicy   `json:"cookie,omitempty"`
 	SourceIP *SourceIPHashPolicy `json:"source_ip,omitempty"`
+	Terminal bool                `json:"terminal,omitempty"`
 }
 
 type HeaderHashPolicy struct {
 	Key string `json:"key,omitempty"`
 }
 
+// CookieHashPolicy hashes the cookie value of the Name.
+// If the cookie is not found and the TTL is set, a cookie is generated to hash,
+// and it is set on the response with the Path and TTL.
 type CookieHashPolicy struct {
 	Name string             `json:"name,omitempty"`
 	Path string             `json:"path,omitempty"`
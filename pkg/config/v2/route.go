@@ -70,6 +70,10 @@ type RouterActionConfig struct {
 	RequestHeadersToRemove  []string             `json:"request_headers_to_remove,omitempty"`
 	ResponseHeadersToAdd    []*HeaderValueOption `json:"response_headers_to_add,omitempty"`
 	ResponseHeadersToRemove []string             `json:"response_headers_to_remove,omitempty"`
+	// PathTemplateRewrite rebuilds the path from the captures of the path template, like /v2/orders/{orderId}
+	PathTemplateRewrite string `json:"path_template_rewrite,omitempty"`
+	// StickyCookie keeps a client on the cluster chosen from the weighted clusters at the first request
+	StickyCookie *StickyCookie `json:"sticky_cookie,omitempty"`
 }
 
 type ClusterWeightConfig struct {
@@ -79,10 +83,74 @@ type ClusterWeightConfig struct {
 }
 
 type RetryPolicyConfig struct {
-	RetryOn            bool               `json:"retry_on,omitempty"`
+	RetryOn bool `json:"retry_on,omitempty"`
+	// RetryOnConditions is configured by retry_on as a list, such as ["5xx", "connect-failure"].
+	// If it is not empty, the request is retried only if one of the conditions is matched.
+	RetryOnConditions  []string           `json:"-"`
 	RetryTimeoutConfig api.DurationConfig `json:"retry_timeout,omitempty"`
 	NumRetries         uint32             `json:"num_retries,omitempty"`
 	StatusCodes        []uint32           `json:"status_codes,omitempty"`
+	RetryBudget        *RetryBudget       `json:"retry_budget,omitempty"`
+	RetryBackOff       *RetryBackOff      `json:"retry_back_off,omitempty"`
+	// RetryHostPredicates decide whether a host chosen by the load balancer should be skipped in the retries.
+	RetryHostPredicates []*RetryHostPredicate `json:"retry_host_predicate,omitempty"`
+	// HostSelectionRetryMaxAttempts is the max times to choose a host again if the chosen host is skipped
+	// by the predicates, default is 1.
+	HostSelectionRetryMaxAttempts uint32 `json:"host_selection_retry_max_attempts,omitempty"`
+	// HedgeOnPerTryTimeout sends a new request to retry when the per try timeout fires without resetting the
+	// previous requests, the first received response is used and the others are reset.
+	HedgeOnPerTryTimeout bool `json:"hedge_on_per_try_timeout,omitempty"`
+}
+
+// The retry conditions in retry_on
+const (
+	RetryOnConnectFailure       = "connect-failure"
+	RetryOnReset                = "reset"
+	RetryOn5xx                  = "5xx"
+	RetryOnGatewayError         = "gateway-error"
+	RetryOnRetriableStatusCodes = "retriable-status-codes"
+	RetryOnPerTryTimeout        = "per-try-timeout"
+)
+
+func isValidRetryOnCondition(condition string) bool {
+	switch condition {
+	case RetryOnConnectFailure, RetryOnReset, RetryOn5xx, RetryOnGatewayError,
+		RetryOnRetriableStatusCodes, RetryOnPerTryTimeout:
+		return true
+	}
+	return false
+}
+
+// RetryBudget limits the concurrent retries of a cluster by the active requests.
+type RetryBudget struct {
+	// BudgetPercent is the max active retries as a percentage of the active requests, default is 20.
+	BudgetPercent float64 `json:"budget_percent,omitempty"`
+	// MinRetryConcurrency is the active retries allowed regardless of the budget percent, default is 3.
+	MinRetryConcurrency uint32 `json:"min_retry_concurrency,omitempty"`
+}
+
+// RetryBackOff is the exponential back off with jitter between the retries.
+type RetryBackOff struct {
+	// BaseInterval is the base interval between the retries, default is 25ms.
+	BaseInterval *api.DurationConfig `json:"base_interval,omitempty"`
+	// MaxInterval is the max interval between the retries, default is 10 times of the base interval.
+	MaxInterval *api.DurationConfig `json:"max_interval,omitempty"`
+}
+
+// The names of the retry host predicates
+const (
+	// RetryHostPreviousHosts skips the hosts that have been attempted
+	RetryHostPreviousHosts = "previous_hosts"
+	// RetryHostPreviousLocalities skips the hosts in the same locality as the attempted hosts
+	RetryHostPreviousLocalities = "previous_localities"
+)
+
+// RetryHostPredicate represents a retry host predicate
+type RetryHostPredicate struct {
+	Name string `json:"name,omitempty"`
+	// MetadataKeys are the host metadata keys that make up the locality in previous_localities,
+	// the cluster's locality keys are used if it is empty.
+	MetadataKeys []string `json:"metadata_keys,omitempty"`
 }
 
 // RegexRewrite represents the regex rewrite parameters
@@ -176,13 +244,44 @@ type RetryPolicy struct {
 
 func (rp RetryPolicy) MarshalJSON() (b []byte, err error) {
 	rp.RetryPolicyConfig.RetryTimeoutConfig.Duration = rp.RetryTimeout
-	return json.Marshal(rp.RetryPolicyConfig)
+	// retry_on is marshaled as a list if the conditions are configured
+	cfg := struct {
+		RetryPolicyConfig
+		RetryOn interface{} `json:"retry_on,omitempty"`
+	}{
+		RetryPolicyConfig: rp.RetryPolicyConfig,
+	}
+	if len(rp.RetryOnConditions) > 0 {
+		cfg.RetryOn = rp.RetryOnConditions
+	} else if rp.RetryOn {
+		cfg.RetryOn = true
+	}
+	return json.Marshal(cfg)
 }
 
 func (rp *RetryPolicy) UnmarshalJSON(b []byte) error {
-	if err := json.Unmarshal(b, &rp.RetryPolicyConfig); err != nil {
+	// retry_on can be a bool or a list of conditions
+	cfg := struct {
+		RetryPolicyConfig
+		RetryOn json.RawMessage `json:"retry_on,omitempty"`
+	}{}
+	if err := json.Unmarshal(b, &cfg); err != nil {
 		return err
 	}
+	rp.RetryPolicyConfig = cfg.RetryPolicyConfig
+	if len(cfg.RetryOn) > 0 {
+		if err := json.Unmarshal(cfg.RetryOn, &rp.RetryOn); err != nil {
+			if err := json.Unmarshal(cfg.RetryOn, &rp.RetryOnConditions); err != nil {
+				return fmt.Errorf("retry_on should be a bool or a list of conditions: %s", string(cfg.RetryOn))
+			}
+			for _, condition := range rp.RetryOnConditions {
+				if !isValidRetryOnCondition(condition) {
+					return fmt.Errorf("unknown retry_on condition: %s", condition)
+				}
+			}
+			rp.RetryOn = len(rp.RetryOnConditions) > 0
+		}
+	}
 	rp.RetryTimeout = rp.RetryTimeoutConfig.Duration
 	return nil
 }
@@ -307,6 +406,22 @@ type RouterMatch struct {
 	Headers        []HeaderMatcher        `json:"headers,omitempty"`   // Match request's Headers
 	Variables      []VariableMatcher      `json:"variables,omitempty"` // Match request's variable
 	DslExpressions []DslExpressionMatcher `json:"dsl_expressions,omitempty"`
+	// QueryParameters matches request's query parameters, uses the same match modes as Headers
+	QueryParameters []HeaderMatcher `json:"query_parameters,omitempty"`
+	// PathTemplate matches request's Path with a template like /users/{id}/orders/{orderId}
+	PathTemplate string `json:"path_template,omitempty"`
+	// RuntimeFraction matches a fraction of requests, the fraction can be changed at runtime
+	RuntimeFraction *RuntimeFraction `json:"runtime_fraction,omitempty"`
+}
+
+// RuntimeFraction specifies the percentage of requests that the route should match on.
+// The percentage can be overridden at runtime by the RuntimeKey.
+// If HashHeader is set, the requests with the same header value are always matched or not matched together,
+// otherwise the requests are picked randomly.
+type RuntimeFraction struct {
+	RuntimeKey   string `json:"runtime_key,omitempty"`
+	DefaultValue uint32 `json:"default_value,omitempty"` // percentage in [0, 100]
+	HashHeader   string `json:"hash_header,omitempty"`
 }
 
 // RedirectAction represents the redirect response parameters
@@ -331,11 +446,39 @@ type WeightedCluster struct {
 	Cluster ClusterWeight `json:"cluster,omitempty"`
 }
 
+// StickyCookie makes the weighted clusters sticky.
+// The cluster chosen at the first request is set in a signed cookie with the Name, Path and TTL,
+// and the later requests with the cookie are routed to the same cluster while it is still in the weighted clusters.
+// A zero TTL makes a session cookie. Secret is used to sign the cookie and should always be configured,
+// if it is empty, a random secret is generated with a warning, so the cookies are not valid across the
+// restarts or the instances.
+type StickyCookie struct {
+	CookieHashPolicy
+	Secret string `json:"secret,omitempty"`
+}
+
 // HeaderMatcher specifies a set of headers that the route should match on.
+// Value is matched exactly, or as a regex if Regex is true.
+// At most one of PresentMatch, RangeMatch, PrefixMatch and SuffixMatch should be set,
+// if any of them is set, Value and Regex are ignored.
+// If InvertMatch is true, the match result is inverted, but a missing key never matches
+// unless PresentMatch is used.
 type HeaderMatcher struct {
 	Name  string `json:"name,omitempty"`
 	Value string `json:"value,omitempty"`
 	Regex bool   `json:"regex,omitempty"`
+	// PresentMatch matches if the key is present (true) or absent (false), the value is ignored
+	PresentMatch *bool       `json:"present_match,omitempty"`
+	RangeMatch   *Int64Range `json:"range_match,omitempty"`
+	PrefixMatch  string      `json:"prefix_match,omitempty"`
+	SuffixMatch  string      `json:"suffix_match,omitempty"`
+	InvertMatch  bool        `json:"invert_match,omitempty"`
+}
+
+// Int64Range specifies the int64 range [Start, End)
+type Int64Range struct {
+	Start int64 `json:"start"`
+	End   int64 `json:"end"`
 }
 
 // VariableMatcher specifies a set of variables that the route should match on.
@@ -364,6 +507,11 @@ type StreamRoute struct {
 	DestinationAddrs []CidrRange
 	SourcePort       string
 	DestinationPort  string
+	// ServerNames matches the SNI of the downstream connection, supports
+	// exact names and wildcard names such as "*.example.com".
+	// If any route has server names, the routes are checked before the cluster
+	// of the stream proxy, and the cluster becomes the default route.
+	ServerNames []string `json:"server_names,omitempty"`
 }
 
 // CidrRange ...
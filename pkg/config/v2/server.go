@@ -67,7 +67,7 @@ type ListenerConfig struct {
 	OriginalDst           OriginalDstType     `json:"use_original_dst,omitempty"`
 	AccessLogs            []AccessLog         `json:"access_logs,omitempty"`
 	ListenerFilters       []Filter            `json:"listener_filters,omitempty"`
-	FilterChains          []FilterChain       `json:"filter_chains,omitempty"` // only one filterchains at this time
+	FilterChains          []FilterChain       `json:"filter_chains,omitempty"` // the filter chain is selected by the filter_chain_match
 	StreamFilters         []Filter            `json:"stream_filters,omitempty"`
 	Inspector             bool                `json:"inspector,omitempty"`
 	ConnectionIdleTimeout *api.DurationConfig `json:"connection_idle_timeout,omitempty"`
@@ -192,8 +192,32 @@ type Filter struct {
 }
 
 type FilterChainConfig struct {
-	FilterChainMatch string      `json:"match,omitempty"`
-	TLSConfig        *TLSConfig  `json:"tls_context,omitempty"`
-	TLSConfigs       []TLSConfig `json:"tls_context_set,omitempty"`
-	Filters          []Filter    `json:"filters,omitempty"`
+	// Deprecated: FilterChainMatch is not used to select the filter chain, use Match instead
+	FilterChainMatch string            `json:"match,omitempty"`
+	TLSConfig        *TLSConfig        `json:"tls_context,omitempty"`
+	TLSConfigs       []TLSConfig       `json:"tls_context_set,omitempty"`
+	Filters          []Filter          `json:"filters,omitempty"`
+	Match            *FilterChainMatch `json:"filter_chain_match,omitempty"`
+}
+
+// Transport protocols of the connection, the tls is detected by the tls inspector listener filter
+const (
+	TransportProtocolTLS       = "tls"
+	TransportProtocolRawBuffer = "raw_buffer"
+)
+
+// FilterChainMatch is the criteria to select the filter chain of a connection.
+// An empty criteria matches any connection, and the most specific criteria is preferred
+// if multiple filter chains are matched.
+type FilterChainMatch struct {
+	// ServerNames matches the server name indicated by the tls client, supports the wildcard such as *.example.com
+	ServerNames []string `json:"server_names,omitempty"`
+	// TransportProtocol matches the transport protocol, tls or raw_buffer
+	TransportProtocol string `json:"transport_protocol,omitempty"`
+	// ApplicationProtocols matches any of the alpn protocols offered by the tls client
+	ApplicationProtocols []string `json:"application_protocols,omitempty"`
+	// DestinationPort matches the local port, or the restored local port of the connection
+	DestinationPort uint32 `json:"destination_port,omitempty"`
+	// SourcePrefixRanges matches the remote address in the CIDR ranges, such as 10.0.0.0/8
+	SourcePrefixRanges []string `json:"source_prefix_ranges,omitempty"`
 }
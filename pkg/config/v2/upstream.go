@@ -51,6 +51,7 @@ type HostConfig struct {
 	Weight         uint32          `json:"weight,omitempty"`
 	MetaDataConfig *MetadataConfig `json:"metadata,omitempty"`
 	TLSDisable     bool            `json:"tls_disable,omitempty"`
+	Priority       uint32          `json:"priority,omitempty"`
 }
 
 // ClusterType
@@ -58,12 +59,16 @@ type ClusterType string
 
 // Group of cluster type
 const (
-	SIMPLE_CLUSTER      ClusterType = "SIMPLE"
-	STATIC_CLUSTER      ClusterType = "STATIC"
-	DYNAMIC_CLUSTER     ClusterType = "DYNAMIC"
-	EDS_CLUSTER         ClusterType = "EDS"
-	ORIGINALDST_CLUSTER ClusterType = "ORIGINAL_DST"
-	STRICT_DNS_CLUSTER  ClusterType = "STRICT_DNS"
+	SIMPLE_CLUSTER       ClusterType = "SIMPLE"
+	STATIC_CLUSTER       ClusterType = "STATIC"
+	DYNAMIC_CLUSTER      ClusterType = "DYNAMIC"
+	EDS_CLUSTER          ClusterType = "EDS"
+	ORIGINALDST_CLUSTER  ClusterType = "ORIGINAL_DST"
+	STRICT_DNS_CLUSTER   ClusterType = "STRICT_DNS"
+	LOGICAL_DNS_CLUSTER  ClusterType = "LOGICAL_DNS"
+	FILE_EDS_CLUSTER     ClusterType = "FILE_EDS"
+	AGGREGATE_CLUSTER    ClusterType = "AGGREGATE"
+	HTTP_POLLING_CLUSTER ClusterType = "HTTP_POLLING"
 )
 
 // LbType
@@ -76,6 +81,7 @@ const (
 	LB_ORIGINAL_DST  LbType = "LB_ORIGINAL_DST"
 	LB_LEAST_REQUEST LbType = "LB_LEAST_REQUEST"
 	LB_MAGLEV        LbType = "LB_MAGLEV"
+	LB_RING_HASH     LbType = "LB_RING_HASH"
 )
 
 type DnsLookupFamily string
@@ -113,6 +119,63 @@ type Cluster struct {
 	DnsResolverPort      string              `json:"dns_resolver_port,omitempty"`
 	SlowStart            SlowStartConfig     `json:"slow_start,omitempty"`
 	ClusterPoolEnable    bool                `json:"cluster_pool_enable,omitempty"`
+	OutlierDetection     *OutlierDetection   `json:"outlier_detection,omitempty"`
+	// HealthyPanicThreshold is a percent, if the healthy hosts ratio is less than it,
+	// the load balancer ignores the hosts health. 0 means disabled.
+	HealthyPanicThreshold uint32 `json:"healthy_panic_threshold,omitempty"`
+	// EdsFilePath is the json or yaml file of the hosts used by the FILE_EDS cluster,
+	// all the files in it are loaded if it is a directory.
+	EdsFilePath string `json:"eds_file_path,omitempty"`
+	// AggregateClusters are the names of the clusters that the AGGREGATE cluster balances across,
+	// a cluster in the front is preferred.
+	AggregateClusters []string `json:"aggregate_clusters,omitempty"`
+	// HttpDiscovery is the configuration of the HTTP_POLLING cluster
+	HttpDiscovery *HttpDiscovery `json:"http_discovery,omitempty"`
+	// MinConnectionsPerHost is the connections kept in the connection pools of each healthy host
+	// when the prewarm is configured, default is 1. The http2 pools keep the extra connections
+	// as the spares of the active one, and the binding pools keep the connections that are not bound yet.
+	MinConnectionsPerHost uint32 `json:"min_connections_per_host,omitempty"`
+	// Prewarm establishes the connections to the hosts before the requests come
+	Prewarm *PrewarmConfig `json:"prewarm,omitempty"`
+	// MaxConnectionDuration is the max lifetime of the upstream connections, the connections
+	// are retired gracefully when they are expired. 0 means no limit.
+	MaxConnectionDuration *api.DurationConfig `json:"max_connection_duration,omitempty"`
+	// ProxyProtocol sends the PROXY protocol header with the downstream addresses to the upstream hosts,
+	// the connection pools are owned by the downstream connections, so the prewarm is ignored.
+	ProxyProtocol *ProxyProtocolConfig `json:"proxy_protocol,omitempty"`
+}
+
+// PrewarmConfig is a configuration of the connection pool pre-warming.
+// The connections are established when the cluster is created or the hosts are added,
+// and are checked periodically to keep at the MinConnectionsPerHost.
+type PrewarmConfig struct {
+	// Protocols are the protocols of the connection pools to be prewarmed
+	Protocols []api.ProtocolName `json:"protocols,omitempty"`
+	// Interval is the interval of the connections check, default is 5s
+	Interval *api.DurationConfig `json:"interval,omitempty"`
+}
+
+// PROXY protocol versions
+const (
+	ProxyProtocolV1 = "v1"
+	ProxyProtocolV2 = "v2"
+)
+
+// PROXY protocol v2 TLVs sent to the upstream hosts.
+// Both sni and authority are sent as the PP2_TYPE_AUTHORITY, the request authority is preferred.
+const (
+	ProxyProtocolTLVAlpn      = "alpn"
+	ProxyProtocolTLVSni       = "sni"
+	ProxyProtocolTLVAuthority = "authority"
+)
+
+// ProxyProtocolConfig is a configuration of the PROXY protocol header sent to the upstream hosts.
+// The header is written once the upstream connection is established, before the tls handshake.
+type ProxyProtocolConfig struct {
+	// Version is the PROXY protocol version, v1 or v2, default is v1
+	Version string `json:"version,omitempty"`
+	// TLVs are the type-length-values added to the v2 header, supports alpn, sni and authority
+	TLVs []string `json:"tlvs,omitempty"`
 }
 
 type DnsResolverConfig struct {
@@ -131,6 +194,53 @@ type SlowStartConfig struct {
 	MinWeightPercent  float64             `json:"min_weight_percent,omitempty"`
 }
 
+// OutlierDetection is a configuration of passive health check.
+// The consecutive thresholds are disabled if they are zero,
+// and the success rate detection is disabled if SuccessRateStdevFactor is zero.
+type OutlierDetection struct {
+	Consecutive5xx            uint32              `json:"consecutive_5xx,omitempty"`
+	ConsecutiveGatewayFailure uint32              `json:"consecutive_gateway_failure,omitempty"`
+	ConsecutiveConnectFailure uint32              `json:"consecutive_connect_failure,omitempty"`
+	Interval                  *api.DurationConfig `json:"interval,omitempty"`
+	BaseEjectionTime          *api.DurationConfig `json:"base_ejection_time,omitempty"`
+	MaxEjectionTime           *api.DurationConfig `json:"max_ejection_time,omitempty"`
+	MaxEjectionPercent        uint32              `json:"max_ejection_percent,omitempty"`
+	SuccessRateMinimumHosts   uint32              `json:"success_rate_minimum_hosts,omitempty"`
+	SuccessRateRequestVolume  uint32              `json:"success_rate_request_volume,omitempty"`
+	SuccessRateStdevFactor    float64             `json:"success_rate_stdev_factor,omitempty"`
+}
+
+// HttpDiscovery is a configuration of the cluster that polls the hosts from a http url.
+// The response is a json, the hosts are mapped by the Mapping.
+type HttpDiscovery struct {
+	URL     string            `json:"url,omitempty"`
+	Headers map[string]string `json:"headers,omitempty"`
+	// Interval is the polling interval, default is 5s
+	Interval *api.DurationConfig `json:"interval,omitempty"`
+	// Timeout is the timeout of a polling request, default is 3s
+	Timeout *api.DurationConfig `json:"timeout,omitempty"`
+	// MaxBackOff is the max interval when the polling is failed continuously, default is 10 times of the interval
+	MaxBackOff *api.DurationConfig  `json:"max_back_off,omitempty"`
+	Mapping    HttpDiscoveryMapping `json:"mapping,omitempty"`
+}
+
+// HttpDiscoveryMapping maps the fields in the http discovery response to the hosts.
+// The fields are dot separated paths, such as "data.instances",
+// and the list elements are indexed by the number, such as "groups.0.instances".
+type HttpDiscoveryMapping struct {
+	// Hosts is the path of the hosts list in the response, empty means the response is the list
+	Hosts string `json:"hosts,omitempty"`
+	// Address is the field of the host address in "ip:port" format, default is "address".
+	// It is ignored if IP and Port are configured.
+	Address  string `json:"address,omitempty"`
+	IP       string `json:"ip,omitempty"`
+	Port     string `json:"port,omitempty"`
+	Hostname string `json:"hostname,omitempty"`
+	Weight   string `json:"weight,omitempty"`
+	// Metadata is the field of the host metadata, which is a map of strings
+	Metadata string `json:"metadata,omitempty"`
+}
+
 // HealthCheck is a configuration of health check
 // use DurationConfig to parse string to time.Duration
 type HealthCheck struct {
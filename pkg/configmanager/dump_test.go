@@ -21,6 +21,7 @@ import (
 	"io/ioutil"
 	"net"
 	"os"
+	"strings"
 	"testing"
 
 	v2 "mosn.io/mosn/pkg/config/v2"
@@ -171,3 +172,62 @@ func TestYamlConfigDump(t *testing.T) {
 		t.Fatalf("json to yaml is not expected: %+v", content)
 	}
 }
+
+func TestDumpMultipleFilterChains(t *testing.T) {
+	Reset()
+	// mock config path
+	configPath = "/tmp/dump_test/filter_chains/mosn.json"
+	os.RemoveAll("/tmp/dump_test/filter_chains")
+	os.MkdirAll("/tmp/dump_test/filter_chains", 0755)
+	addr, _ := net.ResolveTCPAddr("tcp", "127.0.0.1:8080")
+	listener := v2.Listener{
+		ListenerConfig: v2.ListenerConfig{
+			Name:       "listener",
+			BindToPort: true,
+			FilterChains: []v2.FilterChain{
+				{
+					FilterChainConfig: v2.FilterChainConfig{
+						Match: &v2.FilterChainMatch{
+							ServerNames:       []string{"*.example.com"},
+							TransportProtocol: v2.TransportProtocolTLS,
+						},
+						Filters: []v2.Filter{
+							{Type: "tls_proxy"},
+						},
+					},
+				},
+				{
+					FilterChainConfig: v2.FilterChainConfig{
+						Filters: []v2.Filter{
+							{Type: "default_proxy"},
+						},
+					},
+				},
+			},
+		},
+		Addr: addr,
+	}
+	SetListenerConfig(listener)
+	setDump()
+	DumpConfig()
+	// the filter chains are dumped in order, with the match of every filter chain
+	mosnConfig := Load(configPath)
+	chains := mosnConfig.Servers[0].Listeners[0].FilterChains
+	if len(chains) != 2 {
+		content, _ := ioutil.ReadFile(configPath)
+		t.Fatalf("filter chains dumped invalid: %s", content)
+	}
+	match := chains[0].Match
+	if match == nil || len(match.ServerNames) != 1 || match.ServerNames[0] != "*.example.com" ||
+		match.TransportProtocol != v2.TransportProtocolTLS || chains[0].Filters[0].Type != "tls_proxy" {
+		t.Fatalf("the first filter chain is invalid: %+v", chains[0])
+	}
+	if chains[1].Match != nil || chains[1].Filters[0].Type != "default_proxy" {
+		t.Fatalf("the second filter chain is invalid: %+v", chains[1])
+	}
+	// the match is dumped as filter_chain_match, the deprecated match string is omitted
+	content, _ := ioutil.ReadFile(configPath)
+	if !strings.Contains(string(content), `"filter_chain_match"`) || strings.Contains(string(content), `"match"`) {
+		t.Fatalf("filter chain match dumped invalid: %s", content)
+	}
+}
@@ -157,11 +157,34 @@ func SetClusterManagerTLS(tls v2.TLSConfig) {
 	tryDump()
 }
 
-// DumpJSON marshals the effectiveConfig to bytes
+// clusterStatesGetter returns the runtime states of the clusters, such as the healthy hosts
+// of the priority levels, which are not stored in the effectiveConfig
+var clusterStatesGetter func() interface{}
+
+// RegisterClusterStates registers the getter of the cluster states in the config dump
+func RegisterClusterStates(f func() interface{}) {
+	clusterStatesGetter = f
+}
+
+// DumpJSON marshals the effectiveConfig and the cluster states to bytes
 func DumpJSON() ([]byte, error) {
+	var states interface{}
+	// the states are got without the config lock, as the cluster manager updates the config with it
+	if clusterStatesGetter != nil {
+		states = clusterStatesGetter()
+	}
 	configLock.RLock()
 	defer configLock.RUnlock()
-	return json.Marshal(conf)
+	if states == nil {
+		return json.Marshal(conf)
+	}
+	return json.Marshal(struct {
+		effectiveConfig
+		ClusterStates interface{} `json:"cluster_states,omitempty"`
+	}{
+		effectiveConfig: conf,
+		ClusterStates:   states,
+	})
 }
 
 const (
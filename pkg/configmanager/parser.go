@@ -402,17 +402,50 @@ func GetListenerFilterFactories(listenerName string) []api.ListenerFilterChainFa
 	return nil
 }
 
+// networkFilterFactoryMap stores the network filter factories of every filter chain in the listener
 var networkFilterFactoryMap = sync.Map{}
 
-// AddOrUpdateNetworkFilterFactories adds or updates the network filter factories of a listener
+// AddOrUpdateNetworkFilterFactories adds or updates the network filter factories of a listener,
+// returns the network filter factories of the first filter chain
 func AddOrUpdateNetworkFilterFactories(listenerName string, ln *v2.Listener) []api.NetworkFilterChainFactory {
+	chains := AddOrUpdateFilterChainsNetworkFilterFactories(listenerName, ln)
+	if len(chains) == 0 {
+		return nil
+	}
+	return chains[0]
+}
+
+// AddOrUpdateFilterChainsNetworkFilterFactories adds or updates the network filter factories of a listener,
+// returns the network filter factories of every filter chain, in the order of the filter chains
+func AddOrUpdateFilterChainsNetworkFilterFactories(listenerName string, ln *v2.Listener) [][]api.NetworkFilterChainFactory {
 	if ln == nil || listenerName == "" {
 		log.DefaultLogger.Errorf("[config] network filter create failed, error: nil listener or empty name")
 		return nil
 	}
+	if len(ln.FilterChains) == 0 {
+		log.DefaultLogger.Errorf("[config] network filter create failed, error: no filter chains, listener: %s", listenerName)
+		return nil
+	}
+
+	chains := make([][]api.NetworkFilterChainFactory, len(ln.FilterChains))
+	for i := range ln.FilterChains {
+		chains[i] = CreateNetworkFilterFactories(ln, &ln.FilterChains[i])
+		if len(chains[i]) == 0 {
+			log.DefaultLogger.Errorf("[config] network filter factories len is 0, listener: %s, filter chain: %d", listenerName, i)
+		}
+	}
+
+	networkFilterFactoryMap.Store(listenerName, chains)
+	if log.DefaultLogger.GetLogLevel() >= log.DEBUG {
+		log.DefaultLogger.Debugf("[config] AddOrUpdateNetworkFilterFactories store network filter factories, name: %v", listenerName)
+	}
 
+	return chains
+}
+
+// CreateNetworkFilterFactories creates the network filter factories of a filter chain in the listener
+func CreateNetworkFilterFactories(ln *v2.Listener, c *v2.FilterChain) []api.NetworkFilterChainFactory {
 	var factories []api.NetworkFilterChainFactory
-	c := ln.FilterChains[0]
 	for _, f := range c.Filters {
 		factory, err := api.CreateNetworkFilterChainFactory(f.Type, f.Config)
 		if err != nil {
@@ -429,28 +462,26 @@ func AddOrUpdateNetworkFilterFactories(listenerName string, ln *v2.Listener) []a
 			factories = append(factories, factory)
 		}
 	}
+	return factories
+}
 
-	if len(factories) == 0 {
-		log.DefaultLogger.Errorf("[config] network filter factories len is 0, listener: %+v", ln)
+// GetNetworkFilterFactories returns the network filter factories of the first filter chain in the listener
+func GetNetworkFilterFactories(listenerName string) []api.NetworkFilterChainFactory {
+	chains := GetFilterChainsNetworkFilterFactories(listenerName)
+	if len(chains) == 0 {
 		return nil
 	}
-
-	networkFilterFactoryMap.Store(listenerName, factories)
-	if log.DefaultLogger.GetLogLevel() >= log.DEBUG {
-		log.DefaultLogger.Debugf("[config] AddOrUpdateNetworkFilterFactories store network filter factories, name: %v", listenerName)
-	}
-
-	return factories
+	return chains[0]
 }
 
-// GetNetworkFilterFactories returns a network filter factory by filter.Type
-func GetNetworkFilterFactories(listenerName string) []api.NetworkFilterChainFactory {
+// GetFilterChainsNetworkFilterFactories returns the network filter factories of every filter chain in the listener
+func GetFilterChainsNetworkFilterFactories(listenerName string) [][]api.NetworkFilterChainFactory {
 	if listenerName == "" {
 		return nil
 	}
 
 	if v, ok := networkFilterFactoryMap.Load(listenerName); ok {
-		return v.([]api.NetworkFilterChainFactory)
+		return v.([][]api.NetworkFilterChainFactory)
 	}
 
 	return nil
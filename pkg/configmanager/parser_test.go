@@ -337,4 +337,31 @@ func TestNetworkFilterFactories(t *testing.T) {
 	assert.NotNil(t, factory)
 	assert.Equal(t, factory, factory1)
 	assert.Equal(t, factory1, factory2)
+
+	// the network filter factories of every filter chain are stored
+	listenerConfig.FilterChains = append(listenerConfig.FilterChains, v2.FilterChain{
+		FilterChainConfig: v2.FilterChainConfig{
+			Filters: []v2.Filter{
+				{Type: "test1"},
+				{Type: "test1"},
+			},
+		},
+	})
+	chains := AddOrUpdateFilterChainsNetworkFilterFactories("test_listener", listenerConfig)
+	assert.Len(t, chains, 2)
+	assert.Len(t, chains[1], 2)
+	assert.Equal(t, chains, GetFilterChainsNetworkFilterFactories("test_listener"))
+	assert.Equal(t, chains[0], GetNetworkFilterFactories("test_listener"))
+
+	// the callers of AddOrUpdateNetworkFilterFactories get the first filter chain, and every filter chain is stored
+	factory = AddOrUpdateNetworkFilterFactories("test_listener_chains", listenerConfig)
+	assert.Len(t, factory, 1)
+	assert.Equal(t, factory, GetNetworkFilterFactories("test_listener_chains"))
+	chains = GetFilterChainsNetworkFilterFactories("test_listener_chains")
+	assert.Len(t, chains, 2)
+	assert.Len(t, chains[1], 2)
+
+	// no filter chains
+	assert.Nil(t, AddOrUpdateNetworkFilterFactories("test_listener_empty", &v2.Listener{}))
+	assert.Nil(t, GetFilterChainsNetworkFilterFactories("test_listener_empty"))
 }
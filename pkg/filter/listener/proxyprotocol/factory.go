@@ -0,0 +1,64 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package proxyprotocol
+
+import (
+	"encoding/json"
+	"time"
+
+	"mosn.io/api"
+	v2 "mosn.io/mosn/pkg/config/v2"
+)
+
+// ProxyProtocol filter used to read the PROXY protocol header sent by the proxy in front of mosn,
+// and restores the addresses of the connection with the addresses in the header.
+func init() {
+	api.RegisterListener(v2.PROXY_PROTOCOL_LISTENER_FILTER, CreateProxyProtocolFactory)
+}
+
+const defaultTimeout = 5 * time.Second
+
+type ProxyProtocolConfig struct {
+	// Timeout is the max duration to wait for the header, default is 5s
+	Timeout api.DurationConfig `json:"timeout,omitempty"`
+	// If AllowRequestsWithoutProxyProtocol is setted to true, the connections without the header are accepted
+	// with their own addresses, otherwise they are closed. The connections that send nothing before the timeout
+	// are accepted too.
+	AllowRequestsWithoutProxyProtocol bool `json:"allow_requests_without_proxy_protocol,omitempty"`
+}
+
+type proxyProtocol struct {
+	timeout                   time.Duration
+	allowWithoutProxyProtocol bool
+}
+
+func CreateProxyProtocolFactory(conf map[string]interface{}) (api.ListenerFilterChainFactory, error) {
+	b, _ := json.Marshal(conf)
+	cfg := ProxyProtocolConfig{}
+	if err := json.Unmarshal(b, &cfg); err != nil {
+		return nil, err
+	}
+	timeout := cfg.Timeout.Duration
+	if timeout <= 0 {
+		timeout = defaultTimeout
+	}
+	return &proxyProtocol{
+		timeout:                   timeout,
+		allowWithoutProxyProtocol: cfg.AllowRequestsWithoutProxyProtocol,
+	}, nil
+}
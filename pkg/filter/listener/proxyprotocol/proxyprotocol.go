@@ -0,0 +1,132 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package proxyprotocol
+
+import (
+	"errors"
+	"io"
+	"net"
+	"os"
+	"time"
+
+	"mosn.io/api"
+	"mosn.io/mosn/pkg/log"
+	"mosn.io/mosn/pkg/network"
+	"mosn.io/mosn/pkg/types"
+	"mosn.io/pkg/variable"
+)
+
+const (
+	// peekBufferSize is larger than the v1 header and the v2 header without tlvs
+	peekBufferSize = 256
+	// peekInterval is the interval to peek again when the header is incomplete
+	peekInterval = 5 * time.Millisecond
+)
+
+// OnAccept called when connection accept
+func (filter *proxyProtocol) OnAccept(cb api.ListenerFilterChainFactoryCallbacks) api.FilterStatus {
+	ctx := cb.GetOriContext()
+	// the header of the transferred connection has been consumed before the transfer
+	if ch, err := variable.Get(ctx, types.VariableAcceptChan); err == nil && ch != nil {
+		return api.Continue
+	}
+	conn := cb.Conn()
+	if conn.LocalAddr().Network() == "udp" {
+		log.DefaultLogger.Warnf("[listener] [proxy_protocol] proxy protocol is not supported in udp listener")
+		return api.Continue
+	}
+
+	header, err := filter.readHeader(conn)
+	if err != nil {
+		if err == network.ErrNotProxyProtocol && filter.allowWithoutProxyProtocol {
+			if log.DefaultLogger.GetLogLevel() >= log.DEBUG {
+				log.DefaultLogger.Debugf("[listener] [proxy_protocol] no proxy protocol header from %s", conn.RemoteAddr())
+			}
+			return api.Continue
+		}
+		log.DefaultLogger.Errorf("[listener] [proxy_protocol] read proxy protocol header from %s failed: %v", conn.RemoteAddr(), err)
+		conn.Close()
+		return api.Stop
+	}
+
+	// the header without the addresses keeps the addresses of the connection
+	if header.SourceAddr != nil && header.DestinationAddr != nil {
+		if log.DefaultLogger.GetLogLevel() >= log.DEBUG {
+			log.DefaultLogger.Debugf("[listener] [proxy_protocol] restore addresses of %s, remote addr: %s, local addr: %s",
+				conn.RemoteAddr(), header.SourceAddr, header.DestinationAddr)
+		}
+		_ = variable.Set(ctx, types.VariableOriRemoteAddr, header.SourceAddr)
+		_ = variable.Set(ctx, types.VariableOriLocalAddr, header.DestinationAddr)
+	}
+	return api.Continue
+}
+
+// readHeader reads the PROXY protocol header in the connection.
+// The data is peeked before the header is confirmed, so the data is not consumed if there is no header.
+// If the connections without the header are allowed, a connection that sends nothing before the timeout
+// is treated as no header, such as the server first protocols.
+func (filter *proxyProtocol) readHeader(conn net.Conn) (*network.ProxyProtocolHeader, error) {
+	deadline := time.Now().Add(filter.timeout)
+	if err := conn.SetReadDeadline(deadline); err != nil {
+		return nil, err
+	}
+	defer conn.SetReadDeadline(time.Time{})
+
+	buf := make([]byte, peekBufferSize)
+	// peeked represents whether any data is received
+	peeked := false
+	for {
+		n, err := network.Peek(conn, buf)
+		if err != nil {
+			if errors.Is(err, os.ErrDeadlineExceeded) && filter.allowWithoutProxyProtocol && !peeked {
+				return nil, network.ErrNotProxyProtocol
+			}
+			return nil, err
+		}
+		if n == 0 {
+			return nil, io.EOF
+		}
+		peeked = true
+		header, length, err := network.DecodeProxyProtocolHeader(buf[:n])
+		switch err {
+		case nil:
+			// consume the header
+			if _, err := io.ReadFull(conn, buf[:length]); err != nil {
+				return nil, err
+			}
+			return header, nil
+		case network.ErrProxyProtocolIncomplete:
+			if length > len(buf) {
+				// the header is larger than the peek buffer, reads the whole header directly
+				b := make([]byte, length)
+				if _, err := io.ReadFull(conn, b); err != nil {
+					return nil, err
+				}
+				header, _, err := network.DecodeProxyProtocolHeader(b)
+				return header, err
+			}
+		default:
+			return nil, err
+		}
+		// wait for the rest of the header
+		if !time.Now().Before(deadline) {
+			return nil, os.ErrDeadlineExceeded
+		}
+		time.Sleep(peekInterval)
+	}
+}
@@ -0,0 +1,190 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package proxyprotocol
+
+import (
+	"context"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"mosn.io/api"
+	"mosn.io/mosn/pkg/network"
+	"mosn.io/mosn/pkg/types"
+	"mosn.io/pkg/variable"
+)
+
+type mockCallbacks struct {
+	api.ListenerFilterChainFactoryCallbacks
+	conn net.Conn
+	ctx  context.Context
+}
+
+func (cb *mockCallbacks) Conn() net.Conn {
+	return cb.conn
+}
+
+func (cb *mockCallbacks) GetOriContext() context.Context {
+	return cb.ctx
+}
+
+// acceptWith writes the data by a client, and returns the accepted connection
+func acceptWith(t *testing.T, writes ...[]byte) net.Conn {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.Nil(t, err)
+	defer ln.Close()
+	go func() {
+		conn, err := net.Dial("tcp", ln.Addr().String())
+		if err != nil {
+			return
+		}
+		for _, b := range writes {
+			conn.Write(b)
+			time.Sleep(20 * time.Millisecond)
+		}
+		time.Sleep(100 * time.Millisecond)
+		conn.Close()
+	}()
+	conn, err := ln.Accept()
+	require.Nil(t, err)
+	return conn
+}
+
+func newProxyProtocolFilter(t *testing.T, conf map[string]interface{}) api.ListenerFilterChainFactory {
+	f, err := CreateProxyProtocolFactory(conf)
+	require.Nil(t, err)
+	return f
+}
+
+func TestCreateProxyProtocolFactory(t *testing.T) {
+	f := newProxyProtocolFilter(t, map[string]interface{}{}).(*proxyProtocol)
+	assert.Equal(t, defaultTimeout, f.timeout)
+	assert.False(t, f.allowWithoutProxyProtocol)
+	f = newProxyProtocolFilter(t, map[string]interface{}{
+		"timeout":                               "1s",
+		"allow_requests_without_proxy_protocol": true,
+	}).(*proxyProtocol)
+	assert.Equal(t, time.Second, f.timeout)
+	assert.True(t, f.allowWithoutProxyProtocol)
+}
+
+func TestProxyProtocolOnAccept(t *testing.T) {
+	src, _ := net.ResolveTCPAddr("tcp", "192.168.0.1:56324")
+	dst, _ := net.ResolveTCPAddr("tcp", "192.168.0.11:443")
+	v2Header, err := (&network.ProxyProtocolHeader{Version: "v2", SourceAddr: src, DestinationAddr: dst}).Encode()
+	require.Nil(t, err)
+	for _, tc := range []struct {
+		name   string
+		writes [][]byte
+	}{
+		{
+			name:   "v1",
+			writes: [][]byte{[]byte("PROXY TCP4 192.168.0.1 192.168.0.11 56324 443\r\nhello")},
+		},
+		{
+			name:   "v1 in several packets",
+			writes: [][]byte{[]byte("PRO"), []byte("XY TCP4 192.168.0.1 192.168.0.11 56324 443\r"), []byte("\nhello")},
+		},
+		{
+			name:   "v2",
+			writes: [][]byte{append(append([]byte{}, v2Header...), "hello"...)},
+		},
+		{
+			name:   "v2 in several packets",
+			writes: [][]byte{v2Header[:14], v2Header[14:20], append(append([]byte{}, v2Header[20:]...), "hello"...)},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			conn := acceptWith(t, tc.writes...)
+			defer conn.Close()
+			ctx := variable.NewVariableContext(context.Background())
+			f := newProxyProtocolFilter(t, map[string]interface{}{})
+			require.Equal(t, api.Continue, f.OnAccept(&mockCallbacks{conn: conn, ctx: ctx}))
+			remote, err := variable.Get(ctx, types.VariableOriRemoteAddr)
+			require.Nil(t, err)
+			assert.Equal(t, "192.168.0.1:56324", remote.(net.Addr).String())
+			local, err := variable.Get(ctx, types.VariableOriLocalAddr)
+			require.Nil(t, err)
+			assert.Equal(t, "192.168.0.11:443", local.(net.Addr).String())
+			// the data after the header is kept
+			b := make([]byte, 5)
+			_, err = io.ReadFull(conn, b)
+			require.Nil(t, err)
+			assert.Equal(t, "hello", string(b))
+		})
+	}
+}
+
+func TestProxyProtocolWithoutHeader(t *testing.T) {
+	// the connection is closed
+	conn := acceptWith(t, []byte("GET / HTTP/1.1\r\n"))
+	ctx := variable.NewVariableContext(context.Background())
+	f := newProxyProtocolFilter(t, map[string]interface{}{})
+	assert.Equal(t, api.Stop, f.OnAccept(&mockCallbacks{conn: conn, ctx: ctx}))
+	_, err := conn.Read(make([]byte, 1))
+	assert.NotNil(t, err)
+
+	// the connection is accepted without consuming the data
+	conn = acceptWith(t, []byte("GET / HTTP/1.1\r\n"))
+	defer conn.Close()
+	f = newProxyProtocolFilter(t, map[string]interface{}{
+		"allow_requests_without_proxy_protocol": true,
+	})
+	assert.Equal(t, api.Continue, f.OnAccept(&mockCallbacks{conn: conn, ctx: ctx}))
+	_, err = variable.Get(ctx, types.VariableOriRemoteAddr)
+	assert.NotNil(t, err)
+	b := make([]byte, 3)
+	_, err = io.ReadFull(conn, b)
+	require.Nil(t, err)
+	assert.Equal(t, "GET", string(b))
+}
+
+func TestProxyProtocolTimeout(t *testing.T) {
+	conn := acceptWith(t, []byte("PROXY TCP4"))
+	f := newProxyProtocolFilter(t, map[string]interface{}{
+		"timeout":                               "50ms",
+		"allow_requests_without_proxy_protocol": true,
+	})
+	ctx := variable.NewVariableContext(context.Background())
+	assert.Equal(t, api.Stop, f.OnAccept(&mockCallbacks{conn: conn, ctx: ctx}))
+}
+
+func TestProxyProtocolTimeoutWithoutData(t *testing.T) {
+	// the connection is closed if the header is required
+	conn := acceptWith(t)
+	f := newProxyProtocolFilter(t, map[string]interface{}{
+		"timeout": "50ms",
+	})
+	ctx := variable.NewVariableContext(context.Background())
+	assert.Equal(t, api.Stop, f.OnAccept(&mockCallbacks{conn: conn, ctx: ctx}))
+
+	// the connection sends nothing before the timeout is accepted as no header
+	conn = acceptWith(t)
+	defer conn.Close()
+	f = newProxyProtocolFilter(t, map[string]interface{}{
+		"timeout":                               "50ms",
+		"allow_requests_without_proxy_protocol": true,
+	})
+	assert.Equal(t, api.Continue, f.OnAccept(&mockCallbacks{conn: conn, ctx: ctx}))
+	_, err := variable.Get(ctx, types.VariableOriRemoteAddr)
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,188 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package tlsinspector
+
+import (
+	"errors"
+)
+
+const (
+	recordTypeHandshake       = 0x16
+	handshakeTypeClientHello  = 0x01
+	recordHeaderLen           = 5
+	maxPlaintext              = 16384
+	extensionServerName       = 0x0000
+	extensionALPN             = 0x0010
+	serverNameTypeHostName    = 0x00
+	handshakeHeaderLen        = 4
+	clientHelloRandomLen      = 32
+	clientHelloVersionLen     = 2
+	clientHelloFixedFieldsLen = clientHelloVersionLen + clientHelloRandomLen
+)
+
+var (
+	errNotTLS           = errors.New("not tls")
+	errIncomplete       = errors.New("tls record is incomplete")
+	errInvalidHandshake = errors.New("invalid tls ClientHello")
+)
+
+type clientHello struct {
+	serverName string
+	protocols  []string
+}
+
+// parseClientHello parses the ClientHello in the first tls record of b.
+// If errIncomplete is returned, the returned length is the length of the first record.
+// A ClientHello that spans multiple records is parsed with the data in the first record only.
+func parseClientHello(b []byte) (*clientHello, int, error) {
+	if len(b) == 0 {
+		return nil, 1, errIncomplete
+	}
+	if b[0] != recordTypeHandshake {
+		return nil, 0, errNotTLS
+	}
+	if len(b) < recordHeaderLen {
+		return nil, recordHeaderLen, errIncomplete
+	}
+	// the major version of ssl 3.0 and tls are 3
+	if b[1] != 0x03 {
+		return nil, 0, errNotTLS
+	}
+	length := int(b[3])<<8 | int(b[4])
+	if length > maxPlaintext {
+		return nil, 0, errInvalidHandshake
+	}
+	n := recordHeaderLen + length
+	if len(b) < n {
+		return nil, n, errIncomplete
+	}
+	hello, err := parseHandshake(b[recordHeaderLen:n])
+	return hello, n, err
+}
+
+func parseHandshake(b []byte) (*clientHello, error) {
+	if len(b) < handshakeHeaderLen || b[0] != handshakeTypeClientHello {
+		return nil, errInvalidHandshake
+	}
+	b = b[handshakeHeaderLen:]
+	if len(b) < clientHelloFixedFieldsLen {
+		return nil, errInvalidHandshake
+	}
+	s := reader(b[clientHelloFixedFieldsLen:])
+	// session id, cipher suites and compression methods
+	if _, ok := s.readVector(1); !ok {
+		return nil, errInvalidHandshake
+	}
+	if _, ok := s.readVector(2); !ok {
+		return nil, errInvalidHandshake
+	}
+	if _, ok := s.readVector(1); !ok {
+		return nil, errInvalidHandshake
+	}
+	hello := &clientHello{}
+	if len(s) == 0 {
+		// no extensions
+		return hello, nil
+	}
+	extensions, ok := s.readVector(2)
+	if !ok {
+		return nil, errInvalidHandshake
+	}
+	for len(extensions) > 0 {
+		typ, ok := extensions.readUint16()
+		if !ok {
+			return nil, errInvalidHandshake
+		}
+		data, ok := extensions.readVector(2)
+		if !ok {
+			return nil, errInvalidHandshake
+		}
+		switch typ {
+		case extensionServerName:
+			names, ok := data.readVector(2)
+			if !ok {
+				return nil, errInvalidHandshake
+			}
+			for len(names) > 0 {
+				nameType, ok := names.readUint8()
+				if !ok {
+					return nil, errInvalidHandshake
+				}
+				name, ok := names.readVector(2)
+				if !ok {
+					return nil, errInvalidHandshake
+				}
+				if nameType == serverNameTypeHostName && hello.serverName == "" {
+					hello.serverName = string(name)
+				}
+			}
+		case extensionALPN:
+			protocols, ok := data.readVector(2)
+			if !ok {
+				return nil, errInvalidHandshake
+			}
+			for len(protocols) > 0 {
+				proto, ok := protocols.readVector(1)
+				if !ok || len(proto) == 0 {
+					return nil, errInvalidHandshake
+				}
+				hello.protocols = append(hello.protocols, string(proto))
+			}
+		}
+	}
+	return hello, nil
+}
+
+// reader reads the fields of the tls handshake message
+type reader []byte
+
+func (r *reader) readUint8() (uint8, bool) {
+	if len(*r) < 1 {
+		return 0, false
+	}
+	v := (*r)[0]
+	*r = (*r)[1:]
+	return v, true
+}
+
+func (r *reader) readUint16() (uint16, bool) {
+	if len(*r) < 2 {
+		return 0, false
+	}
+	v := uint16((*r)[0])<<8 | uint16((*r)[1])
+	*r = (*r)[2:]
+	return v, true
+}
+
+// readVector reads a variable-length vector with the length prefix in lenBytes
+func (r *reader) readVector(lenBytes int) (reader, bool) {
+	if len(*r) < lenBytes {
+		return nil, false
+	}
+	length := 0
+	for _, c := range (*r)[:lenBytes] {
+		length = length<<8 | int(c)
+	}
+	*r = (*r)[lenBytes:]
+	if len(*r) < length {
+		return nil, false
+	}
+	v := (*r)[:length]
+	*r = (*r)[length:]
+	return v, true
+}
@@ -0,0 +1,60 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package tlsinspector
+
+import (
+	"encoding/json"
+	"time"
+
+	"mosn.io/api"
+	v2 "mosn.io/mosn/pkg/config/v2"
+)
+
+// TLSInspector filter used to peek the tls ClientHello of a connection without consuming it,
+// the server name and the alpn protocols are used to select the filter chain of the listener.
+func init() {
+	api.RegisterListener(v2.TLS_INSPECTOR_LISTENER_FILTER, CreateTLSInspectorFactory)
+}
+
+const defaultTimeout = 5 * time.Second
+
+type TLSInspectorConfig struct {
+	// Timeout is the max duration to wait for the ClientHello, default is 5s.
+	// The connection without any data in the timeout is treated as a raw_buffer connection,
+	// such as the server-first protocols.
+	Timeout api.DurationConfig `json:"timeout,omitempty"`
+}
+
+type tlsInspector struct {
+	timeout time.Duration
+}
+
+func CreateTLSInspectorFactory(conf map[string]interface{}) (api.ListenerFilterChainFactory, error) {
+	b, _ := json.Marshal(conf)
+	cfg := TLSInspectorConfig{}
+	if err := json.Unmarshal(b, &cfg); err != nil {
+		return nil, err
+	}
+	timeout := cfg.Timeout.Duration
+	if timeout <= 0 {
+		timeout = defaultTimeout
+	}
+	return &tlsInspector{
+		timeout: timeout,
+	}, nil
+}
@@ -0,0 +1,124 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package tlsinspector
+
+import (
+	"errors"
+	"io"
+	"net"
+	"os"
+	"time"
+
+	"mosn.io/api"
+	v2 "mosn.io/mosn/pkg/config/v2"
+	"mosn.io/mosn/pkg/log"
+	"mosn.io/mosn/pkg/network"
+	"mosn.io/mosn/pkg/types"
+	"mosn.io/pkg/variable"
+)
+
+// peekInterval is the interval to peek again when the ClientHello is incomplete
+const peekInterval = 5 * time.Millisecond
+
+// OnAccept called when connection accept
+func (filter *tlsInspector) OnAccept(cb api.ListenerFilterChainFactoryCallbacks) api.FilterStatus {
+	ctx := cb.GetOriContext()
+	// the tls handshake of the transferred connection has been finished before the transfer
+	if ch, err := variable.Get(ctx, types.VariableAcceptChan); err == nil && ch != nil {
+		return api.Continue
+	}
+	conn := cb.Conn()
+	if conn.LocalAddr().Network() == "udp" {
+		return api.Continue
+	}
+
+	hello, err := filter.inspect(conn)
+	switch {
+	case err == nil:
+		if log.DefaultLogger.GetLogLevel() >= log.DEBUG {
+			log.DefaultLogger.Debugf("[listener] [tls_inspector] tls connection from %s, server name: %s, alpn: %v",
+				conn.RemoteAddr(), hello.serverName, hello.protocols)
+		}
+		_ = variable.SetString(ctx, types.VariableTransportProtocol, v2.TransportProtocolTLS)
+		if hello.serverName != "" {
+			_ = variable.SetString(ctx, types.VariableRequestedServerName, hello.serverName)
+		}
+		if len(hello.protocols) > 0 {
+			_ = variable.Set(ctx, types.VariableApplicationProtocols, hello.protocols)
+		}
+	case err == errNotTLS, err == errNoData:
+		_ = variable.SetString(ctx, types.VariableTransportProtocol, v2.TransportProtocolRawBuffer)
+	case err == errInvalidHandshake:
+		// let the tls handshake report the error
+		if log.DefaultLogger.GetLogLevel() >= log.DEBUG {
+			log.DefaultLogger.Debugf("[listener] [tls_inspector] invalid ClientHello from %s", conn.RemoteAddr())
+		}
+		_ = variable.SetString(ctx, types.VariableTransportProtocol, v2.TransportProtocolTLS)
+	default:
+		log.DefaultLogger.Errorf("[listener] [tls_inspector] inspect connection from %s failed: %v", conn.RemoteAddr(), err)
+		conn.Close()
+		return api.Stop
+	}
+	return api.Continue
+}
+
+// errNoData means the connection has no data in the timeout
+var errNoData = errors.New("no data")
+
+// inspect peeks the ClientHello in the connection
+func (filter *tlsInspector) inspect(conn net.Conn) (*clientHello, error) {
+	deadline := time.Now().Add(filter.timeout)
+	if err := conn.SetReadDeadline(deadline); err != nil {
+		return nil, err
+	}
+	defer conn.SetReadDeadline(time.Time{})
+
+	var buf []byte
+	size := recordHeaderLen
+	peeked := false
+	for {
+		if len(buf) < size {
+			buf = make([]byte, size)
+		}
+		n, err := network.Peek(conn, buf)
+		if err != nil {
+			if !peeked && errors.Is(err, os.ErrDeadlineExceeded) {
+				return nil, errNoData
+			}
+			return nil, err
+		}
+		peeked = true
+		if n == 0 {
+			return nil, io.EOF
+		}
+		hello, length, err := parseClientHello(buf[:n])
+		if err != errIncomplete {
+			return hello, err
+		}
+		if length > size {
+			// peek the whole record in the next round
+			size = length
+			continue
+		}
+		// wait for the rest of the record
+		if !time.Now().Before(deadline) {
+			return nil, os.ErrDeadlineExceeded
+		}
+		time.Sleep(peekInterval)
+	}
+}
@@ -0,0 +1,176 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package tlsinspector
+
+import (
+	"context"
+	"crypto/tls"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"mosn.io/api"
+	v2 "mosn.io/mosn/pkg/config/v2"
+	"mosn.io/mosn/pkg/types"
+	"mosn.io/pkg/variable"
+)
+
+type mockCallbacks struct {
+	api.ListenerFilterChainFactoryCallbacks
+	conn net.Conn
+	ctx  context.Context
+}
+
+func (cb *mockCallbacks) Conn() net.Conn {
+	return cb.conn
+}
+
+func (cb *mockCallbacks) GetOriContext() context.Context {
+	return cb.ctx
+}
+
+// accept returns the connection accepted from the client
+func accept(t *testing.T, client func(conn net.Conn)) net.Conn {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.Nil(t, err)
+	defer ln.Close()
+	go func() {
+		conn, err := net.Dial("tcp", ln.Addr().String())
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		client(conn)
+	}()
+	conn, err := ln.Accept()
+	require.Nil(t, err)
+	return conn
+}
+
+func newTLSInspector(t *testing.T, conf map[string]interface{}) api.ListenerFilterChainFactory {
+	f, err := CreateTLSInspectorFactory(conf)
+	require.Nil(t, err)
+	return f
+}
+
+func TestTLSInspectorClientHello(t *testing.T) {
+	conn := accept(t, func(conn net.Conn) {
+		tlsConn := tls.Client(conn, &tls.Config{
+			ServerName:         "www.example.com",
+			NextProtos:         []string{"h2", "http/1.1"},
+			InsecureSkipVerify: true,
+		})
+		tlsConn.SetDeadline(time.Now().Add(time.Second))
+		tlsConn.Handshake()
+	})
+	defer conn.Close()
+	ctx := variable.NewVariableContext(context.Background())
+	f := newTLSInspector(t, map[string]interface{}{})
+	require.Equal(t, api.Continue, f.OnAccept(&mockCallbacks{conn: conn, ctx: ctx}))
+
+	protocol, err := variable.GetString(ctx, types.VariableTransportProtocol)
+	require.Nil(t, err)
+	assert.Equal(t, v2.TransportProtocolTLS, protocol)
+	serverName, err := variable.GetString(ctx, types.VariableRequestedServerName)
+	require.Nil(t, err)
+	assert.Equal(t, "www.example.com", serverName)
+	protos, err := variable.Get(ctx, types.VariableApplicationProtocols)
+	require.Nil(t, err)
+	assert.Equal(t, []string{"h2", "http/1.1"}, protos)
+	// the ClientHello is not consumed
+	b := make([]byte, 1)
+	_, err = io.ReadFull(conn, b)
+	require.Nil(t, err)
+	assert.Equal(t, byte(recordTypeHandshake), b[0])
+}
+
+func TestTLSInspectorRawBuffer(t *testing.T) {
+	conn := accept(t, func(conn net.Conn) {
+		conn.Write([]byte("GET / HTTP/1.1\r\n"))
+		time.Sleep(100 * time.Millisecond)
+	})
+	defer conn.Close()
+	ctx := variable.NewVariableContext(context.Background())
+	f := newTLSInspector(t, map[string]interface{}{})
+	require.Equal(t, api.Continue, f.OnAccept(&mockCallbacks{conn: conn, ctx: ctx}))
+	protocol, err := variable.GetString(ctx, types.VariableTransportProtocol)
+	require.Nil(t, err)
+	assert.Equal(t, v2.TransportProtocolRawBuffer, protocol)
+	b := make([]byte, 3)
+	_, err = io.ReadFull(conn, b)
+	require.Nil(t, err)
+	assert.Equal(t, "GET", string(b))
+
+	// the server-first connection without data
+	conn = accept(t, func(conn net.Conn) {
+		time.Sleep(200 * time.Millisecond)
+	})
+	defer conn.Close()
+	ctx = variable.NewVariableContext(context.Background())
+	f = newTLSInspector(t, map[string]interface{}{
+		"timeout": "50ms",
+	})
+	require.Equal(t, api.Continue, f.OnAccept(&mockCallbacks{conn: conn, ctx: ctx}))
+	protocol, err = variable.GetString(ctx, types.VariableTransportProtocol)
+	require.Nil(t, err)
+	assert.Equal(t, v2.TransportProtocolRawBuffer, protocol)
+}
+
+func TestTLSInspectorIncompleteClientHello(t *testing.T) {
+	conn := accept(t, func(conn net.Conn) {
+		conn.Write([]byte{recordTypeHandshake, 0x03, 0x01, 0x00, 0x10, handshakeTypeClientHello})
+		time.Sleep(200 * time.Millisecond)
+	})
+	ctx := variable.NewVariableContext(context.Background())
+	f := newTLSInspector(t, map[string]interface{}{
+		"timeout": "50ms",
+	})
+	assert.Equal(t, api.Stop, f.OnAccept(&mockCallbacks{conn: conn, ctx: ctx}))
+}
+
+func TestParseClientHello(t *testing.T) {
+	_, _, err := parseClientHello([]byte("GET / HTTP/1.1\r\n"))
+	assert.Equal(t, errNotTLS, err)
+	_, _, err = parseClientHello([]byte{recordTypeHandshake, 0x01})
+	assert.Equal(t, errIncomplete, err)
+	_, n, err := parseClientHello([]byte{recordTypeHandshake, 0x03, 0x01, 0x01, 0x00, handshakeTypeClientHello})
+	assert.Equal(t, errIncomplete, err)
+	assert.Equal(t, recordHeaderLen+256, n)
+
+	// the ClientHello without extensions
+	body := []byte{0x03, 0x03}
+	body = append(body, make([]byte, clientHelloRandomLen)...)
+	body = append(body, 0x00, 0x00, 0x02, 0x13, 0x01, 0x01, 0x00)
+	handshake := append([]byte{handshakeTypeClientHello, 0x00, 0x00, byte(len(body))}, body...)
+	record := append([]byte{recordTypeHandshake, 0x03, 0x01, 0x00, byte(len(handshake))}, handshake...)
+	hello, n, err := parseClientHello(record)
+	require.Nil(t, err)
+	assert.Equal(t, len(record), n)
+	assert.Equal(t, "", hello.serverName)
+	assert.Empty(t, hello.protocols)
+
+	// the truncated ClientHello
+	handshake = append([]byte{handshakeTypeClientHello, 0x00, 0x00, byte(len(body))}, body[:10]...)
+	record = append([]byte{recordTypeHandshake, 0x03, 0x01, 0x00, byte(len(handshake))}, handshake...)
+	_, _, err = parseClientHello(record)
+	assert.Equal(t, errInvalidHandshake, err)
+}
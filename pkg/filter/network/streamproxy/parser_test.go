@@ -41,6 +41,7 @@ func TestParseTCPProxy(t *testing.T) {
 				},
 				"SourcePort":      "8080",
 				"DestinationPort": "8080",
+				"server_names":    []interface{}{"*.example.com"},
 			},
 		},
 	}
@@ -61,7 +62,9 @@ func TestParseTCPProxy(t *testing.T) {
 			r.DestinationAddrs[0].Address == "127.0.0.1" &&
 			r.DestinationAddrs[0].Length == 32 &&
 			r.SourcePort == "8080" &&
-			r.DestinationPort == "8080") {
+			r.DestinationPort == "8080" &&
+			len(r.ServerNames) == 1 &&
+			r.ServerNames[0] == "*.example.com") {
 			t.Error("route failed")
 		}
 	}
@@ -18,10 +18,16 @@
 package streamproxy
 
 import (
+	"context"
 	"net"
 	"testing"
 
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
 	v2 "mosn.io/mosn/pkg/config/v2"
+	"mosn.io/mosn/pkg/mock"
+	"mosn.io/mosn/pkg/types"
+	"mosn.io/pkg/variable"
 )
 
 func Test_IpRangeList_Contains(t *testing.T) {
@@ -77,3 +83,152 @@ func Test_ParsePortRangeList(t *testing.T) {
 		t.Errorf("test  port range fail")
 	}
 }
+
+func TestGetRouteFromEntriesWithServerName(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	conn := mock.NewMockConnection(ctrl)
+	conn.EXPECT().RemoteAddr().Return(&net.TCPAddr{IP: net.ParseIP("10.0.0.1"), Port: 50000}).AnyTimes()
+	conn.EXPECT().LocalAddr().Return(&net.TCPAddr{IP: net.ParseIP("127.0.0.1"), Port: 443}).AnyTimes()
+
+	config := NewProxyConfig(&v2.StreamProxy{
+		Cluster: "default",
+		Routes: []*v2.StreamRoute{
+			{
+				Cluster:     "exact",
+				ServerNames: []string{"www.Example.com"},
+			},
+			{
+				Cluster:     "wildcard",
+				ServerNames: []string{"*.example.com"},
+			},
+			{
+				Cluster:         "other_port",
+				DestinationPort: "8443",
+				ServerNames:     []string{"*.test.com"},
+			},
+			{
+				Cluster:     "source",
+				SourceAddrs: []v2.CidrRange{*v2.Create("10.0.0.0", 8)},
+				ServerNames: []string{"*.test.com"},
+			},
+		},
+	})
+
+	for _, tc := range []struct {
+		serverName string
+		expected   string
+	}{
+		{"www.example.com", "exact"},
+		{"WWW.EXAMPLE.COM", "exact"},
+		{"api.example.com", "wildcard"},
+		{"a.b.example.com", "wildcard"},
+		{"example.com", "default"},
+		{"api.test.com", "source"},
+		{"", "default"},
+	} {
+		assert.Equal(t, tc.expected, config.(ServerNameProxyConfig).GetRouteFromEntriesWithServerName(conn, tc.serverName), tc.serverName)
+	}
+}
+
+func TestGetRouteFromEntriesDefaultCluster(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	conn := mock.NewMockConnection(ctrl)
+	conn.EXPECT().RemoteAddr().Return(&net.TCPAddr{IP: net.ParseIP("10.0.0.1"), Port: 50000}).AnyTimes()
+	conn.EXPECT().LocalAddr().Return(&net.TCPAddr{IP: net.ParseIP("127.0.0.1"), Port: 443}).AnyTimes()
+
+	config := NewProxyConfig(&v2.StreamProxy{
+		Cluster: "default",
+		Routes: []*v2.StreamRoute{
+			{
+				Cluster:     "sni",
+				ServerNames: []string{"www.example.com"},
+			},
+		},
+	})
+	sniConfig := config.(ServerNameProxyConfig)
+	assert.Equal(t, "sni", sniConfig.GetRouteFromEntriesWithServerName(conn, "www.example.com"))
+	assert.Equal(t, "default", sniConfig.GetRouteFromEntriesWithServerName(conn, "api.example.com"))
+	assert.Equal(t, "default", config.GetRouteFromEntries(conn))
+
+	// only the cluster is configured
+	config = NewProxyConfig(&v2.StreamProxy{Cluster: "default"})
+	assert.Equal(t, "default", config.(ServerNameProxyConfig).GetRouteFromEntriesWithServerName(conn, "www.example.com"))
+	assert.Equal(t, "default", config.GetRouteFromEntries(conn))
+}
+
+func TestGetRouteFromEntriesWithoutServerName(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	conn := mock.NewMockConnection(ctrl)
+	conn.EXPECT().RemoteAddr().Return(&net.TCPAddr{IP: net.ParseIP("10.0.0.1"), Port: 50000}).AnyTimes()
+	conn.EXPECT().LocalAddr().Return(&net.TCPAddr{IP: net.ParseIP("127.0.0.1"), Port: 443}).AnyTimes()
+	matched := &v2.StreamRoute{
+		Cluster:          "matched",
+		SourceAddrs:      []v2.CidrRange{*v2.Create("10.0.0.0", 8)},
+		DestinationAddrs: []v2.CidrRange{*v2.Create("127.0.0.0", 8)},
+		SourcePort:       "50000",
+		DestinationPort:  "443",
+	}
+	// the cluster takes precedence over the routes
+	config := NewProxyConfig(&v2.StreamProxy{
+		Cluster: "default",
+		Routes:  []*v2.StreamRoute{matched},
+	})
+	assert.Equal(t, "default", config.GetRouteFromEntries(conn))
+	// all the conditions are required, the empty condition never matches
+	config = NewProxyConfig(&v2.StreamProxy{
+		Routes: []*v2.StreamRoute{
+			{
+				Cluster: "empty",
+			},
+			{
+				Cluster:     "source_only",
+				SourceAddrs: []v2.CidrRange{*v2.Create("10.0.0.0", 8)},
+			},
+			matched,
+		},
+	})
+	assert.Equal(t, "matched", config.GetRouteFromEntries(conn))
+	config = NewProxyConfig(&v2.StreamProxy{
+		Routes: []*v2.StreamRoute{
+			{
+				Cluster: "empty",
+			},
+		},
+	})
+	assert.Equal(t, "", config.GetRouteFromEntries(conn))
+}
+
+func TestGetUpstreamClusterWithServerName(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	conn := mock.NewMockConnection(ctrl)
+	conn.EXPECT().RemoteAddr().Return(&net.TCPAddr{IP: net.ParseIP("10.0.0.1"), Port: 50000}).AnyTimes()
+	conn.EXPECT().LocalAddr().Return(&net.TCPAddr{IP: net.ParseIP("127.0.0.1"), Port: 443}).AnyTimes()
+	cb := mock.NewMockReadFilterCallbacks(ctrl)
+	cb.EXPECT().Connection().Return(conn).AnyTimes()
+
+	// the server name is read from the context of the proxy
+	ctx := variable.NewVariableContext(context.Background())
+	_ = variable.SetString(ctx, types.VariableRequestedServerName, "www.example.com")
+	p := &proxy{
+		ctx:           ctx,
+		readCallbacks: cb,
+		config: NewProxyConfig(&v2.StreamProxy{
+			Cluster: "default",
+			Routes: []*v2.StreamRoute{
+				{
+					Cluster:     "sni",
+					ServerNames: []string{"*.example.com"},
+				},
+			},
+		}),
+	}
+	assert.Equal(t, "sni", p.getUpstreamCluster())
+}
@@ -166,9 +166,11 @@ func (p *proxy) initializeUpstreamConnection() api.FilterStatus {
 		p.upstreamConnection = upstreamConnection
 		if err := upstreamConnection.Connect(); err != nil {
 			p.clusterInfo.Stats().UpstreamConnectionRetry.Inc(1)
+			putOutlierResult(connectionData.Host, types.OutlierConnectFailure)
 			log.DefaultLogger.Errorf("%s proxy connect to upstream failed, err: %v", p.network, err)
 			continue
 		}
+		putOutlierResult(connectionData.Host, types.OutlierSuccess)
 		connected = true
 		break
 	}
@@ -193,6 +195,16 @@ func (p *proxy) initializeUpstreamConnection() api.FilterStatus {
 	return api.Continue
 }
 
+// putOutlierResult reports the upstream connect result to the outlier detector of the host's cluster
+func putOutlierResult(host types.Host, result types.OutlierResult) {
+	if host == nil {
+		return
+	}
+	if od := host.ClusterInfo().OutlierDetector(); od != nil {
+		od.PutResult(host, result)
+	}
+}
+
 func (p *proxy) closeUpstreamConnection() {
 	// TODO: finalize upstream connection stats
 	p.upstreamConnection.Close(api.NoFlush, api.LocalClose)
@@ -201,6 +213,10 @@ func (p *proxy) closeUpstreamConnection() {
 func (p *proxy) getUpstreamCluster() string {
 	downstreamConnection := p.readCallbacks.Connection()
 
+	if c, ok := p.config.(ServerNameProxyConfig); ok {
+		serverName, _ := variable.GetString(p.ctx, types.VariableRequestedServerName)
+		return c.GetRouteFromEntriesWithServerName(downstreamConnection, serverName)
+	}
 	return p.config.GetRouteFromEntries(downstreamConnection)
 }
 
@@ -323,6 +339,8 @@ type proxyConfig struct {
 	idleTimeout        *time.Duration
 	maxConnectAttempts uint32
 	routes             []*route
+	// routes by server names are configured, the routes are checked before the cluster
+	sniRouting bool
 }
 
 type IpRangeList struct {
@@ -428,10 +446,56 @@ type route struct {
 	destinationAddrs IpRangeList
 	sourcePort       PortRangeList
 	destinationPort  PortRangeList
+	serverNames      []string
+}
+
+// match checks the connection and the server name against the route.
+// All the address conditions are required by the route without server names,
+// while an empty condition matches any connection if the route has server names.
+func (r *route) match(connection api.Connection, serverName string) bool {
+	if len(r.serverNames) == 0 {
+		return r.sourceAddrs.Contains(connection.RemoteAddr()) &&
+			r.sourcePort.Contains(connection.RemoteAddr()) &&
+			r.destinationAddrs.Contains(connection.LocalAddr()) &&
+			r.destinationPort.Contains(connection.LocalAddr())
+	}
+	if len(r.sourceAddrs.cidrRanges) > 0 && !r.sourceAddrs.Contains(connection.RemoteAddr()) {
+		return false
+	}
+	if len(r.sourcePort.portList) > 0 && !r.sourcePort.Contains(connection.RemoteAddr()) {
+		return false
+	}
+	if len(r.destinationAddrs.cidrRanges) > 0 && !r.destinationAddrs.Contains(connection.LocalAddr()) {
+		return false
+	}
+	if len(r.destinationPort.portList) > 0 && !r.destinationPort.Contains(connection.LocalAddr()) {
+		return false
+	}
+	return matchServerName(r.serverNames, serverName)
+}
+
+// matchServerName matches the server name with exact names and wildcard names like "*.example.com"
+func matchServerName(serverNames []string, serverName string) bool {
+	if serverName == "" {
+		return false
+	}
+	serverName = strings.ToLower(serverName)
+	for _, name := range serverNames {
+		if strings.HasPrefix(name, "*.") {
+			suffix := name[1:]
+			if len(serverName) > len(suffix) && strings.HasSuffix(serverName, suffix) {
+				return true
+			}
+		} else if name == serverName {
+			return true
+		}
+	}
+	return false
 }
 
 func NewProxyConfig(config *v2.StreamProxy) ProxyConfig {
 	var routes []*route
+	sniRouting := false
 
 	log.DefaultLogger.Tracef("Stream Proxy :: New Proxy Config = %v", config)
 	for _, routeConfig := range config.Routes {
@@ -442,6 +506,10 @@ func NewProxyConfig(config *v2.StreamProxy) ProxyConfig {
 			sourcePort:       ParsePortRangeList(routeConfig.SourcePort),
 			destinationPort:  ParsePortRangeList(routeConfig.DestinationPort),
 		}
+		for _, name := range routeConfig.ServerNames {
+			route.serverNames = append(route.serverNames, strings.ToLower(name))
+			sniRouting = true
+		}
 		log.DefaultLogger.Tracef("Stream Proxy add one route : %v", route)
 
 		routes = append(routes, route)
@@ -453,6 +521,7 @@ func NewProxyConfig(config *v2.StreamProxy) ProxyConfig {
 		idleTimeout:        config.IdleTimeout,
 		maxConnectAttempts: config.MaxConnectAttempts,
 		routes:             routes,
+		sniRouting:         sniRouting,
 	}
 }
 
@@ -475,28 +544,33 @@ func (pc *proxyConfig) GetReadTimeout(network string) time.Duration {
 	}
 }
 
+// GetRouteFromEntries returns the cluster of the connection without the server name
 func (pc *proxyConfig) GetRouteFromEntries(connection api.Connection) string {
-	if pc.cluster != "" {
+	return pc.GetRouteFromEntriesWithServerName(connection, "")
+}
+
+// GetRouteFromEntriesWithServerName returns the cluster in config if it is configured, otherwise the cluster of
+// the first matched route. If any route matches the server names, the routes are checked first
+// and the cluster in config is used as the default route.
+func (pc *proxyConfig) GetRouteFromEntriesWithServerName(connection api.Connection, serverName string) string {
+	if pc.cluster != "" && !pc.sniRouting {
 		log.DefaultLogger.Tracef("Stream Proxy get cluster from config , cluster name = %v", pc.cluster)
 		return pc.cluster
 	}
 
-	log.DefaultLogger.Tracef("Stream Proxy get route from entries , connection = %v", connection)
-	for _, r := range pc.routes {
-		log.DefaultLogger.Tracef("Stream Proxy check one route = %v", r)
-		if !r.sourceAddrs.Contains(connection.RemoteAddr()) {
-			continue
-		}
-		if !r.sourcePort.Contains(connection.RemoteAddr()) {
-			continue
-		}
-		if !r.destinationAddrs.Contains(connection.LocalAddr()) {
-			continue
-		}
-		if !r.destinationPort.Contains(connection.LocalAddr()) {
-			continue
+	if len(pc.routes) > 0 {
+		log.DefaultLogger.Tracef("Stream Proxy get route from entries , connection = %v, server name = %s", connection, serverName)
+		for _, r := range pc.routes {
+			log.DefaultLogger.Tracef("Stream Proxy check one route = %v", r)
+			if r.match(connection, serverName) {
+				return r.clusterName
+			}
 		}
-		return r.clusterName
+	}
+
+	if pc.cluster != "" {
+		log.DefaultLogger.Tracef("Stream Proxy get default cluster from config , cluster name = %v", pc.cluster)
+		return pc.cluster
 	}
 	log.DefaultLogger.Warnf("Stream Proxy find no cluster , connection = %v", connection)
 
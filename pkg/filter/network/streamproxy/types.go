@@ -41,6 +41,12 @@ type ProxyConfig interface {
 	GetReadTimeout(network string) time.Duration
 }
 
+// ServerNameProxyConfig is an optional interface of ProxyConfig that routes the connection by the server name too,
+// the server name is the requested server name of the tls connection
+type ServerNameProxyConfig interface {
+	GetRouteFromEntriesWithServerName(connection api.Connection, serverName string) string
+}
+
 // UpstreamCallbacks for upstream's callbacks
 type UpstreamCallbacks interface {
 	api.ReadFilter
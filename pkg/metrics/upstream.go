@@ -36,6 +36,8 @@ const (
 	UpstreamConnectionLocalCloseWithActiveRequest  = "connection_local_close_with_active_request"
 	UpstreamConnectionRemoteCloseWithActiveRequest = "connection_remote_close_with_active_request"
 	UpstreamConnectionCloseNotify                  = "connection_close_notify"
+	UpstreamConnectionPrewarm                      = "connection_prewarm"
+	UpstreamConnectionOnDemand                     = "connection_on_demand"
 	UpstreamRequestTotal                           = "request_total"
 	UpstreamRequestActive                          = "request_active"
 	UpstreamRequestLocalReset                      = "request_local_reset"
@@ -57,12 +59,27 @@ const (
 const (
 	UpstreamRequestRetry         = "request_retry"
 	UpstreamRequestRetryOverflow = "request_retry_overflow"
+	UpstreamRequestHedge         = "request_hedge"
+	UpstreamRequestHedgeWin      = "request_hedge_win"
+	UpstreamRequestHedgeCancel   = "request_hedge_cancel"
 	UpstreamLBSubSetsFallBack    = "lb_subsets_fallback"
 	UpstreamLBSubsetsCreated     = "lb_subsets_created"
 	UpstreamBytesReadTotal       = "connection_bytes_read_total"
 	UpstreamBytesReadBuffered    = "connection_bytes_read_buffered"
 	UpstreamBytesWriteTotal      = "connection_bytes_write"
 	UpstreamBytesWriteBuffered   = "connection_bytes_write_buffered"
+
+	UpstreamOutlierEjectionsTotal                     = "outlier_ejections_total"
+	UpstreamOutlierEjectionsActive                    = "outlier_ejections_active"
+	UpstreamOutlierEjectionsOverflow                  = "outlier_ejections_overflow"
+	UpstreamOutlierEjectionsConsecutive5xx            = "outlier_ejections_consecutive_5xx"
+	UpstreamOutlierEjectionsConsecutiveGatewayFailure = "outlier_ejections_consecutive_gateway_failure"
+	UpstreamOutlierEjectionsConsecutiveConnectFailure = "outlier_ejections_consecutive_connect_failure"
+	UpstreamOutlierEjectionsSuccessRate               = "outlier_ejections_success_rate"
+	UpstreamLBZoneRoutingAllDirectly                  = "lb_zone_routing_all_directly"
+	UpstreamLBZoneRoutingSampled                      = "lb_zone_routing_sampled"
+	UpstreamLBZoneRoutingCrossZone                    = "lb_zone_routing_cross_zone"
+	UpstreamLBHealthyPanic                            = "lb_healthy_panic"
 )
 
 // NewHostStats returns a stats that namespace contains cluster and host address
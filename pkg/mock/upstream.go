@@ -505,6 +505,20 @@ func (mr *MockHostSetMockRecorder) Get(i interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Get", reflect.TypeOf((*MockHostSet)(nil).Get), i)
 }
 
+// PriorityLevels mocks base method.
+func (m *MockHostSet) PriorityLevels() []types.HostSet {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PriorityLevels")
+	ret0, _ := ret[0].([]types.HostSet)
+	return ret0
+}
+
+// PriorityLevels indicates an expected call of PriorityLevels.
+func (mr *MockHostSetMockRecorder) PriorityLevels() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PriorityLevels", reflect.TypeOf((*MockHostSet)(nil).PriorityLevels))
+}
+
 // Range mocks base method.
 func (m *MockHostSet) Range(arg0 func(types.Host) bool) {
 	m.ctrl.T.Helper()
@@ -748,6 +762,20 @@ func (mr *MockHostMockRecorder) Metadata() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Metadata", reflect.TypeOf((*MockHost)(nil).Metadata))
 }
 
+// Priority mocks base method.
+func (m *MockHost) Priority() uint32 {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Priority")
+	ret0, _ := ret[0].(uint32)
+	return ret0
+}
+
+// Priority indicates an expected call of Priority.
+func (mr *MockHostMockRecorder) Priority() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Priority", reflect.TypeOf((*MockHost)(nil).Priority))
+}
+
 // SetClusterInfo mocks base method.
 func (m *MockHost) SetClusterInfo(info types.ClusterInfo) {
 	m.ctrl.T.Helper()
@@ -891,6 +919,34 @@ func (mr *MockClusterInfoMockRecorder) ConnectTimeout() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ConnectTimeout", reflect.TypeOf((*MockClusterInfo)(nil).ConnectTimeout))
 }
 
+// DegradedHostsEnabled mocks base method.
+func (m *MockClusterInfo) DegradedHostsEnabled() bool {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DegradedHostsEnabled")
+	ret0, _ := ret[0].(bool)
+	return ret0
+}
+
+// DegradedHostsEnabled indicates an expected call of DegradedHostsEnabled.
+func (mr *MockClusterInfoMockRecorder) DegradedHostsEnabled() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DegradedHostsEnabled", reflect.TypeOf((*MockClusterInfo)(nil).DegradedHostsEnabled))
+}
+
+// HealthyPanicThreshold mocks base method.
+func (m *MockClusterInfo) HealthyPanicThreshold() uint32 {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "HealthyPanicThreshold")
+	ret0, _ := ret[0].(uint32)
+	return ret0
+}
+
+// HealthyPanicThreshold indicates an expected call of HealthyPanicThreshold.
+func (mr *MockClusterInfoMockRecorder) HealthyPanicThreshold() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "HealthyPanicThreshold", reflect.TypeOf((*MockClusterInfo)(nil).HealthyPanicThreshold))
+}
+
 // IdleTimeout mocks base method.
 func (m *MockClusterInfo) IdleTimeout() time.Duration {
 	m.ctrl.T.Helper()
@@ -989,6 +1045,20 @@ func (mr *MockClusterInfoMockRecorder) Mark() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Mark", reflect.TypeOf((*MockClusterInfo)(nil).Mark))
 }
 
+// MaxConnectionDuration mocks base method.
+func (m *MockClusterInfo) MaxConnectionDuration() time.Duration {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MaxConnectionDuration")
+	ret0, _ := ret[0].(time.Duration)
+	return ret0
+}
+
+// MaxConnectionDuration indicates an expected call of MaxConnectionDuration.
+func (mr *MockClusterInfoMockRecorder) MaxConnectionDuration() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MaxConnectionDuration", reflect.TypeOf((*MockClusterInfo)(nil).MaxConnectionDuration))
+}
+
 // MaxRequestsPerConn mocks base method.
 func (m *MockClusterInfo) MaxRequestsPerConn() uint32 {
 	m.ctrl.T.Helper()
@@ -1017,6 +1087,34 @@ func (mr *MockClusterInfoMockRecorder) Name() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Name", reflect.TypeOf((*MockClusterInfo)(nil).Name))
 }
 
+// OutlierDetector mocks base method.
+func (m *MockClusterInfo) OutlierDetector() types.OutlierDetector {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "OutlierDetector")
+	ret0, _ := ret[0].(types.OutlierDetector)
+	return ret0
+}
+
+// OutlierDetector indicates an expected call of OutlierDetector.
+func (mr *MockClusterInfoMockRecorder) OutlierDetector() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "OutlierDetector", reflect.TypeOf((*MockClusterInfo)(nil).OutlierDetector))
+}
+
+// ProxyProtocol mocks base method.
+func (m *MockClusterInfo) ProxyProtocol() *v2.ProxyProtocolConfig {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ProxyProtocol")
+	ret0, _ := ret[0].(*v2.ProxyProtocolConfig)
+	return ret0
+}
+
+// ProxyProtocol indicates an expected call of ProxyProtocol.
+func (mr *MockClusterInfoMockRecorder) ProxyProtocol() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ProxyProtocol", reflect.TypeOf((*MockClusterInfo)(nil).ProxyProtocol))
+}
+
 // ResourceManager mocks base method.
 func (m *MockClusterInfo) ResourceManager() types.ResourceManager {
 	m.ctrl.T.Helper()
@@ -45,13 +45,23 @@ type serverContextManager struct {
 // NewTLSServerContextManager returns a types.TLSContextManager used in TLS Server
 // A Server Manager can contains multiple certificates in provider
 func NewTLSServerContextManager(cfg *v2.Listener) (types.TLSContextManager, error) {
+	return newTLSServerContextManager(cfg, cfg.FilterChains)
+}
+
+// NewTLSFilterChainContextManager returns a types.TLSContextManager used in TLS Server
+// with the certificates of the filter chain only
+func NewTLSFilterChainContextManager(cfg *v2.Listener, fc *v2.FilterChain) (types.TLSContextManager, error) {
+	return newTLSServerContextManager(cfg, []v2.FilterChain{*fc})
+}
+
+func newTLSServerContextManager(cfg *v2.Listener, filterChains []v2.FilterChain) (types.TLSContextManager, error) {
 	mng := &serverContextManager{
 		inspector: cfg.Inspector,
 	}
 	mng.config = &tls.Config{
 		GetConfigForClient: mng.GetConfigForClient,
 	}
-	for _, c := range cfg.FilterChains {
+	for _, c := range filterChains {
 		for _, tlsCfg := range c.TLSContexts {
 			provider, err := NewProvider(serverContextPrefix+cfg.Name, &tlsCfg)
 			if err != nil {
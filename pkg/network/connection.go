@@ -1046,7 +1046,9 @@ func (c *connection) BufferLimit() uint32 {
 }
 
 func (c *connection) SetLocalAddress(localAddress net.Addr, restored bool) {
-	// TODO
+	if localAddress != nil {
+		c.localAddr = localAddress
+	}
 	c.localAddressRestored = restored
 }
 
@@ -1096,6 +1098,8 @@ type clientConnection struct {
 	connectTimeout time.Duration
 
 	connectOnce sync.Once
+
+	proxyProtocolHeader []byte
 }
 
 func newClientConnection(connectTimeout time.Duration, tlsMng types.TLSClientContextManager, remoteAddr net.Addr, stopChan chan struct{}) types.ClientConnection {
@@ -1180,6 +1184,15 @@ func (cc *clientConnection) connect() (event api.ConnectionEvent, err error) {
 		}
 		return
 	}
+	// the PROXY protocol header is sent before the tls handshake
+	if len(cc.proxyProtocolHeader) > 0 {
+		if _, err = cc.rawConnection.Write(cc.proxyProtocolHeader); err != nil {
+			cc.rawConnection.Close()
+			event = api.ConnectFailed
+			return
+		}
+	}
+
 	atomic.StoreUint32(&cc.connected, 1)
 	event = api.Connected
 	cc.localAddr = cc.rawConnection.LocalAddr()
@@ -1248,3 +1261,8 @@ func (cc *clientConnection) Connect() (err error) {
 func (cc *clientConnection) SetMark(mark uint32) {
 	cc.mark = mark
 }
+
+// SetProxyProtocolHeader sets the PROXY protocol header sent once the connection is established
+func (cc *clientConnection) SetProxyProtocolHeader(header []byte) {
+	cc.proxyProtocolHeader = header
+}
@@ -0,0 +1,299 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package network
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+
+	v2 "mosn.io/mosn/pkg/config/v2"
+)
+
+// PROXY protocol v2 TLV types
+const (
+	PP2TypeALPN      byte = 0x01
+	PP2TypeAuthority byte = 0x02
+)
+
+// ProxyProtocolV2Signature is the signature of the PROXY protocol v2 header
+var ProxyProtocolV2Signature = []byte("\r\n\r\n\x00\r\nQUIT\n")
+
+const (
+	proxyProtocolV2VersionLocal = 0x20
+	proxyProtocolV2VersionProxy = 0x21
+	proxyProtocolV2FamilyUnspec = 0x00
+	proxyProtocolV2FamilyTCP4   = 0x11
+	proxyProtocolV2FamilyTCP6   = 0x21
+	proxyProtocolV2FamilyUDP4   = 0x12
+	proxyProtocolV2FamilyUDP6   = 0x22
+	proxyProtocolV2HeaderLen    = 16
+	proxyProtocolV1Unknown      = "PROXY UNKNOWN\r\n"
+	proxyProtocolV1Prefix       = "PROXY "
+	// proxyProtocolV1MaxLen is the max length of the v1 header, including the CRLF
+	proxyProtocolV1MaxLen = 107
+)
+
+var (
+	// ErrNotProxyProtocol means the data does not start with a PROXY protocol header
+	ErrNotProxyProtocol = errors.New("not proxy protocol")
+	// ErrProxyProtocolIncomplete means more data is required to decode the PROXY protocol header
+	ErrProxyProtocolIncomplete = errors.New("proxy protocol header is incomplete")
+)
+
+// ProxyProtocolTLV is a type-length-value of the PROXY protocol v2 header
+type ProxyProtocolTLV struct {
+	Type  byte
+	Value []byte
+}
+
+// ProxyProtocolHeader is the PROXY protocol header that carries the addresses of the downstream connection.
+// The header without the addresses is encoded as UNKNOWN in v1, and LOCAL command in v2.
+type ProxyProtocolHeader struct {
+	Version         string
+	SourceAddr      net.Addr
+	DestinationAddr net.Addr
+	// TLVs are only encoded in v2
+	TLVs []ProxyProtocolTLV
+}
+
+// Encode encodes the header in the wire format of the header version
+func (h *ProxyProtocolHeader) Encode() ([]byte, error) {
+	switch h.Version {
+	case v2.ProxyProtocolV1, "":
+		return h.encodeV1(), nil
+	case v2.ProxyProtocolV2:
+		return h.encodeV2()
+	default:
+		return nil, fmt.Errorf("unsupported proxy protocol version %s", h.Version)
+	}
+}
+
+// tcpAddrs returns the ip and port of the addresses, the ipv4 addresses are mapped to ipv6 if the families are mixed
+func (h *ProxyProtocolHeader) tcpAddrs() (srcIP, dstIP net.IP, srcPort, dstPort int, ok bool) {
+	src, srcOk := h.SourceAddr.(*net.TCPAddr)
+	dst, dstOk := h.DestinationAddr.(*net.TCPAddr)
+	if !srcOk || !dstOk || src == nil || dst == nil {
+		return
+	}
+	srcIP, dstIP = src.IP.To4(), dst.IP.To4()
+	if srcIP == nil || dstIP == nil {
+		srcIP, dstIP = src.IP.To16(), dst.IP.To16()
+	}
+	if srcIP == nil || dstIP == nil {
+		return
+	}
+	return srcIP, dstIP, src.Port, dst.Port, true
+}
+
+func (h *ProxyProtocolHeader) encodeV1() []byte {
+	srcIP, dstIP, srcPort, dstPort, ok := h.tcpAddrs()
+	if !ok {
+		return []byte(proxyProtocolV1Unknown)
+	}
+	if len(srcIP) == net.IPv4len {
+		return []byte("PROXY TCP4 " + srcIP.String() + " " + dstIP.String() + " " +
+			strconv.Itoa(srcPort) + " " + strconv.Itoa(dstPort) + "\r\n")
+	}
+	return []byte("PROXY TCP6 " + ipv6String(srcIP) + " " + ipv6String(dstIP) + " " +
+		strconv.Itoa(srcPort) + " " + strconv.Itoa(dstPort) + "\r\n")
+}
+
+// ipv6String formats the ipv4-mapped address in the ipv6 form, net.IP formats it as ipv4
+func ipv6String(ip net.IP) string {
+	if ip4 := ip.To4(); ip4 != nil {
+		return "::ffff:" + ip4.String()
+	}
+	return ip.String()
+}
+
+func (h *ProxyProtocolHeader) encodeV2() ([]byte, error) {
+	var addrs []byte
+	command := byte(proxyProtocolV2VersionProxy)
+	family := byte(proxyProtocolV2FamilyTCP4)
+	srcIP, dstIP, srcPort, dstPort, ok := h.tcpAddrs()
+	if ok {
+		if len(srcIP) == net.IPv6len {
+			family = proxyProtocolV2FamilyTCP6
+		}
+		addrs = make([]byte, 0, 2*len(srcIP)+4)
+		addrs = append(addrs, srcIP...)
+		addrs = append(addrs, dstIP...)
+		addrs = appendUint16(addrs, uint16(srcPort))
+		addrs = appendUint16(addrs, uint16(dstPort))
+	} else {
+		command = proxyProtocolV2VersionLocal
+		family = proxyProtocolV2FamilyUnspec
+	}
+	length := len(addrs)
+	for _, tlv := range h.TLVs {
+		length += 3 + len(tlv.Value)
+	}
+	if length > 0xffff {
+		return nil, fmt.Errorf("proxy protocol header is too long: %d", length)
+	}
+	buf := make([]byte, 0, len(ProxyProtocolV2Signature)+4+length)
+	buf = append(buf, ProxyProtocolV2Signature...)
+	buf = append(buf, command, family)
+	buf = appendUint16(buf, uint16(length))
+	buf = append(buf, addrs...)
+	for _, tlv := range h.TLVs {
+		buf = append(buf, tlv.Type)
+		buf = appendUint16(buf, uint16(len(tlv.Value)))
+		buf = append(buf, tlv.Value...)
+	}
+	return buf, nil
+}
+
+func appendUint16(b []byte, v uint16) []byte {
+	return append(b, byte(v>>8), byte(v))
+}
+
+// DecodeProxyProtocolHeader decodes the PROXY protocol v1 or v2 header at the beginning of b,
+// returns the header and the length of the header in b.
+// If ErrProxyProtocolIncomplete is returned, the returned length is the minimal length of the data required.
+func DecodeProxyProtocolHeader(b []byte) (*ProxyProtocolHeader, int, error) {
+	if hasPrefix(b, ProxyProtocolV2Signature) {
+		return decodeV2(b)
+	}
+	if hasPrefix(b, []byte(proxyProtocolV1Prefix)) {
+		return decodeV1(b)
+	}
+	return nil, 0, ErrNotProxyProtocol
+}
+
+// hasPrefix reports whether b begins with prefix, or b is a part of the prefix
+func hasPrefix(b, prefix []byte) bool {
+	if len(b) < len(prefix) {
+		return bytes.HasPrefix(prefix, b)
+	}
+	return bytes.HasPrefix(b, prefix)
+}
+
+func decodeV1(b []byte) (*ProxyProtocolHeader, int, error) {
+	end := bytes.Index(b, []byte("\r\n"))
+	if end < 0 {
+		if len(b) >= proxyProtocolV1MaxLen {
+			return nil, 0, errors.New("proxy protocol v1 header is too long")
+		}
+		return nil, len(b) + 1, ErrProxyProtocolIncomplete
+	}
+	n := end + 2
+	if n > proxyProtocolV1MaxLen {
+		return nil, 0, errors.New("proxy protocol v1 header is too long")
+	}
+	header := &ProxyProtocolHeader{
+		Version: v2.ProxyProtocolV1,
+	}
+	fields := strings.Split(string(b[:end]), " ")
+	if len(fields) >= 2 && fields[1] == "UNKNOWN" {
+		// the receiver must ignore the addresses of UNKNOWN
+		return header, n, nil
+	}
+	if len(fields) != 6 || (fields[1] != "TCP4" && fields[1] != "TCP6") {
+		return nil, 0, fmt.Errorf("invalid proxy protocol v1 header: %q", b[:end])
+	}
+	src, err := parseV1Addr(fields[1], fields[2], fields[4])
+	if err != nil {
+		return nil, 0, err
+	}
+	dst, err := parseV1Addr(fields[1], fields[3], fields[5])
+	if err != nil {
+		return nil, 0, err
+	}
+	header.SourceAddr, header.DestinationAddr = src, dst
+	return header, n, nil
+}
+
+func parseV1Addr(protocol, ip, port string) (*net.TCPAddr, error) {
+	addr := net.ParseIP(ip)
+	if addr == nil || (protocol == "TCP4") != (addr.To4() != nil && !strings.Contains(ip, ":")) {
+		return nil, fmt.Errorf("invalid proxy protocol v1 address: %s", ip)
+	}
+	p, err := strconv.ParseUint(port, 10, 16)
+	if err != nil {
+		return nil, fmt.Errorf("invalid proxy protocol v1 port: %s", port)
+	}
+	return &net.TCPAddr{IP: addr, Port: int(p)}, nil
+}
+
+func decodeV2(b []byte) (*ProxyProtocolHeader, int, error) {
+	if len(b) < proxyProtocolV2HeaderLen {
+		return nil, proxyProtocolV2HeaderLen, ErrProxyProtocolIncomplete
+	}
+	length := int(b[14])<<8 | int(b[15])
+	n := proxyProtocolV2HeaderLen + length
+	if len(b) < n {
+		return nil, n, ErrProxyProtocolIncomplete
+	}
+	header := &ProxyProtocolHeader{
+		Version: v2.ProxyProtocolV2,
+	}
+	payload := b[proxyProtocolV2HeaderLen:n]
+	switch b[12] {
+	case proxyProtocolV2VersionLocal:
+		// the connection is established by the proxy itself, the addresses are ignored
+		return header, n, nil
+	case proxyProtocolV2VersionProxy:
+	default:
+		return nil, 0, fmt.Errorf("unsupported proxy protocol v2 version and command: %#x", b[12])
+	}
+	var addrLen int
+	switch b[13] {
+	case proxyProtocolV2FamilyTCP4, proxyProtocolV2FamilyUDP4:
+		addrLen = net.IPv4len
+	case proxyProtocolV2FamilyTCP6, proxyProtocolV2FamilyUDP6:
+		addrLen = net.IPv6len
+	default:
+		// the unspecified and unix addresses are not supported, keeps the connection addresses
+		return header, n, nil
+	}
+	if len(payload) < 2*addrLen+4 {
+		return nil, 0, fmt.Errorf("invalid proxy protocol v2 address length: %d", len(payload))
+	}
+	srcIP := net.IP(append([]byte{}, payload[:addrLen]...))
+	dstIP := net.IP(append([]byte{}, payload[addrLen:2*addrLen]...))
+	srcPort := int(payload[2*addrLen])<<8 | int(payload[2*addrLen+1])
+	dstPort := int(payload[2*addrLen+2])<<8 | int(payload[2*addrLen+3])
+	if b[13] == proxyProtocolV2FamilyUDP4 || b[13] == proxyProtocolV2FamilyUDP6 {
+		header.SourceAddr = &net.UDPAddr{IP: srcIP, Port: srcPort}
+		header.DestinationAddr = &net.UDPAddr{IP: dstIP, Port: dstPort}
+	} else {
+		header.SourceAddr = &net.TCPAddr{IP: srcIP, Port: srcPort}
+		header.DestinationAddr = &net.TCPAddr{IP: dstIP, Port: dstPort}
+	}
+	tlvs := payload[2*addrLen+4:]
+	for len(tlvs) > 0 {
+		if len(tlvs) < 3 {
+			return nil, 0, errors.New("invalid proxy protocol v2 tlv")
+		}
+		l := int(tlvs[1])<<8 | int(tlvs[2])
+		if len(tlvs) < 3+l {
+			return nil, 0, errors.New("invalid proxy protocol v2 tlv")
+		}
+		header.TLVs = append(header.TLVs, ProxyProtocolTLV{
+			Type:  tlvs[0],
+			Value: append([]byte{}, tlvs[3:3+l]...),
+		})
+		tlvs = tlvs[3+l:]
+	}
+	return header, n, nil
+}
@@ -0,0 +1,184 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package network
+
+import (
+	"io"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"mosn.io/api"
+	v2 "mosn.io/mosn/pkg/config/v2"
+)
+
+func TestProxyProtocolHeaderEncodeV1(t *testing.T) {
+	src, _ := net.ResolveTCPAddr("tcp", "192.168.0.1:56324")
+	dst, _ := net.ResolveTCPAddr("tcp", "192.168.0.11:443")
+	src6, _ := net.ResolveTCPAddr("tcp", "[2001:db8::1]:56324")
+	for _, tc := range []struct {
+		header   ProxyProtocolHeader
+		expected string
+	}{
+		{
+			header:   ProxyProtocolHeader{SourceAddr: src, DestinationAddr: dst},
+			expected: "PROXY TCP4 192.168.0.1 192.168.0.11 56324 443\r\n",
+		},
+		{
+			header:   ProxyProtocolHeader{Version: v2.ProxyProtocolV1, SourceAddr: src6, DestinationAddr: dst},
+			expected: "PROXY TCP6 2001:db8::1 ::ffff:192.168.0.11 56324 443\r\n",
+		},
+		{
+			header:   ProxyProtocolHeader{Version: v2.ProxyProtocolV1},
+			expected: "PROXY UNKNOWN\r\n",
+		},
+	} {
+		b, err := tc.header.Encode()
+		require.Nil(t, err)
+		assert.Equal(t, tc.expected, string(b))
+	}
+}
+
+func TestProxyProtocolHeaderEncodeV2(t *testing.T) {
+	src, _ := net.ResolveTCPAddr("tcp", "192.168.0.1:56324")
+	dst, _ := net.ResolveTCPAddr("tcp", "192.168.0.11:443")
+	header := ProxyProtocolHeader{
+		Version:         v2.ProxyProtocolV2,
+		SourceAddr:      src,
+		DestinationAddr: dst,
+		TLVs: []ProxyProtocolTLV{
+			{Type: PP2TypeALPN, Value: []byte("h2")},
+		},
+	}
+	b, err := header.Encode()
+	require.Nil(t, err)
+	expected := append([]byte{}, ProxyProtocolV2Signature...)
+	expected = append(expected, 0x21, 0x11, 0x00, 0x11)
+	expected = append(expected, 192, 168, 0, 1, 192, 168, 0, 11, 0xdc, 0x04, 0x01, 0xbb)
+	expected = append(expected, 0x01, 0x00, 0x02, 'h', '2')
+	assert.Equal(t, expected, b)
+
+	// the LOCAL command without the addresses
+	b, err = (&ProxyProtocolHeader{Version: v2.ProxyProtocolV2}).Encode()
+	require.Nil(t, err)
+	expected = append([]byte{}, ProxyProtocolV2Signature...)
+	expected = append(expected, 0x20, 0x00, 0x00, 0x00)
+	assert.Equal(t, expected, b)
+
+	_, err = (&ProxyProtocolHeader{Version: "v3"}).Encode()
+	assert.NotNil(t, err)
+}
+
+func TestClientConnectionSendProxyProtocolHeader(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.Nil(t, err)
+	defer l.Close()
+	received := make(chan []byte, 1)
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		b := make([]byte, len("PROXY UNKNOWN\r\n"))
+		io.ReadFull(conn, b)
+		received <- b
+	}()
+
+	cc := NewClientConnection(0, nil, l.Addr(), nil)
+	header, err := (&ProxyProtocolHeader{}).Encode()
+	require.Nil(t, err)
+	cc.(*clientConnection).SetProxyProtocolHeader(header)
+	require.Nil(t, cc.Connect())
+	defer cc.Close(api.NoFlush, api.LocalClose)
+	assert.Equal(t, "PROXY UNKNOWN\r\n", string(<-received))
+}
+
+func TestDecodeProxyProtocolHeader(t *testing.T) {
+	src, _ := net.ResolveTCPAddr("tcp", "192.168.0.1:56324")
+	dst, _ := net.ResolveTCPAddr("tcp", "192.168.0.11:443")
+	src6, _ := net.ResolveTCPAddr("tcp", "[2001:db8::1]:56324")
+	dst6, _ := net.ResolveTCPAddr("tcp", "[2001:db8::2]:443")
+	for _, header := range []*ProxyProtocolHeader{
+		{Version: v2.ProxyProtocolV1, SourceAddr: src, DestinationAddr: dst},
+		{Version: v2.ProxyProtocolV1, SourceAddr: src6, DestinationAddr: dst6},
+		{Version: v2.ProxyProtocolV1},
+		{Version: v2.ProxyProtocolV2, SourceAddr: src, DestinationAddr: dst},
+		{Version: v2.ProxyProtocolV2, SourceAddr: src6, DestinationAddr: dst6, TLVs: []ProxyProtocolTLV{
+			{Type: PP2TypeALPN, Value: []byte("h2")},
+			{Type: PP2TypeAuthority, Value: []byte("www.example.com")},
+		}},
+		{Version: v2.ProxyProtocolV2},
+	} {
+		b, err := header.Encode()
+		require.Nil(t, err)
+		decoded, n, err := DecodeProxyProtocolHeader(append(b, "hello"...))
+		require.Nil(t, err)
+		assert.Equal(t, len(b), n)
+		assert.Equal(t, header.Version, decoded.Version)
+		assert.Equal(t, header.TLVs, decoded.TLVs)
+		if header.SourceAddr == nil {
+			assert.Nil(t, decoded.SourceAddr)
+			assert.Nil(t, decoded.DestinationAddr)
+		} else {
+			assert.Equal(t, header.SourceAddr.String(), decoded.SourceAddr.String())
+			assert.Equal(t, header.DestinationAddr.String(), decoded.DestinationAddr.String())
+		}
+		// the incomplete header
+		_, _, err = DecodeProxyProtocolHeader(b[:len(b)-1])
+		assert.Equal(t, ErrProxyProtocolIncomplete, err)
+	}
+
+	for _, b := range []string{
+		"GET / HTTP/1.1\r\n",
+		"PROXZ",
+		"\r\n\r\n\x00\r\nQUIZ",
+	} {
+		_, _, err := DecodeProxyProtocolHeader([]byte(b))
+		assert.Equal(t, ErrNotProxyProtocol, err, b)
+	}
+	for _, b := range []string{
+		"PRO",
+		"PROXY TCP4 192.168.0.1",
+		"\r\n\r\n\x00\r\n",
+	} {
+		_, _, err := DecodeProxyProtocolHeader([]byte(b))
+		assert.Equal(t, ErrProxyProtocolIncomplete, err, b)
+	}
+	// the v2 header requires the whole length
+	_, n, err := DecodeProxyProtocolHeader(append(append([]byte{}, ProxyProtocolV2Signature...), 0x21, 0x11, 0x01, 0x00))
+	assert.Equal(t, ErrProxyProtocolIncomplete, err)
+	assert.Equal(t, 16+256, n)
+
+	for _, b := range []string{
+		"PROXY TCP4 192.168.0.1 192.168.0.11 56324\r\n",
+		"PROXY TCP4 2001:db8::1 192.168.0.11 56324 443\r\n",
+		"PROXY TCP6 192.168.0.1 192.168.0.11 56324 443\r\n",
+		"PROXY TCP4 192.168.0.1 192.168.0.11 56324 65536\r\n",
+		"PROXY " + strings.Repeat("A", 110),
+		"\r\n\r\n\x00\r\nQUIT\n\x31\x11\x00\x00",
+		"\r\n\r\n\x00\r\nQUIT\n\x21\x11\x00\x04\x01\x02\x03\x04",
+	} {
+		_, _, err := DecodeProxyProtocolHeader([]byte(b))
+		assert.NotNil(t, err, b)
+		assert.NotEqual(t, ErrProxyProtocolIncomplete, err, b)
+		assert.NotEqual(t, ErrNotProxyProtocol, err, b)
+	}
+}
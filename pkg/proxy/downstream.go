@@ -67,6 +67,17 @@ type downStream struct {
 	upstreamRequest *upstreamRequest
 	perRetryTimer   *utils.Timer
 	responseTimer   *utils.Timer
+	// set to 1 while the retry is waiting for the back off timer
+	retryWaiting uint32
+	// the back off of the current retry is done
+	retryBackOffDone bool
+
+	// ~~~ hedged upstream requests that are still waiting for the response
+	hedgedRequests []*upstreamRequest
+	// set to 1 while the downstream is waiting for the hedged requests after the latest request failed
+	waitingHedges uint32
+	// a hedged request responds while the downstream is waiting for it
+	hedgeResponseReceived bool
 
 	// ~~~ downstream request buf
 	downstreamReqHeaders  types.HeaderMap
@@ -105,6 +116,9 @@ type downStream struct {
 	reuseBuffer       uint32
 
 	resetReason uatomic.String //types.StreamResetReason
+	// the upstream request that is reset, it may be not the latest upstream request if the request is hedged
+	// it is stored by the stream callbacks and loaded by the worker, stores *upstreamRequest
+	resetUpstreamRequest atomic.Value
 
 	// stream filter chain
 	streamFilterChain         streamFilterChain
@@ -354,6 +368,8 @@ func (s *downStream) OnResetStream(reason types.StreamResetReason) {
 	s.resetReason.Store(reason)
 
 	s.sendNotify()
+	// the retry is waiting for the back off, receive it again to process the reset
+	s.resumeRetry(atomic.LoadUint32(&s.ID))
 }
 
 func (s *downStream) ResetStream(reason types.StreamResetReason) {
@@ -379,7 +395,11 @@ func (s *downStream) OnReceive(ctx context.Context, headers types.HeaderMap, dat
 		log.Proxy.Tracef(s.context, "[proxy] [downstream] OnReceive headers:%+v, data:%+v, trailers:%+v", headers, data, trailers)
 	}
 
-	id := atomic.LoadUint32(&s.ID)
+	s.scheduleReceive(atomic.LoadUint32(&s.ID), types.InitPhase)
+}
+
+// scheduleReceive runs the phases from the phase in the worker pool if it is enabled
+func (s *downStream) scheduleReceive(id uint32, phase types.Phase) {
 	var task = func() {
 		defer func() {
 			if r := recover(); r != nil {
@@ -392,7 +412,6 @@ func (s *downStream) OnReceive(ctx context.Context, headers types.HeaderMap, dat
 			}
 		}()
 
-		phase := types.InitPhase
 		for i := 0; i < 10; i++ {
 			s.cleanNotify()
 
@@ -432,8 +451,6 @@ func (s *downStream) OnReceive(ctx context.Context, headers types.HeaderMap, dat
 	}
 
 	task()
-	return
-
 }
 
 func (s *downStream) printPhaseInfo(phaseId types.Phase, proxyId uint32) {
@@ -576,6 +593,14 @@ func (s *downStream) receive(ctx context.Context, id uint32, phase types.Phase)
 		// retry request
 		case types.Retry:
 			s.printPhaseInfo(phase, id)
+			// the stream is received again from the retry phase after the back off
+			if s.waitRetryBackOff(id) {
+				return types.End
+			}
+			// the stream may be reset or timeout during the back off
+			if p, err := s.processError(id); err != nil {
+				return p
+			}
 			if s.downstreamReqDataBuf != nil {
 				s.downstreamReqDataBuf.Count(1)
 			}
@@ -591,6 +616,7 @@ func (s *downStream) receive(ctx context.Context, id uint32, phase types.Phase)
 			if p, err := s.waitNotify(id); err != nil {
 				return p
 			}
+			s.onHedgeResponse()
 
 			if log.Proxy.GetLogLevel() >= log.DEBUG {
 				log.Proxy.Debugf(s.context, "[proxy] [downstream] OnReceive send downstream response")
@@ -999,6 +1025,15 @@ func (s *downStream) onResponseTimeout() {
 	}()
 	s.cluster.Stats().UpstreamRequestTimeout.Inc(1)
 
+	// the latest request is already finished, wakes up the downstream that is waiting for the hedged requests
+	if atomic.LoadUint32(&s.waitingHedges) == 1 {
+		if atomic.CompareAndSwapUint32(&s.upstreamReset, 0, 1) {
+			s.resetReason.Store(types.UpstreamGlobalTimeout)
+			s.sendNotify()
+		}
+		return
+	}
+
 	if s.upstreamRequest != nil {
 		if s.upstreamRequest.host != nil {
 			s.upstreamRequest.host.HostStats().UpstreamRequestTimeout.Inc(1)
@@ -1012,11 +1047,18 @@ func (s *downStream) onResponseTimeout() {
 		s.upstreamRequest.resetStream()
 		s.upstreamRequest.OnResetStream(types.UpstreamGlobalTimeout)
 	}
+	// the retry is waiting for the back off, receive it again to process the timeout
+	s.resumeRetry(atomic.LoadUint32(&s.ID))
 }
 
 func (s *downStream) setupPerReqTimeout() {
 	timeout := s.timeout
 
+	// no more hedged requests can be sent, waits for the responses of all the requests until the global timeout
+	if s.retryState != nil && s.retryState.hedge && s.retryState.retiesRemaining == 0 {
+		return
+	}
+
 	if timeout.TryTimeout > 0 {
 		if s.perRetryTimer != nil {
 			s.perRetryTimer.Stop()
@@ -1061,8 +1103,11 @@ func (s *downStream) onPerReqTimeout() {
 				s.upstreamRequest.host.AddressString(), s.timeout.TryTimeout.String())
 		}
 
-		s.upstreamRequest.resetStream()
-		s.requestInfo.SetResponseFlag(api.UpstreamRequestTimeout)
+		// the hedged request keeps waiting for the response
+		if s.retryState == nil || !s.retryState.hedge {
+			s.upstreamRequest.resetStream()
+			s.requestInfo.SetResponseFlag(api.UpstreamRequestTimeout)
+		}
 		s.upstreamRequest.OnResetStream(types.UpstreamPerTryTimeout)
 
 		return
@@ -1132,15 +1177,28 @@ func (s *downStream) appendTrailers() {
 // ~~~ upstream event handler
 func (s *downStream) onUpstreamReset(reason types.StreamResetReason) {
 	// todo: update stats
+	resetRequest, _ := s.resetUpstreamRequest.Load().(*upstreamRequest)
+	if resetRequest == nil {
+		resetRequest = s.upstreamRequest
+	}
+	// clear the reset request, the next reset may be reported without it, such as the global timeout
+	s.resetUpstreamRequest.Store((*upstreamRequest)(nil))
+	// the timed out request is not failed if it is hedged, it keeps waiting for the response
+	if !s.isHedgeTimeout(reason) {
+		s.putOutlierResult(resetRequest, resetReasonToOutlierResult(reason))
+	}
 	// see if we need a retry
 	if reason != types.UpstreamGlobalTimeout &&
 		!s.downstreamResponseStarted && s.retryState != nil {
 		retryCheck := s.retryState.retry(s.context, nil, reason)
 
-		if retryCheck == api.ShouldRetry && s.setupRetry(true) {
+		if retryCheck == api.ShouldRetry && (s.setupHedge(reason) || s.setupRetry(true)) {
 			if s.upstreamRequest != nil && s.upstreamRequest.host != nil {
-				s.upstreamRequest.host.HostStats().UpstreamResponseFailed.Inc(1)
-				s.upstreamRequest.host.ClusterInfo().Stats().UpstreamResponseFailed.Inc(1)
+				if !s.upstreamRequest.hedged.Load() {
+					s.upstreamRequest.host.HostStats().UpstreamResponseFailed.Inc(1)
+					s.upstreamRequest.host.ClusterInfo().Stats().UpstreamResponseFailed.Inc(1)
+				}
+				s.retryState.onHostAttempted(s.upstreamRequest.host)
 			}
 
 			// setup retry timer and return
@@ -1154,6 +1212,26 @@ func (s *downStream) onUpstreamReset(reason types.StreamResetReason) {
 		if retryCheck == api.RetryOverflow {
 			s.requestInfo.SetResponseFlag(api.UpstreamOverflow)
 		}
+
+		// the request fails only if none of the hedged requests responds
+		if reason == types.UpstreamPerTryTimeout && s.retryState.hedge && !s.upstreamRequest.hedged.Load() {
+			// the timed out request is not reset, keeps waiting for its response too
+			s.upstreamRequest.hedged.Store(true)
+			s.hedgedRequests = append(s.hedgedRequests, s.upstreamRequest)
+			atomic.CompareAndSwapUint32(&s.upstreamResponseReceived, 1, 0)
+		}
+		if s.waitHedgedRequests() {
+			s.hedgeResponseReceived = true
+			return
+		}
+		if atomic.LoadUint32(&s.downstreamReset) == 1 {
+			s.cleanUp()
+			return
+		}
+		// the global timeout fires while waiting
+		if atomic.LoadUint32(&s.upstreamReset) == 1 {
+			reason = s.resetReason.Load()
+		}
 	}
 
 	// clean up all timers
@@ -1183,6 +1261,8 @@ func (s *downStream) onUpstreamReset(reason types.StreamResetReason) {
 func (s *downStream) onUpstreamHeaders(endStream bool) {
 	headers := s.downstreamRespHeaders
 
+	s.putOutlierResult(s.upstreamRequest, responseCodeToOutlierResult(s.requestInfo.ResponseCode()))
+
 	// check retry
 	if s.retryState != nil {
 		retryCheck := s.retryState.retry(s.context, headers, "")
@@ -1191,6 +1271,7 @@ func (s *downStream) onUpstreamHeaders(endStream bool) {
 			if s.upstreamRequest != nil && s.upstreamRequest.host != nil {
 				s.upstreamRequest.host.HostStats().UpstreamResponseFailed.Inc(1)
 				s.upstreamRequest.host.ClusterInfo().Stats().UpstreamResponseFailed.Inc(1)
+				s.retryState.onHostAttempted(s.upstreamRequest.host)
 			}
 
 			return
@@ -1240,6 +1321,46 @@ func (s *downStream) handleUpstreamStatusCode() {
 	}
 }
 
+// putOutlierResult reports the result of the upstream request to the outlier detector of the host's cluster
+func (s *downStream) putOutlierResult(req *upstreamRequest, result types.OutlierResult) {
+	if result < 0 || req == nil || req.host == nil {
+		return
+	}
+	host := req.host
+	info := host.ClusterInfo()
+	if info == nil {
+		return
+	}
+	if od := info.OutlierDetector(); od != nil {
+		od.PutResult(host, result)
+	}
+}
+
+func responseCodeToOutlierResult(code int) types.OutlierResult {
+	switch {
+	case code == http.BadGateway || code == http.ServiceUnavailable || code == http.GatewayTimeout:
+		return types.OutlierGatewayFailure
+	case code >= http.InternalServerError:
+		return types.Outlier5xx
+	default:
+		return types.OutlierSuccess
+	}
+}
+
+// resetReasonToOutlierResult returns -1 if the reset should not be reported,
+// such as the overflow and local reset
+func resetReasonToOutlierResult(reason types.StreamResetReason) types.OutlierResult {
+	switch reason {
+	case types.StreamConnectionFailed:
+		return types.OutlierConnectFailure
+	case types.StreamConnectionTermination, types.StreamRemoteReset, types.UpstreamReset,
+		types.UpstreamGlobalTimeout, types.UpstreamPerTryTimeout:
+		return types.OutlierGatewayFailure
+	default:
+		return -1
+	}
+}
+
 func (s *downStream) onUpstreamData(endStream bool) {
 	if endStream {
 		s.onUpstreamResponseRecvFinished()
@@ -1290,7 +1411,7 @@ func (s *downStream) onUpstreamResponseRecvFinished() {
 }
 
 func (s *downStream) setupRetry(endStream bool) bool {
-	s.upstreamRequest.setupRetry = true
+	s.upstreamRequest.setupRetry.Store(true)
 
 	if !endStream {
 		s.upstreamRequest.resetStream()
@@ -1307,11 +1428,146 @@ func (s *downStream) setupRetry(endStream bool) bool {
 	return true
 }
 
-// Note: retry-timer MUST be stopped before active stream got recycled, otherwise resetting stream's properties will cause panic here
-func (s *downStream) doRetry() {
-	// retry interval
-	time.Sleep(10 * time.Millisecond)
+// isHedgeTimeout checks whether the reset is a per try timeout that hedges the request
+func (s *downStream) isHedgeTimeout(reason types.StreamResetReason) bool {
+	return reason == types.UpstreamPerTryTimeout && s.retryState != nil && s.retryState.hedge
+}
 
+// setupHedge keeps the timed out upstream request waiting for the response and retries,
+// returns false if the request is not hedged on per try timeout.
+func (s *downStream) setupHedge(reason types.StreamResetReason) bool {
+	if !s.isHedgeTimeout(reason) {
+		return false
+	}
+	// the hedged request is also setup retry to turn into the retry phase,
+	// but its response is still accepted
+	s.upstreamRequest.hedged.Store(true)
+	s.upstreamRequest.setupRetry.Store(true)
+	s.hedgedRequests = append(s.hedgedRequests, s.upstreamRequest)
+	s.cluster.Stats().UpstreamRequestHedge.Inc(1)
+
+	if s.perRetryTimer != nil {
+		s.perRetryTimer.Stop()
+		s.perRetryTimer = nil
+	}
+
+	atomic.CompareAndSwapUint32(&s.upstreamResponseReceived, 1, 0)
+
+	return true
+}
+
+// onHedgeResponse uses the upstream request that receives the response first,
+// and resets the others that are still waiting for the response.
+func (s *downStream) onHedgeResponse() {
+	if len(s.hedgedRequests) == 0 {
+		return
+	}
+	winner := s.upstreamRequest
+	for _, req := range s.hedgedRequests {
+		if atomic.LoadUint32(&req.hedgeState) == hedgeResponded {
+			winner = req
+			break
+		}
+	}
+	if winner != s.upstreamRequest && !s.upstreamRequest.hedged.Load() {
+		s.upstreamRequest.resetStream()
+		s.cluster.Stats().UpstreamRequestHedgeCancel.Inc(1)
+	}
+	// the first request is not the winner, means the hedging works
+	if winner != s.hedgedRequests[0] {
+		s.cluster.Stats().UpstreamRequestHedgeWin.Inc(1)
+	}
+	for _, req := range s.hedgedRequests {
+		if req != winner {
+			req.resetStream()
+			s.cluster.Stats().UpstreamRequestHedgeCancel.Inc(1)
+		}
+	}
+	if log.Proxy.GetLogLevel() >= log.DEBUG {
+		log.Proxy.Debugf(s.context, "[proxy] [downstream] hedged requests: %d, response received from host: %s",
+			len(s.hedgedRequests), winner.host.AddressString())
+	}
+	winner.hedged.Store(false)
+	winner.setupRetry.Store(false)
+	s.upstreamRequest = winner
+	s.hedgedRequests = nil
+}
+
+// waitHedgedRequests blocks until one of the hedged requests responds or all of them are reset,
+// returns true if a response is received. It is called when the latest request fails and
+// no more request can be sent, the downstream or the global timeout also stops the waiting.
+func (s *downStream) waitHedgedRequests() bool {
+	if len(s.hedgedRequests) == 0 || s.downstreamResponseStarted {
+		return false
+	}
+	if s.perRetryTimer != nil {
+		s.perRetryTimer.Stop()
+		s.perRetryTimer = nil
+	}
+	// the hedged requests ignore the response if the upstream reset flag is set
+	atomic.CompareAndSwapUint32(&s.upstreamReset, 1, 0)
+
+	atomic.StoreUint32(&s.waitingHedges, 1)
+	defer atomic.StoreUint32(&s.waitingHedges, 0)
+	for {
+		finished := 0
+		for _, req := range s.hedgedRequests {
+			switch atomic.LoadUint32(&req.hedgeState) {
+			case hedgeResponded:
+				return true
+			case hedgeReset:
+				finished++
+			}
+		}
+		if finished == len(s.hedgedRequests) {
+			return false
+		}
+		if log.Proxy.GetLogLevel() >= log.DEBUG {
+			log.Proxy.Debugf(s.context, "[proxy] [downstream] wait for hedged requests: %d, finished: %d", len(s.hedgedRequests), finished)
+		}
+		<-s.notify
+		if atomic.LoadUint32(&s.downstreamReset) == 1 || atomic.LoadUint32(&s.upstreamReset) == 1 {
+			return false
+		}
+	}
+}
+
+// waitRetryBackOff starts the back off timer of the retry, it returns false if the back off is done or not needed.
+// the worker is not blocked by the back off, the stream is received again from the retry phase when the timer fires,
+// or the stream is reset or timeout during the back off. the timer is not stopped, it does nothing if the retry
+// is resumed already or the stream is recycled.
+func (s *downStream) waitRetryBackOff(id uint32) bool {
+	if s.retryBackOffDone {
+		s.retryBackOffDone = false
+		return false
+	}
+	backOff := defaultRetryInterval
+	if s.retryState != nil {
+		backOff = s.retryState.nextBackOff()
+	}
+	if backOff <= 0 {
+		return false
+	}
+	s.retryBackOffDone = true
+	atomic.StoreUint32(&s.retryWaiting, 1)
+	utils.NewTimer(backOff, func() {
+		s.resumeRetry(id)
+	})
+	return true
+}
+
+// resumeRetry receives the stream from the retry phase if the retry is waiting for the back off
+func (s *downStream) resumeRetry(id uint32) {
+	if id != atomic.LoadUint32(&s.ID) {
+		return
+	}
+	if !atomic.CompareAndSwapUint32(&s.retryWaiting, 1, 0) {
+		return
+	}
+	s.scheduleReceive(id, types.Retry)
+}
+
+func (s *downStream) doRetry() {
 	// no reuse buffer
 	atomic.StoreUint32(&s.reuseBuffer, 0)
 
@@ -1320,11 +1576,24 @@ func (s *downStream) doRetry() {
 	if err != nil {
 		// https://github.com/mosn/mosn/issues/1750
 		if s.upstreamRequest != nil {
-			s.upstreamRequest.setupRetry = false
+			s.upstreamRequest.setupRetry.Store(false)
 		}
 
 		log.Proxy.Alertf(s.context, types.ErrorKeyUpstreamConn, "retry choose conn pool failed, error = %v", err)
-		s.sendHijackReply(api.NoHealthUpstreamCode, s.downstreamReqHeaders)
+		if s.waitHedgedRequests() {
+			s.hedgeResponseReceived = true
+			return
+		}
+		if atomic.LoadUint32(&s.downstreamReset) == 1 {
+			s.cleanUp()
+			return
+		}
+		code := api.NoHealthUpstreamCode
+		if atomic.LoadUint32(&s.upstreamReset) == 1 {
+			atomic.StoreUint32(&s.upstreamReset, 0)
+			code = types.ConvertReasonToCode(s.resetReason.Load())
+		}
+		s.sendHijackReply(code, s.downstreamReqHeaders)
 		s.cleanUp()
 		return
 	}
@@ -1424,6 +1693,13 @@ func (s *downStream) cleanUp() {
 		s.responseTimer = nil
 	}
 
+	// reset the hedged requests that are still waiting for the response
+	for _, req := range s.hedgedRequests {
+		req.resetStream()
+		s.cluster.Stats().UpstreamRequestHedgeCancel.Inc(1)
+	}
+	s.hedgedRequests = nil
+
 }
 
 func (s *downStream) setBufferLimit(bufferLimit uint32) {
@@ -1481,6 +1757,40 @@ func (s *downStream) DownstreamRoute() api.Route {
 	return s.route
 }
 
+// types.HostPredicateContext
+func (s *downStream) AttemptedHosts() []types.Host {
+	if s.retryState == nil {
+		return nil
+	}
+	return s.retryState.attemptedHosts
+}
+
+func (s *downStream) ShouldSelectAnotherHost(host types.Host) bool {
+	// the hedged request is sent to a different host
+	for _, req := range s.hedgedRequests {
+		if req.host != nil && req.host.AddressString() == host.AddressString() {
+			return true
+		}
+	}
+	if s.retryState == nil {
+		return false
+	}
+	return s.retryState.shouldSelectAnotherHost(host)
+}
+
+func (s *downStream) HostSelectionRetryCount() int {
+	if s.retryState == nil {
+		return 0
+	}
+	if len(s.hedgedRequests) > 0 && s.retryState.hostMaxAttempts == 0 {
+		return defaultHostSelectionMaxAttempts
+	}
+	if len(s.retryState.attemptedHosts) == 0 {
+		return 0
+	}
+	return s.retryState.hostMaxAttempts
+}
+
 func (s *downStream) giveStream() {
 	if atomic.LoadUint32(&s.reuseBuffer) != 1 {
 		return
@@ -1572,6 +1882,15 @@ func (s *downStream) processError(id uint32) (phase types.Phase, err error) {
 		return
 	}
 
+	// the latest request failed, but one of the hedged requests responds
+	if s.hedgeResponseReceived {
+		s.hedgeResponseReceived = false
+		s.onHedgeResponse()
+		phase = types.UpFilter
+		err = types.ErrExit
+		return
+	}
+
 	if s.directResponse {
 		variable.SetString(s.context, types.VarProxyIsDirectResponse, types.IsDirectResponse)
 		s.directResponse = false
@@ -1596,9 +1915,9 @@ func (s *downStream) processError(id uint32) (phase types.Phase, err error) {
 		err = types.ErrExit
 	}
 
-	if s.upstreamRequest != nil && s.upstreamRequest.setupRetry {
+	if s.upstreamRequest != nil && s.upstreamRequest.setupRetry.Load() {
 		// https://github.com/mosn/mosn/issues/1750
-		s.upstreamRequest.setupRetry = false
+		s.upstreamRequest.setupRetry.Store(false)
 
 		phase = types.Retry
 		err = types.ErrExit
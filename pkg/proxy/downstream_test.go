@@ -19,6 +19,7 @@ package proxy
 
 import (
 	"context"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -33,6 +34,7 @@ import (
 	"mosn.io/mosn/pkg/streamfilter"
 	"mosn.io/mosn/pkg/trace"
 	"mosn.io/mosn/pkg/types"
+	"mosn.io/mosn/pkg/upstream/cluster"
 	"mosn.io/pkg/buffer"
 	"mosn.io/pkg/variable"
 )
@@ -332,7 +334,7 @@ func TestProcessError(t *testing.T) {
 
 	s = &downStream{}
 	s.upstreamRequest = &upstreamRequest{}
-	s.upstreamRequest.setupRetry = true
+	s.upstreamRequest.setupRetry.Store(true)
 	p, e = s.processError(0)
 	if p != types.Retry || e != types.ErrExit {
 		t.Errorf("TestprocessError Error")
@@ -403,14 +405,12 @@ func TestRetryEmptyUpstreamHosts(t *testing.T) {
 	requestInfo := &network.RequestInfo{}
 
 	s := &downStream{
-		ID:      1,
-		context: ctx,
-		cluster: cluster,
-		upstreamRequest: &upstreamRequest{
-			setupRetry: true,
-		},
-		responseSender: responseSender,
-		requestInfo:    requestInfo,
+		ID:              1,
+		context:         ctx,
+		cluster:         cluster,
+		upstreamRequest: &upstreamRequest{},
+		responseSender:  responseSender,
+		requestInfo:     requestInfo,
 		proxy: &proxy{
 			config: &v2.Proxy{
 				UpstreamProtocol: "HTTP2",
@@ -424,15 +424,100 @@ func TestRetryEmptyUpstreamHosts(t *testing.T) {
 		},
 	}
 	s.upstreamRequest.downStream = s
+	s.upstreamRequest.setupRetry.Store(true)
 	phase, _ := s.processError(1)
 	assert.Equal(t, types.Retry, phase)
+	// the retry waits for the back off without blocking
 	phase = s.receive(ctx, 1, phase)
+	assert.Equal(t, types.End, phase)
+	// resume the retry instead of the back off timer
+	assert.True(t, atomic.CompareAndSwapUint32(&s.retryWaiting, 1, 0))
+	phase = s.receive(ctx, 1, types.Retry)
 	assert.Equal(t, types.UpFilter, phase)
 	phase = s.receive(ctx, 1, phase)
 	assert.Equal(t, types.End, phase)
 	assert.Equal(t, true, s.processDone())
 }
 
+func TestRetryBackOffNotBlocking(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	clusterInfo := cluster.NewClusterInfo(v2.Cluster{Name: "retry_back_off"})
+	clusterManager := mock.NewMockClusterManager(ctrl)
+	clusterManager.EXPECT().ConnPoolForCluster(gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes().Return(nil, nil)
+	responseSender := mock.NewMockStreamSender(ctrl)
+	responseSender.EXPECT().AppendHeaders(gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes().Return(nil)
+	responseSender.EXPECT().GetStream().AnyTimes().Return(nil)
+	serverStreamConn := mock.NewMockServerStreamConnection(ctrl)
+	serverStreamConn.EXPECT().EnableWorkerPool().AnyTimes().Return(false)
+	serverStreamConn.EXPECT().Protocol().AnyTimes().Return(protocol.HTTP1)
+
+	newStream := func(backOff time.Duration) *downStream {
+		s := &downStream{
+			ID:              1,
+			context:         variable.NewVariableContext(context.Background()),
+			cluster:         clusterInfo,
+			upstreamRequest: &upstreamRequest{},
+			retryState: &retryState{
+				cluster:      clusterInfo,
+				baseInterval: backOff,
+				maxInterval:  backOff,
+			},
+			responseSender: responseSender,
+			requestInfo:    &network.RequestInfo{},
+			notify:         make(chan struct{}, 1),
+			proxy: &proxy{
+				config: &v2.Proxy{
+					UpstreamProtocol: "HTTP2",
+				},
+				clusterManager:   clusterManager,
+				serverStreamConn: serverStreamConn,
+				stats:            globalStats,
+				listenerStats:    newListenerStats("test"),
+			},
+			streamFilterChain: streamFilterChain{
+				DefaultStreamFilterChainImpl: &streamfilter.DefaultStreamFilterChainImpl{},
+			},
+		}
+		s.upstreamRequest.downStream = s
+		s.upstreamRequest.setupRetry.Store(true)
+		return s
+	}
+
+	t.Run("resumed by the back off timer", func(t *testing.T) {
+		s := newStream(50 * time.Millisecond)
+		phase, _ := s.processError(1)
+		assert.Equal(t, types.Retry, phase)
+		assert.Equal(t, types.End, s.receive(s.context, 1, phase))
+		assert.Equal(t, uint32(1), atomic.LoadUint32(&s.retryWaiting))
+		// the retry fails as no host is available
+		assert.Eventually(t, func() bool {
+			return s.processDone()
+		}, time.Second, 10*time.Millisecond)
+		assert.Equal(t, uint32(0), atomic.LoadUint32(&s.retryWaiting))
+	})
+
+	t.Run("resumed by the downstream reset", func(t *testing.T) {
+		s := newStream(time.Hour)
+		phase, _ := s.processError(1)
+		assert.Equal(t, types.End, s.receive(s.context, 1, phase))
+		assert.Equal(t, uint32(1), atomic.LoadUint32(&s.retryWaiting))
+		s.OnResetStream(types.StreamRemoteReset)
+		assert.Equal(t, uint32(0), atomic.LoadUint32(&s.retryWaiting))
+		assert.Equal(t, uint32(1), atomic.LoadUint32(&s.downstreamCleaned))
+	})
+
+	t.Run("the stream is recycled", func(t *testing.T) {
+		s := newStream(time.Hour)
+		phase, _ := s.processError(1)
+		assert.Equal(t, types.End, s.receive(s.context, 1, phase))
+		atomic.StoreUint32(&s.ID, 2)
+		s.resumeRetry(1)
+		assert.Equal(t, uint32(1), atomic.LoadUint32(&s.retryWaiting))
+	})
+}
+
 // TestGetUpstreamProtocol
 // 1. default is same as downstream protocol
 // 2. if contextkey is setted, use the contextkey value
@@ -485,3 +570,269 @@ func TestGetUpstreamProtocol(t *testing.T) {
 		assert.Equal(t, tc.expectedProtocol, currentProtocol)
 	}
 }
+
+func TestOutlierResult(t *testing.T) {
+	for code, expected := range map[int]types.OutlierResult{
+		200: types.OutlierSuccess,
+		404: types.OutlierSuccess,
+		500: types.Outlier5xx,
+		502: types.OutlierGatewayFailure,
+		503: types.OutlierGatewayFailure,
+		504: types.OutlierGatewayFailure,
+	} {
+		assert.Equal(t, expected, responseCodeToOutlierResult(code), "code %d", code)
+	}
+	for reason, expected := range map[types.StreamResetReason]types.OutlierResult{
+		types.StreamConnectionFailed:      types.OutlierConnectFailure,
+		types.StreamConnectionTermination: types.OutlierGatewayFailure,
+		types.UpstreamPerTryTimeout:       types.OutlierGatewayFailure,
+		types.StreamOverflow:              -1,
+		types.StreamLocalReset:            -1,
+	} {
+		assert.Equal(t, expected, resetReasonToOutlierResult(reason), "reason %s", reason)
+	}
+	// put result to the host's outlier detector
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	od := &mockOutlierDetector{}
+	info := mock.NewMockClusterInfo(ctrl)
+	info.EXPECT().OutlierDetector().Return(od).AnyTimes()
+	host := mock.NewMockHost(ctrl)
+	host.EXPECT().ClusterInfo().Return(info).AnyTimes()
+	req := &upstreamRequest{
+		host: host,
+	}
+	s := &downStream{
+		upstreamRequest: req,
+		proxy:           &proxy{},
+		requestInfo:     &network.RequestInfo{},
+		context:         variable.NewVariableContext(context.Background()),
+	}
+	s.putOutlierResult(req, types.Outlier5xx)
+	s.putOutlierResult(req, -1)
+	assert.Equal(t, []types.OutlierResult{types.Outlier5xx}, od.results)
+	// the reset is reported to the host of the upstream request that is reset
+	hedgedHost := mock.NewMockHost(ctrl)
+	hedgedHost.EXPECT().ClusterInfo().Return(info).AnyTimes()
+	hedged := &upstreamRequest{
+		host: hedgedHost,
+	}
+	s.resetUpstreamRequest.Store(hedged)
+	s.onUpstreamReset(types.StreamConnectionFailed)
+	assert.Equal(t, []types.Host{host, hedgedHost}, od.hosts)
+	// the host without cluster info is ignored
+	noInfoHost := mock.NewMockHost(ctrl)
+	noInfoHost.EXPECT().ClusterInfo().Return(nil).AnyTimes()
+	s.putOutlierResult(&upstreamRequest{host: noInfoHost}, types.Outlier5xx)
+	assert.Len(t, od.results, 2)
+	// the per try timeout that hedges the request is not a failure
+	assert.False(t, s.isHedgeTimeout(types.UpstreamPerTryTimeout))
+	s.retryState = newTestRetryState(t, v2.RetryPolicyConfig{
+		RetryOn:              true,
+		HedgeOnPerTryTimeout: true,
+		NumRetries:           1,
+	}, &fakeResourceManager{})
+	assert.True(t, s.isHedgeTimeout(types.UpstreamPerTryTimeout))
+	assert.False(t, s.isHedgeTimeout(types.StreamConnectionFailed))
+}
+
+type mockOutlierDetector struct {
+	types.OutlierDetector
+	results []types.OutlierResult
+	hosts   []types.Host
+}
+
+func (d *mockOutlierDetector) PutResult(host types.Host, result types.OutlierResult) {
+	d.results = append(d.results, result)
+	d.hosts = append(d.hosts, host)
+}
+
+func TestHedgeOnPerTryTimeout(t *testing.T) {
+	info := cluster.NewClusterInfo(v2.Cluster{Name: "hedge_test"})
+	newHost := func(addr string) types.Host {
+		return cluster.NewSimpleHost(v2.Host{HostConfig: v2.HostConfig{Address: addr}}, info)
+	}
+	h1, h2, h3 := newHost("127.0.0.1:8080"), newHost("127.0.0.2:8080"), newHost("127.0.0.3:8080")
+	ctx := variable.NewVariableContext(context.Background())
+	s := &downStream{
+		cluster:     info,
+		context:     ctx,
+		requestInfo: network.NewRequestInfo(),
+		notify:      make(chan struct{}, 1),
+		retryState: newTestRetryState(t, v2.RetryPolicyConfig{
+			RetryOn:              true,
+			HedgeOnPerTryTimeout: true,
+		}, &fakeResourceManager{}),
+	}
+	first := &upstreamRequest{downStream: s, host: h1}
+	s.upstreamRequest = first
+	// the per try timeout is retried by the retry on policy
+	assert.Equal(t, api.ShouldRetry, s.retryState.retry(ctx, nil, types.UpstreamPerTryTimeout))
+	noRetryOn := newTestRetryState(t, v2.RetryPolicyConfig{
+		HedgeOnPerTryTimeout: true,
+	}, &fakeResourceManager{})
+	assert.NotEqual(t, api.ShouldRetry, noRetryOn.retry(ctx, nil, types.UpstreamPerTryTimeout))
+	// only hedge on per try timeout
+	assert.False(t, s.setupHedge(types.StreamConnectionFailed))
+	assert.True(t, s.setupHedge(types.UpstreamPerTryTimeout))
+	assert.True(t, first.hedged.Load())
+	assert.Equal(t, int64(1), info.Stats().UpstreamRequestHedge.Count())
+	// the retry phase
+	p, _ := s.processError(0)
+	assert.Equal(t, types.Retry, p)
+	// the reset of the hedged request is only recorded
+	first.OnResetStream(types.StreamRemoteReset)
+	assert.Equal(t, uint32(0), s.upstreamReset)
+	assert.Equal(t, hedgeReset, first.hedgeState)
+	// the hedged request is sent to another host
+	assert.True(t, s.ShouldSelectAnotherHost(h1))
+	assert.False(t, s.ShouldSelectAnotherHost(h2))
+	assert.Equal(t, defaultHostSelectionMaxAttempts, s.HostSelectionRetryCount())
+	// hedges again
+	second := &upstreamRequest{downStream: s, host: h2}
+	s.upstreamRequest = second
+	assert.True(t, s.setupHedge(types.UpstreamPerTryTimeout))
+	s.processError(0)
+	third := &upstreamRequest{downStream: s, host: h3}
+	s.upstreamRequest = third
+	// the second request receives the response first
+	headers := protocol.CommonHeader{}
+	second.OnReceive(ctx, headers, nil, nil)
+	assert.Equal(t, hedgeResponded, second.hedgeState)
+	// the response of the others is ignored
+	first.OnReceive(ctx, protocol.CommonHeader{}, nil, nil)
+	assert.Equal(t, hedgeReset, first.hedgeState)
+	s.onHedgeResponse()
+	assert.Equal(t, second, s.upstreamRequest)
+	assert.False(t, second.hedged.Load())
+	assert.Nil(t, s.hedgedRequests)
+	assert.Equal(t, int64(1), info.Stats().UpstreamRequestHedgeWin.Count())
+	assert.Equal(t, int64(2), info.Stats().UpstreamRequestHedgeCancel.Count())
+	// the latest request receives the response, and the first request is canceled in clean up
+	s.upstreamRequest = first
+	first.hedged.Store(false)
+	assert.True(t, s.setupHedge(types.UpstreamPerTryTimeout))
+	s.processError(0)
+	s.upstreamRequest = second
+	s.onHedgeResponse()
+	assert.Equal(t, second, s.upstreamRequest)
+	assert.Equal(t, int64(2), info.Stats().UpstreamRequestHedgeWin.Count())
+	assert.Equal(t, int64(3), info.Stats().UpstreamRequestHedgeCancel.Count())
+	s.upstreamRequest = first
+	assert.True(t, s.setupHedge(types.UpstreamPerTryTimeout))
+	s.cleanUp()
+	assert.Nil(t, s.hedgedRequests)
+	assert.Equal(t, int64(4), info.Stats().UpstreamRequestHedgeCancel.Count())
+}
+
+func TestHedgeNotConfigured(t *testing.T) {
+	s := &downStream{
+		retryState: newTestRetryState(t, v2.RetryPolicyConfig{}, &fakeResourceManager{}),
+	}
+	assert.False(t, s.setupHedge(types.UpstreamPerTryTimeout))
+	// no hedged requests
+	s.onHedgeResponse()
+	assert.Equal(t, 0, s.HostSelectionRetryCount())
+}
+
+func TestWaitHedgedRequests(t *testing.T) {
+	info := cluster.NewClusterInfo(v2.Cluster{Name: "hedge_wait_test"})
+	newHost := func(addr string) types.Host {
+		return cluster.NewSimpleHost(v2.Host{HostConfig: v2.HostConfig{Address: addr}}, info)
+	}
+	newStream := func(t *testing.T) (*downStream, *upstreamRequest, *upstreamRequest, *upstreamRequest) {
+		s := &downStream{
+			cluster:     info,
+			proxy:       &proxy{},
+			context:     variable.NewVariableContext(context.Background()),
+			requestInfo: network.NewRequestInfo(),
+			notify:      make(chan struct{}, 1),
+			retryState: newTestRetryState(t, v2.RetryPolicyConfig{
+				RetryOn:              true,
+				HedgeOnPerTryTimeout: true,
+				NumRetries:           2,
+			}, &fakeResourceManager{}),
+		}
+		first := &upstreamRequest{downStream: s, host: newHost("127.0.0.1:8080")}
+		second := &upstreamRequest{downStream: s, host: newHost("127.0.0.2:8080")}
+		for _, req := range []*upstreamRequest{first, second} {
+			req.hedged.Store(true)
+			req.setupRetry.Store(true)
+		}
+		latest := &upstreamRequest{downStream: s, host: newHost("127.0.0.3:8080")}
+		s.hedgedRequests = []*upstreamRequest{first, second}
+		s.upstreamRequest = latest
+		return s, first, second, latest
+	}
+	wait := func(s *downStream) chan bool {
+		done := make(chan bool, 1)
+		go func() {
+			done <- s.waitHedgedRequests()
+		}()
+		return done
+	}
+	waitResult := func(t *testing.T, done chan bool) bool {
+		select {
+		case r := <-done:
+			return r
+		case <-time.After(time.Second):
+			t.Fatal("wait hedged requests hang")
+		}
+		return false
+	}
+	t.Run("all hedged requests are reset", func(t *testing.T) {
+		s, first, second, _ := newStream(t)
+		done := wait(s)
+		first.OnResetStream(types.StreamConnectionTermination)
+		second.OnResetStream(types.StreamConnectionTermination)
+		assert.False(t, waitResult(t, done))
+		assert.Equal(t, uint32(0), s.waitingHedges)
+	})
+	t.Run("the global timeout stops the waiting", func(t *testing.T) {
+		s, _, _, _ := newStream(t)
+		done := wait(s)
+		for atomic.LoadUint32(&s.waitingHedges) == 0 {
+			time.Sleep(time.Millisecond)
+		}
+		s.onResponseTimeout()
+		assert.False(t, waitResult(t, done))
+		assert.Equal(t, types.UpstreamGlobalTimeout, s.resetReason.Load())
+	})
+	t.Run("the latest request is reset without retry left", func(t *testing.T) {
+		s, first, second, latest := newStream(t)
+		s.retryState.retiesRemaining = 0
+		go func() {
+			for atomic.LoadUint32(&s.waitingHedges) == 0 {
+				time.Sleep(time.Millisecond)
+			}
+			first.OnResetStream(types.StreamConnectionTermination)
+			second.OnReceive(s.context, protocol.CommonHeader{}, nil, nil)
+		}()
+		latest.OnResetStream(types.StreamConnectionFailed)
+		p, err := s.processError(0)
+		assert.Equal(t, types.ErrExit, err)
+		assert.Equal(t, types.UpFilter, p)
+		assert.Equal(t, second, s.upstreamRequest)
+		assert.False(t, second.hedged.Load())
+		assert.False(t, s.hedgeResponseReceived)
+		assert.Nil(t, s.hedgedRequests)
+	})
+	t.Run("the timed out request keeps waiting if no retry is allowed", func(t *testing.T) {
+		s, first, second, latest := newStream(t)
+		s.retryState.retiesRemaining = 0
+		go func() {
+			for atomic.LoadUint32(&s.waitingHedges) == 0 {
+				time.Sleep(time.Millisecond)
+			}
+			first.OnResetStream(types.StreamConnectionTermination)
+			second.OnResetStream(types.StreamConnectionTermination)
+			latest.OnReceive(s.context, protocol.CommonHeader{}, nil, nil)
+		}()
+		atomic.StoreUint32(&s.upstreamResponseReceived, 1)
+		s.onPerReqTimeout()
+		p, _ := s.processError(0)
+		assert.Equal(t, types.UpFilter, p)
+		assert.Equal(t, latest, s.upstreamRequest)
+		assert.False(t, latest.hedged.Load())
+	})
+}
@@ -56,6 +56,7 @@ func gomockClusterInfo(ctrl *gomock.Controller) types.ClusterInfo {
 		}).AnyTimes()
 		return mng
 	}).AnyTimes()
+	info.EXPECT().OutlierDetector().Return(nil).AnyTimes()
 	return info
 }
 
@@ -19,21 +19,45 @@ package proxy
 
 import (
 	"context"
+	"math/rand"
+	"time"
 
 	"mosn.io/api"
+	v2 "mosn.io/mosn/pkg/config/v2"
 	"mosn.io/mosn/pkg/protocol"
 	"mosn.io/mosn/pkg/types"
 	"mosn.io/pkg/protocol/http"
 	"mosn.io/pkg/variable"
 )
 
+const (
+	defaultNumRetries = 3
+	// the retry interval if the back off is not configured
+	defaultRetryInterval       = 10 * time.Millisecond
+	defaultRetryBaseInterval   = 25 * time.Millisecond
+	defaultBudgetPercent       = 20.0
+	defaultMinRetryConcurrency = 3
+	// the max times to choose a host again if the retry host predicates are configured
+	defaultHostSelectionMaxAttempts = 1
+)
+
 type retryState struct {
-	retryPolicy      api.RetryPolicy
-	requestHeaders   types.HeaderMap // TODO: support retry policy by header
-	cluster          types.ClusterInfo
-	retryOn          bool
-	retiesRemaining  uint32
-	upstreamProtocol types.ProtocolName
+	retryPolicy       api.RetryPolicy
+	requestHeaders    types.HeaderMap // TODO: support retry policy by header
+	cluster           types.ClusterInfo
+	retryOn           bool
+	retryOnConditions []string
+	retryBudget       *v2.RetryBudget
+	baseInterval      time.Duration
+	maxInterval       time.Duration
+	retries           uint32
+	retiesRemaining   uint32
+	upstreamProtocol  types.ProtocolName
+	hostPredicates    []*v2.RetryHostPredicate
+	hostMaxAttempts   int
+	attemptedHosts    []types.Host
+	// hedge sends a new request without resetting the previous one on per try timeout
+	hedge bool
 }
 
 func newRetryState(retryPolicy api.RetryPolicy,
@@ -43,14 +67,34 @@ func newRetryState(retryPolicy api.RetryPolicy,
 		requestHeaders:   requestHeaders,
 		cluster:          cluster,
 		retryOn:          retryPolicy.RetryOn(),
-		retiesRemaining:  3,
+		retiesRemaining:  defaultNumRetries,
 		upstreamProtocol: proto,
 	}
 
-	if retryPolicy.NumRetries() > rs.retiesRemaining {
+	if retryPolicy.NumRetries() > 0 {
 		rs.retiesRemaining = retryPolicy.NumRetries()
 	}
 
+	if policy, ok := retryPolicy.(types.RetryPolicy); ok {
+		rs.retryOnConditions = policy.RetryOnConditions()
+		rs.retryBudget = policy.RetryBudget()
+		rs.baseInterval, rs.maxInterval = policy.RetryBackOff()
+		if rs.baseInterval <= 0 && rs.maxInterval > 0 {
+			rs.baseInterval = defaultRetryBaseInterval
+		}
+		if rs.maxInterval <= 0 {
+			rs.maxInterval = 10 * rs.baseInterval
+		}
+		rs.hedge = policy.HedgeOnPerTryTimeout()
+		rs.hostPredicates = policy.RetryHostPredicates()
+		if len(rs.hostPredicates) > 0 {
+			rs.hostMaxAttempts = defaultHostSelectionMaxAttempts
+			if attempts := policy.HostSelectionRetryMaxAttempts(); attempts > 0 {
+				rs.hostMaxAttempts = int(attempts)
+			}
+		}
+	}
+
 	return rs
 }
 
@@ -80,7 +124,7 @@ func (r *retryState) shouldRetry(ctx context.Context, headers api.HeaderMap, rea
 		return api.NoRetry
 	}
 
-	if !r.cluster.ResourceManager().Retries().CanCreate() {
+	if !r.cluster.ResourceManager().Retries().CanCreate() || !r.budgetAllowed() {
 		r.cluster.Stats().UpstreamRequestRetryOverflow.Inc(1)
 
 		return api.RetryOverflow
@@ -89,6 +133,44 @@ func (r *retryState) shouldRetry(ctx context.Context, headers api.HeaderMap, rea
 	return api.ShouldRetry
 }
 
+// budgetAllowed checks the active retries does not exceed the retry budget,
+// the budget is a percentage of the active requests, and not less than the min retry concurrency.
+func (r *retryState) budgetAllowed() bool {
+	if r.retryBudget == nil {
+		return true
+	}
+	percent := r.retryBudget.BudgetPercent
+	if percent <= 0 {
+		percent = defaultBudgetPercent
+	}
+	minConcurrency := r.retryBudget.MinRetryConcurrency
+	if minConcurrency == 0 {
+		minConcurrency = defaultMinRetryConcurrency
+	}
+	budget := int64(float64(r.cluster.ResourceManager().Requests().Cur()) * percent / 100)
+	if budget < int64(minConcurrency) {
+		budget = int64(minConcurrency)
+	}
+	return r.cluster.ResourceManager().Retries().Cur() < budget
+}
+
+// nextBackOff returns the interval before the next retry.
+// the interval is a random value between 0 and base interval * 2^retries, and not more than the max interval.
+func (r *retryState) nextBackOff() time.Duration {
+	if r.baseInterval <= 0 {
+		return defaultRetryInterval
+	}
+	ceiling := r.maxInterval
+	// avoid overflow
+	if r.retries < 32 {
+		if c := r.baseInterval * time.Duration(uint64(1)<<r.retries); c > 0 && c < ceiling {
+			ceiling = c
+		}
+	}
+	r.retries++
+	return time.Duration(rand.Int63n(int64(ceiling) + 1))
+}
+
 func (r *retryState) doRetryCheck(ctx context.Context, headers types.HeaderMap, reason types.StreamResetReason) bool {
 	if ctx != nil {
 		if disable, err := variable.Get(ctx, types.VarProxyDisableRetry); err == nil {
@@ -102,6 +184,10 @@ func (r *retryState) doRetryCheck(ctx context.Context, headers types.HeaderMap,
 		return false
 	}
 
+	if len(r.retryOnConditions) > 0 {
+		return r.matchConditions(ctx, headers, reason)
+	}
+
 	if r.retryOn {
 		if ctx != nil {
 			code, err := protocol.MappingHeaderStatusCode(ctx, r.upstreamProtocol, headers)
@@ -140,6 +226,96 @@ func (r *retryState) doRetryCheck(ctx context.Context, headers types.HeaderMap,
 	return false
 }
 
+// matchConditions checks the response status code or the reset reason matches one of the retry conditions
+func (r *retryState) matchConditions(ctx context.Context, headers types.HeaderMap, reason types.StreamResetReason) bool {
+	code := 0
+	if reason == "" && ctx != nil {
+		if c, err := protocol.MappingHeaderStatusCode(ctx, r.upstreamProtocol, headers); err == nil {
+			code = c
+		}
+	}
+	noResponse := reason == types.StreamConnectionFailed || reason == types.StreamConnectionTermination ||
+		reason == types.StreamRemoteReset || reason == types.UpstreamReset || reason == types.UpstreamPerTryTimeout
+	for _, condition := range r.retryOnConditions {
+		switch condition {
+		case v2.RetryOnConnectFailure:
+			if reason == types.StreamConnectionFailed {
+				return true
+			}
+		case v2.RetryOnReset:
+			if reason == types.StreamConnectionTermination || reason == types.StreamRemoteReset || reason == types.UpstreamReset {
+				return true
+			}
+		case v2.RetryOnPerTryTimeout:
+			if reason == types.UpstreamPerTryTimeout {
+				return true
+			}
+		case v2.RetryOn5xx:
+			// 5xx includes the cases that upstream does not respond
+			if code >= http.InternalServerError || noResponse {
+				return true
+			}
+		case v2.RetryOnGatewayError:
+			// the upstream that does not respond results in a gateway error
+			if noResponse || code == http.BadGateway || code == http.ServiceUnavailable || code == http.GatewayTimeout {
+				return true
+			}
+		case v2.RetryOnRetriableStatusCodes:
+			for _, it := range r.retryPolicy.RetryableStatusCodes() {
+				if code == int(it) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// onHostAttempted records the host that the request has been sent to and will be retried
+func (r *retryState) onHostAttempted(host types.Host) {
+	if len(r.hostPredicates) > 0 {
+		r.attemptedHosts = append(r.attemptedHosts, host)
+	}
+}
+
+// shouldSelectAnotherHost checks the chosen host by the retry host predicates
+func (r *retryState) shouldSelectAnotherHost(host types.Host) bool {
+	for _, predicate := range r.hostPredicates {
+		switch predicate.Name {
+		case v2.RetryHostPreviousHosts:
+			for _, attempted := range r.attemptedHosts {
+				if attempted.AddressString() == host.AddressString() {
+					return true
+				}
+			}
+		case v2.RetryHostPreviousLocalities:
+			keys := predicate.MetadataKeys
+			if len(keys) == 0 && r.cluster.LbConfig() != nil {
+				keys = r.cluster.LbConfig().LocalityKeys
+			}
+			if len(keys) == 0 {
+				continue
+			}
+			for _, attempted := range r.attemptedHosts {
+				if sameMetadataValues(keys, attempted, host) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+func sameMetadataValues(keys []string, h1, h2 types.Host) bool {
+	m1, m2 := h1.Metadata(), h2.Metadata()
+	for _, key := range keys {
+		if m1[key] != m2[key] {
+			return false
+		}
+	}
+	return true
+}
+
 func (r *retryState) reset() {
 	r.cluster.ResourceManager().Retries().Decrease()
 }
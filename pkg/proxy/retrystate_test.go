@@ -183,3 +183,205 @@ func TestRetryStateStatusCode(t *testing.T) {
 		}
 	}
 }
+
+type fakeBudgetResourceManager struct {
+	types.ResourceManager
+	requests int64
+	retries  int64
+}
+
+func (mgr *fakeBudgetResourceManager) Retries() types.Resource {
+	return &fakeCurResource{cur: mgr.retries}
+}
+
+func (mgr *fakeBudgetResourceManager) Requests() types.Resource {
+	return &fakeCurResource{cur: mgr.requests}
+}
+
+type fakeCurResource struct {
+	fakeResource
+	cur int64
+}
+
+func (r *fakeCurResource) Cur() int64 { return r.cur }
+
+func newTestRetryState(t *testing.T, cfg v2.RetryPolicyConfig, mgr types.ResourceManager) *retryState {
+	rcfg := &v2.Router{}
+	rcfg.Route = v2.RouteAction{
+		RouterActionConfig: v2.RouterActionConfig{
+			RetryPolicy: &v2.RetryPolicy{
+				RetryPolicyConfig: cfg,
+			},
+		},
+	}
+	r, err := router.NewRouteRuleImplBase(nil, rcfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return newRetryState(r.Policy().RetryPolicy(), nil, &fakeClusterInfo{mgr: mgr}, protocol.HTTP1)
+}
+
+func TestRetryStateConditions(t *testing.T) {
+	variable.Register(variable.NewStringVariable(types.VarHeaderStatus, nil, nil, variable.DefaultStringSetter, 0))
+	statusCtx := func(code string) context.Context {
+		ctx := variable.NewVariableContext(context.Background())
+		variable.SetString(ctx, types.VarHeaderStatus, code)
+		return ctx
+	}
+	testcases := []struct {
+		conditions []string
+		ctx        context.Context
+		reason     types.StreamResetReason
+		expected   api.RetryCheckStatus
+	}{
+		{[]string{v2.RetryOnConnectFailure}, nil, types.StreamConnectionFailed, api.ShouldRetry},
+		{[]string{v2.RetryOnConnectFailure}, nil, types.StreamConnectionTermination, api.NoRetry},
+		{[]string{v2.RetryOnConnectFailure}, statusCtx("500"), "", api.NoRetry},
+		{[]string{v2.RetryOnReset}, nil, types.StreamRemoteReset, api.ShouldRetry},
+		{[]string{v2.RetryOnReset}, nil, types.UpstreamPerTryTimeout, api.NoRetry},
+		{[]string{v2.RetryOnPerTryTimeout}, nil, types.UpstreamPerTryTimeout, api.ShouldRetry},
+		{[]string{v2.RetryOn5xx}, statusCtx("500"), "", api.ShouldRetry},
+		{[]string{v2.RetryOn5xx}, statusCtx("404"), "", api.NoRetry},
+		{[]string{v2.RetryOn5xx}, nil, types.StreamConnectionFailed, api.ShouldRetry},
+		{[]string{v2.RetryOnGatewayError}, statusCtx("503"), "", api.ShouldRetry},
+		{[]string{v2.RetryOnGatewayError}, statusCtx("500"), "", api.NoRetry},
+		{[]string{v2.RetryOnRetriableStatusCodes}, statusCtx("409"), "", api.ShouldRetry},
+		{[]string{v2.RetryOnRetriableStatusCodes}, statusCtx("500"), "", api.NoRetry},
+		{[]string{v2.RetryOnConnectFailure, v2.RetryOnGatewayError}, statusCtx("504"), "", api.ShouldRetry},
+		{[]string{v2.RetryOn5xx}, nil, types.StreamOverflow, api.NoRetry},
+	}
+	for i, tc := range testcases {
+		rs := newTestRetryState(t, v2.RetryPolicyConfig{
+			RetryOn:           true,
+			RetryOnConditions: tc.conditions,
+			StatusCodes:       []uint32{409},
+		}, &fakeResourceManager{})
+		if status := rs.retry(tc.ctx, nil, tc.reason); status != tc.expected {
+			t.Errorf("#%d retry state expected %v, but got %v", i, tc.expected, status)
+		}
+	}
+}
+
+func TestRetryStateNumRetries(t *testing.T) {
+	rs := newTestRetryState(t, v2.RetryPolicyConfig{
+		NumRetries: 1,
+	}, &fakeResourceManager{})
+	if status := rs.retry(nil, nil, types.StreamConnectionFailed); status != api.ShouldRetry {
+		t.Errorf("first retry expected should retry, but got %v", status)
+	}
+	if status := rs.retry(nil, nil, types.StreamConnectionFailed); status != api.NoRetry {
+		t.Errorf("second retry expected no retry, but got %v", status)
+	}
+}
+
+func TestRetryStateBudget(t *testing.T) {
+	budget := &v2.RetryBudget{
+		BudgetPercent: 10,
+	}
+	testcases := []struct {
+		requests int64
+		retries  int64
+		expected api.RetryCheckStatus
+	}{
+		// min retry concurrency is 3
+		{10, 2, api.ShouldRetry},
+		{10, 3, api.RetryOverflow},
+		// 10% of 100 requests
+		{100, 9, api.ShouldRetry},
+		{100, 10, api.RetryOverflow},
+	}
+	for i, tc := range testcases {
+		rs := newTestRetryState(t, v2.RetryPolicyConfig{
+			RetryBudget: budget,
+		}, &fakeBudgetResourceManager{requests: tc.requests, retries: tc.retries})
+		if status := rs.retry(nil, nil, types.StreamConnectionFailed); status != tc.expected {
+			t.Errorf("#%d retry state expected %v, but got %v", i, tc.expected, status)
+		}
+	}
+}
+
+func TestRetryStateBackOff(t *testing.T) {
+	// not configured
+	rs := newTestRetryState(t, v2.RetryPolicyConfig{}, &fakeResourceManager{})
+	if backOff := rs.nextBackOff(); backOff != defaultRetryInterval {
+		t.Errorf("expected default retry interval, but got %v", backOff)
+	}
+	rs = newTestRetryState(t, v2.RetryPolicyConfig{
+		RetryBackOff: &v2.RetryBackOff{
+			BaseInterval: &api.DurationConfig{Duration: 10 * time.Millisecond},
+			MaxInterval:  &api.DurationConfig{Duration: 50 * time.Millisecond},
+		},
+	}, &fakeResourceManager{})
+	for i, ceiling := range []time.Duration{10, 20, 40, 50, 50, 50} {
+		ceiling *= time.Millisecond
+		if backOff := rs.nextBackOff(); backOff < 0 || backOff > ceiling {
+			t.Errorf("#%d back off %v is out of range [0, %v]", i, backOff, ceiling)
+		}
+	}
+	// max interval is 10 times of the base interval by default
+	rs = newTestRetryState(t, v2.RetryPolicyConfig{
+		RetryBackOff: &v2.RetryBackOff{
+			BaseInterval: &api.DurationConfig{Duration: time.Millisecond},
+		},
+	}, &fakeResourceManager{})
+	if rs.maxInterval != 10*time.Millisecond {
+		t.Errorf("unexpected max interval %v", rs.maxInterval)
+	}
+}
+
+type fakeHost struct {
+	types.Host
+	addr string
+	meta api.Metadata
+}
+
+func (h *fakeHost) AddressString() string {
+	return h.addr
+}
+
+func (h *fakeHost) Metadata() api.Metadata {
+	return h.meta
+}
+
+func TestRetryStateHostPredicate(t *testing.T) {
+	h1 := &fakeHost{addr: "127.0.0.1:8080", meta: api.Metadata{"zone": "a", "version": "1"}}
+	h2 := &fakeHost{addr: "127.0.0.2:8080", meta: api.Metadata{"zone": "a", "version": "2"}}
+	h3 := &fakeHost{addr: "127.0.0.3:8080", meta: api.Metadata{"zone": "b", "version": "1"}}
+	// not configured
+	rs := newTestRetryState(t, v2.RetryPolicyConfig{}, &fakeResourceManager{})
+	rs.onHostAttempted(h1)
+	if len(rs.attemptedHosts) != 0 || rs.hostMaxAttempts != 0 || rs.shouldSelectAnotherHost(h1) {
+		t.Fatal("host predicate is not configured")
+	}
+	// previous hosts
+	rs = newTestRetryState(t, v2.RetryPolicyConfig{
+		RetryHostPredicates: []*v2.RetryHostPredicate{
+			{Name: v2.RetryHostPreviousHosts},
+		},
+	}, &fakeResourceManager{})
+	if rs.hostMaxAttempts != defaultHostSelectionMaxAttempts {
+		t.Errorf("unexpected host max attempts %d", rs.hostMaxAttempts)
+	}
+	rs.onHostAttempted(h1)
+	if !rs.shouldSelectAnotherHost(h1) || rs.shouldSelectAnotherHost(h2) || rs.shouldSelectAnotherHost(h3) {
+		t.Error("previous hosts predicate unexpected")
+	}
+	// previous localities
+	rs = newTestRetryState(t, v2.RetryPolicyConfig{
+		RetryHostPredicates: []*v2.RetryHostPredicate{
+			{Name: v2.RetryHostPreviousLocalities, MetadataKeys: []string{"zone"}},
+		},
+		HostSelectionRetryMaxAttempts: 5,
+	}, &fakeResourceManager{})
+	if rs.hostMaxAttempts != 5 {
+		t.Errorf("unexpected host max attempts %d", rs.hostMaxAttempts)
+	}
+	rs.onHostAttempted(h1)
+	if !rs.shouldSelectAnotherHost(h1) || !rs.shouldSelectAnotherHost(h2) || rs.shouldSelectAnotherHost(h3) {
+		t.Error("previous localities predicate unexpected")
+	}
+	rs.onHostAttempted(h3)
+	if !rs.shouldSelectAnotherHost(h3) {
+		t.Error("previous localities predicate unexpected")
+	}
+}
@@ -23,12 +23,19 @@ import (
 	"sync/atomic"
 	"time"
 
+	uatomic "go.uber.org/atomic"
 	"mosn.io/mosn/pkg/log"
 	"mosn.io/mosn/pkg/protocol"
 	"mosn.io/mosn/pkg/trace"
 	"mosn.io/mosn/pkg/types"
 )
 
+// the states of the hedged upstream request
+const (
+	hedgeResponded uint32 = iota + 1
+	hedgeReset
+)
+
 // types.StreamEventListener
 // types.StreamReceiveListener
 // types.PoolEventListener
@@ -44,7 +51,12 @@ type upstreamRequest struct {
 	sendComplete bool
 	dataSent     bool
 	trailerSent  bool
-	setupRetry   bool
+	// setupRetry and hedged are written by the worker and read by the stream callbacks
+	setupRetry uatomic.Bool
+	// the request is hedged, it is still waiting for the response while a new request is sent
+	hedged uatomic.Bool
+	// the result of the hedged request, see hedgeResponded and hedgeReset
+	hedgeState uint32
 
 	// time at send upstream request
 	startTime time.Time
@@ -69,7 +81,15 @@ func (r *upstreamRequest) resetStream() {
 // types.StreamEventListener
 // Called by stream layer normally
 func (r *upstreamRequest) OnResetStream(reason types.StreamResetReason) {
-	if r.setupRetry {
+	// the hedged request only records the reset, the downstream fails after all the hedged requests are finished
+	if r.hedged.Load() {
+		atomic.StoreUint32(&r.hedgeState, hedgeReset)
+		if atomic.LoadUint32(&r.downStream.waitingHedges) == 1 {
+			r.downStream.sendNotify()
+		}
+		return
+	}
+	if r.setupRetry.Load() {
 		return
 	}
 	// todo: check if we get a reset on encode request headers. e.g. send failed
@@ -78,6 +98,7 @@ func (r *upstreamRequest) OnResetStream(reason types.StreamResetReason) {
 	}
 
 	r.downStream.resetReason.Store(reason)
+	r.downStream.resetUpstreamRequest.Store(r)
 	r.downStream.sendNotify()
 }
 
@@ -100,7 +121,7 @@ func (r *upstreamRequest) endStream() {
 // types.StreamReceiveListener
 // Method to decode upstream's response message
 func (r *upstreamRequest) OnReceive(ctx context.Context, headers types.HeaderMap, data types.IoBuffer, trailers types.HeaderMap) {
-	if r.downStream.processDone() || r.setupRetry {
+	if r.downStream.processDone() || (r.setupRetry.Load() && !r.hedged.Load()) {
 		return
 	}
 	if !atomic.CompareAndSwapUint32(&r.downStream.upstreamResponseReceived, 0, 1) {
@@ -123,11 +144,16 @@ func (r *upstreamRequest) OnReceive(ctx context.Context, headers types.HeaderMap
 		log.Proxy.Tracef(r.downStream.context, "[proxy] [upstream] OnReceive headers: %+v, data: %+v, trailers: %+v", headers, data, trailers)
 	}
 
+	// the response must be stored before the state is visible to the waiting downstream
+	if r.hedged.Load() {
+		atomic.StoreUint32(&r.hedgeState, hedgeResponded)
+	}
+
 	r.downStream.sendNotify()
 }
 
 func (r *upstreamRequest) receiveHeaders(endStream bool) {
-	if r.downStream.processDone() || r.setupRetry {
+	if r.downStream.processDone() || r.setupRetry.Load() {
 		return
 	}
 
@@ -135,7 +161,7 @@ func (r *upstreamRequest) receiveHeaders(endStream bool) {
 }
 
 func (r *upstreamRequest) receiveData(endStream bool) {
-	if r.downStream.processDone() || r.setupRetry {
+	if r.downStream.processDone() || r.setupRetry.Load() {
 		return
 	}
 
@@ -143,7 +169,7 @@ func (r *upstreamRequest) receiveData(endStream bool) {
 }
 
 func (r *upstreamRequest) receiveTrailers() {
-	if r.downStream.processDone() || r.setupRetry {
+	if r.downStream.processDone() || r.setupRetry.Load() {
 		return
 	}
 
@@ -42,8 +42,9 @@ var (
 
 type RouteRuleImplBase struct {
 	// match
-	vHost       api.VirtualHost
-	routerMatch v2.RouterMatch
+	vHost           api.VirtualHost
+	routerMatch     v2.RouterMatch
+	runtimeFraction *runtimeFractionMatcher
 	// rewrite
 	prefixRewrite         string
 	regexRewrite          v2.RegexRewrite
@@ -67,6 +68,7 @@ type RouteRuleImplBase struct {
 	defaultCluster     *weightedClusterEntry // cluster name and metadata
 	weightedClusters   map[string]weightedClusterEntry
 	totalClusterWeight uint32
+	stickyCookie       *stickyCookie
 	lock               sync.Mutex
 	randInstance       *rand.Rand
 }
@@ -101,34 +103,81 @@ func NewRouteRuleImplBase(vHost api.VirtualHost, route *v2.Router) (*RouteRuleIm
 		base.regexPattern = regexPattern
 	}
 
+	// add runtime fraction match
+	if route.Match.RuntimeFraction != nil {
+		matcher, err := newRuntimeFractionMatcher(route.Match.RuntimeFraction)
+		if err != nil {
+			log.DefaultLogger.Errorf(RouterLogFormat, "routerule", "check runtime fraction failed.", err)
+			return nil, err
+		}
+		base.runtimeFraction = matcher
+	}
+
 	// add clusters
 	base.weightedClusters, base.totalClusterWeight = getWeightedClusterEntry(route.Route.WeightedClusters)
+	if route.Route.StickyCookie != nil && len(base.weightedClusters) > 0 {
+		sc, err := newStickyCookie(route.Route.StickyCookie)
+		if err != nil {
+			log.DefaultLogger.Errorf(RouterLogFormat, "routerule", "check sticky cookie failed.", err)
+			return nil, err
+		}
+		base.stickyCookie = sc
+	}
 	if len(route.Route.MetadataMatch) > 0 {
 		base.defaultCluster.clusterMetadataMatchCriteria = NewMetadataMatchCriteriaImpl(route.Route.MetadataMatch)
 	}
 	// add policy
 	if route.Route.RetryPolicy != nil {
 		base.policy.retryPolicy = &retryPolicyImpl{
-			retryOn:      route.Route.RetryPolicy.RetryOn,
-			retryTimeout: route.Route.RetryPolicy.RetryTimeout,
-			numRetries:   route.Route.RetryPolicy.NumRetries,
-			statusCodes:  route.Route.RetryPolicy.StatusCodes,
+			retryOn:           route.Route.RetryPolicy.RetryOn,
+			retryTimeout:      route.Route.RetryPolicy.RetryTimeout,
+			numRetries:        route.Route.RetryPolicy.NumRetries,
+			statusCodes:       route.Route.RetryPolicy.StatusCodes,
+			retryOnConditions: route.Route.RetryPolicy.RetryOnConditions,
+			retryBudget:       route.Route.RetryPolicy.RetryBudget,
+			hostPredicates:    route.Route.RetryPolicy.RetryHostPredicates,
+			hostMaxAttempts:   route.Route.RetryPolicy.HostSelectionRetryMaxAttempts,
+			hedgeOnTimeout:    route.Route.RetryPolicy.HedgeOnPerTryTimeout,
 		}
-	}
-	// add hash policy
-	if route.Route.HashPolicy != nil && len(route.Route.HashPolicy) >= 1 {
-		hp := route.Route.HashPolicy[0]
-		if hp.Header != nil {
-			base.policy.hashPolicy = &headerHashPolicyImpl{
-				key: hp.Header.Key,
+		if backOff := route.Route.RetryPolicy.RetryBackOff; backOff != nil {
+			if backOff.BaseInterval != nil {
+				base.policy.retryPolicy.baseInterval = backOff.BaseInterval.Duration
+			}
+			if backOff.MaxInterval != nil {
+				base.policy.retryPolicy.maxInterval = backOff.MaxInterval.Duration
 			}
 		}
-		if hp.Cookie != nil {
-			base.policy.hashPolicy = &cookieHashPolicyImpl{
+	}
+	// add hash policy
+	hashPolicies := make([]hashPolicyItem, 0, len(route.Route.HashPolicy))
+	for _, hp := range route.Route.HashPolicy {
+		var hashPolicy api.HashPolicy
+		switch {
+		case hp.Cookie != nil:
+			hashPolicy = &cookieHashPolicyImpl{
 				name: hp.Cookie.Name,
 				path: hp.Cookie.Path,
 				ttl:  hp.Cookie.TTL,
 			}
+		case hp.Header != nil:
+			hashPolicy = &headerHashPolicyImpl{
+				key: hp.Header.Key,
+			}
+		case hp.SourceIP != nil:
+			hashPolicy = &sourceIPHashPolicyImpl{}
+		default:
+			continue
+		}
+		hashPolicies = append(hashPolicies, hashPolicyItem{
+			hashPolicy: hashPolicy,
+			terminal:   hp.Terminal,
+		})
+	}
+	if len(hashPolicies) == 1 {
+		base.policy.hashPolicy = hashPolicies[0].hashPolicy
+	} else if len(hashPolicies) > 1 {
+		base.policy.hashPolicy = &combinedHashPolicyImpl{
+			hashPolicies: hashPolicies,
 		}
 	}
 	// use source ip hash policy as default hash policy
@@ -188,6 +237,18 @@ func NewRouteRuleImplBase(vHost api.VirtualHost, route *v2.Router) (*RouteRuleIm
 	return base, nil
 }
 
+// matchRuntimeFraction checks whether the request is in the runtime fraction, returns true if no runtime fraction is configured
+func (rri *RouteRuleImplBase) matchRuntimeFraction(headers api.HeaderMap) bool {
+	if rri.runtimeFraction == nil {
+		return true
+	}
+	return rri.runtimeFraction.Matches(headers)
+}
+
+func (rri *RouteRuleImplBase) hasRuntimeFraction() bool {
+	return rri.runtimeFraction != nil
+}
+
 func (rri *RouteRuleImplBase) VirtualHost() api.VirtualHost {
 	return rri.vHost
 }
@@ -221,6 +282,23 @@ func (rri *RouteRuleImplBase) ClusterName(ctx context.Context) string {
 		return rri.defaultCluster.clusterName
 	}
 
+	if rri.stickyCookie == nil {
+		return rri.selectWeightedCluster()
+	}
+	// the cluster is already chosen for this request
+	if clusterName, err := variable.GetString(ctx, types.VarStickyCluster); err == nil && clusterName != "" {
+		return clusterName
+	}
+	if clusterName, ok := rri.stickyCluster(ctx); ok {
+		return clusterName
+	}
+	clusterName := rri.selectWeightedCluster()
+	// the sticky cookie is set in FinalizeResponseHeaders
+	variable.SetString(ctx, types.VarStickyCluster, clusterName)
+	return clusterName
+}
+
+func (rri *RouteRuleImplBase) selectWeightedCluster() string {
 	rri.lock.Lock()
 	if rri.randInstance == nil {
 		rri.randInstance = rand.New(rand.NewSource(time.Now().UnixNano()))
@@ -238,6 +316,22 @@ func (rri *RouteRuleImplBase) ClusterName(ctx context.Context) string {
 	return rri.defaultCluster.clusterName
 }
 
+// stickyCluster returns the cluster in the sticky cookie if it is still in the weighted clusters
+func (rri *RouteRuleImplBase) stickyCluster(ctx context.Context) (string, bool) {
+	cookieValue, err := variable.GetProtocolResource(ctx, api.COOKIE, rri.stickyCookie.name)
+	if err != nil || cookieValue == "" {
+		return "", false
+	}
+	clusterName, ok := rri.stickyCookie.cluster(cookieValue, time.Now())
+	if !ok {
+		return "", false
+	}
+	if cluster, ok := rri.weightedClusters[clusterName]; !ok || cluster.clusterWeight == 0 {
+		return "", false
+	}
+	return clusterName, true
+}
+
 func (rri *RouteRuleImplBase) UpstreamProtocol() string {
 	return rri.upstreamProtocol
 }
@@ -334,4 +428,27 @@ func (rri *RouteRuleImplBase) finalizeRequestHeaders(ctx context.Context, header
 func (rri *RouteRuleImplBase) FinalizeResponseHeaders(ctx context.Context, headers api.HeaderMap, requestInfo api.RequestInfo) {
 	rri.responseHeadersParser.evaluateHeaders(ctx, headers)
 	rri.vHost.FinalizeResponseHeaders(ctx, headers, requestInfo)
+	if rri.stickyCookie != nil {
+		if clusterName, err := variable.GetString(ctx, types.VarStickyCluster); err == nil && clusterName != "" {
+			addSetCookie(headers, rri.stickyCookie.setCookie(clusterName, time.Now()))
+		}
+	}
+	// set the cookies generated by the cookie hash policies
+	if v, err := variable.Get(ctx, types.VarGeneratedCookies); err == nil {
+		if cookies, ok := v.(map[string]*http.Cookie); ok {
+			for _, cookie := range cookies {
+				addSetCookie(headers, cookie.String())
+			}
+		}
+	}
+}
+
+// addSetCookie adds the Set-Cookie header, Set-Cookie values can not be joined,
+// so the value is added if the response has cookies already
+func addSetCookie(headers api.HeaderMap, setCookie string) {
+	if _, ok := headers.Get("Set-Cookie"); ok {
+		headers.Add("Set-Cookie", setCookie)
+	} else {
+		headers.Set("Set-Cookie", setCookie)
+	}
 }
@@ -766,7 +766,7 @@ func TestParseHashPolicy(t *testing.T) {
 				{
 					Header: &v2.HeaderHashPolicy{Key: "header_key"},
 				},
-				// test parse first hash policy
+				// test parse all hash policies
 				{
 					SourceIP: &v2.SourceIPHashPolicy{},
 				},
@@ -776,7 +776,14 @@ func TestParseHashPolicy(t *testing.T) {
 
 	rb, err := NewRouteRuleImplBase(nil, routerMock1)
 	assert.NoErrorf(t, err, "new routerule impl failed %+v", err)
-	headerHp, ok := rb.policy.hashPolicy.(*headerHashPolicyImpl)
+	combinedHp, ok := rb.policy.hashPolicy.(*combinedHashPolicyImpl)
+	if !assert.Truef(t, ok, "hash policy should be combinedHashPolicyImpl type") {
+		t.FailNow()
+	}
+	assert.Lenf(t, combinedHp.hashPolicies, 2, "hash policy should be combined by 2 policies")
+	_, ok = combinedHp.hashPolicies[1].hashPolicy.(*sourceIPHashPolicyImpl)
+	assert.Truef(t, ok, "second hash policy should be sourceIPHashPolicyImpl type")
+	headerHp, ok := combinedHp.hashPolicies[0].hashPolicy.(*headerHashPolicyImpl)
 	assert.Truef(t, ok, "hash policy should be headerHashPolicyImpl type")
 	if ok {
 		assert.Equalf(t, "header_key", headerHp.key,
@@ -880,6 +887,117 @@ func TestDefaultHashPolicy(t *testing.T) {
 	assert.IsTypef(t, rb.policy.HashPolicy(), &sourceIPHashPolicyImpl{}, "")
 }
 
+func TestCombinedHashPolicy(t *testing.T) {
+	routerMock := &v2.Router{}
+	routerMock.Route = v2.RouteAction{
+		RouterActionConfig: v2.RouterActionConfig{
+			ClusterName: "defaultCluster",
+			HashPolicy: []v2.HashPolicy{
+				{
+					Header: &v2.HeaderHashPolicy{Key: "combined_header"},
+				},
+				{
+					SourceIP: &v2.SourceIPHashPolicy{},
+					Terminal: true,
+				},
+				{
+					Cookie: &v2.CookieHashPolicy{Name: "combined_cookie"},
+				},
+			},
+		},
+	}
+	rb, err := NewRouteRuleImplBase(nil, routerMock)
+	assert.NoErrorf(t, err, "new routerule impl failed %+v", err)
+	hp, ok := rb.policy.hashPolicy.(*combinedHashPolicyImpl)
+	if !assert.Truef(t, ok, "hash policy should be combinedHashPolicyImpl type") {
+		t.FailNow()
+	}
+	assert.Len(t, hp.hashPolicies, 3)
+
+	testProtocol := types.ProtocolName("CombinedProtocol")
+	ctx := variable.NewVariableContext(context.Background())
+	_ = variable.Set(ctx, types.VariableDownStreamProtocol, testProtocol)
+	headerGetter := func(ctx context.Context, value *variable.IndexedValue, data interface{}) (string, error) {
+		if data.(string) == "CombinedProtocol_request_header_combined_header" {
+			return "test_header_value", nil
+		}
+		return variable.ValueNotFound, variable.ErrValueNotFound
+	}
+	headerValue := variable.NewStringVariable("CombinedProtocol_request_header_", nil, headerGetter, nil, 0)
+	variable.RegisterPrefix(headerValue.Name(), headerValue)
+	variable.RegisterProtocolResource(testProtocol, api.HEADER, types.VarProtocolRequestHeader)
+	cookieGetter := func(ctx context.Context, value *variable.IndexedValue, data interface{}) (string, error) {
+		return "test_cookie_value", nil
+	}
+	cookieValue := variable.NewStringVariable("CombinedProtocol_cookie_", nil, cookieGetter, nil, 0)
+	variable.RegisterPrefix(cookieValue.Name(), cookieValue)
+	variable.RegisterProtocolResource(testProtocol, api.COOKIE, types.VarProtocolCookie)
+
+	headerHash := (&headerHashPolicyImpl{key: "combined_header"}).GenerateHash(ctx)
+	// no source ip, the terminal policy generates no hash, so the cookie is combined
+	cookieHash := (&cookieHashPolicyImpl{name: "combined_cookie"}).GenerateHash(ctx)
+	assert.Equal(t, ((headerHash<<1)|(headerHash>>63))^cookieHash, hp.GenerateHash(ctx))
+	// the source ip is terminal, the cookie is skipped
+	_ = variable.Set(ctx, types.VariableOriRemoteAddr, &net.TCPAddr{
+		IP:   net.IPv4(127, 0, 0, 1),
+		Port: 80,
+	})
+	sourceIPHash := (&sourceIPHashPolicyImpl{}).GenerateHash(ctx)
+	assert.Equal(t, ((headerHash<<1)|(headerHash>>63))^sourceIPHash, hp.GenerateHash(ctx))
+}
+
+func TestCookieHashPolicyGenerateCookie(t *testing.T) {
+	testProtocol := types.ProtocolName("GenerateCookieProtocol")
+	cookieGetter := func(ctx context.Context, value *variable.IndexedValue, data interface{}) (string, error) {
+		return variable.ValueNotFound, variable.ErrValueNotFound
+	}
+	cookieValue := variable.NewStringVariable("GenerateCookieProtocol_cookie_", nil, cookieGetter, nil, 0)
+	variable.RegisterPrefix(cookieValue.Name(), cookieValue)
+	variable.RegisterProtocolResource(testProtocol, api.COOKIE, types.VarProtocolCookie)
+	newContext := func() context.Context {
+		ctx := variable.NewVariableContext(context.Background())
+		_ = variable.Set(ctx, types.VariableDownStreamProtocol, testProtocol)
+		return ctx
+	}
+
+	// no ttl, no cookie is generated
+	ctx := newContext()
+	hp := &cookieHashPolicyImpl{name: "session"}
+	assert.Equal(t, uint64(0), hp.GenerateHash(ctx))
+	_, err := variable.Get(ctx, types.VarGeneratedCookies)
+	assert.NotNil(t, err)
+
+	// the generated cookie is used to hash, and the hash is stable in the request
+	hp = &cookieHashPolicyImpl{
+		name: "session",
+		path: "/",
+		ttl:  api.DurationConfig{Duration: time.Hour},
+	}
+	hash := hp.GenerateHash(ctx)
+	assert.NotEqual(t, uint64(0), hash)
+	assert.Equal(t, hash, hp.GenerateHash(ctx))
+	assert.NotEqual(t, hash, hp.GenerateHash(newContext()))
+
+	// the generated cookie is set on the response
+	vh, err := NewVirtualHostImpl(&v2.VirtualHost{Domains: []string{"*"}})
+	assert.NoError(t, err)
+	vh.globalRouteConfig = NewConfigImpl(&v2.RouterConfiguration{})
+	rb, err := NewRouteRuleImplBase(vh, &v2.Router{})
+	assert.NoError(t, err)
+	headers := protocol.CommonHeader(map[string]string{})
+	rb.FinalizeResponseHeaders(ctx, headers, nil)
+	setCookie, ok := headers.Get("Set-Cookie")
+	assert.True(t, ok)
+	resp := &goHttp.Response{Header: goHttp.Header{"Set-Cookie": []string{setCookie}}}
+	if assert.Len(t, resp.Cookies(), 1) {
+		cookie := resp.Cookies()[0]
+		assert.Equal(t, "session", cookie.Name)
+		assert.Equal(t, "/", cookie.Path)
+		assert.Equal(t, 3600, cookie.MaxAge)
+		assert.Equal(t, getHashByString("session="+cookie.Value), hash)
+	}
+}
+
 func TestRedirectRule(t *testing.T) {
 	testCases := []struct {
 		name           string
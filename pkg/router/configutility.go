@@ -19,46 +19,79 @@ package router
 
 import (
 	"context"
+	"fmt"
 	"regexp"
 	"sort"
+	"strconv"
+	"strings"
 
 	"mosn.io/api"
 	v2 "mosn.io/mosn/pkg/config/v2"
 	"mosn.io/mosn/pkg/log"
+	"mosn.io/mosn/pkg/protocol/http"
 	"mosn.io/mosn/pkg/types"
 	"mosn.io/pkg/variable"
 )
 
 // StringMatch describes hwo to match a given string.
-// support regex-based match or exact string match (case-sensitive)
+// support regex-based match, prefix match, suffix match or exact string match (case-sensitive)
 type StringMatch struct {
 	Value        string
 	IsRegex      bool
 	RegexPattern *regexp.Regexp
+	IsPrefix     bool
+	IsSuffix     bool
 }
 
 func (sm StringMatch) Matches(s string) bool {
-	if !sm.IsRegex {
+	switch {
+	case sm.IsRegex:
+		if sm.RegexPattern != nil {
+			return sm.RegexPattern.MatchString(s)
+		}
+		return false
+	case sm.IsPrefix:
+		return strings.HasPrefix(s, sm.Value)
+	case sm.IsSuffix:
+		return strings.HasSuffix(s, sm.Value)
+	default:
 		return s == sm.Value
 	}
-	if sm.RegexPattern != nil {
-		return sm.RegexPattern.MatchString(s)
-	}
-	return false
 }
 
+// ValueMatchMode describes how a KeyValueData matches the value.
+// api.KeyValueMatchType has exact and regex only, so the other modes are described here,
+// and the matchers of the other modes are kept out of the api.KeyValueMatchCriteria
+type ValueMatchMode int
+
+// Value match modes of KeyValueData
+const (
+	ValueMatchExact ValueMatchMode = iota
+	ValueMatchRegex
+	ValueMatchPrefix
+	ValueMatchSuffix
+	ValueMatchPresent
+	ValueMatchRange
+)
+
 // KeyValueData represents a key-value pairs.
 // The value is a StringMatch
 // used in HeaderMatch and QueryParamsMatch
 type KeyValueData struct {
 	Name  string // name should be lower case in router headerdata
 	Value StringMatch
+	// Present checks whether the key is present or absent only if it is not nil
+	Present *bool
+	// Range matches the value as an integer in [Start, End) if it is not nil
+	Range  *v2.Int64Range
+	Invert bool
 }
 
 func (k *KeyValueData) Key() string {
 	return k.Name
 }
 
+// MatchType describes the exact and regex matchers only, use MatchMode for the other modes
 func (k *KeyValueData) MatchType() api.KeyValueMatchType {
 	if k.Value.IsRegex {
 		return api.ValueRegex
@@ -66,25 +99,93 @@ func (k *KeyValueData) MatchType() api.KeyValueMatchType {
 	return api.ValueExact
 }
 
+// isValueMatch checks whether the KeyValueData can be described by api.KeyValueMatchType
+func (k *KeyValueData) isValueMatch() bool {
+	mode := k.MatchMode()
+	return (mode == ValueMatchExact || mode == ValueMatchRegex) && !k.Invert
+}
+
+// MatchMode returns the actual match mode of the value
+func (k *KeyValueData) MatchMode() ValueMatchMode {
+	switch {
+	case k.Present != nil:
+		return ValueMatchPresent
+	case k.Range != nil:
+		return ValueMatchRange
+	case k.Value.IsRegex:
+		return ValueMatchRegex
+	case k.Value.IsPrefix:
+		return ValueMatchPrefix
+	case k.Value.IsSuffix:
+		return ValueMatchSuffix
+	}
+	return ValueMatchExact
+}
+
 func (k *KeyValueData) Matcher() string {
+	switch {
+	case k.Present != nil:
+		return strconv.FormatBool(*k.Present)
+	case k.Range != nil:
+		return fmt.Sprintf("[%d,%d)", k.Range.Start, k.Range.End)
+	}
 	return k.Value.Value
 }
 
+// Matches checks the value of the key, exists represents whether the key is found.
+// a missing key never matches unless it is a present match, even if the match is inverted.
+func (k *KeyValueData) Matches(value string, exists bool) bool {
+	if k.Present != nil {
+		return (exists == *k.Present) != k.Invert
+	}
+	if !exists {
+		return false
+	}
+	var matched bool
+	if k.Range != nil {
+		i, err := strconv.ParseInt(value, 10, 64)
+		matched = err == nil && i >= k.Range.Start && i < k.Range.End
+	} else {
+		matched = k.Value.Matches(value)
+	}
+	return matched != k.Invert
+}
+
 func NewKeyValueData(header v2.HeaderMatcher) (*KeyValueData, error) {
 	kvData := &KeyValueData{
-		Name: header.Name,
-		Value: StringMatch{
+		Name:    header.Name,
+		Present: header.PresentMatch,
+		Invert:  header.InvertMatch,
+	}
+	switch {
+	case header.PresentMatch != nil:
+	case header.RangeMatch != nil:
+		if header.RangeMatch.Start >= header.RangeMatch.End {
+			return nil, fmt.Errorf("invalid range [%d,%d) of %s", header.RangeMatch.Start, header.RangeMatch.End, header.Name)
+		}
+		kvData.Range = header.RangeMatch
+	case header.PrefixMatch != "":
+		kvData.Value = StringMatch{
+			Value:    header.PrefixMatch,
+			IsPrefix: true,
+		}
+	case header.SuffixMatch != "":
+		kvData.Value = StringMatch{
+			Value:    header.SuffixMatch,
+			IsSuffix: true,
+		}
+	default:
+		kvData.Value = StringMatch{
 			Value:   header.Value,
 			IsRegex: header.Regex,
-		},
-	}
-	if header.Regex {
-		p, err := regexp.Compile(header.Value)
-		if err != nil {
-			log.DefaultLogger.Errorf("parse route header matcher config failed, ignore it, error: %v", err)
-			return nil, err
 		}
-		kvData.Value.RegexPattern = p
+		if header.Regex {
+			p, err := regexp.Compile(header.Value)
+			if err != nil {
+				return nil, err
+			}
+			kvData.Value.RegexPattern = p
+		}
 	}
 	return kvData, nil
 }
@@ -109,8 +210,19 @@ func (m commonHeaderMatcherImpl) Range(f func(api.KeyValueMatchCriterion) bool)
 	}
 }
 
+// HeaderMatchCriteria returns the exact and regex matchers only
 func (m commonHeaderMatcherImpl) HeaderMatchCriteria() api.KeyValueMatchCriteria {
-	return m
+	criteria := &keyValueMatchCriteria{
+		items: make([]*KeyValueData, 0, len(m)),
+	}
+	for _, kv := range m {
+		if kv.isValueMatch() {
+			criteria.items = append(criteria.items, kv)
+		} else {
+			criteria.partial = true
+		}
+	}
+	return criteria
 }
 
 func (m commonHeaderMatcherImpl) Matches(_ context.Context, headers api.HeaderMap) bool {
@@ -123,30 +235,75 @@ func (m commonHeaderMatcherImpl) Matches(_ context.Context, headers api.HeaderMa
 		// if a condition is not matched, return false
 		// ll condition matched, return true
 		value, exists := headers.Get(cfgName)
-		if !exists {
-			return false
-		}
-		if !headerData.Value.Matches(value) {
+		if !headerData.Matches(value, exists) {
 			return false
 		}
 	}
 	return true
 }
 
-func CreateCommonHeaderMatcher(headers []v2.HeaderMatcher) types.HeaderMatcher {
-	hm := make(commonHeaderMatcherImpl, 0, len(headers))
+// keyValueMatchCriteria implements api.KeyValueMatchCriteria with the exact and regex matchers,
+// partial represents whether some matchers of the other modes are kept out
+type keyValueMatchCriteria struct {
+	items   []*KeyValueData
+	partial bool
+}
+
+func (c *keyValueMatchCriteria) Get(i int) api.KeyValueMatchCriterion {
+	return c.items[i]
+}
+
+func (c *keyValueMatchCriteria) Len() int {
+	return len(c.items)
+}
+
+func (c *keyValueMatchCriteria) Range(f func(api.KeyValueMatchCriterion) bool) {
+	for _, kv := range c.items {
+		// stop if f return false
+		if !f(kv) {
+			break
+		}
+	}
+}
+
+// parseHeaderMatchers parses the header matchers. an invalid regex is logged and ignored as before,
+// the other invalid matchers are ignored too if strict is false, otherwise an error is returned
+func parseHeaderMatchers(headers []v2.HeaderMatcher, strict bool) ([]*KeyValueData, error) {
+	kvs := make([]*KeyValueData, 0, len(headers))
 	for _, header := range headers {
-		if kv, err := NewKeyValueData(header); err == nil {
-			hm = append(hm, kv)
+		kv, err := NewKeyValueData(header)
+		if err != nil {
+			// only the range and the regex can be invalid
+			if strict && header.RangeMatch != nil {
+				return nil, fmt.Errorf("invalid header matcher: %v", err)
+			}
+			log.DefaultLogger.Errorf("parse route header matcher config failed, ignore it, error: %v", err)
+			continue
 		}
+		kvs = append(kvs, kv)
+	}
+	return kvs, nil
+}
+
+// CreateCommonHeaderMatcher creates a types.HeaderMatcher, the invalid header matchers are ignored
+func CreateCommonHeaderMatcher(headers []v2.HeaderMatcher) types.HeaderMatcher {
+	kvs, _ := parseHeaderMatchers(headers, false)
+	return commonHeaderMatcherImpl(kvs)
+}
+
+// newCommonHeaderMatcher creates a types.HeaderMatcher, returns an error if any new match mode is invalid
+func newCommonHeaderMatcher(headers []v2.HeaderMatcher) (types.HeaderMatcher, error) {
+	kvs, err := parseHeaderMatchers(headers, true)
+	if err != nil {
+		return nil, err
 	}
-	return hm
+	return commonHeaderMatcherImpl(kvs), nil
 }
 
 // http header matcher is quite different from common header matcher.
-// some keys in the header will be matched in variables and support exact match only
+// some keys in the header will be matched in variables
 type httpHeaderMatcherImpl struct {
-	variables map[string]string
+	variables map[string]*KeyValueData
 	headers   commonHeaderMatcherImpl
 }
 
@@ -162,41 +319,47 @@ func (m *httpHeaderMatcherImpl) Matches(ctx context.Context, headers api.HeaderM
 		log.DefaultLogger.Debugf(RouterLogFormat, "config utility", "try match http header", headers)
 	}
 	// check http variables
-	for vkey, vvalue := range m.variables {
+	for vkey, kv := range m.variables {
 		value, err := variable.GetString(ctx, vkey)
-		if err != nil {
-			return false
-		}
-		if value != vvalue {
+		if !kv.Matches(value, err == nil) {
 			return false
 		}
 	}
 	return m.headers.Matches(ctx, headers)
 }
 
-// CreateHTTPHeaderMatcher creates a http header matcher as a types.HeaderMatcher
+// CreateHTTPHeaderMatcher creates a http header matcher as a types.HeaderMatcher, the invalid header matchers are ignored
 func CreateHTTPHeaderMatcher(headers []v2.HeaderMatcher) types.HeaderMatcher {
+	matcher, _ := createHTTPHeaderMatcher(headers, false)
+	return matcher
+}
+
+// newHTTPHeaderMatcher creates a http header matcher, returns an error if any new match mode is invalid
+func newHTTPHeaderMatcher(headers []v2.HeaderMatcher) (types.HeaderMatcher, error) {
+	return createHTTPHeaderMatcher(headers, true)
+}
+
+func createHTTPHeaderMatcher(headers []v2.HeaderMatcher, strict bool) (types.HeaderMatcher, error) {
+	kvs, err := parseHeaderMatchers(headers, strict)
+	if err != nil {
+		return nil, err
+	}
 	matcher := &httpHeaderMatcherImpl{
-		variables: make(map[string]string, 1),
-		headers:   make(commonHeaderMatcherImpl, 0, len(headers)),
+		variables: make(map[string]*KeyValueData, 1),
+		headers:   make(commonHeaderMatcherImpl, 0, len(kvs)),
 	}
-	for _, header := range headers {
-		switch header.Name {
+	for _, kv := range kvs {
+		switch kv.Name {
 		case "method":
-			matcher.variables[types.VarMethod] = header.Value
+			matcher.variables[types.VarMethod] = kv
 		default:
-			if kv, err := NewKeyValueData(header); err == nil {
-				matcher.headers = append(matcher.headers, kv)
-			}
+			matcher.headers = append(matcher.headers, kv)
 		}
 	}
-	return matcher
-
+	return matcher, nil
 }
 
-// TODO: support query params match
 // queryParameterMatcherImpl implements a types.QueryParamsMatcher
-// nolint: unused
 type queryParameterMatcherImpl []*KeyValueData
 
 func (m queryParameterMatcherImpl) Matches(ctx context.Context, queryParams types.QueryParams) bool {
@@ -206,12 +369,45 @@ func (m queryParameterMatcherImpl) Matches(ctx context.Context, queryParams type
 	for _, configQueryParam := range m {
 		cfgName := configQueryParam.Name
 		value, ok := queryParams[cfgName]
-		if !ok {
+		if !configQueryParam.Matches(value, ok) {
 			return false
 		}
-		if !configQueryParam.Value.Matches(value) {
-			return false
+	}
+	return true
+}
+
+// CreateQueryParameterMatcher creates a types.QueryParameterMatcher, returns nil if there is no query parameter config.
+// returns an error if any query parameter matcher is invalid
+func CreateQueryParameterMatcher(params []v2.HeaderMatcher) (types.QueryParameterMatcher, error) {
+	if len(params) == 0 {
+		return nil, nil
+	}
+	qm := make(queryParameterMatcherImpl, 0, len(params))
+	for _, param := range params {
+		kv, err := NewKeyValueData(param)
+		if err != nil {
+			return nil, fmt.Errorf("invalid query parameter matcher: %v", err)
+		}
+		qm = append(qm, kv)
+	}
+	return qm, nil
+}
+
+// matchQueryParameters matches the query string in the variables with the query parameter matcher
+func matchQueryParameters(ctx context.Context, matcher types.QueryParameterMatcher) bool {
+	if matcher == nil {
+		return true
+	}
+	var queryParams types.QueryParams
+	queryString, err := variable.GetString(ctx, types.VarQueryString)
+	if err == nil && queryString != "" {
+		queryParams = http.ParseQueryString(queryString)
+	}
+	if !matcher.Matches(ctx, queryParams) {
+		if log.DefaultLogger.GetLogLevel() >= log.DEBUG {
+			log.DefaultLogger.Debugf(RouterLogFormat, "routerule", "match query params", queryParams)
 		}
+		return false
 	}
 	return true
 }
@@ -279,6 +279,128 @@ func TestHTTPHeaderMatch(t *testing.T) {
 			t.Errorf("invalid regexkey should be ignored")
 		}
 	})
+	t.Run("header match modes", func(t *testing.T) {
+		present := true
+		absent := false
+		headersConfig := []v2.HeaderMatcher{
+			{
+				Name:        "prefix",
+				PrefixMatch: "v1.",
+			},
+			{
+				Name:        "suffix",
+				SuffixMatch: ".com",
+			},
+			{
+				Name:         "present",
+				PresentMatch: &present,
+			},
+			{
+				Name:         "absent",
+				PresentMatch: &absent,
+			},
+			{
+				Name:       "range",
+				RangeMatch: &v2.Int64Range{Start: 10, End: 20},
+			},
+			{
+				Name:        "invert",
+				Value:       "prod",
+				InvertMatch: true,
+			},
+		}
+		matcher := CreateHTTPHeaderMatcher(headersConfig)
+		mimpl := matcher.(*httpHeaderMatcherImpl)
+		for idx, mode := range []ValueMatchMode{
+			ValueMatchPrefix, ValueMatchSuffix, ValueMatchPresent, ValueMatchPresent, ValueMatchRange, ValueMatchExact,
+		} {
+			if mimpl.headers[idx].MatchMode() != mode {
+				t.Errorf("No. %d header match mode is not expected", idx)
+			}
+		}
+		// the new match modes and the inverted match are kept out of the match criteria
+		if matcher.HeaderMatchCriteria().Len() != 0 {
+			t.Errorf("header match criteria should not contain the new match modes")
+		}
+		base := map[string]string{
+			"prefix":  "v1.2",
+			"suffix":  "mosn.com",
+			"present": "",
+			"range":   "10",
+			"invert":  "dev",
+		}
+		for idx, c := range []struct {
+			key     string
+			value   string
+			deleted bool
+			matched bool
+		}{
+			{key: "prefix", value: "v1.2", matched: true},
+			{key: "prefix", value: "v2.1", matched: false},
+			{key: "suffix", value: "mosn.io", matched: false},
+			{key: "present", deleted: true, matched: false},
+			{key: "absent", value: "any", matched: false},
+			{key: "range", value: "19", matched: true},
+			{key: "range", value: "20", matched: false},
+			{key: "range", value: "abc", matched: false},
+			{key: "invert", value: "prod", matched: false},
+			// a missing key is not matched by an inverted value match
+			{key: "invert", deleted: true, matched: false},
+		} {
+			h := make(map[string]string, len(base))
+			for k, v := range base {
+				h[k] = v
+			}
+			if c.deleted {
+				delete(h, c.key)
+			} else {
+				h[c.key] = c.value
+			}
+			if matcher.Matches(context.Background(), protocol.CommonHeader(h)) != c.matched {
+				t.Errorf("No. %d case test failed", idx)
+			}
+		}
+	})
+	t.Run("invalid range header config", func(t *testing.T) {
+		headersConfig := []v2.HeaderMatcher{
+			{
+				Name:       "range",
+				RangeMatch: &v2.Int64Range{Start: 20, End: 10},
+			},
+		}
+		matcher := CreateHTTPHeaderMatcher(headersConfig)
+		mimpl := matcher.(*httpHeaderMatcherImpl)
+		if len(mimpl.headers) != 0 {
+			t.Errorf("invalid range should be ignored")
+		}
+		if _, err := newHTTPHeaderMatcher(headersConfig); err == nil {
+			t.Errorf("invalid range should returns an error")
+		}
+	})
+	t.Run("http method regex test", func(t *testing.T) {
+		headersConfig := []v2.HeaderMatcher{
+			{
+				Name:  "method",
+				Value: "GET|HEAD",
+				Regex: true,
+			},
+		}
+		matcher := CreateHTTPHeaderMatcher(headersConfig)
+		for _, c := range []struct {
+			method  string
+			matched bool
+		}{
+			{"GET", true},
+			{"HEAD", true},
+			{"POST", false},
+		} {
+			ctx := variable.NewVariableContext(context.Background())
+			variable.SetString(ctx, types.VarMethod, c.method)
+			if matcher.Matches(ctx, protocol.CommonHeader(map[string]string{})) != c.matched {
+				t.Errorf("method %s match result should be %v", c.method, c.matched)
+			}
+		}
+	})
 	t.Run("http method test", func(t *testing.T) {
 		headersConfig := []v2.HeaderMatcher{
 			{
@@ -323,7 +445,6 @@ func TestHTTPHeaderMatch(t *testing.T) {
 	})
 }
 
-// TODO: support query match in routers
 func TestMatchQueryParams(t *testing.T) {
 	qpm := queryParameterMatcherImpl{}
 	configs := []v2.HeaderMatcher{
@@ -382,3 +503,41 @@ func TestMatchQueryParams(t *testing.T) {
 		}
 	}
 }
+
+func TestCreateQueryParameterMatcher(t *testing.T) {
+	if qpm, err := CreateQueryParameterMatcher(nil); qpm != nil || err != nil {
+		t.Fatal("no query parameter config should returns nil")
+	}
+	absent := false
+	qpm, err := CreateQueryParameterMatcher([]v2.HeaderMatcher{
+		{
+			Name:  "version",
+			Value: "beta",
+		},
+		{
+			Name:         "debug",
+			PresentMatch: &absent,
+		},
+	})
+	if err != nil {
+		t.Fatalf("create query parameter matcher failed: %v", err)
+	}
+	for idx, c := range []struct {
+		query    string
+		expected bool
+	}{
+		{"version=beta", true},
+		{"version=beta&other=1", true},
+		{"version=stable", false},
+		{"version=beta&debug=true", false},
+		{"", false},
+	} {
+		ctx := variable.NewVariableContext(context.Background())
+		if c.query != "" {
+			variable.SetString(ctx, types.VarQueryString, c.query)
+		}
+		if matchQueryParameters(ctx, qpm) != c.expected {
+			t.Errorf("No. %d case test failed", idx)
+		}
+	}
+}
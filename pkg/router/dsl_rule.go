@@ -61,6 +61,9 @@ func (drri *DslExpressionRouteRuleImpl) FinalizeRequestHeaders(ctx context.Conte
 }
 
 func (drri *DslExpressionRouteRuleImpl) Match(ctx context.Context, headers api.HeaderMap) api.Route {
+	if !drri.matchRuntimeFraction(headers) {
+		return nil
+	}
 	parentBag := extract.ExtractAttributes(ctx, headers, nil, nil, nil, nil, time.Now())
 	bag := attribute.NewMutableBag(parentBag)
 	bag.Set(extract.KContext, ctx)
@@ -25,7 +25,6 @@ import (
 	"mosn.io/api"
 	v2 "mosn.io/mosn/pkg/config/v2"
 	"mosn.io/mosn/pkg/log"
-	"mosn.io/mosn/pkg/protocol/http"
 	"mosn.io/mosn/pkg/types"
 	"mosn.io/pkg/variable"
 )
@@ -33,9 +32,10 @@ import (
 type BaseHTTPRouteRule struct {
 	*RouteRuleImplBase
 	configHeaders         types.HeaderMatcher
-	configQueryParameters types.QueryParameterMatcher //TODO: not implement yet
+	configQueryParameters types.QueryParameterMatcher
 }
 
+// NewBaseHTTPRouteRule creates a BaseHTTPRouteRule, the invalid header matchers are ignored
 func NewBaseHTTPRouteRule(base *RouteRuleImplBase, headers []v2.HeaderMatcher) *BaseHTTPRouteRule {
 	return &BaseHTTPRouteRule{
 		RouteRuleImplBase: base,
@@ -43,6 +43,24 @@ func NewBaseHTTPRouteRule(base *RouteRuleImplBase, headers []v2.HeaderMatcher) *
 	}
 }
 
+// NewBaseHTTPRouteRuleWithQueryParameters creates a BaseHTTPRouteRule that matches the query parameters too,
+// returns an error if any query parameter matcher or new header match mode is invalid
+func NewBaseHTTPRouteRuleWithQueryParameters(base *RouteRuleImplBase, headers []v2.HeaderMatcher, queryParams []v2.HeaderMatcher) (*BaseHTTPRouteRule, error) {
+	configHeaders, err := newHTTPHeaderMatcher(headers)
+	if err != nil {
+		return nil, err
+	}
+	configQueryParameters, err := CreateQueryParameterMatcher(queryParams)
+	if err != nil {
+		return nil, err
+	}
+	return &BaseHTTPRouteRule{
+		RouteRuleImplBase:     base,
+		configHeaders:         configHeaders,
+		configQueryParameters: configQueryParameters,
+	}, nil
+}
+
 func (rri *BaseHTTPRouteRule) HeaderMatchCriteria() api.KeyValueMatchCriteria {
 	if rri.configHeaders != nil {
 		return rri.configHeaders.HeaderMatchCriteria()
@@ -59,22 +77,11 @@ func (rri *BaseHTTPRouteRule) matchRoute(ctx context.Context, headers api.Header
 		return false
 	}
 	// 2. match query parameters
-	if rri.configQueryParameters != nil {
-		var queryParams types.QueryParams
-		QueryString, err := variable.GetString(ctx, types.VarQueryString)
-		if err == nil && QueryString != "" {
-			queryParams = http.ParseQueryString(QueryString)
-		}
-		if len(queryParams) != 0 {
-			if !rri.configQueryParameters.Matches(ctx, queryParams) {
-				if log.DefaultLogger.GetLogLevel() >= log.DEBUG {
-					log.DefaultLogger.Debugf(RouterLogFormat, "routerule", "match query params", queryParams)
-				}
-				return false
-			}
-		}
+	if !matchQueryParameters(ctx, rri.configQueryParameters) {
+		return false
 	}
-	return true
+	// 3. match runtime fraction
+	return rri.matchRuntimeFraction(headers)
 }
 
 type PathRouteRuleImpl struct {
@@ -210,3 +217,100 @@ func (rrei *RegexRouteRuleImpl) Match(ctx context.Context, headers api.HeaderMap
 	}
 	return nil
 }
+
+// PathTemplateRouteRuleImpl used to "match path" with "path template match"
+type PathTemplateRouteRuleImpl struct {
+	*BaseHTTPRouteRule
+	template *pathTemplate
+	rewrite  *pathTemplateRewrite
+}
+
+func (ptri *PathTemplateRouteRuleImpl) PathMatchCriterion() api.PathMatchCriterion {
+	return ptri
+}
+
+func (ptri *PathTemplateRouteRuleImpl) RouteRule() api.RouteRule {
+	return ptri
+}
+
+func (ptri *PathTemplateRouteRuleImpl) Matcher() string {
+	return ptri.template.template
+}
+
+// MatchType returns api.Variable, the path template is matched with the path variable,
+// mosn.io/api has no path template match type, and a local one may conflict with the future ones
+func (ptri *PathTemplateRouteRuleImpl) MatchType() api.PathMatchType {
+	return api.Variable
+}
+
+// FinalizeRequestHeaders rewrites the path with the captures if path template rewrite is configured,
+// otherwise the literal prefix of the template is used as the matched path in prefix rewrite
+func (ptri *PathTemplateRouteRuleImpl) FinalizeRequestHeaders(ctx context.Context, headers api.HeaderMap, requestInfo api.RequestInfo) {
+	ptri.finalizeRequestHeaders(ctx, headers, requestInfo)
+	if ptri.rewrite == nil {
+		ptri.finalizePathHeader(ctx, headers, ptri.template.literalPrefix())
+		return
+	}
+	path, err := variable.GetString(ctx, types.VarPath)
+	if err != nil || path == "" {
+		return
+	}
+	var captures map[string]string
+	if v, err := variable.Get(ctx, types.VarPathTemplateCaptures); err == nil {
+		captures, _ = v.(map[string]string)
+	}
+	rewritedPath := ptri.rewrite.rewrite(captures)
+	if rewritedPath != path {
+		headers.Set(types.HeaderOriginalPath, path)
+		variable.SetString(ctx, types.VarPath, rewritedPath)
+		if log.DefaultLogger.GetLogLevel() >= log.INFO {
+			log.DefaultLogger.Infof(RouterLogFormat, "routerule", "finalizePathHeader", "path template rewrite path, rewrited path is "+rewritedPath)
+		}
+	}
+}
+
+func (ptri *PathTemplateRouteRuleImpl) Match(ctx context.Context, headers api.HeaderMap) api.Route {
+	headerPathValue, err := variable.GetString(ctx, types.VarPath)
+	if err == nil && headerPathValue != "" {
+		if captures, ok := ptri.template.match(headerPathValue); ok {
+			return ptri.matchWithCaptures(ctx, headers, captures)
+		}
+	}
+	if log.DefaultLogger.GetLogLevel() >= log.DEBUG {
+		log.DefaultLogger.Debugf(RouterLogFormat, "path template route rule", "failed match", headers)
+	}
+	return nil
+}
+
+// matchWithCaptures matches the route with the path already matched by the template,
+// the virtual host matches the path in the path template trie and calls it directly
+func (ptri *PathTemplateRouteRuleImpl) matchWithCaptures(ctx context.Context, headers api.HeaderMap, captures map[string]string) api.Route {
+	if !ptri.matchRoute(ctx, headers) {
+		if log.DefaultLogger.GetLogLevel() >= log.DEBUG {
+			log.DefaultLogger.Debugf(RouterLogFormat, "path template route rule", "failed match", headers)
+		}
+		return nil
+	}
+	if captures != nil {
+		variable.Set(ctx, types.VarPathTemplateCaptures, captures)
+	}
+	return ptri
+}
+
+// NewPathTemplateRouteRuleImpl creates a path template route rule, the template and the rewrite are validated
+func NewPathTemplateRouteRuleImpl(base *BaseHTTPRouteRule, template string, rewrite string) (*PathTemplateRouteRuleImpl, error) {
+	pt, err := parsePathTemplate(template)
+	if err != nil {
+		return nil, err
+	}
+	rule := &PathTemplateRouteRuleImpl{
+		BaseHTTPRouteRule: base,
+		template:          pt,
+	}
+	if rewrite != "" {
+		if rule.rewrite, err = parsePathTemplateRewrite(rewrite, pt); err != nil {
+			return nil, err
+		}
+	}
+	return rule, nil
+}
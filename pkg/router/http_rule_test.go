@@ -81,6 +81,45 @@ func TestHTTPRuleMatchMethod(t *testing.T) {
 
 }
 
+func TestHTTPRuleMatchQueryParameters(t *testing.T) {
+	route := &v2.Router{
+		RouterConfig: v2.RouterConfig{
+			Match: v2.RouterMatch{
+				Prefix: "/",
+				QueryParameters: []v2.HeaderMatcher{
+					{
+						Name:  "version",
+						Value: "beta",
+					},
+				},
+			},
+			Route: v2.RouteAction{
+				RouterActionConfig: v2.RouterActionConfig{
+					ClusterName: "test",
+				},
+			},
+		},
+	}
+	rb, err := NewRouteBase(&VirtualHostImpl{virtualHostName: "test"}, route)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	headers := protocol.CommonHeader(map[string]string{})
+	for _, tc := range []struct {
+		query    string
+		expected bool
+	}{
+		{"version=beta", true},
+		{"version=stable", false},
+		{"", false},
+	} {
+		ctx := variable.NewVariableContext(context.Background())
+		variable.SetString(ctx, types.VarPath, "/test")
+		variable.SetString(ctx, types.VarQueryString, tc.query)
+		assert.Equalf(t, tc.expected, rb.Match(ctx, headers) != nil, "query %s", tc.query)
+	}
+}
+
 func TestPrefixRouteRuleImpl(t *testing.T) {
 	virtualHostImpl := &VirtualHostImpl{virtualHostName: "test"}
 	testCases := []struct {
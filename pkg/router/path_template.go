@@ -0,0 +1,362 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package router
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// path template segment kinds
+const (
+	segmentLiteral = iota
+	segmentWildcard
+	segmentRest
+)
+
+var (
+	errInvalidPathTemplate = errors.New("invalid path template")
+)
+
+type templateSegment struct {
+	kind  int
+	value string // literal value or capture name, empty means no capture
+}
+
+// pathTemplate is a compiled path template, like /users/{id}/orders/{orderId}.
+// {name} and * match exactly one segment, {name=**} and ** match the remaining segments and
+// must be the last segment. the named segments are captured.
+type pathTemplate struct {
+	template string
+	segments []templateSegment
+	names    []string
+}
+
+func isValidCaptureName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for i, c := range name {
+		switch {
+		case c == '_', c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z':
+		case c >= '0' && c <= '9' && i > 0:
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+func parsePathTemplate(template string) (*pathTemplate, error) {
+	if !strings.HasPrefix(template, "/") {
+		return nil, fmt.Errorf("%w: %s should start with /", errInvalidPathTemplate, template)
+	}
+	pt := &pathTemplate{
+		template: template,
+	}
+	names := map[string]struct{}{}
+	parts := strings.Split(template[1:], "/")
+	for i, part := range parts {
+		seg := templateSegment{}
+		switch {
+		case part == "*":
+			seg.kind = segmentWildcard
+		case part == "**":
+			seg.kind = segmentRest
+		case strings.HasPrefix(part, "{") && strings.HasSuffix(part, "}"):
+			name := part[1 : len(part)-1]
+			seg.kind = segmentWildcard
+			if strings.HasSuffix(name, "=**") {
+				name = strings.TrimSuffix(name, "=**")
+				seg.kind = segmentRest
+			}
+			if !isValidCaptureName(name) {
+				return nil, fmt.Errorf("%w: %s has invalid capture name %s", errInvalidPathTemplate, template, name)
+			}
+			if _, ok := names[name]; ok {
+				return nil, fmt.Errorf("%w: %s has duplicate capture name %s", errInvalidPathTemplate, template, name)
+			}
+			names[name] = struct{}{}
+			seg.value = name
+			pt.names = append(pt.names, name)
+		default:
+			if strings.ContainsAny(part, "{}*") {
+				return nil, fmt.Errorf("%w: %s has invalid segment %s", errInvalidPathTemplate, template, part)
+			}
+			seg.kind = segmentLiteral
+			seg.value = part
+		}
+		if seg.kind == segmentRest && i != len(parts)-1 {
+			return nil, fmt.Errorf("%w: %s, ** should be the last segment", errInvalidPathTemplate, template)
+		}
+		pt.segments = append(pt.segments, seg)
+	}
+	return pt, nil
+}
+
+// literalPrefix returns the path prefix before the first non literal segment
+func (pt *pathTemplate) literalPrefix() string {
+	var sb strings.Builder
+	for _, seg := range pt.segments {
+		if seg.kind != segmentLiteral {
+			sb.WriteString("/")
+			break
+		}
+		sb.WriteString("/")
+		sb.WriteString(seg.value)
+	}
+	return sb.String()
+}
+
+// match matches the path with the template, and returns the captures if matched.
+// the captures is nil if the template has no named segments.
+func (pt *pathTemplate) match(path string) (map[string]string, bool) {
+	if !strings.HasPrefix(path, "/") {
+		return nil, false
+	}
+	var captures map[string]string
+	if len(pt.names) > 0 {
+		captures = make(map[string]string, len(pt.names))
+	}
+	rest := path[1:]
+	for i, seg := range pt.segments {
+		if seg.kind == segmentRest {
+			if seg.value != "" {
+				captures[seg.value] = rest
+			}
+			return captures, true
+		}
+		part := rest
+		idx := strings.IndexByte(rest, '/')
+		if idx >= 0 {
+			part = rest[:idx]
+		}
+		switch seg.kind {
+		case segmentLiteral:
+			if part != seg.value {
+				return nil, false
+			}
+		case segmentWildcard:
+			if part == "" {
+				return nil, false
+			}
+			if seg.value != "" {
+				captures[seg.value] = part
+			}
+		}
+		if idx < 0 {
+			// the path is finished, matched if the template is finished too,
+			// or only a ** left
+			if i == len(pt.segments)-1 {
+				return captures, true
+			}
+			if i == len(pt.segments)-2 && pt.segments[i+1].kind == segmentRest {
+				if name := pt.segments[i+1].value; name != "" {
+					captures[name] = ""
+				}
+				return captures, true
+			}
+			return nil, false
+		}
+		rest = rest[idx+1:]
+	}
+	return nil, false
+}
+
+// pathTemplateRewrite rebuilds a path from the captures, like /v2/orders/{orderId}
+type pathTemplateRewrite struct {
+	// parts are the literal strings and the capture names, the capture names are at the odd indexes
+	parts []string
+}
+
+func parsePathTemplateRewrite(rewrite string, pt *pathTemplate) (*pathTemplateRewrite, error) {
+	r := &pathTemplateRewrite{}
+	s := rewrite
+	for {
+		start := strings.IndexByte(s, '{')
+		if start < 0 {
+			if strings.IndexByte(s, '}') >= 0 {
+				return nil, fmt.Errorf("%w: invalid rewrite %s", errInvalidPathTemplate, rewrite)
+			}
+			r.parts = append(r.parts, s)
+			return r, nil
+		}
+		end := strings.IndexByte(s[start:], '}')
+		if end < 0 {
+			return nil, fmt.Errorf("%w: invalid rewrite %s", errInvalidPathTemplate, rewrite)
+		}
+		name := s[start+1 : start+end]
+		found := false
+		for _, n := range pt.names {
+			if n == name {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("%w: rewrite %s uses undefined capture %s", errInvalidPathTemplate, rewrite, name)
+		}
+		r.parts = append(r.parts, s[:start], name)
+		s = s[start+end+1:]
+	}
+}
+
+func (r *pathTemplateRewrite) rewrite(captures map[string]string) string {
+	var sb strings.Builder
+	for i, part := range r.parts {
+		if i%2 == 1 {
+			sb.WriteString(captures[part])
+		} else {
+			sb.WriteString(part)
+		}
+	}
+	return sb.String()
+}
+
+// pathTemplateTrie indexes the path templates of a virtual host by segments,
+// so the path only needs to be walked once to find all the matched templates and their captures
+type pathTemplateTrie struct {
+	root *pathTemplateNode
+}
+
+// pathTemplateEntry is a template indexed in the trie, the index is the route index in the virtual host
+type pathTemplateEntry struct {
+	index    int
+	template *pathTemplate
+}
+
+type pathTemplateNode struct {
+	literals map[string]*pathTemplateNode
+	wildcard *pathTemplateNode
+	// ends are the templates that end at this node
+	ends []pathTemplateEntry
+	// rests are the templates that end with ** at this node
+	rests []pathTemplateEntry
+}
+
+// pathTemplateMatch is a template matched in the trie
+type pathTemplateMatch struct {
+	index    int
+	captures map[string]string
+}
+
+func newPathTemplateTrie() *pathTemplateTrie {
+	return &pathTemplateTrie{
+		root: &pathTemplateNode{},
+	}
+}
+
+func (t *pathTemplateTrie) insert(pt *pathTemplate, index int) {
+	entry := pathTemplateEntry{
+		index:    index,
+		template: pt,
+	}
+	node := t.root
+	for _, seg := range pt.segments {
+		switch seg.kind {
+		case segmentRest:
+			node.rests = append(node.rests, entry)
+			return
+		case segmentWildcard:
+			if node.wildcard == nil {
+				node.wildcard = &pathTemplateNode{}
+			}
+			node = node.wildcard
+		default:
+			if node.literals == nil {
+				node.literals = make(map[string]*pathTemplateNode)
+			}
+			child, ok := node.literals[seg.value]
+			if !ok {
+				child = &pathTemplateNode{}
+				node.literals[seg.value] = child
+			}
+			node = child
+		}
+	}
+	node.ends = append(node.ends, entry)
+}
+
+// match returns the templates that match the path with their captures, sorted by the route index
+func (t *pathTemplateTrie) match(path string) []pathTemplateMatch {
+	if !strings.HasPrefix(path, "/") {
+		return nil
+	}
+	var matched []pathTemplateMatch
+	t.root.match(path[1:], nil, &matched)
+	sort.Slice(matched, func(i, j int) bool {
+		return matched[i].index < matched[j].index
+	})
+	return matched
+}
+
+// match walks the rest of the path, wildcards are the values of the wildcard segments walked
+func (n *pathTemplateNode) match(rest string, wildcards []string, matched *[]pathTemplateMatch) {
+	appendPathTemplateMatches(n.rests, wildcards, rest, matched)
+	part := rest
+	idx := strings.IndexByte(rest, '/')
+	if idx >= 0 {
+		part = rest[:idx]
+	}
+	if child, ok := n.literals[part]; ok {
+		child.matchChild(rest, idx, wildcards, matched)
+	}
+	if part != "" && n.wildcard != nil {
+		n.wildcard.matchChild(rest, idx, append(wildcards, part), matched)
+	}
+}
+
+// matchChild matches the rest of the path after the segment that leads to the node
+func (n *pathTemplateNode) matchChild(rest string, idx int, wildcards []string, matched *[]pathTemplateMatch) {
+	if idx < 0 {
+		appendPathTemplateMatches(n.ends, wildcards, "", matched)
+		appendPathTemplateMatches(n.rests, wildcards, "", matched)
+		return
+	}
+	n.match(rest[idx+1:], wildcards, matched)
+}
+
+// appendPathTemplateMatches builds the captures of the templates, the wildcard segments of a template
+// are the same as the wildcard nodes walked, and the ** captures the rest of the path
+func appendPathTemplateMatches(entries []pathTemplateEntry, wildcards []string, rest string, matched *[]pathTemplateMatch) {
+	for _, entry := range entries {
+		m := pathTemplateMatch{
+			index: entry.index,
+		}
+		if len(entry.template.names) > 0 {
+			m.captures = make(map[string]string, len(entry.template.names))
+			i := 0
+			for _, seg := range entry.template.segments {
+				switch seg.kind {
+				case segmentWildcard:
+					if seg.value != "" {
+						m.captures[seg.value] = wildcards[i]
+					}
+					i++
+				case segmentRest:
+					if seg.value != "" {
+						m.captures[seg.value] = rest
+					}
+				}
+			}
+		}
+		*matched = append(*matched, m)
+	}
+}
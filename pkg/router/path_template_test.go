@@ -0,0 +1,235 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package router
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"mosn.io/api"
+	"mosn.io/pkg/variable"
+
+	v2 "mosn.io/mosn/pkg/config/v2"
+	"mosn.io/mosn/pkg/protocol"
+	"mosn.io/mosn/pkg/types"
+)
+
+func TestParsePathTemplate(t *testing.T) {
+	for _, tc := range []struct {
+		template string
+		valid    bool
+	}{
+		{"/users/{id}/orders/{orderId}", true},
+		{"/users/*/orders", true},
+		{"/static/{file=**}", true},
+		{"/static/**", true},
+		{"/", true},
+		{"users/{id}", false},
+		{"/users/{id}/orders/{id}", false},
+		{"/users/{1d}", false},
+		{"/users/{}", false},
+		{"/users/{id=**}/orders", false},
+		{"/users/id{id}", false},
+	} {
+		_, err := parsePathTemplate(tc.template)
+		assert.Equalf(t, tc.valid, err == nil, "template %s", tc.template)
+	}
+}
+
+func TestPathTemplateMatch(t *testing.T) {
+	for _, tc := range []struct {
+		template string
+		path     string
+		matched  bool
+		captures map[string]string
+	}{
+		{"/users/{id}/orders/{orderId}", "/users/1/orders/2", true, map[string]string{"id": "1", "orderId": "2"}},
+		{"/users/{id}/orders/{orderId}", "/users/1/orders", false, nil},
+		{"/users/{id}/orders/{orderId}", "/users/1/orders/2/items", false, nil},
+		{"/users/{id}/orders/{orderId}", "/users//orders/2", false, nil},
+		{"/users/*/orders", "/users/1/orders", true, nil},
+		{"/static/{file=**}", "/static/css/main.css", true, map[string]string{"file": "css/main.css"}},
+		{"/static/{file=**}", "/static", true, map[string]string{"file": ""}},
+		{"/static/**", "/static/a/b", true, nil},
+		{"/static/**", "/other/a", false, nil},
+		{"/", "/", true, nil},
+		{"/", "/a", false, nil},
+	} {
+		pt, err := parsePathTemplate(tc.template)
+		require.Nil(t, err)
+		captures, ok := pt.match(tc.path)
+		assert.Equalf(t, tc.matched, ok, "template %s, path %s", tc.template, tc.path)
+		assert.Equalf(t, tc.captures, captures, "template %s, path %s", tc.template, tc.path)
+	}
+}
+
+func TestPathTemplateTrie(t *testing.T) {
+	templates := []string{
+		"/users/{id}",
+		"/users/me",
+		"/users/{id}/orders/{orderId}",
+		"/users/**",
+		"/static/{file=**}",
+		"/**",
+	}
+	trie := newPathTemplateTrie()
+	for i, template := range templates {
+		pt, err := parsePathTemplate(template)
+		require.Nil(t, err)
+		trie.insert(pt, i)
+	}
+	for _, tc := range []struct {
+		path     string
+		expected []int
+	}{
+		{"/users/me", []int{0, 1, 3, 5}},
+		{"/users/1", []int{0, 3, 5}},
+		{"/users/1/orders/2", []int{2, 3, 5}},
+		{"/users", []int{3, 5}},
+		{"/static/a/b.js", []int{4, 5}},
+		{"/other", []int{5}},
+		{"invalid", nil},
+	} {
+		matched := trie.match(tc.path)
+		var indexes []int
+		for _, m := range matched {
+			indexes = append(indexes, m.index)
+			// the trie should be consistent with the template match
+			pt, _ := parsePathTemplate(templates[m.index])
+			captures, ok := pt.match(tc.path)
+			assert.Truef(t, ok, "template %s should match path %s", templates[m.index], tc.path)
+			assert.Equalf(t, captures, m.captures, "template %s captures of path %s", templates[m.index], tc.path)
+		}
+		assert.Equalf(t, tc.expected, indexes, "path %s", tc.path)
+	}
+}
+
+func TestPathTemplateRewrite(t *testing.T) {
+	pt, err := parsePathTemplate("/users/{id}/orders/{orderId}")
+	require.Nil(t, err)
+	r, err := parsePathTemplateRewrite("/v2/orders/{orderId}?user={id}", pt)
+	require.Nil(t, err)
+	assert.Equal(t, "/v2/orders/2?user=1", r.rewrite(map[string]string{"id": "1", "orderId": "2"}))
+
+	for _, invalid := range []string{"/v2/{name}", "/v2/{id", "/v2/id}"} {
+		_, err := parsePathTemplateRewrite(invalid, pt)
+		assert.NotNilf(t, err, "rewrite %s should be invalid", invalid)
+	}
+}
+
+func TestPathTemplateRouteRule(t *testing.T) {
+	route := &v2.Router{
+		RouterConfig: v2.RouterConfig{
+			Match: v2.RouterMatch{
+				PathTemplate: "/users/{id}/orders/{orderId}",
+			},
+			Route: v2.RouteAction{
+				RouterActionConfig: v2.RouterActionConfig{
+					ClusterVariable:     "path_param_id",
+					PathTemplateRewrite: "/orders/{orderId}",
+				},
+			},
+		},
+	}
+	vh, err := NewVirtualHostImpl(&v2.VirtualHost{Name: "test"})
+	require.Nil(t, err)
+	vh.globalRouteConfig = NewConfigImpl(&v2.RouterConfiguration{})
+	rb, err := NewRouteBase(vh, route)
+	require.Nil(t, err)
+	assert.Equal(t, api.Variable, rb.RouteRule().PathMatchCriterion().MatchType())
+
+	headers := protocol.CommonHeader(map[string]string{})
+	ctx := variable.NewVariableContext(context.Background())
+	variable.SetString(ctx, types.VarPath, "/users/1/orders")
+	assert.Nil(t, rb.Match(ctx, headers))
+
+	ctx = variable.NewVariableContext(context.Background())
+	variable.SetString(ctx, types.VarPath, "/users/cluster1/orders/2")
+	r := rb.Match(ctx, headers)
+	require.NotNil(t, r)
+	orderId, err := variable.GetString(ctx, "path_param_orderId")
+	assert.Nil(t, err)
+	assert.Equal(t, "2", orderId)
+	_, err = variable.GetString(ctx, "path_param_unknown")
+	assert.NotNil(t, err)
+	// captures used in cluster variable
+	assert.Equal(t, "cluster1", r.RouteRule().ClusterName(ctx))
+	// rewrite
+	r.RouteRule().FinalizeRequestHeaders(ctx, headers, nil)
+	path, _ := variable.GetString(ctx, types.VarPath)
+	assert.Equal(t, "/orders/2", path)
+	originalPath, _ := headers.Get(types.HeaderOriginalPath)
+	assert.Equal(t, "/users/cluster1/orders/2", originalPath)
+
+	// invalid template and rewrite
+	route.Route.PathTemplateRewrite = "/orders/{unknown}"
+	_, err = NewRouteBase(vh, route)
+	assert.NotNil(t, err)
+	route.Match.PathTemplate = "/users/{id"
+	route.Route.PathTemplateRewrite = ""
+	_, err = NewRouteBase(vh, route)
+	assert.NotNil(t, err)
+}
+
+func TestVirtualHostPathTemplateOrder(t *testing.T) {
+	newRouter := func(match v2.RouterMatch, cluster string) v2.Router {
+		return v2.Router{
+			RouterConfig: v2.RouterConfig{
+				Match: match,
+				Route: v2.RouteAction{
+					RouterActionConfig: v2.RouterActionConfig{
+						ClusterName: cluster,
+					},
+				},
+			},
+		}
+	}
+	vh, err := NewVirtualHostImpl(&v2.VirtualHost{
+		Domains: []string{"*"},
+		Routers: []v2.Router{
+			newRouter(v2.RouterMatch{Path: "/users/me"}, "me"),
+			newRouter(v2.RouterMatch{PathTemplate: "/users/{id}/orders/{orderId}"}, "orders"),
+			newRouter(v2.RouterMatch{
+				PathTemplate: "/users/{id}",
+				Headers:      []v2.HeaderMatcher{{Name: "version", Value: "beta"}},
+			}, "user_beta"),
+			newRouter(v2.RouterMatch{PathTemplate: "/users/{id}"}, "user"),
+			newRouter(v2.RouterMatch{Prefix: "/"}, "default"),
+		},
+	})
+	require.Nil(t, err)
+	for _, tc := range []struct {
+		path     string
+		headers  map[string]string
+		expected string
+	}{
+		{"/users/me", nil, "me"},
+		{"/users/1", nil, "user"},
+		{"/users/1", map[string]string{"version": "beta"}, "user_beta"},
+		{"/users/1/orders/2", nil, "orders"},
+		{"/users/1/items", nil, "default"},
+	} {
+		ctx := variable.NewVariableContext(context.Background())
+		variable.SetString(ctx, types.VarPath, tc.path)
+		r := vh.GetRouteFromEntries(ctx, protocol.CommonHeader(tc.headers))
+		require.NotNilf(t, r, "path %s", tc.path)
+		assert.Equalf(t, tc.expected, r.RouteRule().ClusterName(ctx), "path %s", tc.path)
+	}
+}
@@ -30,8 +30,9 @@ import (
 // SofaRule supports only simple headers match. and use fastmatch for compatible old mode
 type RPCRouteRuleImpl struct {
 	*RouteRuleImplBase
-	configHeaders types.HeaderMatcher
-	fastmatch     string // compatible field
+	configHeaders         types.HeaderMatcher
+	configQueryParameters types.QueryParameterMatcher
+	fastmatch             string // compatible field
 }
 
 func (srri *RPCRouteRuleImpl) HeaderMatchCriteria() api.KeyValueMatchCriteria {
@@ -62,8 +63,11 @@ func (srri *RPCRouteRuleImpl) FinalizeRequestHeaders(ctx context.Context, header
 }
 
 func (srri *RPCRouteRuleImpl) Match(ctx context.Context, headers api.HeaderMap) api.Route {
+	if !srri.matchRuntimeFraction(headers) {
+		return nil
+	}
 	if srri.fastmatch == "" {
-		if srri.configHeaders.Matches(ctx, headers) {
+		if srri.configHeaders.Matches(ctx, headers) && matchQueryParameters(ctx, srri.configQueryParameters) {
 			return srri
 		}
 	} else {
@@ -81,14 +85,41 @@ func (srri *RPCRouteRuleImpl) Match(ctx context.Context, headers api.HeaderMap)
 	return nil
 }
 
+// CreateRPCRule creates a rpc route rule, the invalid header matchers are ignored
 func CreateRPCRule(base *RouteRuleImplBase, headers []v2.HeaderMatcher) RouteBase {
 	r := &RPCRouteRuleImpl{
 		RouteRuleImplBase: base,
 	}
 	// compatible for simple sofa rule
-	if len(headers) == 1 && headers[0].Name == types.RPCRouteMatchKey {
+	if len(headers) == 1 && headers[0].Name == types.RPCRouteMatchKey && isValueMatcher(headers[0]) {
 		r.fastmatch = headers[0].Value
 	}
 	r.configHeaders = CreateCommonHeaderMatcher(headers)
 	return r
 }
+
+// NewRPCRouteRule creates a rpc route rule that matches the query parameters too,
+// returns an error if any query parameter matcher or new header match mode is invalid
+func NewRPCRouteRule(base *RouteRuleImplBase, headers []v2.HeaderMatcher, queryParams []v2.HeaderMatcher) (RouteBase, error) {
+	r := &RPCRouteRuleImpl{
+		RouteRuleImplBase: base,
+	}
+	// compatible for simple sofa rule
+	if len(headers) == 1 && headers[0].Name == types.RPCRouteMatchKey && isValueMatcher(headers[0]) && len(queryParams) == 0 {
+		r.fastmatch = headers[0].Value
+	}
+	var err error
+	if r.configHeaders, err = newCommonHeaderMatcher(headers); err != nil {
+		return nil, err
+	}
+	if r.configQueryParameters, err = CreateQueryParameterMatcher(queryParams); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// isValueMatcher checks the header matcher matches the value only, without other match modes
+func isValueMatcher(header v2.HeaderMatcher) bool {
+	return header.PresentMatch == nil && header.RangeMatch == nil &&
+		header.PrefixMatch == "" && header.SuffixMatch == "" && !header.InvertMatch
+}
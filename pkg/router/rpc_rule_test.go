@@ -25,6 +25,7 @@ import (
 	v2 "mosn.io/mosn/pkg/config/v2"
 	"mosn.io/mosn/pkg/mock"
 	"mosn.io/mosn/pkg/types"
+	"mosn.io/pkg/variable"
 )
 
 func TestRPCRouteRuleSimple(t *testing.T) {
@@ -97,3 +98,48 @@ func TestSofaRouteRuleHeaderMatch(t *testing.T) {
 	}
 
 }
+
+func TestRPCRouteRuleMatchModes(t *testing.T) {
+	route := &v2.Router{
+		RouterConfig: v2.RouterConfig{
+			Match: v2.RouterMatch{
+				Headers: []v2.HeaderMatcher{
+					{
+						Name:        "service",
+						PrefixMatch: "com.alipay.",
+					},
+				},
+				QueryParameters: []v2.HeaderMatcher{
+					{
+						Name:  "version",
+						Value: "beta",
+					},
+				},
+			},
+		},
+	}
+	vh := &VirtualHostImpl{}
+	base, err := NewRouteRuleImplBase(vh, route)
+	if err != nil {
+		t.Fatalf("create base route failed: %v", err)
+	}
+	rpcroute, err := NewRPCRouteRule(base, route.Match.Headers, route.Match.QueryParameters)
+	if err != nil {
+		t.Fatalf("create rpc route failed: %v", err)
+	}
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	headers := mock.NewMockHeaderMap(ctrl)
+	headers.EXPECT().Get("service").Return("com.alipay.test.Service", true).AnyTimes()
+
+	ctx := variable.NewVariableContext(context.Background())
+	variable.SetString(ctx, types.VarQueryString, "version=beta")
+	if rpcroute.Match(ctx, headers) == nil {
+		t.Fatalf("rpc route rule matched header and query failed")
+	}
+	ctx = variable.NewVariableContext(context.Background())
+	variable.SetString(ctx, types.VarQueryString, "version=stable")
+	if rpcroute.Match(ctx, headers) != nil {
+		t.Fatalf("rpc route rule matched success, but expected not")
+	}
+}
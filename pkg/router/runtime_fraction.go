@@ -0,0 +1,144 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package router
+
+import (
+	"fmt"
+	"math/rand"
+	"sync"
+	"sync/atomic"
+
+	"mosn.io/api"
+	v2 "mosn.io/mosn/pkg/config/v2"
+	"mosn.io/mosn/pkg/log"
+)
+
+// runtimeFractions stores the runtime overrides of the route runtime fractions,
+// the value type is map[string]uint32, it is copied on write so the routes can read it without lock
+var (
+	runtimeFractions     atomic.Value
+	runtimeFractionsLock sync.Mutex
+)
+
+func init() {
+	runtimeFractions.Store(map[string]uint32{})
+}
+
+func loadRuntimeFractions() map[string]uint32 {
+	return runtimeFractions.Load().(map[string]uint32)
+}
+
+// SetRuntimeFraction overrides the percentage of the runtime key, the value should be in [0, 100]
+func SetRuntimeFraction(key string, value uint32) error {
+	if key == "" {
+		return fmt.Errorf("runtime key is empty")
+	}
+	if value > 100 {
+		return fmt.Errorf("invalid runtime fraction %d of %s, should be in [0, 100]", value, key)
+	}
+	runtimeFractionsLock.Lock()
+	defer runtimeFractionsLock.Unlock()
+	old := loadRuntimeFractions()
+	fractions := make(map[string]uint32, len(old)+1)
+	for k, v := range old {
+		fractions[k] = v
+	}
+	fractions[key] = value
+	runtimeFractions.Store(fractions)
+	log.DefaultLogger.Infof(RouterLogFormat, "runtime fraction", "set", fmt.Sprintf("%s=%d", key, value))
+	return nil
+}
+
+// RemoveRuntimeFraction removes the override of the runtime key, the default value in the route config is used again
+func RemoveRuntimeFraction(key string) {
+	runtimeFractionsLock.Lock()
+	defer runtimeFractionsLock.Unlock()
+	old := loadRuntimeFractions()
+	if _, ok := old[key]; !ok {
+		return
+	}
+	fractions := make(map[string]uint32, len(old))
+	for k, v := range old {
+		if k != key {
+			fractions[k] = v
+		}
+	}
+	runtimeFractions.Store(fractions)
+	log.DefaultLogger.Infof(RouterLogFormat, "runtime fraction", "remove", key)
+}
+
+// GetRuntimeFractions returns a copy of the runtime overrides
+func GetRuntimeFractions() map[string]uint32 {
+	old := loadRuntimeFractions()
+	fractions := make(map[string]uint32, len(old))
+	for k, v := range old {
+		fractions[k] = v
+	}
+	return fractions
+}
+
+type runtimeFractionMatcher struct {
+	runtimeKey   string
+	defaultValue uint32
+	hashHeader   string
+}
+
+func newRuntimeFractionMatcher(cfg *v2.RuntimeFraction) (*runtimeFractionMatcher, error) {
+	if cfg.DefaultValue > 100 {
+		return nil, fmt.Errorf("invalid runtime fraction default value %d, should be in [0, 100]", cfg.DefaultValue)
+	}
+	return &runtimeFractionMatcher{
+		runtimeKey:   cfg.RuntimeKey,
+		defaultValue: cfg.DefaultValue,
+		hashHeader:   cfg.HashHeader,
+	}, nil
+}
+
+func (m *runtimeFractionMatcher) value() uint32 {
+	if m.runtimeKey != "" {
+		if v, ok := loadRuntimeFractions()[m.runtimeKey]; ok {
+			return v
+		}
+	}
+	return m.defaultValue
+}
+
+// Matches picks the request by the stable hash of the header value, or randomly if the header is not found
+func (m *runtimeFractionMatcher) Matches(headers api.HeaderMap) bool {
+	value := m.value()
+	if value == 0 {
+		return false
+	}
+	if value >= 100 {
+		return true
+	}
+	var bucket uint64
+	if hv, ok := m.headerValue(headers); ok {
+		bucket = getHashByString(m.runtimeKey+":"+hv) % 100
+	} else {
+		bucket = uint64(rand.Intn(100))
+	}
+	return bucket < uint64(value)
+}
+
+func (m *runtimeFractionMatcher) headerValue(headers api.HeaderMap) (string, bool) {
+	if m.hashHeader == "" || headers == nil {
+		return "", false
+	}
+	return headers.Get(m.hashHeader)
+}
@@ -0,0 +1,145 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package router
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"mosn.io/pkg/variable"
+
+	v2 "mosn.io/mosn/pkg/config/v2"
+	"mosn.io/mosn/pkg/protocol"
+	"mosn.io/mosn/pkg/types"
+)
+
+func TestRuntimeFractionOverrides(t *testing.T) {
+	defer RemoveRuntimeFraction("test")
+	require.NotNil(t, SetRuntimeFraction("", 10))
+	require.NotNil(t, SetRuntimeFraction("test", 101))
+	require.Nil(t, SetRuntimeFraction("test", 10))
+	fractions := GetRuntimeFractions()
+	assert.Equal(t, map[string]uint32{"test": 10}, fractions)
+	// the returned map is a copy
+	fractions["test"] = 50
+	assert.Equal(t, uint32(10), GetRuntimeFractions()["test"])
+	RemoveRuntimeFraction("test")
+	assert.Len(t, GetRuntimeFractions(), 0)
+}
+
+func TestRuntimeFractionMatcher(t *testing.T) {
+	defer RemoveRuntimeFraction("test")
+	_, err := newRuntimeFractionMatcher(&v2.RuntimeFraction{DefaultValue: 101})
+	require.NotNil(t, err)
+	m, err := newRuntimeFractionMatcher(&v2.RuntimeFraction{
+		RuntimeKey:   "test",
+		DefaultValue: 0,
+		HashHeader:   "x-user",
+	})
+	require.Nil(t, err)
+	headers := protocol.CommonHeader(map[string]string{"x-user": "u1"})
+	assert.False(t, m.Matches(headers))
+	require.Nil(t, SetRuntimeFraction("test", 100))
+	assert.True(t, m.Matches(headers))
+	// the same header value always gets the same result
+	require.Nil(t, SetRuntimeFraction("test", 50))
+	matched := 0
+	for i := 0; i < 100; i++ {
+		headers := protocol.CommonHeader(map[string]string{"x-user": fmt.Sprintf("u%d", i)})
+		result := m.Matches(headers)
+		for j := 0; j < 10; j++ {
+			require.Equal(t, result, m.Matches(headers))
+		}
+		if result {
+			matched++
+		}
+	}
+	assert.True(t, matched > 0 && matched < 100, "matched %d", matched)
+	// remove the override, the default value is used
+	RemoveRuntimeFraction("test")
+	assert.False(t, m.Matches(headers))
+}
+
+func TestRuntimeFractionRoute(t *testing.T) {
+	defer RemoveRuntimeFraction("canary")
+	vh, err := NewVirtualHostImpl(&v2.VirtualHost{
+		Domains: []string{"*"},
+		Routers: []v2.Router{
+			{
+				RouterConfig: v2.RouterConfig{
+					Match: v2.RouterMatch{
+						Headers: []v2.HeaderMatcher{
+							{
+								Name:  types.RPCRouteMatchKey,
+								Value: "service",
+							},
+						},
+						RuntimeFraction: &v2.RuntimeFraction{
+							RuntimeKey: "canary",
+							HashHeader: "x-user",
+						},
+					},
+					Route: v2.RouteAction{
+						RouterActionConfig: v2.RouterActionConfig{
+							ClusterName: "canary",
+						},
+					},
+				},
+			},
+			{
+				RouterConfig: v2.RouterConfig{
+					Match: v2.RouterMatch{
+						Headers: []v2.HeaderMatcher{
+							{
+								Name:  types.RPCRouteMatchKey,
+								Value: "service",
+							},
+						},
+					},
+					Route: v2.RouteAction{
+						RouterActionConfig: v2.RouterActionConfig{
+							ClusterName: "stable",
+						},
+					},
+				},
+			},
+		},
+	})
+	require.Nil(t, err)
+	// fractional routes are not fast indexed
+	route := vh.GetRouteFromHeaderKV(types.RPCRouteMatchKey, "service")
+	require.NotNil(t, route)
+	assert.Equal(t, "stable", route.RouteRule().ClusterName(context.Background()))
+
+	headers := protocol.CommonHeader(map[string]string{
+		types.RPCRouteMatchKey: "service",
+		"x-user":               "u1",
+	})
+	ctx := variable.NewVariableContext(context.Background())
+	route = vh.GetRouteFromEntries(ctx, headers)
+	require.NotNil(t, route)
+	assert.Equal(t, "stable", route.RouteRule().ClusterName(ctx))
+	// changes the fraction at runtime without new router configs
+	require.Nil(t, SetRuntimeFraction("canary", 100))
+	route = vh.GetRouteFromEntries(ctx, headers)
+	require.NotNil(t, route)
+	assert.Equal(t, "canary", route.RouteRule().ClusterName(ctx))
+}
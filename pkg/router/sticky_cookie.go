@@ -0,0 +1,123 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package router
+
+import (
+	"crypto/hmac"
+	crand "crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	v2 "mosn.io/mosn/pkg/config/v2"
+	"mosn.io/mosn/pkg/log"
+)
+
+// defaultStickySecret signs the sticky cookies if no secret is configured
+var defaultStickySecret = func() []byte {
+	secret := make([]byte, 32)
+	crand.Read(secret)
+	return secret
+}()
+
+// stickyCookie issues and verifies the cookie naming the chosen cluster of the weighted clusters.
+// the cookie value is base64(cluster).expires.signature, expires is 0 if the cookie has no ttl.
+type stickyCookie struct {
+	name   string
+	path   string
+	ttl    time.Duration
+	secret []byte
+}
+
+func newStickyCookie(cfg *v2.StickyCookie) (*stickyCookie, error) {
+	if cfg.Name == "" {
+		return nil, errors.New("sticky cookie name is empty")
+	}
+	sc := &stickyCookie{
+		name:   cfg.Name,
+		path:   cfg.Path,
+		ttl:    cfg.TTL.Duration,
+		secret: []byte(cfg.Secret),
+	}
+	if len(sc.secret) == 0 {
+		log.DefaultLogger.Warnf(RouterLogFormat, "routerule", "sticky cookie "+cfg.Name,
+			"no secret is configured, a random secret is used, the cookies are not valid after restart or on other instances")
+		sc.secret = defaultStickySecret
+	}
+	return sc, nil
+}
+
+func (sc *stickyCookie) sign(payload string) string {
+	mac := hmac.New(sha256.New, sc.secret)
+	mac.Write([]byte(payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func (sc *stickyCookie) value(cluster string, now time.Time) string {
+	var expires int64
+	if sc.ttl > 0 {
+		expires = now.Add(sc.ttl).Unix()
+	}
+	payload := base64.RawURLEncoding.EncodeToString([]byte(cluster)) + "." + strconv.FormatInt(expires, 10)
+	return payload + "." + sc.sign(payload)
+}
+
+// cluster returns the cluster in the cookie value, the value is not valid if the signature
+// is mismatched or the cookie is expired.
+func (sc *stickyCookie) cluster(value string, now time.Time) (string, bool) {
+	idx := strings.LastIndexByte(value, '.')
+	if idx < 0 {
+		return "", false
+	}
+	payload, signature := value[:idx], value[idx+1:]
+	if !hmac.Equal([]byte(signature), []byte(sc.sign(payload))) {
+		return "", false
+	}
+	idx = strings.IndexByte(payload, '.')
+	if idx < 0 {
+		return "", false
+	}
+	expires, err := strconv.ParseInt(payload[idx+1:], 10, 64)
+	if err != nil || (expires > 0 && now.Unix() >= expires) {
+		return "", false
+	}
+	cluster, err := base64.RawURLEncoding.DecodeString(payload[:idx])
+	if err != nil {
+		return "", false
+	}
+	return string(cluster), true
+}
+
+// setCookie returns the Set-Cookie header value of the cluster
+func (sc *stickyCookie) setCookie(cluster string, now time.Time) string {
+	cookie := &http.Cookie{
+		Name:     sc.name,
+		Value:    sc.value(cluster, now),
+		Path:     sc.path,
+		HttpOnly: true,
+	}
+	if sc.ttl > 0 {
+		cookie.MaxAge = int(sc.ttl / time.Second)
+		cookie.Expires = now.Add(sc.ttl)
+	}
+	return cookie.String()
+}
@@ -0,0 +1,152 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package router
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"mosn.io/api"
+	"mosn.io/pkg/variable"
+
+	v2 "mosn.io/mosn/pkg/config/v2"
+	"mosn.io/mosn/pkg/protocol"
+	"mosn.io/mosn/pkg/types"
+)
+
+func TestStickyCookieValue(t *testing.T) {
+	_, err := newStickyCookie(&v2.StickyCookie{})
+	require.NotNil(t, err)
+	sc, err := newStickyCookie(&v2.StickyCookie{
+		CookieHashPolicy: v2.CookieHashPolicy{
+			Name: "canary",
+			TTL:  api.DurationConfig{Duration: time.Hour},
+		},
+		Secret: "secret",
+	})
+	require.Nil(t, err)
+	now := time.Now()
+	value := sc.value("outbound|80||canary.svc", now)
+	cluster, ok := sc.cluster(value, now)
+	assert.True(t, ok)
+	assert.Equal(t, "outbound|80||canary.svc", cluster)
+	// expired
+	_, ok = sc.cluster(value, now.Add(2*time.Hour))
+	assert.False(t, ok)
+	// forged
+	forged := sc.value("stable", now)
+	forged = forged[:strings.LastIndexByte(forged, '.')] + value[strings.LastIndexByte(value, '.'):]
+	_, ok = sc.cluster(forged, now)
+	assert.False(t, ok)
+	// signed by another secret
+	other, _ := newStickyCookie(&v2.StickyCookie{
+		CookieHashPolicy: v2.CookieHashPolicy{Name: "canary"},
+	})
+	_, ok = other.cluster(value, now)
+	assert.False(t, ok)
+	for _, invalid := range []string{"", "invalid", "a.b", "a.b.c"} {
+		_, ok = sc.cluster(invalid, now)
+		assert.Falsef(t, ok, "value %s", invalid)
+	}
+	setCookie := sc.setCookie("canary", now)
+	assert.True(t, strings.HasPrefix(setCookie, "canary="))
+	assert.Contains(t, setCookie, "Max-Age=3600")
+}
+
+func TestStickyWeightedClusters(t *testing.T) {
+	testProtocol := types.ProtocolName("StickyProtocol")
+	var cookie string
+	cookieGetter := func(ctx context.Context, value *variable.IndexedValue, data interface{}) (string, error) {
+		if data.(string) == "StickyProtocol_cookie_sticky" && cookie != "" {
+			return cookie, nil
+		}
+		return variable.ValueNotFound, variable.ErrValueNotFound
+	}
+	cookieValue := variable.NewStringVariable("StickyProtocol_cookie_", nil, cookieGetter, nil, 0)
+	variable.RegisterPrefix(cookieValue.Name(), cookieValue)
+	variable.RegisterProtocolResource(testProtocol, api.COOKIE, types.VarProtocolCookie)
+
+	vh, err := NewVirtualHostImpl(&v2.VirtualHost{Domains: []string{"*"}})
+	require.Nil(t, err)
+	vh.globalRouteConfig = NewConfigImpl(&v2.RouterConfiguration{})
+	rb, err := NewRouteRuleImplBase(vh, &v2.Router{
+		RouterConfig: v2.RouterConfig{
+			Route: v2.RouteAction{
+				RouterActionConfig: v2.RouterActionConfig{
+					WeightedClusters: []v2.WeightedCluster{
+						{Cluster: v2.ClusterWeight{ClusterWeightConfig: v2.ClusterWeightConfig{Name: "stable", Weight: 50}}},
+						{Cluster: v2.ClusterWeight{ClusterWeightConfig: v2.ClusterWeightConfig{Name: "canary", Weight: 50}}},
+						{Cluster: v2.ClusterWeight{ClusterWeightConfig: v2.ClusterWeightConfig{Name: "removed", Weight: 0}}},
+					},
+					StickyCookie: &v2.StickyCookie{
+						CookieHashPolicy: v2.CookieHashPolicy{
+							Name: "sticky",
+							Path: "/",
+							TTL:  api.DurationConfig{Duration: time.Hour},
+						},
+					},
+				},
+			},
+		},
+	})
+	require.Nil(t, err)
+	newContext := func() context.Context {
+		ctx := variable.NewVariableContext(context.Background())
+		_ = variable.Set(ctx, types.VariableDownStreamProtocol, testProtocol)
+		return ctx
+	}
+
+	// first request chooses a cluster and sets the cookie
+	ctx := newContext()
+	clusterName := rb.ClusterName(ctx)
+	headers := protocol.CommonHeader(map[string]string{})
+	rb.FinalizeResponseHeaders(ctx, headers, nil)
+	setCookie, ok := headers.Get("Set-Cookie")
+	require.True(t, ok)
+	assert.Contains(t, setCookie, "Path=/")
+
+	// the cluster is chosen once for a request, the route may be asked for the cluster name more than once
+	for i := 0; i < 20; i++ {
+		ctx := newContext()
+		assert.Equal(t, rb.ClusterName(ctx), rb.ClusterName(ctx))
+	}
+
+	// later requests with the cookie stick to the cluster without setting the cookie again
+	cookie = setCookie[len("sticky=") : len("sticky=")+strings.IndexByte(setCookie[len("sticky="):], ';')]
+	for i := 0; i < 20; i++ {
+		ctx = newContext()
+		assert.Equal(t, clusterName, rb.ClusterName(ctx))
+		headers = protocol.CommonHeader(map[string]string{})
+		rb.FinalizeResponseHeaders(ctx, headers, nil)
+		_, ok = headers.Get("Set-Cookie")
+		assert.False(t, ok)
+	}
+
+	// the cluster is not in the weighted clusters any more, choose again
+	cookie = rb.stickyCookie.value("removed", time.Now())
+	ctx = newContext()
+	assert.NotEqual(t, "removed", rb.ClusterName(ctx))
+	headers = protocol.CommonHeader(map[string]string{})
+	rb.FinalizeResponseHeaders(ctx, headers, nil)
+	_, ok = headers.Get("Set-Cookie")
+	assert.True(t, ok)
+}
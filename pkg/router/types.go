@@ -19,11 +19,14 @@ package router
 
 import (
 	"context"
+	crand "crypto/rand"
 	"encoding/binary"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"math/rand"
 	"net"
+	"net/http"
 	"sync"
 	"time"
 
@@ -96,10 +99,17 @@ func (p *policy) MirrorPolicy() api.MirrorPolicy {
 }
 
 type retryPolicyImpl struct {
-	retryOn      bool
-	retryTimeout time.Duration
-	numRetries   uint32
-	statusCodes  []uint32
+	retryOn           bool
+	retryTimeout      time.Duration
+	numRetries        uint32
+	statusCodes       []uint32
+	retryOnConditions []string
+	retryBudget       *v2.RetryBudget
+	baseInterval      time.Duration
+	maxInterval       time.Duration
+	hostPredicates    []*v2.RetryHostPredicate
+	hostMaxAttempts   uint32
+	hedgeOnTimeout    bool
 }
 
 func (p *retryPolicyImpl) RetryOn() bool {
@@ -130,6 +140,48 @@ func (p *retryPolicyImpl) RetryableStatusCodes() []uint32 {
 	return p.statusCodes
 }
 
+func (p *retryPolicyImpl) RetryOnConditions() []string {
+	if p == nil {
+		return nil
+	}
+	return p.retryOnConditions
+}
+
+func (p *retryPolicyImpl) RetryBudget() *v2.RetryBudget {
+	if p == nil {
+		return nil
+	}
+	return p.retryBudget
+}
+
+func (p *retryPolicyImpl) RetryBackOff() (time.Duration, time.Duration) {
+	if p == nil {
+		return 0, 0
+	}
+	return p.baseInterval, p.maxInterval
+}
+
+func (p *retryPolicyImpl) RetryHostPredicates() []*v2.RetryHostPredicate {
+	if p == nil {
+		return nil
+	}
+	return p.hostPredicates
+}
+
+func (p *retryPolicyImpl) HostSelectionRetryMaxAttempts() uint32 {
+	if p == nil {
+		return 0
+	}
+	return p.hostMaxAttempts
+}
+
+func (p *retryPolicyImpl) HedgeOnPerTryTimeout() bool {
+	if p == nil {
+		return false
+	}
+	return p.hedgeOnTimeout
+}
+
 type shadowPolicyImpl struct {
 	cluster    string
 	runtimeKey string
@@ -206,16 +258,16 @@ func (hp *headerHashPolicyImpl) GenerateHash(ctx context.Context) uint64 {
 type cookieHashPolicyImpl struct {
 	name string
 	// path and ttl field are used for generate cookie value,
-	// they are not being used currently.
+	// the cookie is generated only if the ttl is set.
 	path string
 	ttl  api.DurationConfig
 }
 
 // GenerateHash is httpCookieHashPolicyImpl hash generate logic.
 //
-// !!! please notice, in envoy or istio cookie may be generated if cookie value is not found,
-// MOSN does NOT implement this strategy yet. When cookie value is not found a
-// hash '0' will always be returned.
+// If the cookie value is not found and the ttl is set, a cookie value is generated
+// to hash as envoy does, the generated cookie is set on the response by FinalizeResponseHeaders.
+// Otherwise a hash '0' will always be returned.
 func (hp *cookieHashPolicyImpl) GenerateHash(ctx context.Context) uint64 {
 	cookieName := hp.name
 	cookieValue, err := variable.GetProtocolResource(ctx, api.COOKIE, cookieName)
@@ -223,9 +275,44 @@ func (hp *cookieHashPolicyImpl) GenerateHash(ctx context.Context) uint64 {
 		h := getHashByString(fmt.Sprintf("%s=%s", cookieName, cookieValue))
 		return h
 	}
+	if hp.ttl.Duration > 0 {
+		if cookieValue, ok := hp.generateCookie(ctx); ok {
+			return getHashByString(fmt.Sprintf("%s=%s", cookieName, cookieValue))
+		}
+	}
 	return 0
 }
 
+// generateCookie generates the cookie once in a request, so the retries get the same hash
+func (hp *cookieHashPolicyImpl) generateCookie(ctx context.Context) (string, bool) {
+	var cookies map[string]*http.Cookie
+	if v, err := variable.Get(ctx, types.VarGeneratedCookies); err == nil {
+		cookies, _ = v.(map[string]*http.Cookie)
+	}
+	if cookie, ok := cookies[hp.name]; ok {
+		return cookie.Value, true
+	}
+	if cookies == nil {
+		cookies = make(map[string]*http.Cookie)
+		if err := variable.Set(ctx, types.VarGeneratedCookies, cookies); err != nil {
+			return "", false
+		}
+	}
+	value := make([]byte, 16)
+	crand.Read(value)
+	now := time.Now()
+	cookie := &http.Cookie{
+		Name:     hp.name,
+		Value:    hex.EncodeToString(value),
+		Path:     hp.path,
+		MaxAge:   int(hp.ttl.Duration / time.Second),
+		Expires:  now.Add(hp.ttl.Duration),
+		HttpOnly: true,
+	}
+	cookies[hp.name] = cookie
+	return cookie.Value, true
+}
+
 type sourceIPHashPolicyImpl struct{}
 
 func (hp *sourceIPHashPolicyImpl) GenerateHash(ctx context.Context) uint64 {
@@ -237,6 +324,34 @@ func (hp *sourceIPHashPolicyImpl) GenerateHash(ctx context.Context) uint64 {
 	return 0
 }
 
+type hashPolicyItem struct {
+	hashPolicy api.HashPolicy
+	terminal   bool
+}
+
+// combinedHashPolicyImpl combines the hashes of the hash policies in order,
+// a policy that generates no hash is ignored, and the policies after a terminal
+// policy that generates a hash are skipped.
+type combinedHashPolicyImpl struct {
+	hashPolicies []hashPolicyItem
+}
+
+func (hp *combinedHashPolicyImpl) GenerateHash(ctx context.Context) uint64 {
+	var hash uint64
+	for _, item := range hp.hashPolicies {
+		h := item.hashPolicy.GenerateHash(ctx)
+		if h == 0 {
+			continue
+		}
+		// rotate the hash left by 1 bit before combining, so the same hashes are not cancelled out
+		hash = ((hash << 1) | (hash >> 63)) ^ h
+		if item.terminal {
+			break
+		}
+	}
+	return hash
+}
+
 func getHashByAddr(addr net.Addr) (hash uint64) {
 	if tcpaddr, ok := addr.(*net.TCPAddr); ok {
 		if len(tcpaddr.IP) == 16 || len(tcpaddr.IP) == 4 {
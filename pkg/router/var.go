@@ -18,6 +18,8 @@
 package router
 
 import (
+	"context"
+
 	"mosn.io/mosn/pkg/types"
 	"mosn.io/pkg/variable"
 )
@@ -26,6 +28,15 @@ var (
 	builtinVariables = []variable.Variable{
 		// value type of VarRouterMeta should be map[string]string
 		variable.NewVariable(types.VarRouterMeta, nil, nil, variable.DefaultSetter, 0),
+		// value type of VarPathTemplateCaptures should be map[string]string
+		variable.NewVariable(types.VarPathTemplateCaptures, nil, nil, variable.DefaultSetter, 0),
+		variable.NewStringVariable(types.VarStickyCluster, nil, nil, variable.DefaultStringSetter, 0),
+		// value type of VarGeneratedCookies should be map[string]*http.Cookie
+		variable.NewVariable(types.VarGeneratedCookies, nil, nil, variable.DefaultSetter, 0),
+	}
+
+	prefixVariables = []variable.Variable{
+		variable.NewStringVariable(types.VarPrefixPathParam, nil, pathParamGetter, nil, 0),
 	}
 )
 
@@ -34,4 +45,26 @@ func init() {
 	for idx := range builtinVariables {
 		variable.Register(builtinVariables[idx])
 	}
+
+	// register prefix variables, like path_param_xxx
+	for idx := range prefixVariables {
+		variable.RegisterPrefix(prefixVariables[idx].Name(), prefixVariables[idx])
+	}
+}
+
+// pathParamGetter gets the capture of the matched path template
+func pathParamGetter(ctx context.Context, value *variable.IndexedValue, data interface{}) (string, error) {
+	v, err := variable.Get(ctx, types.VarPathTemplateCaptures)
+	if err != nil {
+		return variable.ValueNotFound, err
+	}
+	captures, ok := v.(map[string]string)
+	if !ok {
+		return variable.ValueNotFound, variable.ErrValueNotFound
+	}
+	name, _ := data.(string)
+	if capture, ok := captures[name[len(types.VarPrefixPathParam):]]; ok {
+		return capture, nil
+	}
+	return variable.ValueNotFound, variable.ErrValueNotFound
 }
@@ -64,6 +64,9 @@ func (vrri *VariableRouteRuleImpl) FinalizeRequestHeaders(ctx context.Context, h
 }
 
 func (vrri *VariableRouteRuleImpl) Match(ctx context.Context, headers api.HeaderMap) api.Route {
+	if !vrri.matchRuntimeFraction(headers) {
+		return nil
+	}
 	result := true
 	walkVarName := ""
 	lastMode := AND
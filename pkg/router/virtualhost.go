@@ -25,6 +25,8 @@ import (
 	"mosn.io/api"
 	v2 "mosn.io/mosn/pkg/config/v2"
 	"mosn.io/mosn/pkg/log"
+	"mosn.io/mosn/pkg/types"
+	"mosn.io/pkg/variable"
 )
 
 type VirtualHostImpl struct {
@@ -32,6 +34,7 @@ type VirtualHostImpl struct {
 	mutex                 sync.RWMutex
 	routes                []RouteBase
 	fastIndex             map[string]map[string]api.Route
+	pathTemplates         *pathTemplateTrie
 	globalRouteConfig     *configImpl
 	requestHeadersParser  *headerParser
 	responseHeadersParser *headerParser
@@ -49,10 +52,18 @@ func (vh *VirtualHostImpl) addRouteBase(route api.RouteBase) {
 	vh.mutex.Lock()
 	defer vh.mutex.Unlock()
 	vh.routes = append(vh.routes, route)
+	// index the path template route, the route index is used to keep the routes order
+	if ptr, ok := route.(*PathTemplateRouteRuleImpl); ok {
+		if vh.pathTemplates == nil {
+			vh.pathTemplates = newPathTemplateTrie()
+		}
+		vh.pathTemplates.insert(ptr.template, len(vh.routes)-1)
+	}
 	// make fast index, used in certain scenarios
 	// TODO: rule can be extended
 	hmc := route.RouteRule().HeaderMatchCriteria()
-	if hmc != nil && hmc.Len() == 1 && hmc.Get(0).MatchType() == api.ValueExact {
+	if hmc != nil && hmc.Len() == 1 && hmc.Get(0).MatchType() == api.ValueExact && !isPartialCriteria(hmc) &&
+		!isFractionalRoute(route) {
 		key := hmc.Get(0).Key()
 		value := hmc.Get(0).Matcher()
 		valueMap, ok := vh.fastIndex[key]
@@ -66,9 +77,26 @@ func (vh *VirtualHostImpl) addRouteBase(route api.RouteBase) {
 
 }
 
+// isPartialCriteria checks whether some header matchers are kept out of the criteria, which can not be used in fast index
+func isPartialCriteria(hmc api.KeyValueMatchCriteria) bool {
+	c, ok := hmc.(*keyValueMatchCriteria)
+	return ok && c.partial
+}
+
+// isFractionalRoute checks whether the route matches a fraction of requests, which can not be used in fast index
+func isFractionalRoute(route api.RouteBase) bool {
+	r, ok := route.(interface {
+		hasRuntimeFraction() bool
+	})
+	return ok && r.hasRuntimeFraction()
+}
+
 func (vh *VirtualHostImpl) GetRouteFromEntries(ctx context.Context, headers api.HeaderMap) api.Route {
 	vh.mutex.RLock()
 	defer vh.mutex.RUnlock()
+	if vh.pathTemplates != nil {
+		return vh.getRouteWithPathTemplates(ctx, headers)
+	}
 	for _, route := range vh.routes {
 		if routeEntry := route.Match(ctx, headers); routeEntry != nil {
 			return routeEntry
@@ -77,6 +105,35 @@ func (vh *VirtualHostImpl) GetRouteFromEntries(ctx context.Context, headers api.
 	return nil
 }
 
+// getRouteWithPathTemplates matches the path template routes in the trie first, the matched
+// routes use the captures of the trie, and the other path template routes are skipped
+func (vh *VirtualHostImpl) getRouteWithPathTemplates(ctx context.Context, headers api.HeaderMap) api.Route {
+	var candidates []pathTemplateMatch
+	if path, err := variable.GetString(ctx, types.VarPath); err == nil && path != "" {
+		candidates = vh.pathTemplates.match(path)
+	}
+	for idx, route := range vh.routes {
+		ptr, ok := route.(*PathTemplateRouteRuleImpl)
+		if !ok {
+			if routeEntry := route.Match(ctx, headers); routeEntry != nil {
+				return routeEntry
+			}
+			continue
+		}
+		// candidates are sorted, skip the indexes before the route
+		for len(candidates) > 0 && candidates[0].index < idx {
+			candidates = candidates[1:]
+		}
+		if len(candidates) == 0 || candidates[0].index != idx {
+			continue
+		}
+		if routeEntry := ptr.matchWithCaptures(ctx, headers, candidates[0].captures); routeEntry != nil {
+			return routeEntry
+		}
+	}
+	return nil
+}
+
 func (vh *VirtualHostImpl) GetAllRoutesFromEntries(ctx context.Context, headers api.HeaderMap) []api.Route {
 	vh.mutex.RLock()
 	defer vh.mutex.RUnlock()
@@ -111,6 +168,7 @@ func (vh *VirtualHostImpl) RemoveAllRoutes() {
 	defer vh.mutex.Unlock()
 	// clear the value map
 	vh.fastIndex = make(map[string]map[string]api.Route)
+	vh.pathTemplates = nil
 	// clear the routes
 	vh.routes = vh.routes[:0]
 	return
@@ -157,13 +215,23 @@ func NewRouteBase(vh api.VirtualHost, route *v2.Router) (api.RouteBase, error) {
 	}
 	var router RouteBase
 	if route.Match.Prefix != "" {
+		httpBase, err := NewBaseHTTPRouteRuleWithQueryParameters(base, route.Match.Headers, route.Match.QueryParameters)
+		if err != nil {
+			log.DefaultLogger.Errorf(RouterLogFormat, "virtualhost", "NewRouteBase", err)
+			return nil, err
+		}
 		router = &PrefixRouteRuleImpl{
-			BaseHTTPRouteRule: NewBaseHTTPRouteRule(base, route.Match.Headers),
+			BaseHTTPRouteRule: httpBase,
 			prefix:            route.Match.Prefix,
 		}
 	} else if route.Match.Path != "" {
+		httpBase, err := NewBaseHTTPRouteRuleWithQueryParameters(base, route.Match.Headers, route.Match.QueryParameters)
+		if err != nil {
+			log.DefaultLogger.Errorf(RouterLogFormat, "virtualhost", "NewRouteBase", err)
+			return nil, err
+		}
 		router = &PathRouteRuleImpl{
-			BaseHTTPRouteRule: NewBaseHTTPRouteRule(base, route.Match.Headers),
+			BaseHTTPRouteRule: httpBase,
 			path:              route.Match.Path,
 		}
 	} else if route.Match.Regex != "" {
@@ -172,11 +240,25 @@ func NewRouteBase(vh api.VirtualHost, route *v2.Router) (api.RouteBase, error) {
 			log.DefaultLogger.Errorf(RouterLogFormat, "virtualhost", "NewRouteBase", err)
 			return nil, err
 		}
+		httpBase, err := NewBaseHTTPRouteRuleWithQueryParameters(base, route.Match.Headers, route.Match.QueryParameters)
+		if err != nil {
+			log.DefaultLogger.Errorf(RouterLogFormat, "virtualhost", "NewRouteBase", err)
+			return nil, err
+		}
 		router = &RegexRouteRuleImpl{
-			BaseHTTPRouteRule: NewBaseHTTPRouteRule(base, route.Match.Headers),
+			BaseHTTPRouteRule: httpBase,
 			regexStr:          route.Match.Regex,
 			regexPattern:      regPattern,
 		}
+	} else if route.Match.PathTemplate != "" {
+		httpBase, err := NewBaseHTTPRouteRuleWithQueryParameters(base, route.Match.Headers, route.Match.QueryParameters)
+		if err == nil {
+			router, err = NewPathTemplateRouteRuleImpl(httpBase, route.Match.PathTemplate, route.Route.PathTemplateRewrite)
+		}
+		if err != nil {
+			log.DefaultLogger.Errorf(RouterLogFormat, "virtualhost", "NewRouteBase", err)
+			return nil, err
+		}
 	} else if len(route.Match.Variables) > 0 {
 		variableRouter := &VariableRouteRuleImpl{
 			RouteRuleImplBase: base,
@@ -194,7 +276,11 @@ func NewRouteBase(vh api.VirtualHost, route *v2.Router) (api.RouteBase, error) {
 		}
 		router = dslRouter
 	} else {
-		router = CreateRPCRule(base, route.Match.Headers)
+		router, err = NewRPCRouteRule(base, route.Match.Headers, route.Match.QueryParameters)
+		if err != nil {
+			log.DefaultLogger.Errorf(RouterLogFormat, "virtualhost", "NewRouteBase", err)
+			return nil, err
+		}
 	}
 	return router, nil
 }
@@ -198,3 +198,115 @@ func TestAllRouter(t *testing.T) {
 		}
 	}
 }
+
+func TestFastIndexIgnoreInvertedMatch(t *testing.T) {
+	vh, err := NewVirtualHostImpl(&v2.VirtualHost{
+		Domains: []string{"*"},
+		Routers: []v2.Router{
+			{
+				RouterConfig: v2.RouterConfig{
+					Match: v2.RouterMatch{
+						Headers: []v2.HeaderMatcher{
+							{
+								Name:        types.RPCRouteMatchKey,
+								Value:       "inverted",
+								InvertMatch: true,
+							},
+						},
+					},
+					Route: v2.RouteAction{
+						RouterActionConfig: v2.RouterActionConfig{
+							ClusterName: "test",
+						},
+					},
+				},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("create virtual host failed: %v", err)
+	}
+	if vh.GetRouteFromHeaderKV(types.RPCRouteMatchKey, "inverted") != nil {
+		t.Fatal("inverted match should not be indexed")
+	}
+	headers := protocol.CommonHeader(map[string]string{types.RPCRouteMatchKey: "other"})
+	if vh.GetRouteFromEntries(variable.NewVariableContext(context.Background()), headers) == nil {
+		t.Fatal("inverted match should match other values")
+	}
+}
+
+func TestInvalidMatcherRoute(t *testing.T) {
+	for i, match := range []v2.RouterMatch{
+		{
+			Prefix: "/",
+			Headers: []v2.HeaderMatcher{
+				{Name: "range", RangeMatch: &v2.Int64Range{Start: 20, End: 10}},
+			},
+		},
+		{
+			Path: "/test",
+			QueryParameters: []v2.HeaderMatcher{
+				{Name: "regex", Value: "[0-9", Regex: true},
+			},
+		},
+		{
+			Headers: []v2.HeaderMatcher{
+				{Name: types.RPCRouteMatchKey, RangeMatch: &v2.Int64Range{Start: 20, End: 10}},
+			},
+		},
+	} {
+		_, err := NewVirtualHostImpl(&v2.VirtualHost{
+			Domains: []string{"*"},
+			Routers: []v2.Router{
+				{
+					RouterConfig: v2.RouterConfig{
+						Match: match,
+						Route: v2.RouteAction{
+							RouterActionConfig: v2.RouterActionConfig{
+								ClusterName: "test",
+							},
+						},
+					},
+				},
+			},
+		})
+		if err == nil {
+			t.Errorf("#%d invalid matcher should fail the route creation", i)
+		}
+	}
+}
+
+func TestInvalidHeaderRegexIgnored(t *testing.T) {
+	for i, match := range []v2.RouterMatch{
+		{
+			Prefix: "/",
+			Headers: []v2.HeaderMatcher{
+				{Name: "regex", Value: "[0-9", Regex: true},
+			},
+		},
+		{
+			Headers: []v2.HeaderMatcher{
+				{Name: types.RPCRouteMatchKey, Value: "[0-9", Regex: true},
+			},
+		},
+	} {
+		_, err := NewVirtualHostImpl(&v2.VirtualHost{
+			Domains: []string{"*"},
+			Routers: []v2.Router{
+				{
+					RouterConfig: v2.RouterConfig{
+						Match: match,
+						Route: v2.RouteAction{
+							RouterActionConfig: v2.RouterActionConfig{
+								ClusterName: "test",
+							},
+						},
+					},
+				},
+			},
+		})
+		if err != nil {
+			t.Errorf("#%d invalid header regex should be ignored, but got error: %v", i, err)
+		}
+	}
+}
@@ -0,0 +1,234 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package server
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+
+	"mosn.io/api"
+	v2 "mosn.io/mosn/pkg/config/v2"
+	"mosn.io/mosn/pkg/mtls"
+	"mosn.io/mosn/pkg/types"
+	"mosn.io/pkg/variable"
+)
+
+// activeFilterChain is a filter chain of the listener,
+// the connection is created with the tls context and the network filters of the selected filter chain
+type activeFilterChain struct {
+	match                   *v2.FilterChainMatch
+	sourceRanges            []*net.IPNet
+	networkFiltersFactories []api.NetworkFilterChainFactory
+	tlsMng                  types.TLSContextManager
+}
+
+// newActiveFilterChains creates the filter chains of the listener,
+// the network filter factories of every filter chain are created by the caller
+func newActiveFilterChains(lc *v2.Listener, networkFiltersFactories [][]api.NetworkFilterChainFactory) ([]*activeFilterChain, error) {
+	filterChains := make([]*activeFilterChain, 0, len(lc.FilterChains))
+	for i := range lc.FilterChains {
+		fc := &lc.FilterChains[i]
+		afc := &activeFilterChain{
+			match: fc.Match,
+		}
+		if fc.Match != nil {
+			for _, cidr := range fc.Match.SourcePrefixRanges {
+				_, ipNet, err := net.ParseCIDR(cidr)
+				if err != nil {
+					return nil, fmt.Errorf("invalid source prefix range %s in filter chain %d: %v", cidr, i, err)
+				}
+				afc.sourceRanges = append(afc.sourceRanges, ipNet)
+			}
+		}
+		if i < len(networkFiltersFactories) {
+			afc.networkFiltersFactories = networkFiltersFactories[i]
+		}
+		mgr, err := mtls.NewTLSFilterChainContextManager(lc, fc)
+		if err != nil {
+			return nil, err
+		}
+		afc.tlsMng = mgr
+		filterChains = append(filterChains, afc)
+	}
+	return filterChains, nil
+}
+
+// filterChainMatchInfo is the connection information to select the filter chain
+type filterChainMatchInfo struct {
+	destinationPort      int
+	serverName           string
+	transportProtocol    string
+	applicationProtocols []string
+	sourceIP             net.IP
+}
+
+func newFilterChainMatchInfo(ctx context.Context, rawc net.Conn) *filterChainMatchInfo {
+	info := &filterChainMatchInfo{
+		transportProtocol: v2.TransportProtocolRawBuffer,
+	}
+	localAddr := rawc.LocalAddr()
+	if addr, err := variable.Get(ctx, types.VariableOriLocalAddr); err == nil && addr != nil {
+		localAddr = addr.(net.Addr)
+	}
+	_, info.destinationPort = addrIPPort(localAddr)
+	remoteAddr := rawc.RemoteAddr()
+	if addr, err := variable.Get(ctx, types.VariableOriRemoteAddr); err == nil && addr != nil {
+		remoteAddr = addr.(net.Addr)
+	}
+	info.sourceIP, _ = addrIPPort(remoteAddr)
+	if serverName, err := variable.GetString(ctx, types.VariableRequestedServerName); err == nil {
+		info.serverName = strings.ToLower(serverName)
+	}
+	if protocol, err := variable.GetString(ctx, types.VariableTransportProtocol); err == nil && protocol != "" {
+		info.transportProtocol = protocol
+	}
+	if protos, err := variable.Get(ctx, types.VariableApplicationProtocols); err == nil && protos != nil {
+		info.applicationProtocols = protos.([]string)
+	}
+	return info
+}
+
+func addrIPPort(addr net.Addr) (net.IP, int) {
+	switch a := addr.(type) {
+	case *net.TCPAddr:
+		return a.IP, a.Port
+	case *net.UDPAddr:
+		return a.IP, a.Port
+	}
+	return nil, 0
+}
+
+// filterChainMatchers are the criteria in the order of matching.
+// a matcher returns -1 if the filter chain is not matched, 0 if the criteria is not configured,
+// and a larger value for a more specific criteria.
+var filterChainMatchers = []func(fc *activeFilterChain, info *filterChainMatchInfo) int{
+	matchDestinationPort,
+	matchServerName,
+	matchTransportProtocol,
+	matchApplicationProtocols,
+	matchSourceIP,
+}
+
+// selectFilterChain returns the filter chain of the connection, returns nil if no filter chain is matched.
+// The filter chains are filtered by the criteria in order, and only the most specific ones are kept in each criteria,
+// the first filter chain is selected if multiple filter chains are left.
+func selectFilterChain(filterChains []*activeFilterChain, info *filterChainMatchInfo) *activeFilterChain {
+	candidates := filterChains
+	for _, matcher := range filterChainMatchers {
+		best := -1
+		var matched []*activeFilterChain
+		for _, fc := range candidates {
+			score := matcher(fc, info)
+			if score < 0 || score < best {
+				continue
+			}
+			if score > best {
+				best = score
+				matched = matched[:0]
+			}
+			matched = append(matched, fc)
+		}
+		if len(matched) == 0 {
+			return nil
+		}
+		candidates = matched
+	}
+	return candidates[0]
+}
+
+func matchDestinationPort(fc *activeFilterChain, info *filterChainMatchInfo) int {
+	if fc.match == nil || fc.match.DestinationPort == 0 {
+		return 0
+	}
+	if int(fc.match.DestinationPort) == info.destinationPort {
+		return 1
+	}
+	return -1
+}
+
+// exactServerNameScore is larger than the score of any wildcard server name
+const exactServerNameScore = 1 << 16
+
+func matchServerName(fc *activeFilterChain, info *filterChainMatchInfo) int {
+	if fc.match == nil || len(fc.match.ServerNames) == 0 {
+		return 0
+	}
+	best := -1
+	if info.serverName == "" {
+		return best
+	}
+	for _, name := range fc.match.ServerNames {
+		name = strings.ToLower(name)
+		if name == info.serverName {
+			return exactServerNameScore
+		}
+		// the wildcard matches the subdomains, the longer suffix is more specific
+		if strings.HasPrefix(name, "*.") {
+			suffix := name[1:]
+			if len(info.serverName) > len(suffix) && strings.HasSuffix(info.serverName, suffix) && len(suffix) > best {
+				best = len(suffix)
+			}
+		}
+	}
+	return best
+}
+
+func matchTransportProtocol(fc *activeFilterChain, info *filterChainMatchInfo) int {
+	if fc.match == nil || fc.match.TransportProtocol == "" {
+		return 0
+	}
+	if fc.match.TransportProtocol == info.transportProtocol {
+		return 1
+	}
+	return -1
+}
+
+func matchApplicationProtocols(fc *activeFilterChain, info *filterChainMatchInfo) int {
+	if fc.match == nil || len(fc.match.ApplicationProtocols) == 0 {
+		return 0
+	}
+	for _, proto := range fc.match.ApplicationProtocols {
+		for _, p := range info.applicationProtocols {
+			if proto == p {
+				return 1
+			}
+		}
+	}
+	return -1
+}
+
+func matchSourceIP(fc *activeFilterChain, info *filterChainMatchInfo) int {
+	if len(fc.sourceRanges) == 0 {
+		return 0
+	}
+	best := -1
+	if info.sourceIP == nil {
+		return best
+	}
+	// the longer prefix is more specific, the score of 0.0.0.0/0 is 1
+	for _, ipNet := range fc.sourceRanges {
+		if ipNet.Contains(info.sourceIP) {
+			if ones, _ := ipNet.Mask.Size(); ones+1 > best {
+				best = ones + 1
+			}
+		}
+	}
+	return best
+}
@@ -0,0 +1,243 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package server
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	v2 "mosn.io/mosn/pkg/config/v2"
+	"mosn.io/mosn/pkg/configmanager"
+	_ "mosn.io/mosn/pkg/filter/listener/tlsinspector"
+	"mosn.io/mosn/pkg/mtls"
+	"mosn.io/mosn/pkg/types"
+	"mosn.io/pkg/variable"
+)
+
+func newTestFilterChains(t *testing.T, matches ...*v2.FilterChainMatch) []*activeFilterChain {
+	addr, _ := net.ResolveTCPAddr("tcp", "127.0.0.1:8080")
+	lc := &v2.Listener{
+		ListenerConfig: v2.ListenerConfig{Name: "filter_chains"},
+		Addr:           addr,
+	}
+	for _, match := range matches {
+		lc.FilterChains = append(lc.FilterChains, v2.FilterChain{
+			FilterChainConfig: v2.FilterChainConfig{
+				Match: match,
+			},
+		})
+	}
+	filterChains, err := newActiveFilterChains(lc, nil)
+	require.Nil(t, err)
+	return filterChains
+}
+
+func TestSelectFilterChain(t *testing.T) {
+	filterChains := newTestFilterChains(t,
+		nil,
+		&v2.FilterChainMatch{ServerNames: []string{"*.example.com"}},
+		&v2.FilterChainMatch{ServerNames: []string{"www.example.com"}},
+		&v2.FilterChainMatch{ServerNames: []string{"*.api.example.com"}},
+		&v2.FilterChainMatch{ServerNames: []string{"*.api.example.com"}, ApplicationProtocols: []string{"h2"}},
+		&v2.FilterChainMatch{DestinationPort: 8443},
+		&v2.FilterChainMatch{DestinationPort: 8443, SourcePrefixRanges: []string{"10.0.0.0/8"}},
+		&v2.FilterChainMatch{DestinationPort: 8443, SourcePrefixRanges: []string{"10.1.0.0/16"}},
+		&v2.FilterChainMatch{TransportProtocol: v2.TransportProtocolTLS, DestinationPort: 9443},
+	)
+	for _, tc := range []struct {
+		name     string
+		info     filterChainMatchInfo
+		expected int
+	}{
+		{"default", filterChainMatchInfo{destinationPort: 80}, 0},
+		{"exact server name", filterChainMatchInfo{serverName: "www.example.com"}, 2},
+		{"wildcard server name", filterChainMatchInfo{serverName: "foo.example.com"}, 1},
+		{"longest wildcard server name", filterChainMatchInfo{serverName: "foo.api.example.com"}, 3},
+		{"wildcard does not match the domain", filterChainMatchInfo{serverName: "example.com"}, 0},
+		{"alpn", filterChainMatchInfo{serverName: "foo.api.example.com", applicationProtocols: []string{"http/1.1", "h2"}}, 4},
+		{"destination port", filterChainMatchInfo{destinationPort: 8443, sourceIP: net.ParseIP("192.168.0.1")}, 5},
+		{"source ip", filterChainMatchInfo{destinationPort: 8443, sourceIP: net.ParseIP("10.2.0.1")}, 6},
+		{"longest source prefix", filterChainMatchInfo{destinationPort: 8443, sourceIP: net.ParseIP("10.1.0.1")}, 7},
+		// the destination port is matched before the server name
+		{"destination port before server name", filterChainMatchInfo{destinationPort: 8443, serverName: "www.example.com"}, 5},
+		{"transport protocol", filterChainMatchInfo{destinationPort: 9443, transportProtocol: v2.TransportProtocolTLS}, 8},
+		{"no transport protocol matched", filterChainMatchInfo{destinationPort: 9443, transportProtocol: v2.TransportProtocolRawBuffer}, -1},
+	} {
+		fc := selectFilterChain(filterChains, &tc.info)
+		if tc.expected < 0 {
+			assert.Nil(t, fc, tc.name)
+			continue
+		}
+		assert.Same(t, filterChains[tc.expected], fc, tc.name)
+	}
+}
+
+func TestNewActiveFilterChainsInvalidSourceRange(t *testing.T) {
+	addr, _ := net.ResolveTCPAddr("tcp", "127.0.0.1:8080")
+	_, err := newActiveFilterChains(&v2.Listener{
+		ListenerConfig: v2.ListenerConfig{
+			Name: "filter_chains",
+			FilterChains: []v2.FilterChain{
+				{
+					FilterChainConfig: v2.FilterChainConfig{
+						Match: &v2.FilterChainMatch{SourcePrefixRanges: []string{"10.0.0.1"}},
+					},
+				},
+			},
+		},
+		Addr: addr,
+	}, nil)
+	assert.NotNil(t, err)
+}
+
+func TestNewFilterChainMatchInfo(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.Nil(t, err)
+	defer ln.Close()
+	go func() {
+		if conn, err := net.Dial("tcp", ln.Addr().String()); err == nil {
+			time.Sleep(100 * time.Millisecond)
+			conn.Close()
+		}
+	}()
+	conn, err := ln.Accept()
+	require.Nil(t, err)
+	defer conn.Close()
+
+	ctx := variable.NewVariableContext(context.Background())
+	info := newFilterChainMatchInfo(ctx, conn)
+	assert.Equal(t, ln.Addr().(*net.TCPAddr).Port, info.destinationPort)
+	assert.Equal(t, "127.0.0.1", info.sourceIP.String())
+	assert.Equal(t, v2.TransportProtocolRawBuffer, info.transportProtocol)
+
+	// the restored addresses and the tls information
+	src, _ := net.ResolveTCPAddr("tcp", "192.168.0.1:56324")
+	dst, _ := net.ResolveTCPAddr("tcp", "192.168.0.11:443")
+	_ = variable.Set(ctx, types.VariableOriRemoteAddr, src)
+	_ = variable.Set(ctx, types.VariableOriLocalAddr, dst)
+	_ = variable.SetString(ctx, types.VariableTransportProtocol, v2.TransportProtocolTLS)
+	_ = variable.SetString(ctx, types.VariableRequestedServerName, "WWW.Example.com")
+	_ = variable.Set(ctx, types.VariableApplicationProtocols, []string{"h2"})
+	info = newFilterChainMatchInfo(ctx, conn)
+	assert.Equal(t, &filterChainMatchInfo{
+		destinationPort:      443,
+		serverName:           "www.example.com",
+		transportProtocol:    v2.TransportProtocolTLS,
+		applicationProtocols: []string{"h2"},
+		sourceIP:             src.IP,
+	}, info)
+}
+
+func TestListenerWithMultipleFilterChains(t *testing.T) {
+	setup()
+	defer tearDown()
+
+	addrStr := "127.0.0.1:8081"
+	listenerConfig := baseListenerConfig(addrStr, "filter_chains")
+	listenerConfig.ListenerFilters = []v2.Filter{
+		{Type: v2.TLS_INSPECTOR_LISTENER_FILTER},
+	}
+	// the tls filter chain for the server name, and the default raw filter chain
+	listenerConfig.FilterChains[0].Match = &v2.FilterChainMatch{
+		ServerNames:       []string{"*.example.com"},
+		TransportProtocol: v2.TransportProtocolTLS,
+	}
+	listenerConfig.FilterChains = append(listenerConfig.FilterChains, v2.FilterChain{
+		FilterChainConfig: v2.FilterChainConfig{
+			Filters: []v2.Filter{
+				{Type: "mock_network"},
+			},
+		},
+		TLSContexts: []v2.TLSConfig{{}},
+	})
+	require.Nil(t, GetListenerAdapterInstance().AddOrUpdateListener(testServerName, listenerConfig))
+	time.Sleep(time.Second) // wait listener start
+
+	dialer := &net.Dialer{Timeout: time.Second}
+	handshake := func(serverName string) error {
+		conn, err := tls.DialWithDialer(dialer, "tcp", addrStr, &tls.Config{
+			ServerName:         serverName,
+			InsecureSkipVerify: true,
+		})
+		if err == nil {
+			conn.Close()
+		}
+		return err
+	}
+	assert.Nil(t, handshake("www.example.com"))
+	// the default filter chain without tls
+	assert.NotNil(t, handshake("www.example.org"))
+	conn, err := net.DialTimeout("tcp", addrStr, time.Second)
+	require.Nil(t, err)
+	conn.Close()
+
+	// the filter chains are updated
+	handler := listenerAdapterInstance.defaultConnHandler.(*connHandler)
+	al := handler.findActiveListenerByName("filter_chains")
+	require.NotNil(t, al)
+	require.Len(t, al.filterChains, 2)
+	// the network filter factories of every filter chain are stored in the config manager
+	factories := configmanager.GetFilterChainsNetworkFilterFactories("filter_chains")
+	require.Len(t, factories, 2)
+	for i, fc := range al.filterChains {
+		assert.Equal(t, factories[i], fc.networkFiltersFactories)
+	}
+	assert.Equal(t, factories[0], configmanager.GetNetworkFilterFactories("filter_chains"))
+	listenerConfig.FilterChains = listenerConfig.FilterChains[:1]
+	require.Nil(t, GetListenerAdapterInstance().AddOrUpdateListener(testServerName, listenerConfig))
+	require.Len(t, al.filterChains, 1)
+	assert.Len(t, al.listener.Config().FilterChains, 1)
+	assert.Len(t, configmanager.GetFilterChainsNetworkFilterFactories("filter_chains"), 1)
+}
+
+// the tls conn is created after the listener filters, so the listener filters see the raw conn of a tls listener
+func TestTLSListenerWithListenerFilter(t *testing.T) {
+	setup()
+	defer tearDown()
+
+	addrStr := "127.0.0.1:8084"
+	listenerConfig := baseListenerConfig(addrStr, "tls_listener_filter")
+	listenerConfig.ListenerFilters = []v2.Filter{
+		{Type: "mock_listener"},
+	}
+	require.Nil(t, GetListenerAdapterInstance().AddOrUpdateListener(testServerName, listenerConfig))
+	time.Sleep(time.Second) // wait listener start
+
+	dialer := &net.Dialer{Timeout: time.Second}
+	conn, err := tls.DialWithDialer(dialer, "tcp", addrStr, &tls.Config{
+		InsecureSkipVerify: true,
+	})
+	require.Nil(t, err)
+	conn.Close()
+
+	select {
+	case c := <-mockListenerConns:
+		_, isTLS := c.(*mtls.TLSConn)
+		assert.False(t, isTLS, "listener filter should see the raw conn")
+		_, isTCP := c.(*net.TCPConn)
+		assert.True(t, isTCP)
+	case <-time.After(time.Second):
+		t.Fatal("listener filter is not called")
+	}
+}
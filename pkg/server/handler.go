@@ -41,7 +41,6 @@ import (
 	"mosn.io/mosn/pkg/filter/listener/originaldst"
 	"mosn.io/mosn/pkg/log"
 	"mosn.io/mosn/pkg/metrics"
-	"mosn.io/mosn/pkg/mtls"
 	"mosn.io/mosn/pkg/network"
 	"mosn.io/mosn/pkg/streamfilter"
 	"mosn.io/mosn/pkg/types"
@@ -109,16 +108,15 @@ func (ch *connHandler) AddOrUpdateListener(lc *v2.Listener) (types.ListenerEvent
 	} else {
 		listenerName = lc.Name
 	}
-	// currently, we just support one filter chain
-	if len(lc.FilterChains) != 1 {
-		return nil, errors.New("error updating listener, listener have filter chains count is not 1")
+	if len(lc.FilterChains) == 0 {
+		return nil, errors.New("error updating listener, listener have no filter chains")
 	}
 	// set listener filter , network filter and stream filter
 	var listenerFiltersFactories []api.ListenerFilterChainFactory
-	var networkFiltersFactories []api.NetworkFilterChainFactory
+	var networkFiltersFactories [][]api.NetworkFilterChainFactory
 	listenerFiltersFactories = configmanager.AddOrUpdateListenerFilterFactories(listenerName, lc.ListenerFilters)
 	streamfilter.GetStreamFilterManager().AddOrUpdateStreamFilterConfig(listenerName, lc.StreamFilters)
-	networkFiltersFactories = configmanager.AddOrUpdateNetworkFilterFactories(listenerName, lc)
+	networkFiltersFactories = configmanager.AddOrUpdateFilterChainsNetworkFilterFactories(listenerName, lc)
 
 	var al *activeListener
 	if al = ch.findActiveListenerByName(listenerName); al != nil {
@@ -135,25 +133,20 @@ func (ch *connHandler) AddOrUpdateListener(lc *v2.Listener) (types.ListenerEvent
 
 		al.listenerFiltersFactories = listenerFiltersFactories
 		rawConfig.ListenerFilters = lc.ListenerFilters
-		al.networkFiltersFactories = networkFiltersFactories
-		rawConfig.FilterChains[0].FilterChainMatch = lc.FilterChains[0].FilterChainMatch
-		rawConfig.FilterChains[0].Filters = lc.FilterChains[0].Filters
 
 		rawConfig.StreamFilters = lc.StreamFilters
 
-		// tls update only take effects on new connections
+		// filter chains and tls update only take effects on new connections
 		// config changed
-		rawConfig.FilterChains[0].TLSContexts = lc.FilterChains[0].TLSContexts
-		rawConfig.FilterChains[0].TLSConfig = lc.FilterChains[0].TLSConfig
-		rawConfig.FilterChains[0].TLSConfigs = lc.FilterChains[0].TLSConfigs
+		rawConfig.FilterChains = lc.FilterChains
 		rawConfig.Inspector = lc.Inspector
-		mgr, err := mtls.NewTLSServerContextManager(rawConfig)
+		filterChains, err := newActiveFilterChains(rawConfig, networkFiltersFactories)
 		if err != nil {
-			log.DefaultLogger.Errorf("[server] [conn handler] [update listener] create tls context manager failed, %v", err)
+			log.DefaultLogger.Errorf("[server] [conn handler] [update listener] create filter chains failed, %v", err)
 			return nil, err
 		}
 		// object changed
-		al.tlsMng = mgr
+		al.filterChains = filterChains
 		// some simle config update
 		rawConfig.PerConnBufferLimitBytes = lc.PerConnBufferLimitBytes
 		al.listener.SetPerConnBufferLimitBytes(lc.PerConnBufferLimitBytes)
@@ -371,7 +364,7 @@ func (ch *connHandler) StopConnection() {
 type activeListener struct {
 	listener                 types.Listener
 	listenerFiltersFactories []api.ListenerFilterChainFactory
-	networkFiltersFactories  []api.NetworkFilterChainFactory
+	filterChains             []*activeFilterChain
 	listenIP                 string
 	listenPort               int
 	defaultReadBufferSize    int
@@ -382,12 +375,11 @@ type activeListener struct {
 	accessLogs               []api.AccessLog
 	updatedLabel             bool
 	idleTimeout              *api.DurationConfig
-	tlsMng                   types.TLSContextManager
 }
 
 func newActiveListener(listener types.Listener, lc *v2.Listener, accessLoggers []api.AccessLog,
 	listenerFiltersFactories []api.ListenerFilterChainFactory,
-	networkFiltersFactories []api.NetworkFilterChainFactory,
+	networkFiltersFactories [][]api.NetworkFilterChainFactory,
 	handler *connHandler, stopChan chan struct{}) (*activeListener, error) {
 	al := &activeListener{
 		listener:                 listener,
@@ -398,7 +390,6 @@ func newActiveListener(listener types.Listener, lc *v2.Listener, accessLoggers [
 		accessLogs:               accessLoggers,
 		updatedLabel:             false,
 		idleTimeout:              lc.ConnectionIdleTimeout,
-		networkFiltersFactories:  networkFiltersFactories,
 		listenerFiltersFactories: listenerFiltersFactories,
 	}
 
@@ -415,12 +406,12 @@ func newActiveListener(listener types.Listener, lc *v2.Listener, accessLoggers [
 	al.listenPort = listenPort
 	al.stats = newListenerStats(al.listener.Name())
 
-	mgr, err := mtls.NewTLSServerContextManager(lc)
+	filterChains, err := newActiveFilterChains(lc, networkFiltersFactories)
 	if err != nil {
-		log.DefaultLogger.Errorf("[server] [new listener] create tls context manager failed, %v", err)
+		log.DefaultLogger.Errorf("[server] [new listener] create filter chains failed, %v", err)
 		return nil, err
 	}
-	al.tlsMng = mgr
+	al.filterChains = filterChains
 
 	return al, nil
 }
@@ -459,21 +450,16 @@ func (al *activeListener) OnAccept(rawc net.Conn, useOriginalDst bool, oriRemote
 				}
 			}
 		}
-		// if ch is not nil, the conn has been initialized in func transferNewConn
-		if al.tlsMng != nil && ch == nil {
-			conn, err := al.tlsMng.Conn(rawc)
-			if err != nil {
-				if log.DefaultLogger.GetLogLevel() >= log.INFO {
-					log.DefaultLogger.Infof("[server] [listener] accept connection failed, error: %v", err)
-				}
-				rawc.Close()
-				return
-			}
-			rawc = conn
-		}
 	}
 
 	arc := newActiveRawConn(rawc, al)
+	// if ch is not nil, the conn has been initialized in func transferNewConn.
+	// NOTICE: the tls conn is created in ContinueFilterChain after all the listener filters, not at accept,
+	// so the listener filters of a tls listener see the raw conn instead of the tls conn.
+	// the tls context is decided by the filter chain selected with the information from the listener filters,
+	// such as the server name from the tls inspector, and the filters may consume the data before the tls handshake,
+	// such as the proxy protocol header
+	arc.tlsEnabled = !useOriginalDst && ch == nil
 
 	// listener filter chain.
 	for _, lfcf := range al.listenerFiltersFactories {
@@ -492,7 +478,6 @@ func (al *activeListener) OnAccept(rawc net.Conn, useOriginalDst bool, oriRemote
 	_ = variable.Set(ctx, types.VariableListenerType, al.listener.Config().Type)
 	_ = variable.Set(ctx, types.VariableListenerName, al.listener.Name())
 	_ = variable.Set(ctx, types.VariableConnDefaultReadBufferSize, al.defaultReadBufferSize)
-	_ = variable.Set(ctx, types.VariableNetworkFilterChainFactories, al.networkFiltersFactories)
 	_ = variable.Set(ctx, types.VariableAccessLogs, al.accessLogs)
 	if rawf != nil {
 		_ = variable.Set(ctx, types.VariableConnectionFd, rawf)
@@ -520,8 +505,10 @@ func (al *activeListener) OnAccept(rawc net.Conn, useOriginalDst bool, oriRemote
 func (al *activeListener) OnNewConnection(ctx context.Context, conn api.Connection) {
 	//Register Proxy's Filter
 	filterManager := conn.FilterManager()
-	for _, nfcf := range al.networkFiltersFactories {
-		nfcf.CreateFilterChain(ctx, filterManager)
+	if factories, err := variable.Get(ctx, types.VariableNetworkFilterChainFactories); err == nil && factories != nil {
+		for _, nfcf := range factories.([]api.NetworkFilterChainFactory) {
+			nfcf.CreateFilterChain(ctx, filterManager)
+		}
 	}
 
 	ac := newActiveConnection(al, conn)
@@ -656,6 +643,10 @@ func (al *activeListener) newConnection(ctx context.Context, rawc net.Conn) {
 	if err == nil && oriRemoteAddr != nil {
 		conn.SetRemoteAddr(oriRemoteAddr.(net.Addr))
 	}
+	oriLocalAddr, err := variable.Get(ctx, types.VariableOriLocalAddr)
+	if err == nil && oriLocalAddr != nil {
+		conn.SetLocalAddress(oriLocalAddr.(net.Addr), true)
+	}
 	listeners, err := variable.Get(ctx, types.VariableConnectionEventListeners)
 	if err == nil && listeners != nil {
 		for _, listener := range listeners.([]api.ConnectionEventListener) {
@@ -678,6 +669,7 @@ type activeRawConn struct {
 	originalDstPort     int
 	oriRemoteAddr       net.Addr
 	useOriginalDst      bool
+	tlsEnabled          bool
 	rawcElement         *list.Element
 	activeListener      *activeListener
 	acceptedFilters     []api.ListenerFilterChainFactory
@@ -774,6 +766,32 @@ func (arc *activeRawConn) ContinueFilterChain(ctx context.Context, success bool)
 		}
 	}
 
+	fc := selectFilterChain(arc.activeListener.filterChains, newFilterChainMatchInfo(ctx, arc.rawc))
+	if fc == nil {
+		if log.DefaultLogger.GetLogLevel() >= log.INFO {
+			log.DefaultLogger.Infof("[server] [listener] no filter chain matched, close connection from %s", arc.rawc.RemoteAddr())
+		}
+		// the udp conn is shared by the listener
+		if arc.rawc.LocalAddr().Network() != "udp" {
+			arc.rawc.Close()
+		}
+		return
+	}
+	_ = variable.Set(ctx, types.VariableNetworkFilterChainFactories, fc.networkFiltersFactories)
+
+	// the tls conn is created after all the listener filters, see OnAccept
+	if arc.tlsEnabled && fc.tlsMng != nil {
+		conn, err := fc.tlsMng.Conn(arc.rawc)
+		if err != nil {
+			if log.DefaultLogger.GetLogLevel() >= log.INFO {
+				log.DefaultLogger.Infof("[server] [listener] accept connection failed, error: %v", err)
+			}
+			arc.rawc.Close()
+			return
+		}
+		arc.rawc = conn
+	}
+
 	arc.activeListener.newConnection(ctx, arc.rawc)
 
 }
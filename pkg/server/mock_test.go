@@ -19,6 +19,7 @@ package server
 
 import (
 	"context"
+	"net"
 
 	"mosn.io/api"
 	"mosn.io/mosn/pkg/types"
@@ -68,7 +69,27 @@ func CreateMockStreamFilterFactory(conf map[string]interface{}) (api.StreamFilte
 	return &mockStreamFilterFactory{}, nil
 }
 
+// mockListenerFilterFactory sends the connection seen by the listener filter to conns
+type mockListenerFilterFactory struct {
+	conns chan net.Conn
+}
+
+func (lf *mockListenerFilterFactory) OnAccept(cb api.ListenerFilterChainFactoryCallbacks) api.FilterStatus {
+	select {
+	case lf.conns <- cb.Conn():
+	default:
+	}
+	return api.Continue
+}
+
+var mockListenerConns = make(chan net.Conn, 1)
+
+func CreateMockListenerFilterFactory(conf map[string]interface{}) (api.ListenerFilterChainFactory, error) {
+	return &mockListenerFilterFactory{conns: mockListenerConns}, nil
+}
+
 func init() {
+	api.RegisterListener("mock_listener", CreateMockListenerFilterFactory)
 	api.RegisterNetwork("mock_network", CreateMockFilerFactory)
 	api.RegisterNetwork("mock_network2", CreateMockFilerFactory)
 	api.RegisterStream("mock_stream", CreateMockStreamFilterFactory)
@@ -19,6 +19,7 @@ package stream
 
 import (
 	"context"
+	"time"
 
 	metrics "github.com/rcrowley/go-metrics"
 	"mosn.io/api"
@@ -182,3 +183,16 @@ func (w *clientStreamReceiverWrapper) OnReceive(ctx context.Context, headers typ
 func (w *clientStreamReceiverWrapper) OnDecodeError(ctx context.Context, err error, headers types.HeaderMap) {
 	w.streamReceiver.OnDecodeError(ctx, err, headers)
 }
+
+// ShouldRetireConnection returns true if the upstream connection should not be assigned new streams,
+// which has served the max requests per connection, or has lived longer than the max connection duration.
+// The pools retire the connection gracefully, it is closed after the in-flight streams are finished.
+func ShouldRetireConnection(info types.ClusterInfo, createTime time.Time, totalStream uint64) bool {
+	if maxRequests := info.MaxRequestsPerConn(); maxRequests > 0 && totalStream >= uint64(maxRequests) {
+		return true
+	}
+	if maxDuration := info.MaxConnectionDuration(); maxDuration > 0 && time.Since(createTime) >= maxDuration {
+		return true
+	}
+	return false
+}
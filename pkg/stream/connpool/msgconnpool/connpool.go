@@ -68,7 +68,14 @@ func NewConn(hostAddr string, connectTryTimes int,
 	getReadFilterAndKeepalive func() ([]api.ReadFilter, KeepAlive), autoReconnectWhenClose bool) Connection {
 	// use connData addr as cluster name, for the count of metrics
 	cl := basicCluster(hostAddr, []string{hostAddr})
-	host := cluster.NewSimpleHost(cl.Hosts[0], cluster.NewCluster(cl).Snapshot().ClusterInfo())
+	var info types.ClusterInfo
+	if c := cluster.NewCluster(cl); c != nil {
+		info = c.Snapshot().ClusterInfo()
+	} else {
+		// the cluster config is invalid, use the cluster info for the metrics only
+		info = cluster.NewClusterInfo(cl)
+	}
+	host := cluster.NewSimpleHost(cl.Hosts[0], info)
 
 	// if user configure this to -1, then retry is unlimited
 	if connectTryTimes == -1 {
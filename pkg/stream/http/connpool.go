@@ -110,12 +110,42 @@ func (p *connPool) NewStream(ctx context.Context, receiver types.StreamReceiveLi
 	host.ClusterInfo().Stats().UpstreamRequestActive.Inc(1)
 	host.ClusterInfo().ResourceManager().Requests().Increase()
 
+	atomic.AddUint64(&c.totalStream, 1)
 	streamEncoder := c.client.NewStream(ctx, receiver)
 	streamEncoder.GetStream().AddEventListener(c)
 	return host, streamEncoder, ""
 }
 
+// closeRetiredClients closes the available clients that are expired, they have no active streams
+func (p *connPool) closeRetiredClients() {
+	info := p.Host().ClusterInfo()
+	if info.MaxConnectionDuration() == 0 {
+		return
+	}
+	var retired []*activeClient
+	p.clientMux.Lock()
+	available := p.availableClients[:0]
+	for _, c := range p.availableClients {
+		if str.ShouldRetireConnection(info, c.createTime, atomic.LoadUint64(&c.totalStream)) {
+			retired = append(retired, c)
+		} else {
+			available = append(available, c)
+		}
+	}
+	for i := len(available); i < len(p.availableClients); i++ {
+		p.availableClients[i] = nil
+	}
+	p.availableClients = available
+	p.clientMux.Unlock()
+
+	// the connection close event locks the clientMux
+	for _, c := range retired {
+		c.client.Close()
+	}
+}
+
 func (p *connPool) getAvailableClient(ctx context.Context) (*activeClient, types.PoolFailureReason) {
+	p.closeRetiredClients()
 
 	p.clientMux.Lock()
 	host := p.Host()
@@ -128,7 +158,7 @@ func (p *connPool) getAvailableClient(ctx context.Context) (*activeClient, types
 		if maxConns == 0 || atomic.LoadUint64(&p.totalClientCount) <= maxConns {
 			// Unlock immediately, allowing concurrent connections
 			p.clientMux.Unlock()
-			ac, reason := newActiveClient(ctx, p)
+			ac, reason := newActiveClient(ctx, p, false)
 			if ac == nil || reason != "" {
 				// To subtract a signed positive constant value c from x, do AddUint64(&x, ^uint64(c-1)).
 				atomic.AddUint64(&p.totalClientCount, ^uint64(0))
@@ -161,6 +191,37 @@ func (p *connPool) getAvailableClient(ctx context.Context) (*activeClient, types
 	}
 }
 
+// Prewarm creates the connections until the total connections reach minConns,
+// the new connections are available for the requests.
+func (p *connPool) Prewarm(ctx context.Context, minConns int) int {
+	host := p.Host()
+	maxConns := host.ClusterInfo().ResourceManager().Connections().Max()
+	created := 0
+	for {
+		p.clientMux.Lock()
+		total := atomic.LoadUint64(&p.totalClientCount)
+		if total >= uint64(minConns) || (maxConns != 0 && total >= maxConns) {
+			p.clientMux.Unlock()
+			return created
+		}
+		atomic.AddUint64(&p.totalClientCount, 1)
+		p.clientMux.Unlock()
+
+		ac, reason := newActiveClient(ctx, p, true)
+		if ac == nil || reason != "" {
+			// To subtract a signed positive constant value c from x, do AddUint64(&x, ^uint64(c-1)).
+			atomic.AddUint64(&p.totalClientCount, ^uint64(0))
+			return created
+		}
+		created++
+		p.clientMux.Lock()
+		if !ac.closed {
+			p.availableClients = append(p.availableClients, ac)
+		}
+		p.clientMux.Unlock()
+	}
+}
+
 func (p *connPool) Close() {
 	p.clientMux.Lock()
 	defer p.clientMux.Unlock()
@@ -290,14 +351,16 @@ type activeClient struct {
 	client             str.Client
 	host               types.CreateConnectionData
 	totalStream        uint64
+	createTime         time.Time
 	closeWithActiveReq bool
 	closed             bool
 	closeConn          bool
 }
 
-func newActiveClient(ctx context.Context, pool *connPool) (*activeClient, types.PoolFailureReason) {
+func newActiveClient(ctx context.Context, pool *connPool, prewarm bool) (*activeClient, types.PoolFailureReason) {
 	ac := &activeClient{
-		pool: pool,
+		pool:       pool,
+		createTime: time.Now(),
 	}
 
 	host := pool.Host()
@@ -317,6 +380,13 @@ func newActiveClient(ctx context.Context, pool *connPool) (*activeClient, types.
 	host.HostStats().UpstreamConnectionActive.Inc(1)
 	host.ClusterInfo().Stats().UpstreamConnectionTotal.Inc(1)
 	host.ClusterInfo().Stats().UpstreamConnectionActive.Inc(1)
+	if prewarm {
+		host.HostStats().UpstreamConnectionPrewarm.Inc(1)
+		host.ClusterInfo().Stats().UpstreamConnectionPrewarm.Inc(1)
+	} else {
+		host.HostStats().UpstreamConnectionOnDemand.Inc(1)
+		host.ClusterInfo().Stats().UpstreamConnectionOnDemand.Inc(1)
+	}
 
 	// bytes total adds all connections' data together
 	codecClient.SetConnectionCollector(host.ClusterInfo().Stats().UpstreamBytesReadTotal, host.ClusterInfo().Stats().UpstreamBytesWriteTotal)
@@ -331,6 +401,10 @@ func (ac *activeClient) OnEvent(event api.ConnectionEvent) {
 
 // types.StreamEventListener
 func (ac *activeClient) OnDestroyStream() {
+	// the retired client is closed instead of returning to the pool
+	if !ac.closeConn && str.ShouldRetireConnection(ac.pool.Host().ClusterInfo(), ac.createTime, atomic.LoadUint64(&ac.totalStream)) {
+		ac.closeConn = true
+	}
 	if !ac.closed && ac.closeConn {
 		ac.client.Close()
 	}
@@ -19,15 +19,20 @@ package http
 
 import (
 	"context"
+	"net"
 	"sync"
 	"testing"
 	"time"
 
 	metrics "github.com/rcrowley/go-metrics"
+	"mosn.io/api"
 	v2 "mosn.io/mosn/pkg/config/v2"
 	"mosn.io/mosn/pkg/network"
 	"mosn.io/mosn/pkg/types"
 	"mosn.io/mosn/pkg/upstream/cluster"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 type fakeClusterInfo struct {
@@ -79,6 +84,18 @@ func (ci *fakeClusterInfo) ConnBufferLimitBytes() uint32 {
 	return 0
 }
 
+func (ci *fakeClusterInfo) MaxRequestsPerConn() uint32 {
+	return 0
+}
+
+func (ci *fakeClusterInfo) MaxConnectionDuration() time.Duration {
+	return 0
+}
+
+func (ci *fakeClusterInfo) ProxyProtocol() *v2.ProxyProtocolConfig {
+	return nil
+}
+
 func (ci *fakeClusterInfo) Stats() *types.ClusterStats {
 	return &types.ClusterStats{
 		UpstreamRequestPendingOverflow:                 metrics.NewCounter(),
@@ -144,3 +161,95 @@ func TestGetAvailableClient(t *testing.T) {
 		t.Fatal("limit max connections failed")
 	}
 }
+
+func TestConnPoolPrewarm(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.Nil(t, err)
+	defer ln.Close()
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+		}
+	}()
+
+	ci := cluster.NewClusterInfo(v2.Cluster{Name: "prewarm"})
+	host := cluster.NewSimpleHost(v2.Host{
+		HostConfig: v2.HostConfig{
+			Address: ln.Addr().String(),
+		},
+	}, ci)
+	pool := NewConnPool(context.TODO(), host).(*connPool)
+
+	assert.Equal(t, 2, pool.Prewarm(context.Background(), 2))
+	assert.Equal(t, 0, pool.Prewarm(context.Background(), 2))
+	assert.Len(t, pool.availableClients, 2)
+	assert.Equal(t, int64(2), host.HostStats().UpstreamConnectionPrewarm.Count())
+	assert.Equal(t, int64(2), ci.Stats().UpstreamConnectionPrewarm.Count())
+
+	// the prewarmed connections are used by the requests
+	client, reason := pool.getAvailableClient(context.Background())
+	require.NotNil(t, client)
+	require.Equal(t, types.PoolFailureReason(""), reason)
+	assert.Len(t, pool.availableClients, 1)
+	assert.Equal(t, int64(0), host.HostStats().UpstreamConnectionOnDemand.Count())
+	// the connections in use are counted
+	assert.Equal(t, 0, pool.Prewarm(context.Background(), 2))
+	assert.Equal(t, 1, pool.Prewarm(context.Background(), 3))
+}
+
+func TestConnPoolRetireClient(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.Nil(t, err)
+	defer ln.Close()
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+		}
+	}()
+
+	ci := cluster.NewClusterInfo(v2.Cluster{
+		Name:                  "retire",
+		MaxRequestPerConn:     2,
+		MaxConnectionDuration: &api.DurationConfig{Duration: 50 * time.Millisecond},
+	})
+	host := cluster.NewSimpleHost(v2.Host{
+		HostConfig: v2.HostConfig{
+			Address: ln.Addr().String(),
+		},
+	}, ci)
+	pool := NewConnPool(context.TODO(), host).(*connPool)
+
+	// the client that served the max requests is closed after the stream is finished
+	client, reason := pool.getAvailableClient(context.Background())
+	require.Equal(t, types.PoolFailureReason(""), reason)
+	client.totalStream = 1
+	client.OnDestroyStream()
+	assert.False(t, client.closeConn)
+	assert.Len(t, pool.availableClients, 1)
+	client, _ = pool.getAvailableClient(context.Background())
+	client.totalStream = 2
+	client.OnDestroyStream()
+	assert.True(t, client.closeConn)
+	assert.True(t, client.closed)
+	assert.Len(t, pool.availableClients, 0)
+
+	// the expired clients are not assigned new streams
+	assert.Equal(t, 2, pool.Prewarm(context.Background(), 2))
+	expired := append([]*activeClient{}, pool.availableClients...)
+	time.Sleep(60 * time.Millisecond)
+	client, reason = pool.getAvailableClient(context.Background())
+	require.Equal(t, types.PoolFailureReason(""), reason)
+	assert.NotContains(t, expired, client)
+	assert.Len(t, pool.availableClients, 0)
+	for _, c := range expired {
+		assert.True(t, c.closed)
+	}
+}
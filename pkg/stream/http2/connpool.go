@@ -21,6 +21,7 @@ import (
 	"context"
 	"sync"
 	"sync/atomic"
+	"time"
 
 	"mosn.io/api"
 	"mosn.io/mosn/pkg/log"
@@ -35,6 +36,8 @@ import (
 // host is the upstream
 type connPool struct {
 	activeClient *activeClient
+	// the prewarmed clients that replace the active client when it is deleted
+	spareClients []*activeClient
 	host         atomic.Value
 	tlsHash      *types.HashValue
 
@@ -82,8 +85,15 @@ func (p *connPool) NewStream(ctx context.Context, responseDecoder types.StreamRe
 		if p.activeClient != nil && atomic.LoadUint32(&p.activeClient.goaway) == 1 {
 			p.deleteActiveClient()
 		}
+		if p.activeClient != nil && p.activeClient.shouldRetire() {
+			p.activeClient.retire()
+			p.deleteActiveClient()
+		}
 		if p.activeClient == nil {
-			p.activeClient = newActiveClient(ctx, p)
+			p.activeClient = p.takeSpareClient()
+		}
+		if p.activeClient == nil {
+			p.activeClient = newActiveClient(ctx, p, false)
 		}
 		return p.activeClient
 	}()
@@ -102,6 +112,7 @@ func (p *connPool) NewStream(ctx context.Context, responseDecoder types.StreamRe
 	}
 
 	atomic.AddUint64(&activeClient.totalStream, 1)
+	atomic.AddInt64(&activeClient.activeStream, 1)
 	host.HostStats().UpstreamRequestTotal.Inc(1)
 	host.HostStats().UpstreamRequestActive.Inc(1)
 	host.ClusterInfo().Stats().UpstreamRequestTotal.Inc(1)
@@ -113,11 +124,50 @@ func (p *connPool) NewStream(ctx context.Context, responseDecoder types.StreamRe
 	return host, streamEncoder, ""
 }
 
+// Prewarm creates the connections until the pool has minConns connections.
+// All the streams are multiplexed on the active client, the others are kept as the spare clients,
+// which replace the active client without connecting when it goes away or retires.
+func (p *connPool) Prewarm(ctx context.Context, minConns int) int {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+	if p.activeClient != nil && atomic.LoadUint32(&p.activeClient.goaway) == 1 {
+		p.deleteActiveClient()
+	}
+	if p.activeClient == nil {
+		p.activeClient = p.takeSpareClient()
+	}
+	total := len(p.spareClients)
+	if p.activeClient != nil {
+		total++
+	}
+	created := 0
+	for ; total < minConns; total++ {
+		ac := newActiveClient(ctx, p, true)
+		if ac == nil {
+			break
+		}
+		created++
+		if p.activeClient == nil {
+			p.activeClient = ac
+		} else {
+			p.spareClients = append(p.spareClients, ac)
+		}
+	}
+	return created
+}
+
 func (p *connPool) Close() {
+	p.mux.Lock()
 	activeClient := p.activeClient
+	spareClients := p.spareClients
+	p.spareClients = nil
+	p.mux.Unlock()
 	if activeClient != nil {
 		activeClient.client.Close()
 	}
+	for _, ac := range spareClients {
+		ac.client.Close()
+	}
 }
 
 func (p *connPool) Shutdown() {
@@ -158,7 +208,11 @@ func (p *connPool) onConnectionEvent(client *activeClient, event api.ConnectionE
 			return
 		}
 		p.mux.Lock()
-		p.deleteActiveClient()
+		if client == p.activeClient {
+			p.deleteActiveClient()
+		} else {
+			p.deleteSpareClient(client)
+		}
 		p.mux.Unlock()
 	} else if event == api.ConnectTimeout {
 		host.HostStats().UpstreamRequestTimeout.Inc(1)
@@ -174,6 +228,11 @@ func (p *connPool) onStreamDestroy(client *activeClient) {
 	host.HostStats().UpstreamRequestActive.Dec(1)
 	host.ClusterInfo().Stats().UpstreamRequestActive.Dec(1)
 	host.ClusterInfo().ResourceManager().Requests().Decrease()
+
+	// close the retired client after the in-flight streams are finished
+	if atomic.AddInt64(&client.activeStream, -1) == 0 && atomic.LoadUint32(&client.retired) == 1 {
+		client.client.Close()
+	}
 }
 
 func (p *connPool) onStreamReset(client *activeClient, reason types.StreamResetReason) {
@@ -201,6 +260,34 @@ func (p *connPool) deleteActiveClient() {
 	p.activeClient = nil
 }
 
+// takeSpareClient removes and returns the first spare client that is not going away, the mux should be held
+func (p *connPool) takeSpareClient() *activeClient {
+	for len(p.spareClients) > 0 {
+		ac := p.spareClients[0]
+		p.spareClients = p.spareClients[1:]
+		if atomic.LoadUint32(&ac.goaway) == 0 {
+			return ac
+		}
+		// no stream is created on the spare client
+		p.Host().HostStats().UpstreamConnectionActive.Dec(1)
+		p.Host().ClusterInfo().Stats().UpstreamConnectionActive.Dec(1)
+		ac.client.Close()
+	}
+	return nil
+}
+
+// deleteSpareClient removes the closed spare client, the mux should be held
+func (p *connPool) deleteSpareClient(client *activeClient) {
+	for i, ac := range p.spareClients {
+		if ac == client {
+			p.spareClients = append(p.spareClients[:i], p.spareClients[i+1:]...)
+			p.Host().HostStats().UpstreamConnectionActive.Dec(1)
+			p.Host().ClusterInfo().Stats().UpstreamConnectionActive.Dec(1)
+			return
+		}
+	}
+}
+
 // types.StreamEventListener
 // types.ConnectionEventListener
 // types.StreamConnectionEventListener
@@ -210,12 +297,16 @@ type activeClient struct {
 	host               types.CreateConnectionData
 	closeWithActiveReq bool
 	totalStream        uint64
+	activeStream       int64
+	createTime         time.Time
 	goaway             uint32
+	retired            uint32
 }
 
-func newActiveClient(ctx context.Context, pool *connPool) *activeClient {
+func newActiveClient(ctx context.Context, pool *connPool, prewarm bool) *activeClient {
 	ac := &activeClient{
-		pool: pool,
+		pool:       pool,
+		createTime: time.Now(),
 	}
 
 	host := pool.Host()
@@ -239,6 +330,13 @@ func newActiveClient(ctx context.Context, pool *connPool) *activeClient {
 	host.HostStats().UpstreamConnectionActive.Inc(1)
 	host.ClusterInfo().Stats().UpstreamConnectionTotal.Inc(1)
 	host.ClusterInfo().Stats().UpstreamConnectionActive.Inc(1)
+	if prewarm {
+		host.HostStats().UpstreamConnectionPrewarm.Inc(1)
+		host.ClusterInfo().Stats().UpstreamConnectionPrewarm.Inc(1)
+	} else {
+		host.HostStats().UpstreamConnectionOnDemand.Inc(1)
+		host.ClusterInfo().Stats().UpstreamConnectionOnDemand.Inc(1)
+	}
 
 	// bytes total adds all connections data together, but buffered data not
 	codecClient.SetConnectionCollector(host.ClusterInfo().Stats().UpstreamBytesReadTotal, host.ClusterInfo().Stats().UpstreamBytesWriteTotal)
@@ -259,6 +357,20 @@ func (ac *activeClient) OnResetStream(reason types.StreamResetReason) {
 	ac.pool.onStreamReset(ac, reason)
 }
 
+func (ac *activeClient) shouldRetire() bool {
+	return str.ShouldRetireConnection(ac.pool.Host().ClusterInfo(), ac.createTime, atomic.LoadUint64(&ac.totalStream))
+}
+
+// retire stops the client from being assigned new streams, and closes it after the in-flight streams are finished.
+// the goaway flag is set, so the pool's active client is not deleted again when the client is closed.
+func (ac *activeClient) retire() {
+	atomic.StoreUint32(&ac.goaway, 1)
+	atomic.StoreUint32(&ac.retired, 1)
+	if atomic.LoadInt64(&ac.activeStream) == 0 {
+		ac.client.Close()
+	}
+}
+
 // types.StreamConnectionEventListener
 func (ac *activeClient) OnGoAway() {
 	atomic.StoreUint32(&ac.goaway, 1)
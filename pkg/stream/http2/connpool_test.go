@@ -0,0 +1,136 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package http2
+
+import (
+	"context"
+	"net"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"mosn.io/api"
+	v2 "mosn.io/mosn/pkg/config/v2"
+	"mosn.io/mosn/pkg/types"
+	"mosn.io/mosn/pkg/upstream/cluster"
+	"mosn.io/pkg/variable"
+)
+
+// startConnPoolTestServer accepts the connections and keeps them open until the listener is closed
+func startConnPoolTestServer(t *testing.T) net.Listener {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.Nil(t, err)
+	go func() {
+		var conns []net.Conn
+		defer func() {
+			for _, conn := range conns {
+				conn.Close()
+			}
+		}()
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			conns = append(conns, conn)
+		}
+	}()
+	return ln
+}
+
+func newConnPoolTestHost(addr string, config v2.Cluster) types.Host {
+	return cluster.NewSimpleHost(v2.Host{
+		HostConfig: v2.HostConfig{
+			Address: addr,
+		},
+	}, cluster.NewClusterInfo(config))
+}
+
+func TestConnPoolPrewarm(t *testing.T) {
+	ln := startConnPoolTestServer(t)
+	defer ln.Close()
+	host := newConnPoolTestHost(ln.Addr().String(), v2.Cluster{Name: "prewarm"})
+	pool := NewConnPool(context.TODO(), host).(*connPool)
+	defer pool.Close()
+
+	assert.Equal(t, 3, pool.Prewarm(context.Background(), 3))
+	assert.Equal(t, 0, pool.Prewarm(context.Background(), 3))
+	require.NotNil(t, pool.activeClient)
+	require.Len(t, pool.spareClients, 2)
+	assert.Equal(t, int64(3), host.HostStats().UpstreamConnectionPrewarm.Count())
+	assert.Equal(t, int64(3), host.HostStats().UpstreamConnectionActive.Count())
+
+	// the spare client replaces the active client that goes away
+	active := pool.activeClient
+	spare := pool.spareClients[0]
+	active.OnGoAway()
+	assert.Equal(t, 1, pool.Prewarm(context.Background(), 3))
+	assert.Equal(t, spare, pool.activeClient)
+	assert.Len(t, pool.spareClients, 2)
+
+	// the closed spare client is removed
+	spare = pool.spareClients[0]
+	spare.client.Close()
+	assert.Len(t, pool.spareClients, 1)
+	assert.NotContains(t, pool.spareClients, spare)
+}
+
+func TestConnPoolRetireClient(t *testing.T) {
+	ln := startConnPoolTestServer(t)
+	defer ln.Close()
+	host := newConnPoolTestHost(ln.Addr().String(), v2.Cluster{
+		Name:              "retire",
+		MaxRequestPerConn: 2,
+	})
+	pool := NewConnPool(context.TODO(), host).(*connPool)
+	defer pool.Close()
+
+	newStream := func() *activeClient {
+		_, sender, reason := pool.NewStream(variable.NewVariableContext(context.Background()), nil)
+		require.Equal(t, types.PoolFailureReason(""), reason)
+		require.NotNil(t, sender)
+		return pool.activeClient
+	}
+	old := newStream()
+	assert.Equal(t, old, newStream())
+	assert.Equal(t, int64(2), atomic.LoadInt64(&old.activeStream))
+
+	// the client that served the max requests is retired, the new streams are assigned to a new client
+	client := newStream()
+	assert.NotEqual(t, old, client)
+	assert.Equal(t, uint32(1), atomic.LoadUint32(&old.retired))
+	assert.Equal(t, uint32(1), atomic.LoadUint32(&old.goaway))
+	// the retired client is closed after the in-flight streams are finished
+	old.OnDestroyStream()
+	assert.Equal(t, api.ConnActive, old.host.Connection.State())
+	old.OnDestroyStream()
+	assert.Equal(t, api.ConnClosed, old.host.Connection.State())
+	// the close of the retired client does not delete the active client
+	assert.Equal(t, client, pool.activeClient)
+
+	// the client is not closed if it is not retired
+	client.OnDestroyStream()
+	assert.Equal(t, int64(0), atomic.LoadInt64(&client.activeStream))
+	assert.Equal(t, api.ConnActive, client.host.Connection.State())
+
+	// the retired client without streams is closed immediately
+	client.retire()
+	assert.Equal(t, api.ConnClosed, client.host.Connection.State())
+}
@@ -27,6 +27,7 @@ import (
 	"mosn.io/api"
 	"mosn.io/mosn/pkg/stream"
 	"mosn.io/mosn/pkg/types"
+	"mosn.io/pkg/utils"
 	"mosn.io/pkg/variable"
 )
 
@@ -40,6 +41,8 @@ type poolBinding struct {
 
 	clientMux   sync.Mutex
 	idleClients map[uint64]*activeClientBinding // downstream connection id --> upstream client
+	// the prewarmed clients that are not bound to any downstream connection yet
+	spareClients []*activeClientBinding
 }
 
 // NewPoolBinding generates a binding connection pool
@@ -83,6 +86,7 @@ func (p *poolBinding) NewStream(ctx context.Context, receiver types.StreamReceiv
 
 	c.addDownConnListenerOnce(ctx)
 
+	atomic.AddUint64(&c.totalStream, 1)
 	var streamSender = c.codecClient.NewStream(ctx, receiver)
 
 	streamSender.GetStream().AddEventListener(c) // OnResetStream, OnDestroyStream
@@ -110,17 +114,37 @@ func (p *poolBinding) GetActiveClient(ctx context.Context) (*activeClientBinding
 		return nil, types.Overflow
 	}
 
+	downstreamConnID := getDownstreamConnID(ctx)
+	p.clientMux.Lock()
+	c, ok := p.idleClients[downstreamConnID]
+	p.clientMux.Unlock()
+	if ok {
+		if !c.shouldRetire() {
+			// the client was already initialized
+			return c, ""
+		}
+		// retire the client like go away, it is closed after the in-flight streams are finished,
+		// and the downstream connection is bound to a new client
+		c.OnGoAway()
+	}
+
 	p.clientMux.Lock()
 	defer p.clientMux.Unlock()
 
-	downstreamConnID := getDownstreamConnID(ctx)
 	if c, ok := p.idleClients[downstreamConnID]; ok {
-		// the client was already initialized
+		return c, ""
+	}
+
+	if c := p.takeSpareClientLocked(); c != nil {
+		c.downstreamConnID = downstreamConnID
+		p.idleClients[downstreamConnID] = c
+		_ = variable.Set(ctx, types.VariableUpstreamConnectionID, c.host.Connection.ID())
+		_ = variable.Set(ctx, types.VariableUpstreamProtocol, c.protocol)
 		return c, ""
 	}
 
 	// no available client
-	c, reason := p.newActiveClient(ctx)
+	c, reason := p.newActiveClient(ctx, false)
 	if c != nil && reason == "" {
 		p.idleClients[downstreamConnID] = c
 	}
@@ -128,20 +152,61 @@ func (p *poolBinding) GetActiveClient(ctx context.Context) (*activeClientBinding
 	return c, reason
 }
 
+// Prewarm creates the spare clients until there are minConns ones,
+// the bound clients are not counted, as they can not be used by the new downstream connections.
+func (p *poolBinding) Prewarm(ctx context.Context, minConns int) int {
+	created := 0
+	for {
+		p.clientMux.Lock()
+		n := len(p.spareClients)
+		p.clientMux.Unlock()
+		if n >= minConns {
+			return created
+		}
+		c, reason := p.newActiveClient(ctx, true)
+		if c == nil || reason != "" {
+			return created
+		}
+		created++
+		p.clientMux.Lock()
+		p.spareClients = append(p.spareClients, c)
+		p.clientMux.Unlock()
+	}
+}
+
+// takeSpareClientLocked removes and returns the first spare client that can be bound,
+// the retired ones are closed
+func (p *poolBinding) takeSpareClientLocked() *activeClientBinding {
+	for len(p.spareClients) > 0 {
+		c := p.spareClients[0]
+		p.spareClients[0] = nil
+		p.spareClients = p.spareClients[1:]
+		if atomic.LoadUint32(&c.goaway) != GoAway && !c.shouldRetire() {
+			return c
+		}
+		// the connection close event locks the clientMux
+		utils.GoWithRecover(func() {
+			c.host.Connection.Close(api.NoFlush, api.LocalClose)
+		}, nil)
+	}
+	return nil
+}
+
 func (p *poolBinding) Close() {
-	p.clientMux.Lock()
-	defer p.clientMux.Unlock()
+	p.closeSpareClients()
 
-	for _, c := range p.idleClients {
+	// the connection close event locks the clientMux
+	for _, c := range p.boundClients() {
 		c.host.Connection.Close(api.NoFlush, api.LocalClose)
 	}
 }
 
 func (p *poolBinding) Shutdown() {
-	p.clientMux.Lock()
-	defer p.clientMux.Unlock()
+	// no stream is on the spare clients, they are closed directly
+	p.closeSpareClients()
 
-	for _, c := range p.idleClients {
+	// the go away removes the client from the pool, which locks the clientMux
+	for _, c := range p.boundClients() {
 		c.OnGoAway()
 		if c.keepAlive != nil {
 			c.keepAlive.keepAlive.Stop()
@@ -149,13 +214,39 @@ func (p *poolBinding) Shutdown() {
 	}
 }
 
-func (p *poolBinding) newActiveClient(ctx context.Context) (*activeClientBinding, types.PoolFailureReason) {
+// boundClients returns the clients that are bound to the downstream connections
+func (p *poolBinding) boundClients() []*activeClientBinding {
+	p.clientMux.Lock()
+	defer p.clientMux.Unlock()
+
+	clients := make([]*activeClientBinding, 0, len(p.idleClients))
+	for _, c := range p.idleClients {
+		clients = append(clients, c)
+	}
+	return clients
+}
+
+// closeSpareClients removes and closes the spare clients
+func (p *poolBinding) closeSpareClients() {
+	p.clientMux.Lock()
+	spareClients := p.spareClients
+	p.spareClients = nil
+	p.clientMux.Unlock()
+
+	// the connection close event locks the clientMux
+	for _, c := range spareClients {
+		c.host.Connection.Close(api.NoFlush, api.LocalClose)
+	}
+}
+
+func (p *poolBinding) newActiveClient(ctx context.Context, prewarm bool) (*activeClientBinding, types.PoolFailureReason) {
 	connID := getDownstreamConnID(ctx)
 	ac := &activeClientBinding{
 		protocol:         p.connpool.codec.ProtocolName(),
 		downstreamConnID: connID,
 		pool:             p,
 		host:             p.Host().CreateConnection(ctx),
+		createTime:       time.Now(),
 	}
 
 	host := p.Host()
@@ -198,6 +289,13 @@ func (p *poolBinding) newActiveClient(ctx context.Context) (*activeClientBinding
 	host.HostStats().UpstreamConnectionActive.Inc(1)
 	host.ClusterInfo().Stats().UpstreamConnectionTotal.Inc(1)
 	host.ClusterInfo().Stats().UpstreamConnectionActive.Inc(1)
+	if prewarm {
+		host.HostStats().UpstreamConnectionPrewarm.Inc(1)
+		host.ClusterInfo().Stats().UpstreamConnectionPrewarm.Inc(1)
+	} else {
+		host.HostStats().UpstreamConnectionOnDemand.Inc(1)
+		host.ClusterInfo().Stats().UpstreamConnectionOnDemand.Inc(1)
+	}
 
 	return ac, ""
 }
@@ -210,6 +308,8 @@ type activeClientBinding struct {
 	closeWithActiveReq bool
 	downstreamConnID   uint64
 	goaway             uint32
+	totalStream        uint64
+	createTime         time.Time
 	protocol           types.ProtocolName
 	keepAlive          *keepAliveListener
 	pool               *poolBinding
@@ -247,7 +347,17 @@ func (ac *activeClientBinding) removeFromPool() {
 	p.clientMux.Lock()
 	defer p.clientMux.Unlock()
 
-	delete(p.idleClients, ac.downstreamConnID)
+	// the downstream connection may be bound to a new client
+	if c, ok := p.idleClients[ac.downstreamConnID]; ok && c == ac {
+		delete(p.idleClients, ac.downstreamConnID)
+		return
+	}
+	for i, c := range p.spareClients {
+		if c == ac {
+			p.spareClients = append(p.spareClients[:i], p.spareClients[i+1:]...)
+			return
+		}
+	}
 }
 
 // types.ConnectionEventListener
@@ -357,6 +467,10 @@ func (ac *activeClientBinding) OnGoAway() {
 	}
 }
 
+func (ac *activeClientBinding) shouldRetire() bool {
+	return stream.ShouldRetireConnection(ac.pool.Host().ClusterInfo(), ac.createTime, atomic.LoadUint64(&ac.totalStream))
+}
+
 // SetHeartBeater set the heart beat for an active client
 func (ac *activeClientBinding) SetHeartBeater(hb types.KeepAlive) {
 	// clear the previous keepAlive
@@ -22,6 +22,7 @@ import (
 	"errors"
 	"fmt"
 	"net"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -245,3 +246,124 @@ func TestUpperGoAway(t *testing.T) {
 	// close the connpool should not panic
 	pInst.Close()
 }
+
+func TestBindingPrewarm(t *testing.T) {
+
+	ctx := variable.NewVariableContext(context.Background())
+
+	var addr = "127.0.0.1:10086"
+	go server.start(t, addr)
+	defer server.stop(t)
+	// wait for server to start
+	time.Sleep(time.Second * 2)
+
+	cl := basicCluster(addr, []string{addr})
+	host := cluster.NewSimpleHost(cl.Hosts[0], cluster.NewCluster(cl).Snapshot().ClusterInfo())
+
+	p := &connpool{
+		protocol: api.ProtocolName(dubbo.ProtocolName),
+		tlsHash:  &types.HashValue{},
+		codec:    &dubbo.XCodec{},
+	}
+	p.host.Store(host)
+
+	var pool = NewPoolBinding(p)
+	var pInst = pool.(*poolBinding)
+
+	assert.Equal(t, 2, pInst.Prewarm(context.Background(), 2))
+	assert.Equal(t, 0, pInst.Prewarm(context.Background(), 2))
+	assert.Len(t, pInst.spareClients, 2)
+	spare := pInst.spareClients[0]
+
+	// the stats are shared by the hosts with the same address
+	onDemand := host.HostStats().UpstreamConnectionOnDemand.Count()
+
+	sConn, err := net.Dial("tcp4", addr)
+	assert.Nil(t, err)
+
+	var sstopChan = make(chan struct{})
+	sConnI := network.NewServerConnection(context.Background(), sConn, sstopChan)
+
+	_ = variable.Set(ctx, types.VarConnection, sConnI)
+	_ = variable.Set(ctx, types.VarConnectionID, sConnI.ID())
+
+	_, _, failReason := pInst.NewStream(ctx, nil)
+	assert.Equal(t, failReason, types.PoolFailureReason(""))
+
+	// the spare client is bound to the downstream connection
+	assert.Equal(t, spare, pInst.idleClients[sConnI.ID()])
+	assert.Equal(t, sConnI.ID(), spare.downstreamConnID)
+	assert.Len(t, pInst.spareClients, 1)
+	assert.Equal(t, onDemand, host.HostStats().UpstreamConnectionOnDemand.Count())
+
+	// the bound clients are not counted
+	assert.Equal(t, 1, pInst.Prewarm(context.Background(), 2))
+
+	// the closed spare client is removed
+	pInst.spareClients[0].Close(errors.New("closeclose"))
+	assert.Len(t, pInst.spareClients, 1)
+
+	// the downstream conn close should not close the spare clients
+	sConnI.Close(api.NoFlush, api.LocalClose)
+	assert.Nil(t, pInst.idleClients[sConnI.ID()])
+	assert.Len(t, pInst.spareClients, 1)
+
+	pInst.Close()
+	assert.Len(t, pInst.spareClients, 0)
+}
+
+func TestBindingRetireClient(t *testing.T) {
+
+	var addr = "127.0.0.1:10086"
+	go server.start(t, addr)
+	defer server.stop(t)
+	// wait for server to start
+	time.Sleep(time.Second * 2)
+
+	cl := basicCluster(addr, []string{addr})
+	cl.MaxRequestPerConn = 1
+	host := cluster.NewSimpleHost(cl.Hosts[0], cluster.NewCluster(cl).Snapshot().ClusterInfo())
+
+	p := &connpool{
+		protocol: api.ProtocolName(dubbo.ProtocolName),
+		tlsHash:  &types.HashValue{},
+		codec:    &dubbo.XCodec{},
+	}
+	p.host.Store(host)
+
+	var pool = NewPoolBinding(p)
+	var pInst = pool.(*poolBinding)
+
+	sConn, err := net.Dial("tcp4", addr)
+	assert.Nil(t, err)
+
+	var sstopChan = make(chan struct{})
+	sConnI := network.NewServerConnection(context.Background(), sConn, sstopChan)
+
+	newContext := func() context.Context {
+		ctx := variable.NewVariableContext(context.Background())
+		_ = variable.Set(ctx, types.VarConnection, sConnI)
+		_ = variable.Set(ctx, types.VarConnectionID, sConnI.ID())
+		return ctx
+	}
+
+	c1, failReason := pInst.GetActiveClient(newContext())
+	assert.Equal(t, failReason, types.PoolFailureReason(""))
+	// the client is reused until it serves the max requests
+	c, _ := pInst.GetActiveClient(newContext())
+	assert.Equal(t, c1, c)
+
+	atomic.AddUint64(&c1.totalStream, 1)
+	c2, failReason := pInst.GetActiveClient(newContext())
+	assert.Equal(t, failReason, types.PoolFailureReason(""))
+
+	// the retired client goes away, and the downstream connection is bound to a new client
+	assert.NotEqual(t, c1, c2)
+	assert.Equal(t, uint32(GoAway), atomic.LoadUint32(&c1.goaway))
+	assert.Equal(t, api.ConnClosed, c1.host.Connection.State())
+	assert.Equal(t, c2, pInst.idleClients[sConnI.ID()])
+	assert.Equal(t, sConnI.State(), api.ConnActive)
+
+	pInst.Close()
+	sConnI.Close(api.NoFlush, api.LocalClose)
+}
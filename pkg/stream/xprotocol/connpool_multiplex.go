@@ -80,29 +80,62 @@ func NewPoolMultiplex(p *connpool) types.ConnectionPool {
 	}
 }
 
-func (p *poolMultiplex) init(sub types.ProtocolName, index int) {
+func (p *poolMultiplex) init(downstreamCtx context.Context, sub types.ProtocolName, index int) {
+	ctx := context.Background() // TODO: a new context ?
+	// the PROXY protocol header is built with the downstream connection
+	if p.Host().ClusterInfo().ProxyProtocol() != nil {
+		if conn := getDownstreamConn(downstreamCtx); conn != nil {
+			ctx = variable.NewVariableContext(ctx)
+			_ = variable.Set(ctx, types.VariableConnection, conn)
+		}
+	}
 	utils.GoWithRecover(func() {
 		if log.DefaultLogger.GetLogLevel() >= log.DEBUG {
 			log.DefaultLogger.Debugf("[stream] [sofarpc] [connpool] init host %s", p.Host().AddressString())
 		}
+		p.connect(ctx, sub, index, false)
+	}, nil)
+}
 
-		p.clientMux.Lock()
-		defer p.clientMux.Unlock()
+// connect creates the client at the index, the client state should be Connecting.
+// returns false if the client is not created.
+func (p *poolMultiplex) connect(ctx context.Context, sub types.ProtocolName, index int, prewarm bool) bool {
+	p.clientMux.Lock()
+	defer p.clientMux.Unlock()
+
+	// if the pool is already shut down, do nothing directly return
+	if p.shutdown {
+		return false
+	}
+	client, _ := p.newActiveClient(ctx, sub, prewarm)
+	if client != nil {
+		client.state = Connected
+		client.indexInPool = index
+		p.activeClients[index].Store(sub, client)
+		return true
+	}
+	p.activeClients[index].Delete(sub)
+	return false
+}
 
-		// if the pool is already shut down, do nothing directly return
-		if p.shutdown {
-			return
+// Prewarm connects the clients at the first minConns indexes synchronously,
+// the connections are not more than the max connections of the pool.
+func (p *poolMultiplex) Prewarm(ctx context.Context, minConns int) int {
+	subProtocol := p.connpool.codec.ProtocolName()
+	created := 0
+	for i := 0; i < minConns && i < len(p.activeClients); i++ {
+		v, _ := p.activeClients[i].LoadOrStore(subProtocol, &activeClientMultiplex{state: Init})
+		client := v.(*activeClientMultiplex)
+		// the client is connected or connecting by the requests
+		if !atomic.CompareAndSwapUint32(&client.state, Init, Connecting) &&
+			!atomic.CompareAndSwapUint32(&client.state, GoAway, Connecting) {
+			continue
 		}
-		ctx := context.Background() // TODO: a new context ?
-		client, _ := p.newActiveClient(ctx, sub)
-		if client != nil {
-			client.state = Connected
-			client.indexInPool = index
-			p.activeClients[index].Store(sub, client)
-		} else {
-			p.activeClients[index].Delete(sub)
+		if p.connect(ctx, subProtocol, i, true) {
+			created++
 		}
-	}, nil)
+	}
+	return created
 }
 
 // CheckAndInit init the connection pool
@@ -130,13 +163,17 @@ func (p *poolMultiplex) CheckAndInit(ctx context.Context) bool {
 	}
 
 	if atomic.LoadUint32(&client.state) == Connected {
-		return true
+		if !p.shouldRetire(client) {
+			return true
+		}
+		// the new client is initialized after the old one is retired
+		client.retire()
 	}
 
 	// init connection when client is Init or GoAway.
 	if atomic.CompareAndSwapUint32(&client.state, Init, Connecting) ||
 		atomic.CompareAndSwapUint32(&client.state, GoAway, Connecting) {
-		p.init(subProtocol, int(clientIdx))
+		p.init(ctx, subProtocol, int(clientIdx))
 	}
 
 	return false
@@ -229,10 +266,11 @@ func (p *poolMultiplex) createStreamClient(context context.Context, connData typ
 	return stream.NewStreamClient(context, p.connpool.protocol, connData.Connection, connData.Host)
 }
 
-func (p *poolMultiplex) newActiveClient(ctx context.Context, subProtocol api.ProtocolName) (*activeClientMultiplex, types.PoolFailureReason) {
+func (p *poolMultiplex) newActiveClient(ctx context.Context, subProtocol api.ProtocolName, prewarm bool) (*activeClientMultiplex, types.PoolFailureReason) {
 	ac := &activeClientMultiplex{
 		subProtocol: subProtocol,
 		pool:        p,
+		createTime:  time.Now(),
 	}
 
 	host := p.Host()
@@ -271,6 +309,13 @@ func (p *poolMultiplex) newActiveClient(ctx context.Context, subProtocol api.Pro
 	host.HostStats().UpstreamConnectionActive.Inc(1)
 	host.ClusterInfo().Stats().UpstreamConnectionTotal.Inc(1)
 	host.ClusterInfo().Stats().UpstreamConnectionActive.Inc(1)
+	if prewarm {
+		host.HostStats().UpstreamConnectionPrewarm.Inc(1)
+		host.ClusterInfo().Stats().UpstreamConnectionPrewarm.Inc(1)
+	} else {
+		host.HostStats().UpstreamConnectionOnDemand.Inc(1)
+		host.ClusterInfo().Stats().UpstreamConnectionOnDemand.Inc(1)
+	}
 
 	// bytes total adds all connections data together
 	codecClient.SetConnectionCollector(host.ClusterInfo().Stats().UpstreamBytesReadTotal, host.ClusterInfo().Stats().UpstreamBytesWriteTotal)
@@ -328,7 +373,10 @@ func (p *poolMultiplex) onConnectionEvent(ac *activeClientMultiplex, event api.C
 		// since the goaway state client has already been overwritten.
 		if atomic.LoadUint32(&ac.state) != GoAway {
 			p.clientMux.Lock()
-			p.activeClients[ac.indexInPool].Delete(ac.subProtocol)
+			// the retired client may be replaced by a new one
+			if v, ok := p.activeClients[ac.indexInPool].Load(ac.subProtocol); ok && v == ac {
+				p.activeClients[ac.indexInPool].Delete(ac.subProtocol)
+			}
 			p.clientMux.Unlock()
 		}
 	} else if event == api.ConnectTimeout {
@@ -345,12 +393,18 @@ func (p *poolMultiplex) onConnectionEvent(ac *activeClientMultiplex, event api.C
 // types.ConnectionEventListener
 // types.StreamConnectionEventListener
 // nolint: maligned
+func (p *poolMultiplex) shouldRetire(ac *activeClientMultiplex) bool {
+	return stream.ShouldRetireConnection(p.Host().ClusterInfo(), ac.createTime, atomic.LoadUint64(&ac.totalStream))
+}
+
 type activeClientMultiplex struct {
 	closeWithActiveReq bool
 	totalStream        uint64
 	subProtocol        types.ProtocolName
 	keepAlive          *keepAliveListener
 	state              uint32 // for async connection
+	retired            uint32
+	createTime         time.Time
 	pool               *poolMultiplex
 	indexInPool        int
 	codecClient        stream.Client
@@ -370,7 +424,19 @@ func (ac *activeClientMultiplex) OnDestroyStream() {
 	host.ClusterInfo().Stats().UpstreamRequestActive.Dec(1)
 	host.ClusterInfo().ResourceManager().Requests().Decrease()
 
-	if atomic.LoadUint32(&ac.state) == GoAway && ac.codecClient.ActiveRequestsNum() == 0 {
+	if (atomic.LoadUint32(&ac.state) == GoAway || atomic.LoadUint32(&ac.retired) == 1) && ac.codecClient.ActiveRequestsNum() == 0 {
+		ac.codecClient.Close()
+	}
+}
+
+// retire stops the client from being assigned new streams, and closes it after the in-flight streams are finished.
+// the client state is changed to GoAway, so a new client will be initialized at the same index.
+func (ac *activeClientMultiplex) retire() {
+	if !atomic.CompareAndSwapUint32(&ac.state, Connected, GoAway) {
+		return
+	}
+	atomic.StoreUint32(&ac.retired, 1)
+	if ac.codecClient.ActiveRequestsNum() == 0 {
 		ac.codecClient.Close()
 	}
 }
@@ -20,6 +20,7 @@ package xprotocol
 import (
 	"context"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -154,6 +155,65 @@ func TestMultiplexParallelShutdown(t *testing.T) {
 	wg.Wait() // should not stuck here
 }
 
+func TestMultiplexRetireClient(t *testing.T) {
+	var addr = "127.0.0.1:10086"
+	go server.start(t, addr)
+	defer server.stop(t)
+	// wait for server to start
+	time.Sleep(time.Second * 2)
+
+	ctx := context.Background()
+
+	cl := basicCluster(addr, []string{addr})
+	cl.CirBreThresholds.Thresholds[0].MaxConnections = 1
+	cl.MaxRequestPerConn = 2
+
+	host := cluster.NewSimpleHost(cl.Hosts[0], cluster.NewCluster(cl).Snapshot().ClusterInfo())
+	p := connpool{
+		protocol: api.ProtocolName(dubbo.ProtocolName),
+		tlsHash:  &types.HashValue{},
+		codec:    &dubbo.XCodec{},
+	}
+	p.host.Store(host)
+
+	pInst := NewPoolMultiplex(&p).(*poolMultiplex)
+	pInst.CheckAndInit(ctx)
+	// sleep to wait for the connection to be established
+	time.Sleep(time.Second * 2)
+
+	var xsList []*xStream
+	for i := 0; i < 2; i++ {
+		_, sender, failReason := pInst.NewStream(ctx, &receiver{})
+		assert.Equal(t, types.PoolFailureReason(""), failReason)
+		xs := sender.(*xStream)
+		xs.direction = stream.ServerStream
+		xsList = append(xsList, xs)
+	}
+	v, ok := pInst.activeClients[0].Load(types.ProtocolName(dubbo.ProtocolName))
+	assert.True(t, ok)
+	oldClient := v.(*activeClientMultiplex)
+
+	// the client served the max requests is retired, and a new client is initialized
+	assert.False(t, pInst.CheckAndInit(ctx))
+	assert.Equal(t, uint32(1), atomic.LoadUint32(&oldClient.retired))
+	assert.Eventually(t, func() bool {
+		v, ok := pInst.activeClients[0].Load(types.ProtocolName(dubbo.ProtocolName))
+		return ok && v != oldClient && atomic.LoadUint32(&v.(*activeClientMultiplex).state) == Connected
+	}, 3*time.Second, 10*time.Millisecond)
+	assert.True(t, pInst.CheckAndInit(ctx))
+
+	// the retired client is not closed until the in-flight streams are finished
+	for _, xs := range xsList {
+		xs.AppendHeaders(context.TODO(), &dubbo.Frame{Header: dubbo.Header{
+			Magic:     []byte{1, 2},
+			Direction: 0,
+		}}, true)
+	}
+	assert.Equal(t, 2, oldClient.codecClient.ActiveRequestsNum())
+	assert.Equal(t, api.ConnActive, oldClient.host.Connection.State())
+	pInst.Shutdown()
+}
+
 func basicCluster(name string, hosts []string) v2.Cluster {
 	var vhosts []v2.Host
 	for _, addr := range hosts {
@@ -64,6 +64,7 @@ func (p *poolPingPong) NewStream(ctx context.Context, receiver types.StreamRecei
 	}
 	_ = variable.Set(ctx, types.VariableUpstreamConnectionID, c.codecClient.ConnID())
 
+	atomic.AddUint64(&c.totalStream, 1)
 	var streamSender = c.codecClient.NewStream(ctx, receiver)
 
 	streamSender.GetStream().AddEventListener(c) // OnResetStream, OnDestroyStream
@@ -92,6 +93,8 @@ func (p *poolPingPong) GetActiveClient(ctx context.Context) (*activeClientPingPo
 		return nil, types.Overflow
 	}
 
+	p.closeRetiredClients()
+
 	p.clientMux.Lock()
 
 	proto := p.connpool.codec.ProtocolName()
@@ -111,7 +114,7 @@ func (p *poolPingPong) GetActiveClient(ctx context.Context) (*activeClientPingPo
 			// connection not multiplex,
 			// so we can concurrently build connections here
 			p.clientMux.Unlock()
-			c, reason = p.newActiveClient(ctx, proto)
+			c, reason = p.newActiveClient(ctx, proto, false)
 			if c != nil && reason == "" {
 				p.totalClientCount.Inc()
 			}
@@ -156,11 +159,70 @@ RET:
 	return c, reason
 }
 
+// Prewarm creates the idle clients until the total clients reach minConns
+func (p *poolPingPong) Prewarm(ctx context.Context, minConns int) int {
+	host := p.Host()
+	proto := p.connpool.codec.ProtocolName()
+	maxConns := host.ClusterInfo().ResourceManager().Connections().Max()
+	created := 0
+	for {
+		// the client is counted before it is connected, so the requests will not create more than the max connections
+		p.clientMux.Lock()
+		total := p.totalClientCount.Load()
+		if total >= uint64(minConns) || (maxConns != 0 && total >= maxConns) {
+			p.clientMux.Unlock()
+			return created
+		}
+		p.totalClientCount.Inc()
+		p.clientMux.Unlock()
+
+		c, reason := p.newActiveClient(ctx, proto, true)
+		if c == nil || reason != "" {
+			p.totalClientCount.Dec()
+			return created
+		}
+		created++
+		p.clientMux.Lock()
+		p.putClientToPoolLocked(c)
+		p.clientMux.Unlock()
+	}
+}
+
+// closeRetiredClients closes the idle clients that are expired
+func (p *poolPingPong) closeRetiredClients() {
+	info := p.Host().ClusterInfo()
+	if info.MaxConnectionDuration() == 0 {
+		return
+	}
+	var retired []*activeClientPingPong
+	p.clientMux.Lock()
+	idle := p.idleClients[:0]
+	for _, c := range p.idleClients {
+		if c.shouldRetire() {
+			retired = append(retired, c)
+		} else {
+			idle = append(idle, c)
+		}
+	}
+	for i := len(idle); i < len(p.idleClients); i++ {
+		p.idleClients[i] = nil
+	}
+	p.idleClients = idle
+	p.clientMux.Unlock()
+
+	// the connection close event locks the clientMux
+	for _, c := range retired {
+		c.host.Connection.Close(api.NoFlush, api.LocalClose)
+	}
+}
+
 func (p *poolPingPong) Close() {
 	p.clientMux.Lock()
-	defer p.clientMux.Unlock()
+	idleClients := append([]*activeClientPingPong{}, p.idleClients...)
+	p.clientMux.Unlock()
 
-	for _, c := range p.idleClients {
+	// the connection close event locks the clientMux
+	for _, c := range idleClients {
 		c.host.Connection.Close(api.NoFlush, api.LocalClose)
 	}
 }
@@ -185,11 +247,12 @@ func (p *poolPingPong) putClientToPoolLocked(client *activeClientPingPong) {
 	}
 }
 
-func (p *poolPingPong) newActiveClient(ctx context.Context, subProtocol api.ProtocolName) (*activeClientPingPong, types.PoolFailureReason) {
+func (p *poolPingPong) newActiveClient(ctx context.Context, subProtocol api.ProtocolName, prewarm bool) (*activeClientPingPong, types.PoolFailureReason) {
 	ac := &activeClientPingPong{
 		pool:        p,
 		subProtocol: subProtocol,
 		host:        p.Host().CreateConnection(ctx),
+		createTime:  time.Now(),
 	}
 
 	host := p.Host()
@@ -229,6 +292,13 @@ func (p *poolPingPong) newActiveClient(ctx context.Context, subProtocol api.Prot
 	host.HostStats().UpstreamConnectionActive.Inc(1)
 	host.ClusterInfo().Stats().UpstreamConnectionTotal.Inc(1)
 	host.ClusterInfo().Stats().UpstreamConnectionActive.Inc(1)
+	if prewarm {
+		host.HostStats().UpstreamConnectionPrewarm.Inc(1)
+		host.ClusterInfo().Stats().UpstreamConnectionPrewarm.Inc(1)
+	} else {
+		host.HostStats().UpstreamConnectionOnDemand.Inc(1)
+		host.ClusterInfo().Stats().UpstreamConnectionOnDemand.Inc(1)
+	}
 
 	return ac, ""
 }
@@ -245,6 +315,8 @@ type activeClientPingPong struct {
 	subProtocol     types.ProtocolName
 	keepAlive       *keepAliveListener
 	state           uint32 // for async connection
+	totalStream     uint64
+	createTime      time.Time
 
 	pool        *poolPingPong
 	codecClient stream.Client
@@ -260,12 +332,22 @@ func (ac *activeClientPingPong) Close(err error) {
 		return
 	}
 
+	// the retired client is closed instead of returning to the pool
+	if ac.shouldRetire() {
+		ac.host.Connection.Close(api.NoFlush, api.LocalClose)
+		return
+	}
+
 	// return to pool
 	ac.pool.clientMux.Lock()
 	defer ac.pool.clientMux.Unlock()
 	ac.pool.putClientToPoolLocked(ac)
 }
 
+func (ac *activeClientPingPong) shouldRetire() bool {
+	return stream.ShouldRetireConnection(ac.pool.Host().ClusterInfo(), ac.createTime, atomic.LoadUint64(&ac.totalStream))
+}
+
 // removeFromPool removes this client from connection pool
 func (ac *activeClientPingPong) removeFromPool() {
 	p := ac.pool
@@ -146,3 +146,38 @@ func (r *receiver) OnDecodeError(ctx context.Context, err error,
 	headers api.HeaderMap) {
 	fmt.Println("decode error")
 }
+
+func TestPingPongCloseRetiredClients(t *testing.T) {
+	var addr = "127.0.0.1:10086"
+	go server.start(t, addr)
+	defer server.stop(t)
+	// wait for server to start
+	time.Sleep(time.Second * 2)
+
+	cl := basicCluster(addr, []string{addr})
+	cl.MaxConnectionDuration = &api.DurationConfig{Duration: time.Second}
+	host := cluster.NewSimpleHost(cl.Hosts[0], cluster.NewCluster(cl).Snapshot().ClusterInfo())
+
+	p := connpool{
+		protocol: api.ProtocolName(dubbo.ProtocolName),
+		tlsHash:  &types.HashValue{},
+		codec:    &dubbo.XCodec{},
+	}
+	p.host.Store(host)
+
+	pInst := NewPoolPingPong(&p).(*poolPingPong)
+	defer pInst.Close()
+	require.Equal(t, 2, pInst.Prewarm(context.Background(), 2))
+	expired := append([]*activeClientPingPong{}, pInst.idleClients...)
+	time.Sleep(time.Second)
+	require.Equal(t, 1, pInst.Prewarm(context.Background(), 3))
+
+	// the expired idle clients are closed, and the others are kept
+	pInst.closeRetiredClients()
+	require.Len(t, pInst.idleClients, 1)
+	assert.NotContains(t, expired, pInst.idleClients[0])
+	assert.Equal(t, uint64(1), pInst.totalClientCount.Load())
+	for _, c := range expired {
+		assert.Equal(t, api.ConnClosed, c.host.Connection.State())
+	}
+}
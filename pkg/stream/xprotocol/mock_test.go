@@ -25,6 +25,7 @@ import (
 	"time"
 
 	"mosn.io/api"
+	v2 "mosn.io/mosn/pkg/config/v2"
 	"mosn.io/mosn/pkg/network"
 	"mosn.io/mosn/pkg/protocol/xprotocol"
 	"mosn.io/mosn/pkg/protocol/xprotocol/bolt"
@@ -172,3 +173,7 @@ func (ci *mockClusterInfo) IdleTimeout() time.Duration {
 func (ci *mockClusterInfo) SlowStart() types.SlowStart {
 	return types.SlowStart{}
 }
+
+func (ci *mockClusterInfo) ProxyProtocol() *v2.ProxyProtocolConfig {
+	return nil
+}
@@ -17,6 +17,16 @@
 
 package types
 
+import "mosn.io/api"
+
+// DEGRADED_ACTIVE_HC marks the host is degraded by the active health check.
+// A degraded host is still healthy, but the load balancers prefer the hosts that are not degraded,
+// so the implementations of Health should ignore this flag.
+//
+// The other health flags are defined in mosn.io/api, which is an external module, so this flag is
+// kept here and uses the next free bit of them, it should be moved to mosn.io/api if the module defines it.
+const DEGRADED_ACTIVE_HC api.HealthFlag = 0x04
+
 // FailureType is the type of a failure
 type FailureType string
 
@@ -36,6 +46,11 @@ type HealthCheckLog interface {
 	Log(host Host, changedState bool, isHealthy bool)
 }
 
+// HealthCheckDetailLog is an optional interface of HealthCheckLog, which records the detail of a check
+type HealthCheckDetailLog interface {
+	LogDetail(host Host, changedState bool, isHealthy bool, detail string)
+}
+
 // HealthChecker is a framework for connection management
 // When NewCluster is called, and the config contains health check related, mosn will create
 // a cluster with health check to make sure load balance always choose the "good" host
@@ -60,7 +75,46 @@ type HealthCheckSession interface {
 	OnTimeout()
 }
 
+// HealthCheckSessionDetail is an optional interface of HealthCheckSession,
+// the detail of the last check is recorded in the health check log, such as the response status code.
+type HealthCheckSessionDetail interface {
+	LastCheckDetail() string
+}
+
+// HealthCheckSessionCloser is an optional interface of HealthCheckSession,
+// it is called when the health check of the host is stopped, and releases the resources such as the connections.
+type HealthCheckSessionCloser interface {
+	Close()
+}
+
 // HealthCheckSessionFactory creates a HealthCheckSession
 type HealthCheckSessionFactory interface {
 	NewSession(cfg map[string]interface{}, host Host) HealthCheckSession
 }
+
+// OutlierResult is the result of an upstream request or connection reported to the OutlierDetector
+type OutlierResult int
+
+// Outlier results
+const (
+	// OutlierSuccess means the request is finished with a non 5xx response
+	OutlierSuccess OutlierResult = iota
+	// Outlier5xx means the request is finished with a 5xx response, except gateway errors
+	Outlier5xx
+	// OutlierGatewayFailure means the request is finished with 502/503/504, reset or timeout
+	OutlierGatewayFailure
+	// OutlierConnectFailure means the connection to the host is failed
+	OutlierConnectFailure
+)
+
+// OutlierDetector is a passive health checker, it ejects the hosts that behave as outliers
+// by the results reported by the proxy. An ejected host is marked with api.FAILED_OUTLIER_CHECK
+// until the ejection time is expired.
+type OutlierDetector interface {
+	// PutResult reports a result of the host
+	PutResult(host Host, result OutlierResult)
+	// SetHostSet resets the hosts that the detector checks
+	SetHostSet(HostSet)
+	// Stop terminates the detector, the ejected hosts will be restored
+	Stop()
+}
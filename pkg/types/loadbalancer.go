@@ -38,6 +38,7 @@ const (
 	RequestRoundRobin     LoadBalancerType = "LB_REQUEST_ROUNDROBIN"
 	LeastActiveConnection LoadBalancerType = "LB_LEAST_CONNECTION"
 	PeakEwma              LoadBalancerType = "LB_PEAK_EWMA"
+	RingHash              LoadBalancerType = "LB_RING_HASH"
 )
 
 type SlowStartMode string
@@ -80,6 +81,19 @@ type LoadBalancerContext interface {
 	DownstreamRoute() api.Route
 }
 
+// HostPredicateContext is an optional interface of LoadBalancerContext.
+// The context of a retried request implements it, so the hosts already attempted can be skipped.
+type HostPredicateContext interface {
+	// AttemptedHosts returns the hosts that the request has been sent to
+	AttemptedHosts() []Host
+
+	// ShouldSelectAnotherHost returns true if the chosen host should be skipped
+	ShouldSelectAnotherHost(host Host) bool
+
+	// HostSelectionRetryCount returns the max times to choose a host again
+	HostSelectionRetryCount() int
+}
+
 const (
 	AllHostMetaKey  = "MOSN-Subset-All"
 	FallbackMetaKey = "MOSN-Subset-Fallback"
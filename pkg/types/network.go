@@ -202,6 +202,12 @@ type ClientConnection interface {
 	SetMark(uint32)
 }
 
+// ProxyProtocolSender is an optional interface of the ClientConnection,
+// the connection sends the PROXY protocol header before any other data once it is established.
+type ProxyProtocolSender interface {
+	SetProxyProtocolHeader(header []byte)
+}
+
 // Default connection arguments
 var (
 	DefaultConnReadTimeout  = 15 * time.Second
@@ -91,6 +91,23 @@ type RouterWrapper interface {
 	GetRoutersConfig() v2.RouterConfiguration
 }
 
+// RetryPolicy extends the api.RetryPolicy, the retry policy of a route implements it
+type RetryPolicy interface {
+	api.RetryPolicy
+	// RetryOnConditions returns the retry conditions, the request is retried by RetryOn if it is empty
+	RetryOnConditions() []string
+	// RetryBudget returns the retry budget, nil means only the cluster's max retries is limited
+	RetryBudget() *v2.RetryBudget
+	// RetryBackOff returns the base and max interval between the retries, 0 means not configured
+	RetryBackOff() (time.Duration, time.Duration)
+	// RetryHostPredicates returns the predicates that decide whether a host should be skipped in the retries
+	RetryHostPredicates() []*v2.RetryHostPredicate
+	// HostSelectionRetryMaxAttempts returns the max times to choose a host again, 0 means not configured
+	HostSelectionRetryMaxAttempts() uint32
+	// HedgeOnPerTryTimeout returns true if the request is hedged when the per try timeout fires
+	HedgeOnPerTryTimeout() bool
+}
+
 type HeaderFormat interface {
 	Format(info api.RequestInfo) string
 	Append() bool
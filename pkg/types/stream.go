@@ -256,6 +256,15 @@ type ConnectionPool interface {
 	Host() Host
 }
 
+// ConnectionPoolPrewarmer is an optional interface of the ConnectionPool,
+// the pool establishes the connections ahead of the requests.
+type ConnectionPoolPrewarmer interface {
+	// Prewarm establishes the connections until the pool has minConns connections,
+	// returns the number of the new connections.
+	// The connections are created synchronously, so it should not be called in the request path.
+	Prewarm(ctx context.Context, minConns int) int
+}
+
 // NewConnPool is a function to create ConnectionPool
 type NewConnPool func(ctx context.Context, host Host) ConnectionPool
 
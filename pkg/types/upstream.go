@@ -146,6 +146,9 @@ type HostSet interface {
 	Get(i int) Host
 	// Range iterates each host in hostSet
 	Range(func(Host) bool)
+	// PriorityLevels returns the hosts partitioned by priority, sorted from the highest priority (0)
+	// to the lowest. The levels without hosts are skipped, so it returns one level at least.
+	PriorityLevels() []HostSet
 }
 
 // Host is an upstream host
@@ -173,6 +176,9 @@ type Host interface {
 	// Config creates a host config by the host attributes
 	Config() v2.Host
 
+	// Priority returns the host's priority, 0 is the highest priority
+	Priority() uint32
+
 	// LastHealthCheckPassTime returns the timestamp when host has translated from unhealthy to healthy state
 	LastHealthCheckPassTime() time.Time
 	// SetLastHealthCheckPassTime updates the timestamp when host has translated from unhealthy to healthy state,
@@ -197,6 +203,9 @@ type ClusterInfo interface {
 	// MaxRequestsPerConn returns a connection's max request
 	MaxRequestsPerConn() uint32
 
+	// MaxConnectionDuration returns a connection's max lifetime, 0 means no limit
+	MaxConnectionDuration() time.Duration
+
 	Mark() uint32
 
 	// Stats returns the cluster's stats metrics
@@ -231,6 +240,19 @@ type ClusterInfo interface {
 
 	// IsClusterPoolEnable returns the cluster pool enable or not
 	IsClusterPoolEnable() bool
+
+	// OutlierDetector returns the cluster's outlier detector, returns nil if it is not configured
+	OutlierDetector() OutlierDetector
+
+	// HealthyPanicThreshold returns the healthy hosts percent that the load balancer enters panic mode
+	HealthyPanicThreshold() uint32
+
+	// DegradedHostsEnabled returns true if the hosts can be marked degraded by the health check,
+	// the load balancer prefers the hosts that are not degraded.
+	DegradedHostsEnabled() bool
+
+	// ProxyProtocol returns the PROXY protocol config of the upstream connections, returns nil if it is not configured
+	ProxyProtocol() *v2.ProxyProtocolConfig
 }
 
 // ResourceManager manages different types of Resource
@@ -269,6 +291,8 @@ type HostStats struct {
 	UpstreamConnectionLocalCloseWithActiveRequest  metrics.Counter
 	UpstreamConnectionRemoteCloseWithActiveRequest metrics.Counter
 	UpstreamConnectionCloseNotify                  metrics.Counter
+	UpstreamConnectionPrewarm                      metrics.Counter
+	UpstreamConnectionOnDemand                     metrics.Counter
 	UpstreamRequestTotal                           metrics.Counter
 	UpstreamRequestActive                          metrics.Counter
 	UpstreamRequestLocalReset                      metrics.Counter
@@ -298,6 +322,8 @@ type ClusterStats struct {
 	UpstreamConnectionLocalCloseWithActiveRequest  metrics.Counter
 	UpstreamConnectionRemoteCloseWithActiveRequest metrics.Counter
 	UpstreamConnectionCloseNotify                  metrics.Counter
+	UpstreamConnectionPrewarm                      metrics.Counter
+	UpstreamConnectionOnDemand                     metrics.Counter
 	UpstreamBytesReadTotal                         metrics.Counter
 	UpstreamBytesWriteTotal                        metrics.Counter
 	UpstreamRequestTotal                           metrics.Counter
@@ -306,6 +332,9 @@ type ClusterStats struct {
 	UpstreamRequestRemoteReset                     metrics.Counter
 	UpstreamRequestRetry                           metrics.Counter
 	UpstreamRequestRetryOverflow                   metrics.Counter
+	UpstreamRequestHedge                           metrics.Counter
+	UpstreamRequestHedgeWin                        metrics.Counter
+	UpstreamRequestHedgeCancel                     metrics.Counter
 	UpstreamRequestTimeout                         metrics.Counter
 	UpstreamRequestFailureEject                    metrics.Counter
 	UpstreamRequestPendingOverflow                 metrics.Counter
@@ -316,6 +345,17 @@ type ClusterStats struct {
 	UpstreamResponseFailed                         metrics.Counter
 	LBSubSetsFallBack                              metrics.Counter
 	LBSubsetsCreated                               metrics.Gauge
+	OutlierEjectionsTotal                          metrics.Counter
+	OutlierEjectionsActive                         metrics.Gauge
+	OutlierEjectionsOverflow                       metrics.Counter
+	OutlierEjectionsConsecutive5xx                 metrics.Counter
+	OutlierEjectionsConsecutiveGatewayFailure      metrics.Counter
+	OutlierEjectionsConsecutiveConnectFailure      metrics.Counter
+	OutlierEjectionsSuccessRate                    metrics.Counter
+	LBZoneRoutingAllDirectly                       metrics.Counter
+	LBZoneRoutingSampled                           metrics.Counter
+	LBZoneRoutingCrossZone                         metrics.Counter
+	LBHealthyPanic                                 metrics.Counter
 }
 
 type CreateConnectionData struct {
@@ -19,9 +19,11 @@ package types
 
 import (
 	"context"
+	gotls "crypto/tls"
 	"errors"
 
 	"mosn.io/api"
+	"mosn.io/mosn/pkg/mtls/crypto/tls"
 	"mosn.io/pkg/variable"
 )
 
@@ -89,6 +91,14 @@ const (
 // [Route]: internal
 const (
 	VarRouterMeta string = "x-mosn-router-meta"
+	// VarPathTemplateCaptures stores the captures of the matched path template, the value type is map[string]string
+	VarPathTemplateCaptures string = "x-mosn-path-template-captures"
+	// VarPrefixPathParam is the prefix of the path template captures, like path_param_id
+	VarPrefixPathParam string = "path_param_"
+	// VarStickyCluster stores the cluster chosen for a sticky cookie that should be set on the response
+	VarStickyCluster string = "x-mosn-sticky-cluster"
+	// VarGeneratedCookies stores the cookies generated by the cookie hash policies, the value type is map[string]*http.Cookie
+	VarGeneratedCookies string = "x-mosn-generated-cookies"
 )
 
 // [Protocol]: common
@@ -162,6 +172,9 @@ const (
 	VarConnectionEventListeners    = "connection_event_listeners"
 	VarUpstreamConnectionID        = "upstream_connection_id"
 	VarOriRemoteAddr               = "ori_remote_addr"
+	VarOriLocalAddr                = "ori_local_addr"
+	VarApplicationProtocols        = "application_protocols"
+	VarTransportProtocol           = "transport_protocol"
 	VarDownStreamProtocol          = "downstream_protocol"
 	VarUpStreamProtocol            = "upstream_protocol"
 	VarDownStreamReqHeaders        = "downstream_req_headers"
@@ -188,6 +201,10 @@ var (
 	VariableConnectionEventListeners    = variable.NewVariable(VarConnectionEventListeners, nil, nil, variable.DefaultSetter, 0)
 	VariableUpstreamConnectionID        = variable.NewVariable(VarUpstreamConnectionID, nil, nil, variable.DefaultSetter, 0)
 	VariableOriRemoteAddr               = variable.NewVariable(VarOriRemoteAddr, nil, nil, variable.DefaultSetter, 0)
+	VariableOriLocalAddr                = variable.NewVariable(VarOriLocalAddr, nil, nil, variable.DefaultSetter, 0)
+	VariableApplicationProtocols        = variable.NewVariable(VarApplicationProtocols, nil, nil, variable.DefaultSetter, 0)
+	VariableRequestedServerName         = variable.NewStringVariable(VarRequestedServerName, nil, requestedServerNameGetter, variable.DefaultStringSetter, 0)
+	VariableTransportProtocol           = variable.NewStringVariable(VarTransportProtocol, nil, nil, variable.DefaultStringSetter, 0)
 	VariableTraceSpankey                = variable.NewVariable(VarTraceSpanKey, nil, nil, variable.DefaultSetter, 0)
 	VariableDownStreamProtocol          = variable.NewVariable(VarDownStreamProtocol, nil, nil, variable.DefaultSetter, 0)
 	VariableUpstreamProtocol            = variable.NewVariable(VarUpStreamProtocol, nil, nil, variable.DefaultSetter, 0)
@@ -202,7 +219,8 @@ func init() {
 		VariableListenerPort, VariableListenerName, VariableListenerType, VariableConnDefaultReadBufferSize, VariableNetworkFilterChainFactories,
 		VariableAccessLogs, VariableAcceptChan, VariableAcceptBuffer, VariableConnectionFd,
 		VariableTraceSpankey, VariableTraceId, VariableProxyGeneralConfig, VariableConnectionEventListeners,
-		VariableUpstreamConnectionID, VariableOriRemoteAddr,
+		VariableUpstreamConnectionID, VariableOriRemoteAddr, VariableOriLocalAddr,
+		VariableRequestedServerName, VariableApplicationProtocols, VariableTransportProtocol,
 		VariableDownStreamProtocol, VariableUpstreamProtocol, VariableDownStreamReqHeaders, VariableDownStreamRespHeaders, VariableTraceSpan,
 	}
 	for _, v := range builtinVariables {
@@ -220,3 +238,28 @@ func init() {
 		return api.ProtocolName("-"), errors.New("invalid protocol name")
 	}
 }
+
+// requestedServerNameGetter is used when no listener filter has set the server name,
+// it returns the SNI negotiated by the downstream tls connection if there is one
+func requestedServerNameGetter(ctx context.Context, value *variable.IndexedValue, data interface{}) (string, error) {
+	v, err := variable.Get(ctx, VariableConnection)
+	if err != nil {
+		return variable.ValueNotFound, err
+	}
+	conn, ok := v.(api.Connection)
+	if !ok || conn == nil {
+		return variable.ValueNotFound, variable.ErrValueNotFound
+	}
+	var sni string
+	// the mtls connection returns the connection state of the standard library
+	switch tlsConn := conn.RawConn().(type) {
+	case interface{ ConnectionState() tls.ConnectionState }:
+		sni = tlsConn.ConnectionState().ServerName
+	case interface{ ConnectionState() gotls.ConnectionState }:
+		sni = tlsConn.ConnectionState().ServerName
+	}
+	if sni != "" {
+		return sni, nil
+	}
+	return variable.ValueNotFound, variable.ErrValueNotFound
+}
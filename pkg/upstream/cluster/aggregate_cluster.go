@@ -0,0 +1,114 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cluster
+
+import (
+	"sync"
+
+	v2 "mosn.io/mosn/pkg/config/v2"
+	"mosn.io/mosn/pkg/log"
+	"mosn.io/mosn/pkg/types"
+)
+
+func init() {
+	RegisterClusterType(v2.AGGREGATE_CLUSTER, newAggregateCluster)
+}
+
+// aggregateCluster balances across the hosts of the referenced clusters.
+// The hosts of each referenced cluster are mapped to the priority levels after the previous cluster's,
+// so the priority load balancer prefers the clusters in order, and the requests spill over to the
+// next cluster by the healthy hosts ratio and the overprovisioning factor.
+// The hosts are refreshed when the referenced clusters change, or the hosts of them are updated,
+// or the health of them is changed by the health checker or the outlier detector.
+type aggregateCluster struct {
+	*simpleCluster
+	clusters []string
+	mutex    sync.Mutex
+}
+
+// aggregateHost is a host of a referenced cluster with the priority in the aggregate cluster
+type aggregateHost struct {
+	types.Host
+	priority uint32
+}
+
+func (h *aggregateHost) Priority() uint32 {
+	return h.priority
+}
+
+func newAggregateCluster(clusterConfig v2.Cluster) types.Cluster {
+	return &aggregateCluster{
+		simpleCluster: newSimpleCluster(clusterConfig).(*simpleCluster),
+		clusters:      clusterConfig.AggregateClusters,
+	}
+}
+
+// UpdateHosts ignores the hosts, the hosts are collected from the referenced clusters.
+func (ac *aggregateCluster) UpdateHosts(types.HostSet) {
+	if log.DefaultLogger.GetLogLevel() >= log.DEBUG {
+		log.DefaultLogger.Debugf("[upstream] [aggregate cluster] cluster %s ignores the hosts update", ac.info.Name())
+	}
+}
+
+func (ac *aggregateCluster) references(clusterName string) bool {
+	for _, name := range ac.clusters {
+		if name == clusterName {
+			return true
+		}
+	}
+	return false
+}
+
+// refresh collects the hosts of the referenced clusters that are found by getCluster
+func (ac *aggregateCluster) refresh(getCluster func(string) types.Cluster) {
+	ac.mutex.Lock()
+	defer ac.mutex.Unlock()
+	var hosts []types.Host
+	offset := uint32(0)
+	for _, name := range ac.clusters {
+		c := getCluster(name)
+		if c == nil {
+			continue
+		}
+		// the nested aggregate cluster is not supported, which may cause a reference loop
+		if _, ok := c.(*aggregateCluster); ok {
+			log.DefaultLogger.Errorf("[upstream] [aggregate cluster] cluster %s references an aggregate cluster %s", ac.info.Name(), name)
+			continue
+		}
+		hs := c.Snapshot().HostSet()
+		if hs == nil || hs.Size() == 0 {
+			continue
+		}
+		maxPriority := uint32(0)
+		hs.Range(func(host types.Host) bool {
+			if host.Priority() > maxPriority {
+				maxPriority = host.Priority()
+			}
+			hosts = append(hosts, &aggregateHost{
+				Host:     host,
+				priority: offset + host.Priority(),
+			})
+			return true
+		})
+		offset += maxPriority + 1
+	}
+	ac.simpleCluster.UpdateHosts(NewHostSet(hosts))
+	if log.DefaultLogger.GetLogLevel() >= log.INFO {
+		log.DefaultLogger.Infof("[upstream] [aggregate cluster] cluster %s hosts refreshed, hosts count: %d", ac.info.Name(), len(hosts))
+	}
+}
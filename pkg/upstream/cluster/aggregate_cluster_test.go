@@ -0,0 +1,210 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cluster
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	v2 "mosn.io/mosn/pkg/config/v2"
+	"mosn.io/mosn/pkg/types"
+)
+
+func newAggregateTestHosts(addrs ...string) []v2.Host {
+	hosts := make([]v2.Host, 0, len(addrs))
+	for _, addr := range addrs {
+		hosts = append(hosts, v2.Host{HostConfig: v2.HostConfig{Address: addr, Weight: 1}})
+	}
+	return hosts
+}
+
+func TestAggregateCluster(t *testing.T) {
+	_createClusterManager()
+	cm := clusterManagerInstance
+	require.Nil(t, cm.AddOrUpdatePrimaryCluster(v2.Cluster{
+		Name:              "aggregate",
+		ClusterType:       v2.AGGREGATE_CLUSTER,
+		LbType:            v2.LB_ROUNDROBIN,
+		AggregateClusters: []string{"primary-dc", "backup-dc", "aggregate"},
+	}))
+	getSnapshot := func() types.ClusterSnapshot {
+		return cm.GetClusterSnapshot(context.Background(), "aggregate")
+	}
+	// no referenced clusters
+	assert.Equal(t, 0, getSnapshot().HostNum(nil))
+	assert.Nil(t, getSnapshot().LoadBalancer().ChooseHost(nil))
+
+	// the referenced clusters are added
+	require.Nil(t, cm.AddOrUpdateClusterAndHost(v2.Cluster{Name: "primary-dc", LbType: v2.LB_ROUNDROBIN},
+		newAggregateTestHosts("127.0.10.1:8080", "127.0.10.2:8080")))
+	require.Nil(t, cm.AddOrUpdateClusterAndHost(v2.Cluster{Name: "backup-dc", LbType: v2.LB_ROUNDROBIN},
+		newAggregateTestHosts("127.0.20.1:8080")))
+	snap := getSnapshot()
+	assert.Equal(t, 3, snap.HostNum(nil))
+	_, ok := snap.LoadBalancer().(*priorityLoadBalancer)
+	require.True(t, ok)
+	choose := func() map[string]int {
+		result := map[string]int{}
+		lb := getSnapshot().LoadBalancer()
+		for i := 0; i < 20; i++ {
+			h := lb.ChooseHost(nil)
+			require.NotNil(t, h)
+			result[h.AddressString()]++
+		}
+		return result
+	}
+	// all the requests go to the primary cluster
+	result := choose()
+	assert.Equal(t, 2, len(result))
+	assert.Equal(t, 0, result["127.0.20.1:8080"])
+
+	// the hosts keep the primary cluster info
+	getSnapshot().HostSet().Range(func(host types.Host) bool {
+		if host.AddressString() == "127.0.20.1:8080" {
+			assert.Equal(t, "backup-dc", host.ClusterInfo().Name())
+			assert.Equal(t, uint32(1), host.Priority())
+		} else {
+			assert.Equal(t, "primary-dc", host.ClusterInfo().Name())
+			assert.Equal(t, uint32(0), host.Priority())
+		}
+		return true
+	})
+
+	// fails over to the backup cluster when the primary cluster has no healthy hosts
+	var primaryHosts []types.Host
+	cm.GetClusterSnapshot(context.Background(), "primary-dc").HostSet().Range(func(host types.Host) bool {
+		primaryHosts = append(primaryHosts, host)
+		return true
+	})
+	setHostsHealth(primaryHosts, false)
+	result = choose()
+	setHostsHealth(primaryHosts, true)
+	assert.Equal(t, map[string]int{"127.0.20.1:8080": 20}, result)
+
+	// the hosts of the referenced cluster are changed
+	require.Nil(t, cm.UpdateClusterHosts("primary-dc", newAggregateTestHosts("127.0.10.3:8080")))
+	assert.Equal(t, map[string]int{"127.0.10.3:8080": 20}, choose())
+	// the referenced cluster is updated
+	require.Nil(t, cm.AddOrUpdatePrimaryCluster(v2.Cluster{Name: "primary-dc", LbType: v2.LB_RANDOM}))
+	assert.Equal(t, map[string]int{"127.0.10.3:8080": 20}, choose())
+	// the hosts of the referenced cluster are updated by the cluster itself, such as the dns clusters
+	primary := cm.getCluster("primary-dc")
+	primary.UpdateHosts(NewHostSet([]types.Host{
+		NewSimpleHost(newAggregateTestHosts("127.0.10.4:8080")[0], primary.Snapshot().ClusterInfo()),
+	}))
+	assert.Equal(t, map[string]int{"127.0.10.4:8080": 20}, choose())
+	// the health of the referenced cluster's hosts is changed, the aggregate cluster is refreshed
+	snap = getSnapshot()
+	onClusterHealthChanged("primary-dc")
+	assert.Eventually(t, func() bool {
+		return getSnapshot() != snap
+	}, time.Second, 10*time.Millisecond)
+	// the hosts of the aggregate cluster can not be updated directly
+	require.Nil(t, cm.UpdateClusterHosts("aggregate", newAggregateTestHosts("127.0.30.1:8080")))
+	assert.Equal(t, 2, getSnapshot().HostNum(nil))
+	// the aggregate cluster is updated
+	require.Nil(t, cm.AddOrUpdatePrimaryCluster(v2.Cluster{
+		Name:              "aggregate",
+		ClusterType:       v2.AGGREGATE_CLUSTER,
+		LbType:            v2.LB_ROUNDROBIN,
+		AggregateClusters: []string{"backup-dc", "primary-dc"},
+	}))
+	assert.Equal(t, map[string]int{"127.0.20.1:8080": 20}, choose())
+	// the referenced cluster is removed
+	require.Nil(t, cm.RemovePrimaryCluster("backup-dc"))
+	assert.Equal(t, map[string]int{"127.0.10.4:8080": 20}, choose())
+	require.Nil(t, cm.RemovePrimaryCluster("primary-dc"))
+	assert.Equal(t, 0, getSnapshot().HostNum(nil))
+}
+
+func TestClusterHealthChangedCoalesced(t *testing.T) {
+	_createClusterManager()
+	cm := clusterManagerInstance
+	require.Nil(t, cm.AddOrUpdateClusterAndHost(v2.Cluster{Name: "primary-dc", LbType: v2.LB_ROUNDROBIN},
+		newAggregateTestHosts("127.0.10.1:8080")))
+	require.Nil(t, cm.AddOrUpdatePrimaryCluster(v2.Cluster{
+		Name:              "aggregate",
+		ClusterType:       v2.AGGREGATE_CLUSTER,
+		LbType:            v2.LB_ROUNDROBIN,
+		AggregateClusters: []string{"primary-dc"},
+	}))
+	getSnapshot := func() types.ClusterSnapshot {
+		return cm.GetClusterSnapshot(context.Background(), "aggregate")
+	}
+	// a refresh is waiting, the change is coalesced
+	snap := getSnapshot()
+	cm.healthChanged.Store("primary-dc", struct{}{})
+	onClusterHealthChanged("primary-dc")
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, snap, getSnapshot())
+	// the waiting refresh is done
+	cm.healthChanged.Delete("primary-dc")
+	onClusterHealthChanged("primary-dc")
+	assert.Eventually(t, func() bool {
+		return getSnapshot() != snap
+	}, time.Second, 10*time.Millisecond)
+	assert.Eventually(t, func() bool {
+		_, ok := cm.healthChanged.Load("primary-dc")
+		return !ok
+	}, time.Second, 10*time.Millisecond)
+
+	// no refresh after the cluster manager is destroyed
+	clusterManagerInstance.Destroy()
+	assert.Nil(t, getClusterManager())
+	onClusterHealthChanged("primary-dc")
+}
+
+func TestAggregateClusterOutlierEjection(t *testing.T) {
+	_createClusterManager()
+	cm := clusterManagerInstance
+	require.Nil(t, cm.AddOrUpdateClusterAndHost(v2.Cluster{
+		Name:   "outlier-dc",
+		LbType: v2.LB_ROUNDROBIN,
+		OutlierDetection: &v2.OutlierDetection{
+			Consecutive5xx:     1,
+			MaxEjectionPercent: 100,
+		},
+	}, newAggregateTestHosts("127.0.40.1:8080", "127.0.40.2:8080")))
+	defer cm.RemovePrimaryCluster("outlier-dc")
+	require.Nil(t, cm.AddOrUpdatePrimaryCluster(v2.Cluster{
+		Name:              "outlier-aggregate",
+		ClusterType:       v2.AGGREGATE_CLUSTER,
+		LbType:            v2.LB_ROUNDROBIN,
+		AggregateClusters: []string{"outlier-dc"},
+	}))
+	defer cm.RemovePrimaryCluster("outlier-aggregate")
+	snap := cm.GetClusterSnapshot(context.Background(), "outlier-aggregate")
+	assert.Equal(t, 2, snap.HostNum(nil))
+
+	// the ejection refreshes the aggregate cluster
+	outlier := cm.GetClusterSnapshot(context.Background(), "outlier-dc")
+	var host types.Host
+	outlier.HostSet().Range(func(h types.Host) bool {
+		host = h
+		return false
+	})
+	outlier.ClusterInfo().OutlierDetector().PutResult(host, types.Outlier5xx)
+	require.False(t, host.Health())
+	assert.Eventually(t, func() bool {
+		return cm.GetClusterSnapshot(context.Background(), "outlier-aggregate") != snap
+	}, time.Second, 10*time.Millisecond)
+}
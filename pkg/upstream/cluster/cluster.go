@@ -46,6 +46,10 @@ func init() {
 }
 
 func NewCluster(clusterConfig v2.Cluster) types.Cluster {
+	if err := checkProxyProtocolConfig(clusterConfig.ProxyProtocol); err != nil {
+		log.DefaultLogger.Alertf("cluster.config", "[upstream] [cluster] [new cluster] cluster %s invalid proxy protocol config, %v", clusterConfig.Name, err)
+		return nil
+	}
 	if f, ok := clusterFactories[clusterConfig.ClusterType]; ok {
 		return f(clusterConfig)
 	}
@@ -57,20 +61,29 @@ func NewCluster(clusterConfig v2.Cluster) types.Cluster {
 
 func NewClusterInfo(clusterConfig v2.Cluster) types.ClusterInfo {
 	info := &clusterInfo{
-		name:                 clusterConfig.Name,
-		clusterType:          clusterConfig.ClusterType,
-		subType:              clusterConfig.SubType,
-		maxRequestsPerConn:   clusterConfig.MaxRequestPerConn,
-		mark:                 clusterConfig.Mark,
-		connBufferLimitBytes: clusterConfig.ConnBufferLimitBytes,
-		stats:                newClusterStats(clusterConfig.Name),
-		lbSubsetInfo:         NewLBSubsetInfo(&clusterConfig.LBSubSetConfig), // new subset load balancer info
-		lbOriDstInfo:         NewLBOriDstInfo(&clusterConfig.LBOriDstConfig), // new oridst load balancer info
-		lbType:               types.LoadBalancerType(clusterConfig.LbType),
-		resourceManager:      NewResourceManager(clusterConfig.CirBreThresholds),
-		clusterManagerTLS:    clusterConfig.ClusterManagerTLS,
-		clusterPoolEnable:    clusterConfig.ClusterPoolEnable,
-		lbConfig:             clusterConfig.LbConfig,
+		name:                  clusterConfig.Name,
+		clusterType:           clusterConfig.ClusterType,
+		subType:               clusterConfig.SubType,
+		maxRequestsPerConn:    clusterConfig.MaxRequestPerConn,
+		mark:                  clusterConfig.Mark,
+		connBufferLimitBytes:  clusterConfig.ConnBufferLimitBytes,
+		stats:                 newClusterStats(clusterConfig.Name),
+		lbSubsetInfo:          NewLBSubsetInfo(&clusterConfig.LBSubSetConfig), // new subset load balancer info
+		lbOriDstInfo:          NewLBOriDstInfo(&clusterConfig.LBOriDstConfig), // new oridst load balancer info
+		lbType:                types.LoadBalancerType(clusterConfig.LbType),
+		resourceManager:       NewResourceManager(clusterConfig.CirBreThresholds),
+		clusterManagerTLS:     clusterConfig.ClusterManagerTLS,
+		clusterPoolEnable:     clusterConfig.ClusterPoolEnable,
+		lbConfig:              clusterConfig.LbConfig,
+		healthyPanicThreshold: clusterConfig.HealthyPanicThreshold,
+		proxyProtocol:         clusterConfig.ProxyProtocol,
+	}
+	// set OutlierDetection
+	if clusterConfig.OutlierDetection != nil {
+		info.outlierDetector = newOutlierDetector(info, clusterConfig.OutlierDetection)
+	}
+	if clusterConfig.HealthCheck.ServiceName != "" {
+		info.degradedHostsEnabled = healthcheck.DegradedEnabled(clusterConfig.HealthCheck)
 	}
 	// set ConnectTimeout
 	if clusterConfig.ConnectTimeout != nil {
@@ -84,6 +97,11 @@ func NewClusterInfo(clusterConfig v2.Cluster) types.ClusterInfo {
 		info.idleTimeout = clusterConfig.IdleTimeout.Duration
 	}
 
+	// set MaxConnectionDuration
+	if clusterConfig.MaxConnectionDuration != nil {
+		info.maxConnectionDuration = clusterConfig.MaxConnectionDuration.Duration
+	}
+
 	// set SlowStart
 	if clusterConfig.SlowStart.Mode != "" {
 		info.slowStart.Mode = types.SlowStartMode(clusterConfig.SlowStart.Mode)
@@ -150,6 +168,11 @@ func newSimpleCluster(clusterConfig v2.Cluster) types.Cluster {
 			log.DefaultLogger.Infof("[upstream] [cluster] [new cluster] cluster %s have health check", clusterConfig.Name)
 		}
 		cluster.healthChecker = healthcheck.CreateHealthCheck(clusterConfig.HealthCheck)
+		cluster.healthChecker.AddHostCheckCompleteCb(func(_ types.Host, changed bool, _ bool) {
+			if changed {
+				onClusterHealthChanged(info.Name())
+			}
+		})
 	}
 	return cluster
 }
@@ -168,7 +191,6 @@ func (sc *simpleCluster) UpdateHosts(hostSet types.HostSet) {
 		lb = NewLoadBalancer(info, hostSet)
 	}
 	sc.mutex.Lock()
-	defer sc.mutex.Unlock()
 	sc.lbInstance = lb
 	sc.hostSet = hostSet
 	sc.snapshot.Store(&clusterSnapshot{
@@ -179,6 +201,11 @@ func (sc *simpleCluster) UpdateHosts(hostSet types.HostSet) {
 	if sc.healthChecker != nil {
 		sc.healthChecker.SetHealthCheckerHostSet(hostSet)
 	}
+	if od := info.OutlierDetector(); od != nil {
+		od.SetHostSet(hostSet)
+	}
+	sc.mutex.Unlock()
+	onClusterHostsChanged(info.Name())
 }
 
 func (sc *simpleCluster) Snapshot() types.ClusterSnapshot {
@@ -203,27 +230,35 @@ func (sc *simpleCluster) StopHealthChecking() {
 	if sc.healthChecker != nil {
 		sc.healthChecker.Stop()
 	}
+	if od := sc.info.OutlierDetector(); od != nil {
+		od.Stop()
+	}
 }
 
 type clusterInfo struct {
-	name                 string
-	clusterType          v2.ClusterType
-	subType              string
-	lbType               types.LoadBalancerType // if use subset lb , lbType is used as inner LB algorithm for choosing subset's host
-	connBufferLimitBytes uint32
-	maxRequestsPerConn   uint32
-	mark                 uint32
-	resourceManager      types.ResourceManager
-	stats                *types.ClusterStats
-	lbSubsetInfo         types.LBSubsetInfo
-	lbOriDstInfo         types.LBOriDstInfo
-	clusterManagerTLS    bool
-	tlsMng               types.TLSClientContextManager
-	connectTimeout       time.Duration
-	idleTimeout          time.Duration
-	lbConfig             *v2.LbConfig
-	slowStart            types.SlowStart
-	clusterPoolEnable    bool
+	name                  string
+	clusterType           v2.ClusterType
+	subType               string
+	lbType                types.LoadBalancerType // if use subset lb , lbType is used as inner LB algorithm for choosing subset's host
+	connBufferLimitBytes  uint32
+	maxRequestsPerConn    uint32
+	maxConnectionDuration time.Duration
+	mark                  uint32
+	resourceManager       types.ResourceManager
+	stats                 *types.ClusterStats
+	lbSubsetInfo          types.LBSubsetInfo
+	lbOriDstInfo          types.LBOriDstInfo
+	clusterManagerTLS     bool
+	tlsMng                types.TLSClientContextManager
+	connectTimeout        time.Duration
+	idleTimeout           time.Duration
+	lbConfig              *v2.LbConfig
+	slowStart             types.SlowStart
+	clusterPoolEnable     bool
+	outlierDetector       types.OutlierDetector
+	healthyPanicThreshold uint32
+	degradedHostsEnabled  bool
+	proxyProtocol         *v2.ProxyProtocolConfig
 }
 
 func (ci *clusterInfo) Name() string {
@@ -246,6 +281,10 @@ func (ci *clusterInfo) MaxRequestsPerConn() uint32 {
 	return ci.maxRequestsPerConn
 }
 
+func (ci *clusterInfo) MaxConnectionDuration() time.Duration {
+	return ci.maxConnectionDuration
+}
+
 func (ci *clusterInfo) Mark() uint32 {
 	return ci.mark
 }
@@ -297,6 +336,22 @@ func (ci *clusterInfo) IsClusterPoolEnable() bool {
 	return ci.clusterPoolEnable
 }
 
+func (ci *clusterInfo) OutlierDetector() types.OutlierDetector {
+	return ci.outlierDetector
+}
+
+func (ci *clusterInfo) HealthyPanicThreshold() uint32 {
+	return ci.healthyPanicThreshold
+}
+
+func (ci *clusterInfo) DegradedHostsEnabled() bool {
+	return ci.degradedHostsEnabled
+}
+
+func (ci *clusterInfo) ProxyProtocol() *v2.ProxyProtocolConfig {
+	return ci.proxyProtocol
+}
+
 type clusterSnapshot struct {
 	info    types.ClusterInfo
 	hostSet types.HostSet
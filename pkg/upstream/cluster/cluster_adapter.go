@@ -18,6 +18,8 @@
 package cluster
 
 import (
+	"errors"
+
 	v2 "mosn.io/mosn/pkg/config/v2"
 	"mosn.io/mosn/pkg/types"
 )
@@ -60,3 +62,22 @@ func (ca *MngAdapter) TriggerHostDel(clusterName string, hosts []string) error {
 func (ca *MngAdapter) TriggerHostAppend(clusterName string, hostAppend []v2.Host) error {
 	return ca.AppendClusterHosts(clusterName, hostAppend)
 }
+
+// hostsUpdater updates the hosts of a cluster, it is implemented by the MngAdapter
+type hostsUpdater interface {
+	TriggerClusterHostUpdate(clusterName string, hosts []v2.Host) error
+	TriggerHostDel(clusterName string, hosts []string) error
+	TriggerHostAppend(clusterName string, hostAppend []v2.Host) error
+}
+
+var errClusterManagerNotCreated = errors.New("cluster manager is not created")
+
+// getHostsUpdater returns the adapter if the cluster manager is created.
+// the clusters that discover hosts by themselves should call it when the hosts are updated,
+// as they may be created before the cluster manager.
+func getHostsUpdater() (hostsUpdater, error) {
+	if getClusterManager() == nil {
+		return nil, errClusterManagerNotCreated
+	}
+	return GetClusterMngAdapterInstance(), nil
+}
@@ -23,6 +23,8 @@ import (
 	"fmt"
 	"reflect"
 	"sort"
+	"strconv"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -34,6 +36,7 @@ import (
 	"mosn.io/mosn/pkg/mtls"
 	"mosn.io/mosn/pkg/protocol"
 	"mosn.io/mosn/pkg/types"
+	"mosn.io/pkg/utils"
 )
 
 var errNilCluster = errors.New("cannot update nil cluster")
@@ -63,9 +66,11 @@ const globalTLSMetrics = "global"
 type clusterManager struct {
 	clustersMap      sync.Map
 	protocolConnPool *connPool
+	prewarmers       sync.Map // cluster name: *connPoolPrewarmer
 	tlsMetrics       *mtls.TLSStats
 	tlsMng           atomic.Value // store types.TLSClientContextManager
 	mux              sync.Mutex
+	healthChanged    sync.Map // cluster name: struct{}, the health changes waiting for refreshing
 }
 
 type connPool struct {
@@ -105,14 +110,18 @@ func (p *connPool) load(proto types.ProtocolName, snapshot types.ClusterSnapshot
 func (p *connPool) shutdown(proto types.ProtocolName, addr string) {
 	shutdownPool := func(value interface{}) {
 		connectionPool := value.(*sync.Map)
-		if cp, ok := connectionPool.Load(addr); ok {
-			pool := cp.(types.ConnectionPool)
-			connectionPool.Delete(addr)
-			pool.Shutdown()
+		// the pools owned by the downstream connections are keyed by the address with the connection id
+		connectionPool.Range(func(key, cp interface{}) bool {
+			if k := key.(string); k != addr && !strings.HasPrefix(k, addr+"#") {
+				return true
+			}
+			connectionPool.Delete(key)
+			cp.(types.ConnectionPool).Shutdown()
 			if log.DefaultLogger.GetLogLevel() >= log.DEBUG {
-				log.DefaultLogger.Debugf("[upstream] [cluster manager] protocol %s address %s connections shutdown", proto, addr)
+				log.DefaultLogger.Debugf("[upstream] [cluster manager] protocol %s address %s connections shutdown", proto, key)
 			}
-		}
+			return true
+		})
 	}
 	if proto == "" {
 		p.clusterPool.Range(func(_, clusterProtoPool interface{}) bool {
@@ -146,12 +155,15 @@ func (p *connPool) shutdown(proto types.ProtocolName, addr string) {
 type clusterManagerSingleton struct {
 	instanceMutex sync.Mutex
 	*clusterManager
+	// instance stores the *clusterManager, it can be read without the instance mutex
+	instance atomic.Value
 }
 
 func (singleton *clusterManagerSingleton) Destroy() {
 	clusterManagerInstance.instanceMutex.Lock()
 	defer clusterManagerInstance.instanceMutex.Unlock()
 	clusterManagerInstance.clusterManager = nil
+	clusterManagerInstance.instance.Store((*clusterManager)(nil))
 }
 
 var clusterManagerInstance = &clusterManagerSingleton{}
@@ -166,6 +178,7 @@ func NewClusterManagerSingleton(clusters []v2.Cluster, clusterMap map[string][]v
 	clusterManagerInstance.clusterManager = &clusterManager{
 		tlsMetrics: mtls.NewStats(globalTLSMetrics),
 	}
+	clusterManagerInstance.instance.Store(clusterManagerInstance.clusterManager)
 	if config == nil {
 		config = &v2.ClusterManagerConfig{}
 	}
@@ -311,6 +324,8 @@ func (cm *clusterManager) UpdateCluster(cluster v2.Cluster, clusterHandler types
 	}
 	cm.clustersMap.Store(clusterName, newCluster)
 	refreshHostsConfig(newCluster)
+	cm.refreshAggregateClusters(clusterName)
+	cm.updatePrewarmer(cluster, newCluster)
 	if log.DefaultLogger.GetLogLevel() >= log.INFO {
 		log.DefaultLogger.Infof("[cluster] [cluster manager] [AddOrUpdatePrimaryCluster] cluster %s updated", clusterName)
 	}
@@ -356,6 +371,8 @@ func (cm *clusterManager) RemovePrimaryCluster(clusterNames ...string) error {
 
 		cm.clustersMap.Delete(clusterName)
 		configmanager.SetRemoveClusterConfig(clusterName)
+		cm.refreshAggregateClusters(clusterName)
+		cm.removePrewarmer(clusterName)
 		if log.DefaultLogger.GetLogLevel() >= log.INFO {
 			log.DefaultLogger.Infof("[upstream] [cluster manager] Remove Primary Cluster, Cluster Name = %s", clusterName)
 		}
@@ -446,10 +463,82 @@ func (cm *clusterManager) UpdateHosts(clusterName string, hostConfigs []v2.Host,
 	if hostHandler != nil {
 		hostHandler(c, hostConfigs)
 	}
+	// the aggregate clusters are refreshed by the hosts update of the cluster
 	refreshHostsConfig(c)
+	cm.triggerPrewarm(clusterName)
 	return nil
 }
 
+func (cm *clusterManager) getCluster(clusterName string) types.Cluster {
+	if v, ok := cm.clustersMap.Load(clusterName); ok {
+		return v.(types.Cluster)
+	}
+	return nil
+}
+
+// refreshAggregateClusters refreshes the aggregate clusters that reference the changed cluster,
+// or the changed cluster itself if it is an aggregate cluster.
+func (cm *clusterManager) refreshAggregateClusters(clusterName string) {
+	cm.clustersMap.Range(func(_, v interface{}) bool {
+		if ac, ok := v.(*aggregateCluster); ok && (ac.info.Name() == clusterName || ac.references(clusterName)) {
+			ac.refresh(cm.getCluster)
+		}
+		return true
+	})
+}
+
+// refreshReferencingClusters refreshes the aggregate clusters that reference the changed cluster.
+// the changed aggregate cluster is ignored, as the nested aggregate cluster is not supported.
+func (cm *clusterManager) refreshReferencingClusters(clusterName string) {
+	if _, ok := cm.getCluster(clusterName).(*aggregateCluster); ok {
+		return
+	}
+	cm.clustersMap.Range(func(_, v interface{}) bool {
+		if ac, ok := v.(*aggregateCluster); ok && ac.references(clusterName) {
+			ac.refresh(cm.getCluster)
+		}
+		return true
+	})
+}
+
+// getClusterManager returns the cluster manager instance, or nil if it is not created.
+// it reads the instance atomically, as the instance mutex is held while the clusters are added in NewClusterManagerSingleton.
+func getClusterManager() *clusterManager {
+	cm, _ := clusterManagerInstance.instance.Load().(*clusterManager)
+	return cm
+}
+
+// onClusterHostsChanged is called when the hosts of the cluster are updated
+func onClusterHostsChanged(clusterName string) {
+	if cm := getClusterManager(); cm != nil {
+		cm.refreshReferencingClusters(clusterName)
+	}
+}
+
+// onClusterHealthChanged is called when the health of the cluster's hosts is changed by
+// the health checker or the outlier detector, the load of the priority levels and the referencing
+// clusters are refreshed asynchronously as it may be called in the request path.
+// the changes are coalesced, it does not refresh again if a refresh of the cluster is waiting.
+func onClusterHealthChanged(clusterName string) {
+	cm := getClusterManager()
+	if cm == nil {
+		return
+	}
+	if _, loaded := cm.healthChanged.LoadOrStore(clusterName, struct{}{}); loaded {
+		return
+	}
+	utils.GoWithRecover(func() {
+		// the changes after this point trigger a new refresh
+		cm.healthChanged.Delete(clusterName)
+		if c := cm.getCluster(clusterName); c != nil {
+			if lb, ok := c.Snapshot().LoadBalancer().(*priorityLoadBalancer); ok {
+				lb.refreshLoad()
+			}
+		}
+		cm.refreshReferencingClusters(clusterName)
+	}, nil)
+}
+
 // GetClusterSnapshot returns cluster snap
 func (cm *clusterManager) GetClusterSnapshot(ctx context.Context, clusterName string) types.ClusterSnapshot {
 	ci, ok := cm.clustersMap.Load(clusterName)
@@ -470,7 +559,11 @@ func (cm *clusterManager) TCPConnForCluster(lbCtx types.LoadBalancerContext, sna
 	if host == nil {
 		return types.CreateConnectionData{}
 	}
-	return host.CreateConnection(context.Background())
+	ctx := context.Background()
+	if lbCtx != nil && lbCtx.DownstreamContext() != nil {
+		ctx = lbCtx.DownstreamContext()
+	}
+	return host.CreateConnection(ctx)
 }
 
 func (cm *clusterManager) UDPConnForCluster(lbCtx types.LoadBalancerContext, snapshot types.ClusterSnapshot) types.CreateConnectionData {
@@ -546,6 +639,89 @@ var (
 	errNoHealthyHost   = errors.New("no health hosts")
 )
 
+// chooseHost chooses a host by the load balancer. If the context decides the chosen host should be skipped,
+// such as it has been attempted in the retries, the host is chosen again in the limited times,
+// and the last chosen host is used if no other host is found.
+func chooseHost(lb types.LoadBalancer, balancerContext types.LoadBalancerContext) types.Host {
+	host := lb.ChooseHost(balancerContext)
+	predicate, ok := balancerContext.(types.HostPredicateContext)
+	if !ok {
+		return host
+	}
+	for i := 0; i < predicate.HostSelectionRetryCount() && host != nil && predicate.ShouldSelectAnotherHost(host); i++ {
+		next := lb.ChooseHost(balancerContext)
+		if next == nil {
+			break
+		}
+		host = next
+	}
+	return host
+}
+
+// loadOrStoreConnPool returns the host's connection pool in the connectionPool, and creates one if it is not exists.
+// we cannot use sync.Map.LoadOrStore directly, because we do not want to new a connpool every time
+func (cm *clusterManager) loadOrStoreConnPool(ctx context.Context, factory types.NewConnPool, connectionPool *sync.Map, host types.Host) (types.ConnectionPool, bool) {
+	key, downstream := connPoolKey(ctx, host)
+	// avoid locking if it is already exists
+	if connPool, ok := connectionPool.Load(key); ok {
+		pool := connPool.(types.ConnectionPool)
+		return pool, true
+	}
+	cm.mux.Lock()
+	defer cm.mux.Unlock()
+	if connPool, ok := connectionPool.Load(key); ok {
+		pool := connPool.(types.ConnectionPool)
+		return pool, true
+	}
+	pool := factory(ctx, host)
+	connectionPool.Store(key, pool)
+	if downstream != nil {
+		downstream.AddConnectionEventListener(&downstreamConnPoolCleaner{
+			cm:             cm,
+			connectionPool: connectionPool,
+			key:            key,
+		})
+	}
+	return pool, false
+}
+
+// connPoolKey returns the key of the host's connection pool.
+// The upstream connections carry the downstream addresses if the PROXY protocol is configured,
+// so the connection pool is owned by the downstream connection, which is returned.
+func connPoolKey(ctx context.Context, host types.Host) (string, api.Connection) {
+	addr := host.AddressString()
+	if host.ClusterInfo().ProxyProtocol() == nil {
+		return addr, nil
+	}
+	downstream := getDownstreamConnection(ctx)
+	if downstream == nil {
+		return addr, nil
+	}
+	return addr + "#" + strconv.FormatUint(downstream.ID(), 10), downstream
+}
+
+// downstreamConnPoolCleaner shuts down the connection pool owned by the downstream connection when it is closed
+type downstreamConnPoolCleaner struct {
+	cm             *clusterManager
+	connectionPool *sync.Map
+	key            string
+}
+
+func (c *downstreamConnPoolCleaner) OnEvent(event api.ConnectionEvent) {
+	if !event.IsClose() {
+		return
+	}
+	c.cm.mux.Lock()
+	connPool, ok := c.connectionPool.Load(c.key)
+	if ok {
+		c.connectionPool.Delete(c.key)
+	}
+	c.cm.mux.Unlock()
+	if ok {
+		connPool.(types.ConnectionPool).Shutdown()
+	}
+}
+
 func (cm *clusterManager) getActiveConnectionPool(balancerContext types.LoadBalancerContext, clusterSnapshot types.ClusterSnapshot, proto types.ProtocolName) (types.ConnectionPool, types.Host, error) {
 	factory, ok := protocol.GetNewPoolFactory(proto)
 	if !ok {
@@ -566,12 +742,13 @@ func (cm *clusterManager) getActiveConnectionPool(balancerContext types.LoadBala
 		try = maxHostsCounts
 	}
 	for i := 0; i < try; i++ {
-		host := clusterSnapshot.LoadBalancer().ChooseHost(balancerContext)
+		host := chooseHost(clusterSnapshot.LoadBalancer(), balancerContext)
 		if host == nil {
 			return nil, nil, errNilHostChoose
 		}
 
 		addr := host.AddressString()
+		key, _ := connPoolKey(balancerContext.DownstreamContext(), host)
 		if log.DefaultLogger.GetLogLevel() >= log.DEBUG {
 			log.DefaultLogger.Debugf("[upstream] [cluster manager] clusterSnapshot.loadbalancer.ChooseHost result is %s, cluster name = %s", addr, clusterSnapshot.ClusterInfo().Name())
 		}
@@ -579,24 +756,7 @@ func (cm *clusterManager) getActiveConnectionPool(balancerContext types.LoadBala
 		if !ok {
 			return nil, nil, errUnknownProtocol
 		}
-		// we cannot use sync.Map.LoadOrStore directly, because we do not want to new a connpool every time
-		loadOrStoreConnPool := func() (types.ConnectionPool, bool) {
-			// avoid locking if it is already exists
-			if connPool, ok := connectionPool.Load(addr); ok {
-				pool := connPool.(types.ConnectionPool)
-				return pool, true
-			}
-			cm.mux.Lock()
-			defer cm.mux.Unlock()
-			if connPool, ok := connectionPool.Load(addr); ok {
-				pool := connPool.(types.ConnectionPool)
-				return pool, true
-			}
-			pool := factory(balancerContext.DownstreamContext(), host)
-			connectionPool.Store(addr, pool)
-			return pool, false
-		}
-		pool, loaded := loadOrStoreConnPool()
+		pool, loaded := cm.loadOrStoreConnPool(balancerContext.DownstreamContext(), factory, connectionPool, host)
 		if loaded {
 			if !pool.TLSHashValue().Equal(host.TLSHashValue()) {
 				if log.DefaultLogger.GetLogLevel() >= log.INFO {
@@ -607,15 +767,15 @@ func (cm *clusterManager) getActiveConnectionPool(balancerContext types.LoadBala
 					cm.mux.Lock()
 					defer cm.mux.Unlock()
 					// recheck whether the pool is changed
-					if connPool, ok := connectionPool.Load(addr); ok {
+					if connPool, ok := connectionPool.Load(key); ok {
 						pool = connPool.(types.ConnectionPool)
 						if pool.TLSHashValue().Equal(host.TLSHashValue()) {
 							return
 						}
-						connectionPool.Delete(addr)
+						connectionPool.Delete(key)
 						pool.Shutdown()
 						pool = factory(balancerContext.DownstreamContext(), host)
-						connectionPool.Store(addr, pool)
+						connectionPool.Store(key, pool)
 						cm.tlsMetrics.TLSConnpoolChanged.Inc(1)
 					}
 				}()
@@ -197,6 +197,57 @@ func clusterPoolExists(addr string, snap types.ClusterSnapshot) bool {
 	return hasClusterPool
 }
 
+type mockPredicateLbContext struct {
+	types.LoadBalancerContext
+	skip     map[string]bool
+	attempts int
+	checked  int
+}
+
+func (ctx *mockPredicateLbContext) AttemptedHosts() []types.Host {
+	return nil
+}
+
+func (ctx *mockPredicateLbContext) ShouldSelectAnotherHost(host types.Host) bool {
+	ctx.checked++
+	return ctx.skip[host.AddressString()]
+}
+
+func (ctx *mockPredicateLbContext) HostSelectionRetryCount() int {
+	return ctx.attempts
+}
+
+func TestChooseHostWithPredicate(t *testing.T) {
+	hosts := newRingHashTestHosts("choose_host_predicate", 0, 1, 1, 1)
+	lb := NewLoadBalancer(NewClusterInfo(v2.Cluster{Name: "choose_host_predicate", LbType: v2.LB_ROUNDROBIN}), NewHostSet(hosts))
+	first := lb.ChooseHost(nil)
+	// not a predicate context
+	require.NotNil(t, chooseHost(lb, newMockLbContext(nil)))
+	// skips the first host
+	ctx := &mockPredicateLbContext{
+		LoadBalancerContext: newMockLbContext(nil),
+		skip:                map[string]bool{first.AddressString(): true},
+		attempts:            2,
+	}
+	for i := 0; i < 10; i++ {
+		host := chooseHost(lb, ctx)
+		require.NotNil(t, host)
+		require.NotEqual(t, first.AddressString(), host.AddressString())
+	}
+	// all hosts are skipped, returns the last chosen host in the max attempts
+	for _, h := range hosts {
+		ctx.skip[h.AddressString()] = true
+	}
+	ctx.checked = 0
+	require.NotNil(t, chooseHost(lb, ctx))
+	require.Equal(t, 2, ctx.checked)
+	// no attempts
+	ctx.attempts = 0
+	ctx.checked = 0
+	require.NotNil(t, chooseHost(lb, ctx))
+	require.Equal(t, 0, ctx.checked)
+}
+
 func TestClusterManager_ShutdownConnectionPool(t *testing.T) {
 	h := v2.Host{
 		HostConfig: v2.HostConfig{
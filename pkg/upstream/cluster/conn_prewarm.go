@@ -0,0 +1,216 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cluster
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	v2 "mosn.io/mosn/pkg/config/v2"
+	"mosn.io/mosn/pkg/log"
+	"mosn.io/mosn/pkg/protocol"
+	"mosn.io/mosn/pkg/types"
+	"mosn.io/pkg/utils"
+)
+
+const (
+	defaultPrewarmInterval = 5 * time.Second
+	// the max number of the hosts that are prewarmed at the same time
+	maxPrewarmConcurrency = 16
+)
+
+// connPoolPrewarmer establishes the connections to the healthy hosts of a cluster ahead of the requests.
+// The connection pools are prewarmed when the cluster is created, the hosts are changed or a host becomes healthy,
+// and are checked periodically to keep the connections at the min connections.
+type connPoolPrewarmer struct {
+	cm          *clusterManager
+	clusterName string
+	protocols   []types.ProtocolName
+	minConns    int
+	interval    time.Duration
+	trigger     chan struct{}
+	stop        chan struct{}
+	stopOnce    sync.Once
+}
+
+func newConnPoolPrewarmer(cm *clusterManager, clusterConfig v2.Cluster) *connPoolPrewarmer {
+	p := &connPoolPrewarmer{
+		cm:          cm,
+		clusterName: clusterConfig.Name,
+		minConns:    int(clusterConfig.MinConnectionsPerHost),
+		interval:    defaultPrewarmInterval,
+		trigger:     make(chan struct{}, 1),
+		stop:        make(chan struct{}),
+	}
+	if p.minConns == 0 {
+		p.minConns = 1
+	}
+	if clusterConfig.Prewarm.Interval != nil && clusterConfig.Prewarm.Interval.Duration > 0 {
+		p.interval = clusterConfig.Prewarm.Interval.Duration
+	}
+	for _, proto := range clusterConfig.Prewarm.Protocols {
+		if _, ok := protocol.GetNewPoolFactory(proto); !ok {
+			log.DefaultLogger.Errorf("[upstream] [conn pool prewarm] cluster %s prewarm protocol %s is not registered", p.clusterName, proto)
+			continue
+		}
+		p.protocols = append(p.protocols, proto)
+	}
+	return p
+}
+
+func (p *connPoolPrewarmer) start() {
+	utils.GoWithRecover(func() {
+		p.run()
+	}, nil)
+}
+
+func (p *connPoolPrewarmer) run() {
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+	for {
+		p.prewarm()
+		select {
+		case <-p.stop:
+			return
+		case <-ticker.C:
+		case <-p.trigger:
+		}
+	}
+}
+
+// Trigger prewarms the connection pools as soon as possible, it does not block
+func (p *connPoolPrewarmer) Trigger() {
+	select {
+	case p.trigger <- struct{}{}:
+	default:
+	}
+}
+
+func (p *connPoolPrewarmer) Stop() {
+	p.stopOnce.Do(func() {
+		close(p.stop)
+	})
+}
+
+func (p *connPoolPrewarmer) stopped() bool {
+	select {
+	case <-p.stop:
+		return true
+	default:
+		return false
+	}
+}
+
+// onHealthCheck prewarms the host that becomes healthy
+func (p *connPoolPrewarmer) onHealthCheck(host types.Host, changed bool, isHealthy bool) {
+	if changed && isHealthy {
+		p.Trigger()
+	}
+}
+
+func (p *connPoolPrewarmer) prewarm() {
+	c := p.cm.getCluster(p.clusterName)
+	if c == nil {
+		return
+	}
+	snapshot := c.Snapshot()
+	// the connections are created synchronously, the hosts are prewarmed concurrently
+	// so a slow host does not delay the others
+	sem := make(chan struct{}, maxPrewarmConcurrency)
+	wg := sync.WaitGroup{}
+	snapshot.HostSet().Range(func(host types.Host) bool {
+		// the unhealthy hosts are not prewarmed, the requests will not be sent to them
+		if !host.Health() {
+			return true
+		}
+		select {
+		case <-p.stop:
+			return false
+		case sem <- struct{}{}:
+		}
+		wg.Add(1)
+		utils.GoWithRecover(func() {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			for _, proto := range p.protocols {
+				p.prewarmHost(snapshot, proto, host)
+			}
+		}, nil)
+		return true
+	})
+	wg.Wait()
+}
+
+// prewarmHost keeps the connections of the host's pool at the min connections, returns the number of the new connections
+func (p *connPoolPrewarmer) prewarmHost(snapshot types.ClusterSnapshot, proto types.ProtocolName, host types.Host) int {
+	factory, ok := protocol.GetNewPoolFactory(proto)
+	if !ok {
+		return 0
+	}
+	connectionPool, ok := p.cm.protocolConnPool.load(proto, snapshot)
+	if !ok {
+		return 0
+	}
+	pool, _ := p.cm.loadOrStoreConnPool(context.Background(), factory, connectionPool, host)
+	// the pool will be replaced in the request path if the tls config is changed
+	if !pool.TLSHashValue().Equal(host.TLSHashValue()) {
+		return 0
+	}
+	prewarmer, ok := pool.(types.ConnectionPoolPrewarmer)
+	if !ok {
+		return 0
+	}
+	created := prewarmer.Prewarm(context.Background(), p.minConns)
+	if created > 0 && log.DefaultLogger.GetLogLevel() >= log.DEBUG {
+		log.DefaultLogger.Debugf("[upstream] [conn pool prewarm] cluster %s protocol %s host %s prewarmed %d connections",
+			p.clusterName, proto, host.AddressString(), created)
+	}
+	return created
+}
+
+// updatePrewarmer replaces the cluster's prewarmer with a new one if the prewarm is configured
+func (cm *clusterManager) updatePrewarmer(clusterConfig v2.Cluster, c types.Cluster) {
+	cm.removePrewarmer(clusterConfig.Name)
+	if clusterConfig.Prewarm == nil {
+		return
+	}
+	// the connection pools are owned by the downstream connections, the prewarmed pool is never used
+	if clusterConfig.ProxyProtocol != nil {
+		log.DefaultLogger.Warnf("[upstream] [conn pool prewarm] cluster %s prewarm is ignored, it is not supported with proxy protocol", clusterConfig.Name)
+		return
+	}
+	prewarmer := newConnPoolPrewarmer(cm, clusterConfig)
+	c.AddHealthCheckCallbacks(prewarmer.onHealthCheck)
+	cm.prewarmers.Store(clusterConfig.Name, prewarmer)
+	prewarmer.start()
+}
+
+func (cm *clusterManager) removePrewarmer(clusterName string) {
+	if v, ok := cm.prewarmers.LoadAndDelete(clusterName); ok {
+		v.(*connPoolPrewarmer).Stop()
+	}
+}
+
+func (cm *clusterManager) triggerPrewarm(clusterName string) {
+	if v, ok := cm.prewarmers.Load(clusterName); ok {
+		v.(*connPoolPrewarmer).Trigger()
+	}
+}
@@ -0,0 +1,210 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cluster
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"mosn.io/api"
+	v2 "mosn.io/mosn/pkg/config/v2"
+	"mosn.io/mosn/pkg/protocol"
+	"mosn.io/mosn/pkg/types"
+)
+
+const mockPrewarmProtocol = types.ProtocolName("mock_prewarm")
+
+type mockPrewarmConnPool struct {
+	mockConnPool
+	mutex sync.Mutex
+	conns int
+}
+
+// mockPrewarmCounter records the max number of the pools that are prewarmed at the same time
+type mockPrewarmCounter struct {
+	mutex  sync.Mutex
+	delay  time.Duration
+	active int
+	max    int
+}
+
+var prewarmCounter = &mockPrewarmCounter{}
+
+func (c *mockPrewarmCounter) reset(delay time.Duration) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.delay, c.active, c.max = delay, 0, 0
+}
+
+func (c *mockPrewarmCounter) enter() time.Duration {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.active++
+	if c.active > c.max {
+		c.max = c.active
+	}
+	return c.delay
+}
+
+func (c *mockPrewarmCounter) leave() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.active--
+}
+
+func (c *mockPrewarmCounter) maxActive() int {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.max
+}
+
+func (p *mockPrewarmConnPool) Prewarm(ctx context.Context, minConns int) int {
+	time.Sleep(prewarmCounter.enter())
+	defer prewarmCounter.leave()
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if p.conns >= minConns {
+		return 0
+	}
+	created := minConns - p.conns
+	p.conns = minConns
+	return created
+}
+
+func (p *mockPrewarmConnPool) connections() int {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	return p.conns
+}
+
+func init() {
+	protocol.RegisterProtocol(mockPrewarmProtocol, func(ctx context.Context, h types.Host) types.ConnectionPool {
+		pool := &mockPrewarmConnPool{}
+		pool.hashvalue = h.TLSHashValue()
+		pool.host.Store(h)
+		return pool
+	}, &mockStreamConnFactory{}, nil)
+}
+
+func TestConnPoolPrewarm(t *testing.T) {
+	_createClusterManager()
+	cm := clusterManagerInstance
+	clusterConfig := v2.Cluster{
+		Name:                  "prewarm",
+		LbType:                v2.LB_ROUNDROBIN,
+		MinConnectionsPerHost: 2,
+		Prewarm: &v2.PrewarmConfig{
+			Protocols: []api.ProtocolName{mockPrewarmProtocol, "unknown"},
+			Interval:  &api.DurationConfig{Duration: 10 * time.Millisecond},
+		},
+	}
+	require.Nil(t, cm.AddOrUpdateClusterAndHost(clusterConfig, newAggregateTestHosts("127.0.0.1:8080")))
+	defer cm.RemovePrimaryCluster("prewarm")
+	connections := func(addr string) int {
+		snapshot := cm.GetClusterSnapshot(context.Background(), "prewarm")
+		connectionPool, ok := cm.protocolConnPool.load(mockPrewarmProtocol, snapshot)
+		require.True(t, ok)
+		pool, ok := connectionPool.Load(addr)
+		if !ok {
+			return 0
+		}
+		return pool.(*mockPrewarmConnPool).connections()
+	}
+	// prewarmed when the cluster is created
+	require.Eventually(t, func() bool {
+		return connections("127.0.0.1:8080") == 2
+	}, time.Second, 10*time.Millisecond)
+
+	// the unhealthy hosts are not prewarmed
+	require.Nil(t, cm.AppendClusterHosts("prewarm", newAggregateTestHosts("127.0.0.2:8080")))
+	var newHost types.Host
+	cm.GetClusterSnapshot(context.Background(), "prewarm").HostSet().Range(func(host types.Host) bool {
+		if host.AddressString() == "127.0.0.2:8080" {
+			newHost = host
+		}
+		return true
+	})
+	require.NotNil(t, newHost)
+	newHost.SetHealthFlag(api.FAILED_ACTIVE_HC)
+	// the new host may be prewarmed before it is marked unhealthy
+	time.Sleep(20 * time.Millisecond)
+	pool, loaded := cm.protocolConnPool.load(mockPrewarmProtocol, cm.GetClusterSnapshot(context.Background(), "prewarm"))
+	require.True(t, loaded)
+	pool.Delete("127.0.0.2:8080")
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, 0, connections("127.0.0.2:8080"))
+	// prewarmed after the host becomes healthy
+	newHost.ClearHealthFlag(api.FAILED_ACTIVE_HC)
+	require.Eventually(t, func() bool {
+		return connections("127.0.0.2:8080") == 2
+	}, time.Second, 10*time.Millisecond)
+
+	// the prewarmer is replaced when the cluster is updated
+	v, ok := cm.prewarmers.Load("prewarm")
+	require.True(t, ok)
+	oldPrewarmer := v.(*connPoolPrewarmer)
+	clusterConfig.Prewarm = nil
+	require.Nil(t, cm.AddOrUpdatePrimaryCluster(clusterConfig))
+	assert.True(t, oldPrewarmer.stopped())
+	_, ok = cm.prewarmers.Load("prewarm")
+	assert.False(t, ok)
+}
+
+func TestConnPoolPrewarmConcurrency(t *testing.T) {
+	_createClusterManager()
+	cm := clusterManagerInstance
+	prewarmCounter.reset(50 * time.Millisecond)
+	defer prewarmCounter.reset(0)
+	addrs := make([]string, 0, 2*maxPrewarmConcurrency)
+	for i := 0; i < 2*maxPrewarmConcurrency; i++ {
+		addrs = append(addrs, fmt.Sprintf("127.0.0.1:%d", 9000+i))
+	}
+	require.Nil(t, cm.AddOrUpdateClusterAndHost(v2.Cluster{
+		Name:   "prewarm_concurrency",
+		LbType: v2.LB_ROUNDROBIN,
+		Prewarm: &v2.PrewarmConfig{
+			Protocols: []api.ProtocolName{mockPrewarmProtocol},
+			Interval:  &api.DurationConfig{Duration: time.Hour},
+		},
+	}, newAggregateTestHosts(addrs...)))
+	defer cm.RemovePrimaryCluster("prewarm_concurrency")
+	connections := func() int {
+		snapshot := cm.GetClusterSnapshot(context.Background(), "prewarm_concurrency")
+		connectionPool, ok := cm.protocolConnPool.load(mockPrewarmProtocol, snapshot)
+		require.True(t, ok)
+		total := 0
+		for _, addr := range addrs {
+			if pool, ok := connectionPool.Load(addr); ok {
+				total += pool.(*mockPrewarmConnPool).connections()
+			}
+		}
+		return total
+	}
+	// the hosts are prewarmed concurrently, it takes 1.6s if they are prewarmed one by one
+	require.Eventually(t, func() bool {
+		return connections() == len(addrs)
+	}, time.Second, 10*time.Millisecond)
+	assert.Greater(t, prewarmCounter.maxActive(), 1)
+	assert.LessOrEqual(t, prewarmCounter.maxActive(), maxPrewarmConcurrency)
+}
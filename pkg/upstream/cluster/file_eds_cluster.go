@@ -0,0 +1,243 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cluster
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/ghodss/yaml"
+
+	v2 "mosn.io/mosn/pkg/config/v2"
+	"mosn.io/mosn/pkg/log"
+	"mosn.io/mosn/pkg/types"
+	"mosn.io/pkg/utils"
+)
+
+func init() {
+	RegisterClusterType(v2.FILE_EDS_CLUSTER, newFileEdsCluster)
+}
+
+// the interval to retry when the watcher or the hosts update is failed
+var fileEdsRetryInterval = time.Second
+
+// fileEdsCluster watches the hosts file, and updates the hosts by the cluster manager when the file changes.
+// The hosts are updated only if all of the files are loaded successfully, otherwise the last hosts are kept.
+type fileEdsCluster struct {
+	*simpleCluster
+	path string
+	// updater updates the hosts of the cluster, the cluster manager adapter is used if it is nil
+	updater hostsUpdater
+	// the last hosts that are updated successfully
+	lastHosts []v2.Host
+	mutex     sync.Mutex
+	stop      chan struct{}
+	stopOnce  sync.Once
+}
+
+func newFileEdsCluster(clusterConfig v2.Cluster) types.Cluster {
+	cluster := &fileEdsCluster{
+		simpleCluster: newSimpleCluster(clusterConfig).(*simpleCluster),
+		path:          clusterConfig.EdsFilePath,
+		stop:          make(chan struct{}),
+	}
+	if cluster.path == "" {
+		log.DefaultLogger.Errorf("[upstream] [file eds cluster] cluster %s eds file path is not configured", clusterConfig.Name)
+		return cluster
+	}
+	utils.GoWithRecover(func() {
+		cluster.watch()
+	}, nil)
+	return cluster
+}
+
+func (fc *fileEdsCluster) StopHealthChecking() {
+	fc.stopOnce.Do(func() {
+		close(fc.stop)
+	})
+	fc.simpleCluster.StopHealthChecking()
+}
+
+func (fc *fileEdsCluster) stopped() bool {
+	select {
+	case <-fc.stop:
+		return true
+	default:
+		return false
+	}
+}
+
+// watch loads the hosts at first, and reloads the hosts when the files change.
+// the directory of the file is watched, so the file can be replaced by renaming.
+func (fc *fileEdsCluster) watch() {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		log.DefaultLogger.Errorf("[upstream] [file eds cluster] create watcher for %s failed: %v", fc.path, err)
+		return
+	}
+	defer watcher.Close()
+	dir := fc.path
+	if info, err := os.Stat(fc.path); err != nil || !info.IsDir() {
+		dir = filepath.Dir(fc.path)
+	}
+	for {
+		if err := watcher.Add(dir); err == nil {
+			break
+		} else {
+			log.DefaultLogger.Errorf("[upstream] [file eds cluster] watch %s failed: %v", dir, err)
+		}
+		select {
+		case <-fc.stop:
+			return
+		case <-time.After(fileEdsRetryInterval):
+		}
+	}
+	// the cluster may be not added into the cluster manager yet, retry until the hosts are updated
+	retry := fc.reload()
+	for {
+		var retryC <-chan time.Time
+		if retry {
+			retryC = time.After(fileEdsRetryInterval)
+		}
+		select {
+		case <-fc.stop:
+			return
+		case ev, ok := <-watcher.Events:
+			if !ok {
+				return
+			}
+			if dir != fc.path && filepath.Clean(ev.Name) != filepath.Clean(fc.path) {
+				continue
+			}
+			if log.DefaultLogger.GetLogLevel() >= log.DEBUG {
+				log.DefaultLogger.Debugf("[upstream] [file eds cluster] file event: %s", ev.String())
+			}
+			retry = fc.reload()
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return
+			}
+			log.DefaultLogger.Errorf("[upstream] [file eds cluster] watch %s error: %v", fc.path, err)
+		case <-retryC:
+			retry = fc.reload()
+		}
+	}
+}
+
+// reload loads the hosts and updates them if they are changed, returns true if the update should be retried
+func (fc *fileEdsCluster) reload() bool {
+	hosts, err := loadHostsFromPath(fc.path)
+	if err != nil {
+		log.DefaultLogger.Errorf("[upstream] [file eds cluster] cluster %s load hosts failed, keep the last hosts: %v", fc.info.Name(), err)
+		return false
+	}
+	fc.mutex.Lock()
+	defer fc.mutex.Unlock()
+	if fc.stopped() || (fc.lastHosts != nil && reflect.DeepEqual(fc.lastHosts, hosts)) {
+		return false
+	}
+	if err := fc.updateHosts(hosts); err != nil {
+		log.DefaultLogger.Warnf("[upstream] [file eds cluster] cluster %s update hosts failed: %v", fc.info.Name(), err)
+		return true
+	}
+	fc.lastHosts = hosts
+	if log.DefaultLogger.GetLogLevel() >= log.INFO {
+		log.DefaultLogger.Infof("[upstream] [file eds cluster] cluster %s hosts updated, hosts count: %d", fc.info.Name(), len(hosts))
+	}
+	return false
+}
+
+// updateHosts replaces the hosts of the cluster, the cluster manager is resolved here
+// as the cluster may be created before it
+func (fc *fileEdsCluster) updateHosts(hosts []v2.Host) error {
+	updater := fc.updater
+	if updater == nil {
+		var err error
+		if updater, err = getHostsUpdater(); err != nil {
+			return err
+		}
+	}
+	return updater.TriggerClusterHostUpdate(fc.info.Name(), hosts)
+}
+
+// loadHostsFromPath loads the hosts from a file, or all the json and yaml files in a directory
+func loadHostsFromPath(path string) ([]v2.Host, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return loadHostsFromFile(path)
+	}
+	files, err := ioutil.ReadDir(path)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(files))
+	for _, f := range files {
+		ext := strings.ToLower(filepath.Ext(f.Name()))
+		if f.IsDir() || (ext != ".json" && ext != ".yaml" && ext != ".yml") {
+			continue
+		}
+		names = append(names, f.Name())
+	}
+	sort.Strings(names)
+	hosts := []v2.Host{}
+	for _, name := range names {
+		fileHosts, err := loadHostsFromFile(filepath.Join(path, name))
+		if err != nil {
+			return nil, err
+		}
+		hosts = append(hosts, fileHosts...)
+	}
+	return hosts, nil
+}
+
+// loadHostsFromFile loads a list of v2.Host from a json or yaml file
+func loadHostsFromFile(path string) ([]v2.Host, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	ext := strings.ToLower(filepath.Ext(path))
+	if ext == ".yaml" || ext == ".yml" {
+		if content, err = yaml.YAMLToJSON(content); err != nil {
+			return nil, fmt.Errorf("translate yaml file %s to json error: %v", path, err)
+		}
+	}
+	hosts := []v2.Host{}
+	if err := json.Unmarshal(content, &hosts); err != nil {
+		return nil, fmt.Errorf("unmarshal file %s error: %v", path, err)
+	}
+	for _, host := range hosts {
+		if _, _, err := net.SplitHostPort(host.Address); err != nil {
+			return nil, fmt.Errorf("invalid host address %q in file %s: %v", host.Address, path, err)
+		}
+	}
+	return hosts, nil
+}
@@ -0,0 +1,162 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cluster
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	v2 "mosn.io/mosn/pkg/config/v2"
+)
+
+func writeHostsFile(t *testing.T, path, content string) {
+	// write a temp file and rename it, so the file is changed atomically
+	tmp := filepath.Join(filepath.Dir(path), "."+filepath.Base(path)+".tmp")
+	require.Nil(t, ioutil.WriteFile(tmp, []byte(content), 0644))
+	require.Nil(t, os.Rename(tmp, path))
+}
+
+func TestLoadHostsFromPath(t *testing.T) {
+	dir, err := ioutil.TempDir("", "file_eds")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	jsonFile := filepath.Join(dir, "a.json")
+	writeHostsFile(t, jsonFile, `[{"address":"127.0.0.1:8080","weight":2,"metadata":{"filter_metadata":{"mosn.lb":{"zone":"a"}}}}]`)
+	hosts, err := loadHostsFromPath(jsonFile)
+	require.Nil(t, err)
+	require.Len(t, hosts, 1)
+	assert.Equal(t, "127.0.0.1:8080", hosts[0].Address)
+	assert.Equal(t, uint32(2), hosts[0].Weight)
+	assert.Equal(t, "a", hosts[0].MetaData["zone"])
+
+	yamlFile := filepath.Join(dir, "b.yaml")
+	writeHostsFile(t, yamlFile, "- address: 127.0.0.2:8080\n- address: 127.0.0.3:8080\n")
+	hosts, err = loadHostsFromPath(yamlFile)
+	require.Nil(t, err)
+	require.Len(t, hosts, 2)
+
+	// all the hosts files in the directory, the other files are ignored
+	writeHostsFile(t, filepath.Join(dir, "c.txt"), "invalid")
+	hosts, err = loadHostsFromPath(dir)
+	require.Nil(t, err)
+	require.Len(t, hosts, 3)
+	assert.Equal(t, "127.0.0.1:8080", hosts[0].Address)
+	assert.Equal(t, "127.0.0.3:8080", hosts[2].Address)
+
+	// any invalid file fails the whole directory
+	writeHostsFile(t, filepath.Join(dir, "d.json"), `[{"address":"127.0.0.4"}]`)
+	_, err = loadHostsFromPath(dir)
+	assert.NotNil(t, err)
+	writeHostsFile(t, filepath.Join(dir, "d.json"), `{"address":"127.0.0.4:8080"}`)
+	_, err = loadHostsFromPath(dir)
+	assert.NotNil(t, err)
+
+	_, err = loadHostsFromPath(filepath.Join(dir, "not_exists.json"))
+	assert.NotNil(t, err)
+}
+
+type fileEdsUpdates struct {
+	// only the TriggerClusterHostUpdate is used
+	hostsUpdater
+	mutex   sync.Mutex
+	updates [][]v2.Host
+	fail    int
+}
+
+func (u *fileEdsUpdates) TriggerClusterHostUpdate(clusterName string, hosts []v2.Host) error {
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+	if u.fail > 0 {
+		u.fail--
+		return errors.New("cluster not found")
+	}
+	u.updates = append(u.updates, hosts)
+	return nil
+}
+
+func (u *fileEdsUpdates) count() int {
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+	return len(u.updates)
+}
+
+func (u *fileEdsUpdates) last() []v2.Host {
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+	return u.updates[len(u.updates)-1]
+}
+
+func TestFileEdsCluster(t *testing.T) {
+	interval := fileEdsRetryInterval
+	fileEdsRetryInterval = 10 * time.Millisecond
+	defer func() {
+		fileEdsRetryInterval = interval
+	}()
+	dir, err := ioutil.TempDir("", "file_eds")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "hosts.json")
+	writeHostsFile(t, path, `[{"address":"127.0.0.1:8080"}]`)
+
+	c := NewCluster(v2.Cluster{
+		Name:        "file_eds_cluster",
+		ClusterType: v2.FILE_EDS_CLUSTER,
+		LbType:      v2.LB_ROUNDROBIN,
+	})
+	fc, ok := c.(*fileEdsCluster)
+	require.True(t, ok)
+	defer fc.StopHealthChecking()
+	// the cluster is not added into the cluster manager at first
+	updates := &fileEdsUpdates{fail: 2}
+	fc.path = path
+	fc.updater = updates
+	go fc.watch()
+
+	require.Eventually(t, func() bool {
+		return updates.count() == 1
+	}, time.Second, 10*time.Millisecond)
+	assert.Equal(t, "127.0.0.1:8080", updates.last()[0].Address)
+
+	writeHostsFile(t, path, `[{"address":"127.0.0.1:8080"},{"address":"127.0.0.2:8080"}]`)
+	require.Eventually(t, func() bool {
+		return updates.count() == 2
+	}, time.Second, 10*time.Millisecond)
+	assert.Len(t, updates.last(), 2)
+
+	// keep the last hosts if the file is invalid, and the unchanged hosts are not updated
+	writeHostsFile(t, path, `[{"address":"127.0.0.3:8080"},`)
+	writeHostsFile(t, filepath.Join(dir, "other.json"), `[{"address":"127.0.0.4:8080"}]`)
+	writeHostsFile(t, path, `[{"address":"127.0.0.1:8080"},{"address":"127.0.0.2:8080"}]`)
+	time.Sleep(100 * time.Millisecond)
+	assert.Equal(t, 2, updates.count())
+
+	// no more updates after stopped
+	fc.StopHealthChecking()
+	writeHostsFile(t, path, `[{"address":"127.0.0.5:8080"}]`)
+	time.Sleep(100 * time.Millisecond)
+	assert.Equal(t, 2, updates.count())
+}
@@ -42,6 +42,7 @@ type simpleHost struct {
 	metaData                api.Metadata
 	tlsDisable              bool
 	weight                  uint32
+	priority                uint32
 	healthFlags             *uint64
 	lastHealthCheckPassTime time.Time
 }
@@ -57,6 +58,7 @@ func NewSimpleHost(config v2.Host, clusterInfo types.ClusterInfo) types.Host {
 		metaData:      config.MetaData,
 		tlsDisable:    config.TLSDisable,
 		weight:        config.Weight,
+		priority:      config.Priority,
 		healthFlags:   GetHealthFlagPointer(config.Address),
 	}
 	h.clusterInfo.Store(clusterInfo)
@@ -103,6 +105,10 @@ func (sh *simpleHost) Weight() uint32 {
 	return sh.weight
 }
 
+func (sh *simpleHost) Priority() uint32 {
+	return sh.priority
+}
+
 func (sh *simpleHost) Config() v2.Host {
 	return v2.Host{
 		HostConfig: v2.HostConfig{
@@ -110,6 +116,7 @@ func (sh *simpleHost) Config() v2.Host {
 			Hostname:   sh.hostname,
 			TLSDisable: sh.tlsDisable,
 			Weight:     sh.weight,
+			Priority:   sh.priority,
 		},
 		MetaData: sh.metaData,
 	}
@@ -133,11 +140,16 @@ func (sh *simpleHost) TLSHashValue() *types.HashValue {
 
 // types.Host Implement
 func (sh *simpleHost) CreateConnection(context context.Context) types.CreateConnectionData {
+	return sh.createConnection(context, sh.Address())
+}
+
+// createConnection creates a client connection to the address
+func (sh *simpleHost) createConnection(ctx context.Context, addr net.Addr) types.CreateConnectionData {
 	var tlsMng types.TLSClientContextManager
 	if sh.SupportTLS() {
 		tlsMng = sh.ClusterInfo().TLSMng()
 	}
-	clientConn := network.NewClientConnection(sh.ClusterInfo().ConnectTimeout(), tlsMng, sh.Address(), nil)
+	clientConn := network.NewClientConnection(sh.ClusterInfo().ConnectTimeout(), tlsMng, addr, nil)
 	clientConn.SetBufferLimit(sh.ClusterInfo().ConnBufferLimitBytes())
 
 	if sh.ClusterInfo().Mark() != 0 {
@@ -146,6 +158,17 @@ func (sh *simpleHost) CreateConnection(context context.Context) types.CreateConn
 
 	clientConn.SetIdleTimeout(types.DefaultConnReadTimeout, sh.ClusterInfo().IdleTimeout())
 
+	if config := sh.ClusterInfo().ProxyProtocol(); config != nil {
+		if sender, ok := clientConn.(types.ProxyProtocolSender); ok {
+			header, err := newProxyProtocolHeader(ctx, config).Encode()
+			if err != nil {
+				log.DefaultLogger.Errorf("[upstream] [host] cluster %s encode proxy protocol header failed: %v", sh.ClusterInfo().Name(), err)
+			} else {
+				sender.SetProxyProtocolHeader(header)
+			}
+		}
+	}
+
 	return types.CreateConnectionData{
 		Connection: clientConn,
 		Host:       sh,
@@ -153,7 +176,11 @@ func (sh *simpleHost) CreateConnection(context context.Context) types.CreateConn
 }
 
 func (sh *simpleHost) CreateUDPConnection(context context.Context) types.CreateConnectionData {
-	clientConn := network.NewClientConnection(sh.ClusterInfo().ConnectTimeout(), nil, sh.UDPAddress(), nil)
+	return sh.createUDPConnection(sh.UDPAddress())
+}
+
+func (sh *simpleHost) createUDPConnection(addr net.Addr) types.CreateConnectionData {
+	clientConn := network.NewClientConnection(sh.ClusterInfo().ConnectTimeout(), nil, addr, nil)
 	clientConn.SetBufferLimit(sh.ClusterInfo().ConnBufferLimitBytes())
 
 	return types.CreateConnectionData{
@@ -164,7 +191,8 @@ func (sh *simpleHost) CreateUDPConnection(context context.Context) types.CreateC
 
 func (sh *simpleHost) ClearHealthFlag(flag api.HealthFlag) {
 	ClearHealthFlag(sh.healthFlags, flag)
-	if atomic.LoadUint64(sh.healthFlags) == 0 {
+	// the degraded flag does not change the health
+	if flag != types.DEGRADED_ACTIVE_HC && sh.Health() {
 		sh.SetLastHealthCheckPassTime(time.Now())
 	}
 }
@@ -175,7 +203,7 @@ func (sh *simpleHost) ContainHealthFlag(flag api.HealthFlag) bool {
 
 func (sh *simpleHost) SetHealthFlag(flag api.HealthFlag) {
 	SetHealthFlag(sh.healthFlags, flag)
-	if atomic.LoadUint64(sh.healthFlags) == 0 {
+	if flag != types.DEGRADED_ACTIVE_HC && sh.Health() {
 		sh.SetLastHealthCheckPassTime(time.Now())
 	}
 }
@@ -184,8 +212,9 @@ func (sh *simpleHost) HealthFlag() api.HealthFlag {
 	return api.HealthFlag(atomic.LoadUint64(sh.healthFlags))
 }
 
+// Health returns true if the host is not marked failed, a degraded host is still healthy
 func (sh *simpleHost) Health() bool {
-	return atomic.LoadUint64(sh.healthFlags) == 0
+	return atomic.LoadUint64(sh.healthFlags)&^uint64(types.DEGRADED_ACTIVE_HC) == 0
 }
 
 func (sh *simpleHost) LastHealthCheckPassTime() time.Time {
@@ -18,6 +18,7 @@
 package cluster
 
 import (
+	"sort"
 	"strings"
 	"sync"
 
@@ -30,6 +31,9 @@ type hostSet struct {
 	once     sync.Once
 	mux      sync.RWMutex
 	allHosts []types.Host
+
+	levelsOnce sync.Once
+	levels     []types.HostSet
 }
 
 func (hs *hostSet) Size() int {
@@ -54,6 +58,46 @@ func (hs *hostSet) Range(f func(types.Host) bool) {
 	}
 }
 
+// PriorityLevels partitions the hosts by priority lazily, the result is cached
+// as the hosts in a hostSet can not be changed.
+func (hs *hostSet) PriorityLevels() []types.HostSet {
+	hs.levelsOnce.Do(func() {
+		hs.levels = partitionByPriority(hs)
+	})
+	return hs.levels
+}
+
+func partitionByPriority(hs *hostSet) []types.HostSet {
+	var byPriority map[uint32][]types.Host
+	for i, h := range hs.allHosts {
+		p := h.Priority()
+		if byPriority == nil {
+			// fast path: all the hosts have the same priority
+			if p == hs.allHosts[0].Priority() {
+				continue
+			}
+			byPriority = make(map[uint32][]types.Host)
+			byPriority[hs.allHosts[0].Priority()] = append([]types.Host{}, hs.allHosts[:i]...)
+		}
+		byPriority[p] = append(byPriority[p], h)
+	}
+	if byPriority == nil {
+		return []types.HostSet{hs}
+	}
+	priorities := make([]uint32, 0, len(byPriority))
+	for p := range byPriority {
+		priorities = append(priorities, p)
+	}
+	sort.Slice(priorities, func(i, j int) bool {
+		return priorities[i] < priorities[j]
+	})
+	levels := make([]types.HostSet, 0, len(priorities))
+	for _, p := range priorities {
+		levels = append(levels, &hostSet{allHosts: byPriority[p]})
+	}
+	return levels
+}
+
 func (hs *hostSet) String() string {
 	var sb strings.Builder
 	sb.Grow(len(hs.allHosts) * 16)
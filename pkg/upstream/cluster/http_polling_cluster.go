@@ -0,0 +1,364 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cluster
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	v2 "mosn.io/mosn/pkg/config/v2"
+	"mosn.io/mosn/pkg/log"
+	"mosn.io/mosn/pkg/types"
+	"mosn.io/mosn/pkg/upstream/healthcheck"
+	"mosn.io/pkg/utils"
+)
+
+func init() {
+	RegisterClusterType(v2.HTTP_POLLING_CLUSTER, newHttpPollingCluster)
+}
+
+const (
+	defaultHttpPollingInterval = 5 * time.Second
+	defaultHttpPollingTimeout  = 3 * time.Second
+	defaultHttpAddressField    = "address"
+	// the max size of the polling response body
+	maxHttpPollingBodySize = 16 * 1024 * 1024
+)
+
+var errNotModified = errors.New("not modified")
+
+// httpPollingCluster polls the hosts from a http url, and updates the hosts by the cluster manager
+// if they are changed. The first polling replaces all the hosts, and the later ones only add and remove
+// the changed hosts, so the unchanged hosts keep their health states and connections. The request carries the If-None-Match header if the last response has an ETag.
+// The polling interval backs off if the polling is failed, and the last hosts are kept.
+type httpPollingCluster struct {
+	*simpleCluster
+	config     v2.HttpDiscovery
+	client     *http.Client
+	interval   time.Duration
+	maxBackOff time.Duration
+	// updater updates the hosts of the cluster, the cluster manager adapter is used if it is nil
+	updater   hostsUpdater
+	etag      string
+	lastHosts []v2.Host
+	failures  uint32
+	stop      chan struct{}
+	stopOnce  sync.Once
+}
+
+func newHttpPollingCluster(clusterConfig v2.Cluster) types.Cluster {
+	cluster := &httpPollingCluster{
+		simpleCluster: newSimpleCluster(clusterConfig).(*simpleCluster),
+		interval:      defaultHttpPollingInterval,
+		stop:          make(chan struct{}),
+	}
+	if clusterConfig.HttpDiscovery == nil || clusterConfig.HttpDiscovery.URL == "" {
+		log.DefaultLogger.Errorf("[upstream] [http polling cluster] cluster %s http discovery url is not configured", clusterConfig.Name)
+		return cluster
+	}
+	cluster.config = *clusterConfig.HttpDiscovery
+	timeout := defaultHttpPollingTimeout
+	if cluster.config.Timeout != nil && cluster.config.Timeout.Duration > 0 {
+		timeout = cluster.config.Timeout.Duration
+	}
+	cluster.client = &http.Client{Timeout: timeout}
+	if cluster.config.Interval != nil && cluster.config.Interval.Duration > 0 {
+		cluster.interval = cluster.config.Interval.Duration
+	}
+	cluster.maxBackOff = 10 * cluster.interval
+	if cluster.config.MaxBackOff != nil && cluster.config.MaxBackOff.Duration > cluster.interval {
+		cluster.maxBackOff = cluster.config.MaxBackOff.Duration
+	}
+	utils.GoWithRecover(func() {
+		cluster.startPolling()
+	}, nil)
+	return cluster
+}
+
+func (pc *httpPollingCluster) StopHealthChecking() {
+	pc.stopOnce.Do(func() {
+		close(pc.stop)
+	})
+	pc.simpleCluster.StopHealthChecking()
+}
+
+func (pc *httpPollingCluster) startPolling() {
+	for {
+		timer := time.NewTimer(pc.poll())
+		select {
+		case <-pc.stop:
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+	}
+}
+
+// poll fetches and updates the hosts, returns the interval of the next polling
+func (pc *httpPollingCluster) poll() time.Duration {
+	err := pc.fetchAndUpdate()
+	if err == nil || err == errNotModified {
+		pc.failures = 0
+		return pc.interval
+	}
+	pc.failures++
+	log.DefaultLogger.Errorf("[upstream] [http polling cluster] cluster %s polling failed %d times, keep the last hosts: %v",
+		pc.info.Name(), pc.failures, err)
+	return pc.nextBackOff()
+}
+
+// nextBackOff doubles the interval for each failure, and not more than the max back off
+func (pc *httpPollingCluster) nextBackOff() time.Duration {
+	backOff := pc.interval
+	for i := uint32(0); i < pc.failures && backOff < pc.maxBackOff; i++ {
+		backOff *= 2
+	}
+	if backOff > pc.maxBackOff {
+		backOff = pc.maxBackOff
+	}
+	return backOff
+}
+
+func (pc *httpPollingCluster) fetchAndUpdate() error {
+	hosts, etag, err := pc.fetch()
+	if err != nil {
+		return err
+	}
+	if pc.lastHosts != nil && reflect.DeepEqual(pc.lastHosts, hosts) {
+		pc.etag = etag
+		return nil
+	}
+	if err := pc.updateHosts(hosts); err != nil {
+		// the hosts of the cluster are unknown if the update is partially failed, replace all of them in the next polling
+		pc.lastHosts = nil
+		return err
+	}
+	// the etag is saved after the hosts are updated, so the hosts will be fetched again if the update is failed
+	pc.etag = etag
+	pc.lastHosts = hosts
+	return nil
+}
+
+// updateHosts replaces all the hosts at the first time, and then applies the changes to the last hosts
+func (pc *httpPollingCluster) updateHosts(hosts []v2.Host) error {
+	updater := pc.updater
+	if updater == nil {
+		var err error
+		if updater, err = getHostsUpdater(); err != nil {
+			return err
+		}
+	}
+	name := pc.info.Name()
+	if pc.lastHosts == nil {
+		if err := updater.TriggerClusterHostUpdate(name, hosts); err != nil {
+			return err
+		}
+		log.DefaultLogger.Infof("[upstream] [http polling cluster] cluster %s hosts replaced: %v", name, hostAddresses(hosts))
+		return nil
+	}
+	added, removed := diffHosts(pc.lastHosts, hosts)
+	if len(removed) > 0 {
+		if err := updater.TriggerHostDel(name, removed); err != nil {
+			return err
+		}
+	}
+	if len(added) > 0 {
+		if err := updater.TriggerHostAppend(name, added); err != nil {
+			return err
+		}
+	}
+	if log.DefaultLogger.GetLogLevel() >= log.INFO {
+		log.DefaultLogger.Infof("[upstream] [http polling cluster] cluster %s hosts updated, added: %v, removed: %v",
+			name, hostAddresses(added), removed)
+	}
+	return nil
+}
+
+func (pc *httpPollingCluster) fetch() ([]v2.Host, string, error) {
+	req, err := http.NewRequest(http.MethodGet, pc.config.URL, nil)
+	if err != nil {
+		return nil, "", err
+	}
+	for k, v := range pc.config.Headers {
+		req.Header.Set(k, v)
+	}
+	if pc.etag != "" {
+		req.Header.Set("If-None-Match", pc.etag)
+	}
+	resp, err := pc.client.Do(req)
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotModified {
+		return nil, "", errNotModified
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, "", fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+	body, err := ioutil.ReadAll(io.LimitReader(resp.Body, maxHttpPollingBodySize))
+	if err != nil {
+		return nil, "", err
+	}
+	hosts, err := parseHttpDiscoveryHosts(body, &pc.config.Mapping)
+	if err != nil {
+		return nil, "", err
+	}
+	return hosts, resp.Header.Get("ETag"), nil
+}
+
+// parseHttpDiscoveryHosts maps the json response to the hosts, the hosts are sorted by address.
+// any invalid host fails the whole response.
+func parseHttpDiscoveryHosts(body []byte, mapping *v2.HttpDiscoveryMapping) ([]v2.Host, error) {
+	var data interface{}
+	if err := json.Unmarshal(body, &data); err != nil {
+		return nil, err
+	}
+	list, ok := lookupJSONField(data, mapping.Hosts)
+	if !ok {
+		return nil, fmt.Errorf("hosts field %q is not found", mapping.Hosts)
+	}
+	items, ok := list.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("hosts field %q is not a list", mapping.Hosts)
+	}
+	hosts := make([]v2.Host, 0, len(items))
+	for i, item := range items {
+		host, err := mapHttpDiscoveryHost(item, mapping)
+		if err != nil {
+			return nil, fmt.Errorf("host %d is invalid: %v", i, err)
+		}
+		hosts = append(hosts, host)
+	}
+	sort.Slice(hosts, func(i, j int) bool {
+		return hosts[i].Address < hosts[j].Address
+	})
+	return hosts, nil
+}
+
+func mapHttpDiscoveryHost(item interface{}, mapping *v2.HttpDiscoveryMapping) (v2.Host, error) {
+	host := v2.Host{}
+	if mapping.IP != "" && mapping.Port != "" {
+		ip, _ := lookupJSONField(item, mapping.IP)
+		port, _ := lookupJSONField(item, mapping.Port)
+		host.Address = net.JoinHostPort(jsonFieldString(ip), jsonFieldString(port))
+	} else {
+		field := mapping.Address
+		if field == "" {
+			field = defaultHttpAddressField
+		}
+		addr, _ := lookupJSONField(item, field)
+		host.Address = jsonFieldString(addr)
+	}
+	if _, port, err := net.SplitHostPort(host.Address); err != nil || port == "" {
+		return host, fmt.Errorf("invalid address %q", host.Address)
+	}
+	if mapping.Hostname != "" {
+		if hostname, ok := lookupJSONField(item, mapping.Hostname); ok {
+			host.Hostname = jsonFieldString(hostname)
+		}
+	}
+	if mapping.Weight != "" {
+		if weight, ok := lookupJSONField(item, mapping.Weight); ok {
+			w, err := strconv.ParseUint(jsonFieldString(weight), 10, 32)
+			if err != nil {
+				return host, fmt.Errorf("invalid weight %v", weight)
+			}
+			host.Weight = uint32(w)
+		}
+	}
+	if mapping.Metadata != "" {
+		if metadata, ok := lookupJSONField(item, mapping.Metadata); ok {
+			m, ok := metadata.(map[string]interface{})
+			if !ok {
+				return host, fmt.Errorf("invalid metadata %v", metadata)
+			}
+			host.MetaData = make(map[string]string, len(m))
+			for k, v := range m {
+				host.MetaData[k] = jsonFieldString(v)
+			}
+		}
+	}
+	return host, nil
+}
+
+// lookupJSONField returns the field at the dot separated path, empty path returns the data itself
+func lookupJSONField(data interface{}, path string) (interface{}, bool) {
+	if path == "" {
+		return data, true
+	}
+	return healthcheck.LookupJSONPath(data, strings.Split(path, "."))
+}
+
+func jsonFieldString(v interface{}) string {
+	switch value := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return value
+	case float64:
+		return strconv.FormatFloat(value, 'f', -1, 64)
+	default:
+		return fmt.Sprint(value)
+	}
+}
+
+// diffHosts returns the added hosts and the removed addresses,
+// the changed hosts are in both of them, as they are removed and added again
+func diffHosts(oldHosts, newHosts []v2.Host) (added []v2.Host, removed []string) {
+	oldByAddr := make(map[string]v2.Host, len(oldHosts))
+	for _, h := range oldHosts {
+		oldByAddr[h.Address] = h
+	}
+	newAddrs := make(map[string]struct{}, len(newHosts))
+	for _, h := range newHosts {
+		newAddrs[h.Address] = struct{}{}
+		old, ok := oldByAddr[h.Address]
+		if !ok {
+			added = append(added, h)
+		} else if !reflect.DeepEqual(old, h) {
+			removed = append(removed, h.Address)
+			added = append(added, h)
+		}
+	}
+	for _, h := range oldHosts {
+		if _, ok := newAddrs[h.Address]; !ok {
+			removed = append(removed, h.Address)
+		}
+	}
+	return added, removed
+}
+
+func hostAddresses(hosts []v2.Host) []string {
+	addrs := make([]string, 0, len(hosts))
+	for _, h := range hosts {
+		addrs = append(addrs, h.Address)
+	}
+	return addrs
+}
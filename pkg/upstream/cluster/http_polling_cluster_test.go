@@ -0,0 +1,240 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cluster
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"mosn.io/api"
+	v2 "mosn.io/mosn/pkg/config/v2"
+)
+
+type pollingTestServer struct {
+	mutex       sync.Mutex
+	body        string
+	etag        string
+	code        int
+	requests    int
+	notModified int
+}
+
+func (s *pollingTestServer) set(code int, body, etag string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.code, s.body, s.etag = code, body, etag
+}
+
+func (s *pollingTestServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.requests++
+	if r.Header.Get("x-token") != "token" {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+	if s.etag != "" && r.Header.Get("If-None-Match") == s.etag {
+		s.notModified++
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	if s.etag != "" {
+		w.Header().Set("ETag", s.etag)
+	}
+	w.WriteHeader(s.code)
+	w.Write([]byte(s.body))
+}
+
+var pollingTestMapping = v2.HttpDiscoveryMapping{
+	Hosts:    "data.instances",
+	IP:       "ip",
+	Port:     "port",
+	Weight:   "weight",
+	Metadata: "meta",
+}
+
+func TestParseHttpDiscoveryHosts(t *testing.T) {
+	body := `{"data":{"instances":[
+		{"ip":"127.0.0.2","port":8080,"weight":10,"meta":{"zone":"a","version":2}},
+		{"ip":"127.0.0.1","port":"8080"}
+	]}}`
+	hosts, err := parseHttpDiscoveryHosts([]byte(body), &pollingTestMapping)
+	require.Nil(t, err)
+	require.Len(t, hosts, 2)
+	// sorted by address
+	assert.Equal(t, "127.0.0.1:8080", hosts[0].Address)
+	assert.Equal(t, uint32(0), hosts[0].Weight)
+	assert.Equal(t, "127.0.0.2:8080", hosts[1].Address)
+	assert.Equal(t, uint32(10), hosts[1].Weight)
+	assert.Equal(t, api.Metadata{"zone": "a", "version": "2"}, hosts[1].MetaData)
+
+	// the default address field, and the response is the hosts list
+	hosts, err = parseHttpDiscoveryHosts([]byte(`[{"address":"127.0.0.1:8080"}]`), &v2.HttpDiscoveryMapping{})
+	require.Nil(t, err)
+	assert.Equal(t, "127.0.0.1:8080", hosts[0].Address)
+
+	// the list elements in the path
+	hosts, err = parseHttpDiscoveryHosts([]byte(`{"groups":[{"instances":[{"address":"127.0.0.1:8080"}]}]}`), &v2.HttpDiscoveryMapping{
+		Hosts: "groups.0.instances",
+	})
+	require.Nil(t, err)
+	assert.Equal(t, "127.0.0.1:8080", hosts[0].Address)
+
+	for _, invalid := range []string{
+		`{"data":{"instances":[{"ip":"127.0.0.1"}]}}`,
+		`{"data":{"instances":[{"ip":"127.0.0.1","port":8080,"weight":"x"}]}}`,
+		`{"data":{"instances":[{"ip":"127.0.0.1","port":8080,"meta":"x"}]}}`,
+		`{"data":{"instances":{}}}`,
+		`{"data":{}}`,
+		`{"data":`,
+	} {
+		_, err = parseHttpDiscoveryHosts([]byte(invalid), &pollingTestMapping)
+		assert.NotNil(t, err, invalid)
+	}
+}
+
+type pollingTestUpdater struct {
+	updates [][]v2.Host
+	appends [][]v2.Host
+	dels    [][]string
+	err     error
+}
+
+func (u *pollingTestUpdater) TriggerClusterHostUpdate(_ string, hosts []v2.Host) error {
+	u.updates = append(u.updates, hosts)
+	return u.err
+}
+
+func (u *pollingTestUpdater) TriggerHostDel(_ string, hosts []string) error {
+	u.dels = append(u.dels, hosts)
+	return u.err
+}
+
+func (u *pollingTestUpdater) TriggerHostAppend(_ string, hosts []v2.Host) error {
+	u.appends = append(u.appends, hosts)
+	return u.err
+}
+
+func TestHttpPollingClusterPoll(t *testing.T) {
+	server := &pollingTestServer{}
+	server.set(http.StatusOK, `{"data":{"instances":[{"ip":"127.0.0.1","port":8080}]}}`, `"v1"`)
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+
+	c := NewCluster(v2.Cluster{
+		Name:        "http_polling_poll",
+		ClusterType: v2.HTTP_POLLING_CLUSTER,
+		LbType:      v2.LB_ROUNDROBIN,
+	})
+	pc, ok := c.(*httpPollingCluster)
+	require.True(t, ok)
+	defer pc.StopHealthChecking()
+	updater := &pollingTestUpdater{}
+	pc.updater = updater
+	pc.config = v2.HttpDiscovery{
+		URL:     ts.URL,
+		Headers: map[string]string{"x-token": "token"},
+		Mapping: pollingTestMapping,
+	}
+	pc.client = &http.Client{Timeout: time.Second}
+	pc.interval = time.Second
+	pc.maxBackOff = 5 * time.Second
+
+	// the first polling replaces the hosts
+	assert.Equal(t, time.Second, pc.poll())
+	require.Len(t, updater.updates, 1)
+	assert.Equal(t, "127.0.0.1:8080", updater.updates[0][0].Address)
+	// not modified
+	assert.Equal(t, time.Second, pc.poll())
+	assert.Equal(t, 1, server.notModified)
+	// the hosts are not changed with a new etag
+	server.set(http.StatusOK, `{"data":{"instances":[{"ip":"127.0.0.1","port":8080}]}}`, `"v2"`)
+	pc.poll()
+	assert.Equal(t, `"v2"`, pc.etag)
+	assert.Len(t, updater.appends, 0)
+	assert.Len(t, updater.dels, 0)
+	// a host is added
+	server.set(http.StatusOK, `{"data":{"instances":[{"ip":"127.0.0.1","port":8080},{"ip":"127.0.0.2","port":8080}]}}`, "")
+	pc.poll()
+	require.Len(t, updater.appends, 1)
+	require.Len(t, updater.appends[0], 1)
+	assert.Equal(t, "127.0.0.2:8080", updater.appends[0][0].Address)
+	assert.Len(t, updater.dels, 0)
+	// a host is changed, and a host is removed
+	server.set(http.StatusOK, `{"data":{"instances":[{"ip":"127.0.0.2","port":8080,"weight":10}]}}`, "")
+	pc.poll()
+	require.Len(t, updater.dels, 1)
+	assert.ElementsMatch(t, []string{"127.0.0.1:8080", "127.0.0.2:8080"}, updater.dels[0])
+	require.Len(t, updater.appends, 2)
+	require.Len(t, updater.appends[1], 1)
+	assert.Equal(t, uint32(10), updater.appends[1][0].Weight)
+	assert.Len(t, updater.updates, 1)
+
+	// back off on errors, and keep the last hosts
+	server.set(http.StatusInternalServerError, "", "")
+	assert.Equal(t, 2*time.Second, pc.poll())
+	assert.Equal(t, 4*time.Second, pc.poll())
+	server.set(http.StatusOK, `{"data":`, "")
+	assert.Equal(t, 5*time.Second, pc.poll())
+	// the update is failed, all the hosts are replaced when it is recovered
+	server.set(http.StatusOK, `{"data":{"instances":[{"ip":"127.0.0.3","port":8080}]}}`, "")
+	updater.err = errors.New("update failed")
+	assert.Equal(t, 5*time.Second, pc.poll())
+	assert.Nil(t, pc.lastHosts)
+	updater.err = nil
+	assert.Equal(t, time.Second, pc.poll())
+	require.Len(t, updater.updates, 2)
+	assert.Equal(t, "127.0.0.3:8080", updater.updates[1][0].Address)
+}
+
+func TestHttpPollingCluster(t *testing.T) {
+	_createClusterManager()
+	server := &pollingTestServer{}
+	server.set(http.StatusOK, `{"data":{"instances":[{"ip":"127.0.0.1","port":8080},{"ip":"127.0.0.2","port":8080}]}}`, `"v1"`)
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+
+	require.Nil(t, clusterManagerInstance.AddOrUpdatePrimaryCluster(v2.Cluster{
+		Name:        "http_polling",
+		ClusterType: v2.HTTP_POLLING_CLUSTER,
+		LbType:      v2.LB_ROUNDROBIN,
+		HttpDiscovery: &v2.HttpDiscovery{
+			URL:      ts.URL,
+			Headers:  map[string]string{"x-token": "token"},
+			Interval: &api.DurationConfig{Duration: 10 * time.Millisecond},
+			Mapping:  pollingTestMapping,
+		},
+	}))
+	defer clusterManagerInstance.RemovePrimaryCluster("http_polling")
+	require.Eventually(t, func() bool {
+		return clusterManagerInstance.GetClusterSnapshot(context.Background(), "http_polling").HostNum(nil) == 2
+	}, time.Second, 10*time.Millisecond)
+
+	server.set(http.StatusOK, `{"data":{"instances":[{"ip":"127.0.0.3","port":8080}]}}`, `"v2"`)
+	require.Eventually(t, func() bool {
+		return clusterManagerInstance.GetClusterSnapshot(context.Background(), "http_polling").HostNum(nil) == 1
+	}, time.Second, 10*time.Millisecond)
+}
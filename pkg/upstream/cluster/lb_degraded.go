@@ -0,0 +1,145 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cluster
+
+import (
+	"math/rand"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"mosn.io/mosn/pkg/log"
+	"mosn.io/mosn/pkg/types"
+)
+
+type degradedState struct {
+	// normalLB and degradedLB are nil if there are no degraded hosts
+	normalLB   types.LoadBalancer
+	degradedLB types.LoadBalancer
+	// degradedKey identifies the degraded hosts that the load balancers are created with
+	degradedKey   string
+	normalPercent uint32
+	updateTime    time.Time
+}
+
+// degradedLoadBalancer prefers the hosts that are not degraded by the health check.
+// The non-degraded hosts receive all the requests if their healthy ratio multiply the
+// overprovisioning factor is not less than 100%, otherwise the requests spill over to
+// the degraded hosts.
+type degradedLoadBalancer struct {
+	// balances all the hosts if there are no degraded hosts
+	types.LoadBalancer
+	info                   types.ClusterInfo
+	hosts                  types.HostSet
+	factory                func(types.ClusterInfo, types.HostSet) types.LoadBalancer
+	overprovisioningFactor uint32
+	state                  atomic.Value // store *degradedState
+	mutex                  sync.Mutex
+	rand                   *rand.Rand
+	nowFunc                func() time.Time
+}
+
+func degradedAwareFactory(factory func(types.ClusterInfo, types.HostSet) types.LoadBalancer) func(types.ClusterInfo, types.HostSet) types.LoadBalancer {
+	return func(info types.ClusterInfo, hosts types.HostSet) types.LoadBalancer {
+		return newDegradedLoadBalancer(info, hosts, factory)
+	}
+}
+
+func newDegradedLoadBalancer(info types.ClusterInfo, hosts types.HostSet, factory func(types.ClusterInfo, types.HostSet) types.LoadBalancer) types.LoadBalancer {
+	if hosts == nil || hosts.Size() == 0 {
+		return factory(info, hosts)
+	}
+	lb := &degradedLoadBalancer{
+		LoadBalancer:           factory(info, hosts),
+		info:                   info,
+		hosts:                  hosts,
+		factory:                factory,
+		overprovisioningFactor: getOverprovisioningFactor(info),
+		rand:                   rand.New(rand.NewSource(time.Now().UnixNano())),
+		nowFunc:                time.Now,
+	}
+	lb.state.Store(&degradedState{})
+	lb.refreshState()
+	return lb
+}
+
+func (lb *degradedLoadBalancer) ChooseHost(context types.LoadBalancerContext) types.Host {
+	state := lb.getState()
+	if state.degradedLB == nil {
+		return lb.LoadBalancer.ChooseHost(context)
+	}
+	first, second := state.normalLB, state.degradedLB
+	if state.normalPercent < 100 {
+		lb.mutex.Lock()
+		n := uint32(lb.rand.Intn(100))
+		lb.mutex.Unlock()
+		if n >= state.normalPercent {
+			first, second = second, first
+		}
+	}
+	if host := first.ChooseHost(context); host != nil {
+		return host
+	}
+	return second.ChooseHost(context)
+}
+
+func (lb *degradedLoadBalancer) getState() *degradedState {
+	state := lb.state.Load().(*degradedState)
+	if lb.nowFunc().Sub(state.updateTime) < healthRefreshInterval {
+		return state
+	}
+	return lb.refreshState()
+}
+
+func (lb *degradedLoadBalancer) refreshState() *degradedState {
+	lb.mutex.Lock()
+	defer lb.mutex.Unlock()
+	last := lb.state.Load().(*degradedState)
+	var normal, degraded []types.Host
+	var key strings.Builder
+	lb.hosts.Range(func(host types.Host) bool {
+		if host.ContainHealthFlag(types.DEGRADED_ACTIVE_HC) {
+			degraded = append(degraded, host)
+			key.WriteString(host.AddressString())
+			key.WriteByte(',')
+		} else {
+			normal = append(normal, host)
+		}
+		return true
+	})
+	state := &degradedState{
+		degradedKey: key.String(),
+		updateTime:  lb.nowFunc(),
+	}
+	if len(degraded) > 0 {
+		normalHosts := &hostSet{allHosts: normal}
+		if state.degradedKey == last.degradedKey {
+			state.normalLB, state.degradedLB = last.normalLB, last.degradedLB
+		} else {
+			state.normalLB = lb.factory(lb.info, normalHosts)
+			state.degradedLB = lb.factory(lb.info, &hostSet{allHosts: degraded})
+			if log.DefaultLogger.GetLogLevel() >= log.INFO {
+				log.DefaultLogger.Infof("[upstream] [degraded lb] cluster %s degraded hosts: %s", lb.info.Name(), state.degradedKey)
+			}
+		}
+		state.normalPercent = healthPercent(countHealthyHosts(normalHosts), lb.hosts.Size(), lb.overprovisioningFactor)
+	}
+	lb.state.Store(state)
+	return state
+}
@@ -0,0 +1,134 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cluster
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"mosn.io/api"
+	v2 "mosn.io/mosn/pkg/config/v2"
+	"mosn.io/mosn/pkg/types"
+	"mosn.io/mosn/pkg/upstream/healthcheck"
+)
+
+func setHostsDegraded(hosts []types.Host, degraded bool) {
+	for _, h := range hosts {
+		if degraded {
+			h.SetHealthFlag(types.DEGRADED_ACTIVE_HC)
+		} else {
+			h.ClearHealthFlag(types.DEGRADED_ACTIVE_HC)
+		}
+	}
+}
+
+func newDegradedTestCluster(name string) v2.Cluster {
+	return v2.Cluster{
+		Name:   name,
+		LbType: v2.LB_ROUNDROBIN,
+		HealthCheck: v2.HealthCheck{
+			HealthCheckConfig: v2.HealthCheckConfig{
+				ServiceName: name,
+				SessionConfig: map[string]interface{}{
+					healthcheck.HTTPCheckConfigKey: map[string]interface{}{
+						"degraded_header": "x-health-degraded",
+					},
+				},
+			},
+		},
+	}
+}
+
+func TestDegradedHostHealth(t *testing.T) {
+	info := NewClusterInfo(v2.Cluster{Name: "degraded_host_health"})
+	h := newPanicTestHosts(info, 230, 1)[0]
+	defer h.ClearHealthFlag(types.DEGRADED_ACTIVE_HC)
+	h.SetHealthFlag(types.DEGRADED_ACTIVE_HC)
+	assert.True(t, h.Health())
+	h.SetHealthFlag(api.FAILED_ACTIVE_HC)
+	assert.False(t, h.Health())
+	h.ClearHealthFlag(api.FAILED_ACTIVE_HC)
+	assert.True(t, h.Health())
+}
+
+func TestDegradedLoadBalancerNotConfigured(t *testing.T) {
+	info := NewClusterInfo(v2.Cluster{Name: "degraded_not_configured", LbType: v2.LB_ROUNDROBIN})
+	assert.False(t, info.DegradedHostsEnabled())
+	lb := NewLoadBalancer(info, NewHostSet(newPanicTestHosts(info, 231, 3)))
+	_, ok := lb.(*degradedLoadBalancer)
+	assert.False(t, ok)
+}
+
+func TestDegradedLoadBalancer(t *testing.T) {
+	info := NewClusterInfo(newDegradedTestCluster("degraded_lb"))
+	require.True(t, info.DegradedHostsEnabled())
+	hosts := newPanicTestHosts(info, 232, 10)
+	defer setHostsDegraded(hosts, false)
+	defer setHostsHealth(hosts, true)
+	lb := NewLoadBalancer(info, NewHostSet(hosts)).(*degradedLoadBalancer)
+	now := time.Now()
+	lb.nowFunc = func() time.Time {
+		return now
+	}
+	chooseDegraded := func() int {
+		count := 0
+		for i := 0; i < 100; i++ {
+			h := lb.ChooseHost(nil)
+			require.NotNil(t, h)
+			if h.ContainHealthFlag(types.DEGRADED_ACTIVE_HC) {
+				count++
+			}
+		}
+		return count
+	}
+	// no degraded hosts
+	assert.Equal(t, 0, chooseDegraded())
+	// 80% non-degraded hosts multiply the default overprovisioning factor is more than 100%
+	setHostsDegraded(hosts[:2], true)
+	now = now.Add(healthRefreshInterval)
+	assert.Equal(t, 0, chooseDegraded())
+	state := lb.getState()
+	require.NotNil(t, state.degradedLB)
+	assert.Equal(t, uint32(100), state.normalPercent)
+	// the degraded hosts receive the requests that spill over
+	setHostsDegraded(hosts[:5], true)
+	now = now.Add(healthRefreshInterval)
+	state = lb.getState()
+	assert.Equal(t, uint32(70), state.normalPercent)
+	assert.True(t, chooseDegraded() > 0)
+	// the load balancers are reused if the degraded hosts are not changed
+	setHostsHealth(hosts[5:7], false)
+	now = now.Add(healthRefreshInterval)
+	newState := lb.getState()
+	assert.Equal(t, uint32(42), newState.normalPercent)
+	assert.True(t, newState.normalLB == state.normalLB)
+	assert.True(t, newState.degradedLB == state.degradedLB)
+	// all the non-degraded hosts are unhealthy
+	setHostsHealth(hosts[5:], false)
+	now = now.Add(healthRefreshInterval)
+	assert.Equal(t, 100, chooseDegraded())
+	// recovered
+	setHostsHealth(hosts, true)
+	setHostsDegraded(hosts, false)
+	now = now.Add(healthRefreshInterval)
+	assert.Nil(t, lb.getState().degradedLB)
+	assert.Equal(t, 0, chooseDegraded())
+}
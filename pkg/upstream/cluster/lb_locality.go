@@ -0,0 +1,358 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cluster
+
+import (
+	"context"
+	"math/rand"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"mosn.io/api"
+	"mosn.io/mosn/pkg/istio"
+	"mosn.io/mosn/pkg/log"
+	"mosn.io/mosn/pkg/types"
+)
+
+const (
+	defaultLocalityMinClusterSize = 6
+	// the metadata key of the labels in the service meta
+	serviceLabelsKey = "LABELS"
+	// the shares of the localities are calculated in basis points
+	localityShareBase = 10000
+)
+
+// localityEntry is a set of hosts in the same locality
+type localityEntry struct {
+	locality string
+	hosts    types.HostSet
+	lb       types.LoadBalancer
+}
+
+// localityState is the routing state calculated by the hosts health
+type localityState struct {
+	// the percent of the requests routed to the local locality directly
+	localPercent uint32
+	// the weights of the remote localities, equals to their residual capacity
+	remoteWeights []int
+	remoteTotal   int
+	updateTime    time.Time
+}
+
+// localityLoadBalancer prefers the hosts in the same locality as the local node, as the zone aware
+// routing of envoy does. The share of the healthy upstream hosts in each locality is compared with the
+// share of the downstream instances, which is the healthy hosts of the local cluster, or the upstream
+// hosts if no local cluster is configured.
+// The local locality receives all the requests if its upstream share is not less than its downstream share,
+// otherwise it receives the requests in proportion to the two shares, and the others spill over to the remote
+// localities, weighted by their residual capacity, that is the upstream share exceeds the downstream share.
+// Each locality has its own load balancer created by the cluster's lb type.
+type localityLoadBalancer struct {
+	info         types.ClusterInfo
+	localCluster string
+	local        *localityEntry
+	remotes      []*localityEntry
+	total        int
+	state        atomic.Value // store *localityState
+	mutex        sync.Mutex
+	rand         *rand.Rand
+	nowFunc      func() time.Time
+}
+
+func localityAwareFactory(factory func(types.ClusterInfo, types.HostSet) types.LoadBalancer) func(types.ClusterInfo, types.HostSet) types.LoadBalancer {
+	return func(info types.ClusterInfo, hosts types.HostSet) types.LoadBalancer {
+		return newLocalityLoadBalancer(info, hosts, factory)
+	}
+}
+
+// newLocalityLoadBalancer returns the load balancer created by the factory directly
+// if the hosts cannot be routed by locality.
+func newLocalityLoadBalancer(info types.ClusterInfo, hosts types.HostSet, factory func(types.ClusterInfo, types.HostSet) types.LoadBalancer) types.LoadBalancer {
+	cfg := info.LbConfig()
+	minSize := uint32(defaultLocalityMinClusterSize)
+	if cfg.LocalityMinClusterSize > 0 {
+		minSize = cfg.LocalityMinClusterSize
+	}
+	if hosts == nil || uint32(hosts.Size()) < minSize {
+		return factory(info, hosts)
+	}
+	local, ok := getLocalLocality(cfg.LocalityKeys, cfg.LocalLocality)
+	if !ok {
+		if log.DefaultLogger.GetLogLevel() >= log.DEBUG {
+			log.DefaultLogger.Debugf("[upstream] [locality lb] cluster %s local locality is unknown, locality aware is disabled", info.Name())
+		}
+		return factory(info, hosts)
+	}
+	byLocality := map[string][]types.Host{}
+	hosts.Range(func(host types.Host) bool {
+		locality := getHostLocality(cfg.LocalityKeys, host)
+		byLocality[locality] = append(byLocality[locality], host)
+		return true
+	})
+	if _, exists := byLocality[local]; !exists || len(byLocality) == 1 {
+		return factory(info, hosts)
+	}
+	lb := &localityLoadBalancer{
+		info:         info,
+		localCluster: cfg.LocalClusterName,
+		total:        hosts.Size(),
+		rand:         rand.New(rand.NewSource(time.Now().UnixNano())),
+		nowFunc:      time.Now,
+	}
+	localities := make([]string, 0, len(byLocality))
+	for locality := range byLocality {
+		localities = append(localities, locality)
+	}
+	sort.Strings(localities)
+	for _, locality := range localities {
+		subHosts := &hostSet{allHosts: byLocality[locality]}
+		entry := &localityEntry{
+			locality: locality,
+			hosts:    subHosts,
+			lb:       factory(info, subHosts),
+		}
+		if locality == local {
+			lb.local = entry
+		} else {
+			lb.remotes = append(lb.remotes, entry)
+		}
+	}
+	lb.refreshState()
+	return lb
+}
+
+func (lb *localityLoadBalancer) ChooseHost(context types.LoadBalancerContext) types.Host {
+	state := lb.getState()
+	var chosen *localityEntry
+	if state.localPercent >= 100 {
+		lb.info.Stats().LBZoneRoutingAllDirectly.Inc(1)
+		chosen = lb.local
+	} else {
+		lb.mutex.Lock()
+		n := uint32(lb.rand.Intn(100))
+		var r int
+		if state.remoteTotal > 0 {
+			r = lb.rand.Intn(state.remoteTotal)
+		} else {
+			r = lb.rand.Intn(len(lb.remotes))
+		}
+		lb.mutex.Unlock()
+		if n < state.localPercent {
+			lb.info.Stats().LBZoneRoutingSampled.Inc(1)
+			chosen = lb.local
+		} else {
+			lb.info.Stats().LBZoneRoutingCrossZone.Inc(1)
+			chosen = lb.chooseRemote(state, r)
+		}
+	}
+	if host := chosen.lb.ChooseHost(context); host != nil {
+		return host
+	}
+	// the chosen locality have no available hosts, try the local locality first, and then the others
+	if chosen != lb.local {
+		if host := lb.local.lb.ChooseHost(context); host != nil {
+			return host
+		}
+	}
+	for _, entry := range lb.remotes {
+		if entry == chosen {
+			continue
+		}
+		if host := entry.lb.ChooseHost(context); host != nil {
+			return host
+		}
+	}
+	return nil
+}
+
+func (lb *localityLoadBalancer) chooseRemote(state *localityState, r int) *localityEntry {
+	if state.remoteTotal == 0 {
+		return lb.remotes[r]
+	}
+	for i, weight := range state.remoteWeights {
+		if r < weight {
+			return lb.remotes[i]
+		}
+		r -= weight
+	}
+	return lb.remotes[len(lb.remotes)-1]
+}
+
+func (lb *localityLoadBalancer) IsExistsHosts(metadata api.MetadataMatchCriteria) bool {
+	return lb.total > 0
+}
+
+func (lb *localityLoadBalancer) HostNum(metadata api.MetadataMatchCriteria) int {
+	return lb.total
+}
+
+func (lb *localityLoadBalancer) getState() *localityState {
+	state := lb.state.Load().(*localityState)
+	if lb.nowFunc().Sub(state.updateTime) < healthRefreshInterval {
+		return state
+	}
+	return lb.refreshState()
+}
+
+func (lb *localityLoadBalancer) refreshState() *localityState {
+	state := &localityState{
+		remoteWeights: make([]int, len(lb.remotes)),
+		updateTime:    lb.nowFunc(),
+	}
+	upstreamShares := lb.upstreamShares()
+	downstreamShares := lb.downstreamShares()
+	localUpstream, localDownstream := upstreamShares[0], downstreamShares[0]
+	if localUpstream >= localDownstream {
+		state.localPercent = 100
+	} else {
+		state.localPercent = uint32(localUpstream * 100 / localDownstream)
+		for i := range lb.remotes {
+			if residual := upstreamShares[i+1] - downstreamShares[i+1]; residual > 0 {
+				state.remoteWeights[i] = residual
+				state.remoteTotal += residual
+			}
+		}
+		// the shares are rounded, no remote locality has residual capacity
+		if state.remoteTotal == 0 {
+			state.localPercent = 100
+		}
+	}
+	lb.state.Store(state)
+	if log.DefaultLogger.GetLogLevel() >= log.DEBUG {
+		log.DefaultLogger.Debugf("[upstream] [locality lb] local locality %s, local percent: %d, remote weights: %v",
+			lb.local.locality, state.localPercent, state.remoteWeights)
+	}
+	return state
+}
+
+// upstreamShares returns the shares of the healthy hosts in the local locality and the remote localities
+func (lb *localityLoadBalancer) upstreamShares() []int {
+	counts := make([]int, len(lb.remotes)+1)
+	counts[0] = countHealthyHosts(lb.local.hosts)
+	for i, entry := range lb.remotes {
+		counts[i+1] = countHealthyHosts(entry.hosts)
+	}
+	return toShares(counts)
+}
+
+// downstreamShares returns the shares of the downstream instances in the local locality and the remote localities.
+// The healthy hosts in the local cluster are the downstream instances, if the local cluster is not configured or
+// has no healthy hosts, the downstream instances are expected to be distributed as the upstream hosts.
+func (lb *localityLoadBalancer) downstreamShares() []int {
+	// the last count is the downstream instances in the localities without upstream hosts
+	counts := make([]int, len(lb.remotes)+2)
+	if hosts := getLocalClusterHosts(lb.localCluster); hosts != nil {
+		index := make(map[string]int, len(lb.remotes)+1)
+		index[lb.local.locality] = 0
+		for i, entry := range lb.remotes {
+			index[entry.locality] = i + 1
+		}
+		keys := lb.info.LbConfig().LocalityKeys
+		healthy := 0
+		hosts.Range(func(host types.Host) bool {
+			if !host.Health() {
+				return true
+			}
+			healthy++
+			if i, ok := index[getHostLocality(keys, host)]; ok {
+				counts[i]++
+			} else {
+				counts[len(counts)-1]++
+			}
+			return true
+		})
+		if healthy > 0 {
+			return toShares(counts)
+		}
+	}
+	counts[0] = lb.local.hosts.Size()
+	for i, entry := range lb.remotes {
+		counts[i+1] = entry.hosts.Size()
+	}
+	return toShares(counts)
+}
+
+// toShares returns the shares of the counts in basis points, the shares are zero if the total is zero.
+func toShares(counts []int) []int {
+	total := 0
+	for _, count := range counts {
+		total += count
+	}
+	shares := make([]int, len(counts))
+	if total == 0 {
+		return shares
+	}
+	for i, count := range counts {
+		shares[i] = count * localityShareBase / total
+	}
+	return shares
+}
+
+// getLocalClusterHosts returns the hosts of the local cluster, it returns nil if the cluster is not found.
+func getLocalClusterHosts(name string) types.HostSet {
+	if name == "" {
+		return nil
+	}
+	cm := getClusterManager()
+	if cm == nil {
+		return nil
+	}
+	snapshot := cm.GetClusterSnapshot(context.Background(), name)
+	if snapshot == nil {
+		return nil
+	}
+	return snapshot.HostSet()
+}
+
+func getHostLocality(keys []string, host types.Host) string {
+	meta := host.Metadata()
+	values := make([]string, len(keys))
+	for i, key := range keys {
+		values[i] = meta[key]
+	}
+	return strings.Join(values, "/")
+}
+
+// getLocalLocality returns the locality of the local node, the configured locality is preferred,
+// and then the service labels and the pod labels.
+func getLocalLocality(keys []string, configured map[string]string) (string, bool) {
+	var serviceLabels map[string]string
+	if meta := istio.GetGlobalXdsInfo().Metadata; meta != nil {
+		if labels := meta.GetFields()[serviceLabelsKey].GetStructValue(); labels != nil {
+			serviceLabels = make(map[string]string, len(labels.GetFields()))
+			for k, v := range labels.GetFields() {
+				serviceLabels[k] = v.GetStringValue()
+			}
+		}
+	}
+	found := false
+	values := make([]string, len(keys))
+	for i, key := range keys {
+		for _, labels := range []map[string]string{configured, serviceLabels, istio.GetPodLabels()} {
+			if v, ok := labels[key]; ok {
+				values[i] = v
+				found = true
+				break
+			}
+		}
+	}
+	return strings.Join(values, "/"), found
+}
@@ -0,0 +1,242 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cluster
+
+import (
+	"fmt"
+	"testing"
+
+	_struct "github.com/golang/protobuf/ptypes/struct"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"mosn.io/api"
+	v2 "mosn.io/mosn/pkg/config/v2"
+	"mosn.io/mosn/pkg/istio"
+	"mosn.io/mosn/pkg/types"
+)
+
+// newLocalityMockHosts creates hosts in subnet, counts[zone] is the number of hosts in the zone
+func newLocalityMockHosts(subnet int, counts map[string]int) []types.Host {
+	hosts := []types.Host{}
+	idx := 0
+	for zone, count := range counts {
+		for i := 0; i < count; i++ {
+			hosts = append(hosts, &mockHost{
+				addr: fmt.Sprintf("127.%d.%d.%d:8080", subnet, idx, i),
+				meta: api.Metadata{
+					"region": "cn",
+					"zone":   zone,
+				},
+			})
+		}
+		idx++
+	}
+	return hosts
+}
+
+func newLocalityClusterInfo(name string, cfg *v2.LbConfig) types.ClusterInfo {
+	return NewClusterInfo(v2.Cluster{
+		Name:     name,
+		LbType:   v2.LB_ROUNDROBIN,
+		LbConfig: cfg,
+	})
+}
+
+func countZones(lb types.LoadBalancer, times int) map[string]int {
+	counts := map[string]int{}
+	for i := 0; i < times; i++ {
+		h := lb.ChooseHost(nil)
+		if h == nil {
+			counts[""]++
+			continue
+		}
+		counts[h.Metadata()["zone"]]++
+	}
+	return counts
+}
+
+func TestLocalityLoadBalancerDisabled(t *testing.T) {
+	cfg := &v2.LbConfig{
+		LocalityKeys:  []string{"region", "zone"},
+		LocalLocality: map[string]string{"region": "cn", "zone": "a"},
+	}
+	info := newLocalityClusterInfo("locality_disabled", cfg)
+	// less than min cluster size
+	lb := NewLoadBalancer(info, NewHostSet(newLocalityMockHosts(220, map[string]int{"a": 2, "b": 2})))
+	_, ok := lb.(*localityLoadBalancer)
+	assert.False(t, ok)
+	// no hosts in local locality
+	lb = NewLoadBalancer(info, NewHostSet(newLocalityMockHosts(220, map[string]int{"b": 3, "c": 3})))
+	_, ok = lb.(*localityLoadBalancer)
+	assert.False(t, ok)
+	// all hosts in local locality
+	lb = NewLoadBalancer(info, NewHostSet(newLocalityMockHosts(220, map[string]int{"a": 6})))
+	_, ok = lb.(*localityLoadBalancer)
+	assert.False(t, ok)
+	// local locality is unknown
+	info = newLocalityClusterInfo("locality_unknown", &v2.LbConfig{
+		LocalityKeys: []string{"unknown_zone_key"},
+	})
+	lb = NewLoadBalancer(info, NewHostSet(newLocalityMockHosts(220, map[string]int{"a": 3, "b": 3})))
+	_, ok = lb.(*localityLoadBalancer)
+	assert.False(t, ok)
+	// enabled
+	info = newLocalityClusterInfo("locality_enabled", cfg)
+	lb = NewLoadBalancer(info, NewHostSet(newLocalityMockHosts(220, map[string]int{"a": 3, "b": 3})))
+	llb, ok := lb.(*localityLoadBalancer)
+	require.True(t, ok)
+	assert.Equal(t, "cn/a", llb.local.locality)
+	assert.Len(t, llb.remotes, 1)
+	assert.Equal(t, 6, lb.HostNum(nil))
+}
+
+func TestLocalityLoadBalancerSpillover(t *testing.T) {
+	hosts := newLocalityMockHosts(221, map[string]int{"a": 10, "b": 10, "c": 30})
+	defer setHostsHealth(hosts, true)
+	zoneHosts := map[string][]types.Host{}
+	for _, h := range hosts {
+		zone := h.Metadata()["zone"]
+		zoneHosts[zone] = append(zoneHosts[zone], h)
+	}
+	info := newLocalityClusterInfo("locality_spillover", &v2.LbConfig{
+		LocalityKeys:  []string{"zone"},
+		LocalLocality: map[string]string{"zone": "a"},
+	})
+	lb := NewLoadBalancer(info, NewHostSet(hosts)).(*localityLoadBalancer)
+	// all healthy
+	assert.Equal(t, map[string]int{"a": 1000}, countZones(lb, 1000))
+	assert.Equal(t, int64(1000), info.Stats().LBZoneRoutingAllDirectly.Count())
+	// the upstream share of the local locality is 5/45, less than the expected 10/50,
+	// 55% requests stay in the local locality, the others are spilled over by the residual capacity
+	setHostsHealth(zoneHosts["a"][:5], false)
+	lb.refreshState()
+	assert.Equal(t, uint32(55), lb.getState().localPercent)
+	counts := countZones(lb, 10000)
+	assert.True(t, counts["a"] > 5000 && counts["a"] < 6000, "unexpected counts %v", counts)
+	assert.True(t, counts["c"] > counts["b"]*2, "unexpected counts %v", counts)
+	assert.Equal(t, int64(10000), info.Stats().LBZoneRoutingSampled.Count()+info.Stats().LBZoneRoutingCrossZone.Count())
+	// no healthy hosts in local locality
+	setHostsHealth(zoneHosts["a"], false)
+	setHostsHealth(zoneHosts["c"], false)
+	lb.refreshState()
+	assert.Equal(t, map[string]int{"b": 1000}, countZones(lb, 1000))
+	// no healthy hosts in remote localities
+	setHostsHealth(zoneHosts["a"][:1], true)
+	setHostsHealth(zoneHosts["b"], false)
+	lb.refreshState()
+	assert.Equal(t, uint32(100), lb.getState().localPercent)
+	assert.Equal(t, map[string]int{"a": 1000}, countZones(lb, 1000))
+	// no healthy hosts at all
+	setHostsHealth(hosts, false)
+	lb.refreshState()
+	assert.Equal(t, map[string]int{"": 10}, countZones(lb, 10))
+}
+
+func TestLocalityLoadBalancerLocalCluster(t *testing.T) {
+	_createClusterManager()
+	cm := clusterManagerInstance
+	// the downstream instances: 40% in zone a, 20% in zone b, 20% in zone c and 20% in zone d without upstream hosts
+	var instances []v2.Host
+	for zone, count := range map[string]int{"a": 2, "b": 1, "c": 1, "d": 1} {
+		for i := 0; i < count; i++ {
+			instances = append(instances, v2.Host{
+				HostConfig: v2.HostConfig{Address: fmt.Sprintf("127.0.%d.%d:8080", zone[0], i)},
+				MetaData:   api.Metadata{"zone": zone},
+			})
+		}
+	}
+	require.Nil(t, cm.AddOrUpdateClusterAndHost(v2.Cluster{Name: "locality_local", LbType: v2.LB_ROUNDROBIN}, instances))
+	defer cm.RemovePrimaryCluster("locality_local")
+
+	// the upstream shares: 20% in zone a, 20% in zone b, 60% in zone c
+	hosts := newLocalityMockHosts(224, map[string]int{"a": 10, "b": 10, "c": 30})
+	info := newLocalityClusterInfo("locality_local_cluster", &v2.LbConfig{
+		LocalityKeys:     []string{"zone"},
+		LocalLocality:    map[string]string{"zone": "a"},
+		LocalClusterName: "locality_local",
+	})
+	lb := NewLoadBalancer(info, NewHostSet(hosts)).(*localityLoadBalancer)
+	// half of the requests stay in zone a, the others go to zone c that has the residual capacity
+	assert.Equal(t, uint32(50), lb.getState().localPercent)
+	counts := countZones(lb, 10000)
+	assert.Equal(t, 0, counts["b"])
+	assert.True(t, counts["a"] > 4500 && counts["a"] < 5500, "unexpected counts %v", counts)
+
+	// the local locality has enough hosts if the local cluster is not found
+	info = newLocalityClusterInfo("locality_local_cluster_unknown", &v2.LbConfig{
+		LocalityKeys:     []string{"zone"},
+		LocalLocality:    map[string]string{"zone": "a"},
+		LocalClusterName: "locality_local_unknown",
+	})
+	lb = NewLoadBalancer(info, NewHostSet(hosts)).(*localityLoadBalancer)
+	assert.Equal(t, uint32(100), lb.getState().localPercent)
+}
+
+func TestLocalityWithPriority(t *testing.T) {
+	hosts := newLocalityMockHosts(222, map[string]int{"a": 3, "b": 3})
+	backups := newLocalityMockHosts(223, map[string]int{"a": 3, "b": 3})
+	for _, h := range backups {
+		h.(*mockHost).priority = 1
+	}
+	info := newLocalityClusterInfo("locality_priority", &v2.LbConfig{
+		LocalityKeys:  []string{"zone"},
+		LocalLocality: map[string]string{"zone": "b"},
+	})
+	lb := NewLoadBalancer(info, NewHostSet(append(hosts, backups...))).(*priorityLoadBalancer)
+	for _, level := range lb.levels {
+		_, ok := level.lb.(*localityLoadBalancer)
+		assert.True(t, ok)
+	}
+	for i := 0; i < 100; i++ {
+		h := lb.ChooseHost(nil)
+		assert.Equal(t, "b", h.Metadata()["zone"])
+		assert.Equal(t, uint32(0), h.Priority())
+	}
+}
+
+func TestGetLocalLocality(t *testing.T) {
+	keys := []string{"region", "zone"}
+	_, ok := getLocalLocality(keys, nil)
+	assert.False(t, ok)
+	locality, ok := getLocalLocality(keys, map[string]string{"zone": "a"})
+	assert.True(t, ok)
+	assert.Equal(t, "/a", locality)
+	// service labels
+	defer istio.SetMetadata(istio.GetGlobalXdsInfo().Metadata)
+	istio.SetMetadata(&_struct.Struct{
+		Fields: map[string]*_struct.Value{
+			serviceLabelsKey: {
+				Kind: &_struct.Value_StructValue{
+					StructValue: &_struct.Struct{
+						Fields: map[string]*_struct.Value{
+							"region": {Kind: &_struct.Value_StringValue{StringValue: "cn"}},
+							"zone":   {Kind: &_struct.Value_StringValue{StringValue: "b"}},
+						},
+					},
+				},
+			},
+		},
+	})
+	locality, ok = getLocalLocality(keys, nil)
+	assert.True(t, ok)
+	assert.Equal(t, "cn/b", locality)
+	// the configured locality is preferred
+	locality, _ = getLocalLocality(keys, map[string]string{"zone": "a"})
+	assert.Equal(t, "cn/a", locality)
+}
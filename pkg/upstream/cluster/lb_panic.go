@@ -0,0 +1,127 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cluster
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"mosn.io/mosn/pkg/log"
+	"mosn.io/mosn/pkg/types"
+)
+
+// panicHost is a host that is always healthy, used by the load balancer in panic mode
+type panicHost struct {
+	types.Host
+}
+
+func (h *panicHost) Health() bool {
+	return true
+}
+
+type panicState struct {
+	inPanic    bool
+	updateTime time.Time
+}
+
+// panicLoadBalancer balances across all the hosts ignoring their health when the
+// healthy hosts ratio is less than the cluster's healthy panic threshold.
+// Otherwise the requests are balanced by the normal load balancer.
+type panicLoadBalancer struct {
+	types.LoadBalancer
+	// panicLB is created by the cluster's lb type with the hosts that are always healthy
+	panicLB   types.LoadBalancer
+	info      types.ClusterInfo
+	hosts     types.HostSet
+	threshold uint32
+	state     atomic.Value // store *panicState
+	mutex     sync.Mutex
+	nowFunc   func() time.Time
+}
+
+// panicAwareFactory wraps the load balancer created by factory, and balances by the
+// load balancer created by panicFactory in panic mode.
+func panicAwareFactory(factory, panicFactory func(types.ClusterInfo, types.HostSet) types.LoadBalancer) func(types.ClusterInfo, types.HostSet) types.LoadBalancer {
+	return func(info types.ClusterInfo, hosts types.HostSet) types.LoadBalancer {
+		return newPanicLoadBalancer(info, hosts, factory, panicFactory)
+	}
+}
+
+func newPanicLoadBalancer(info types.ClusterInfo, hosts types.HostSet, factory, panicFactory func(types.ClusterInfo, types.HostSet) types.LoadBalancer) types.LoadBalancer {
+	if hosts == nil || hosts.Size() == 0 {
+		return factory(info, hosts)
+	}
+	panicHosts := make([]types.Host, 0, hosts.Size())
+	hosts.Range(func(host types.Host) bool {
+		panicHosts = append(panicHosts, &panicHost{Host: host})
+		return true
+	})
+	lb := &panicLoadBalancer{
+		LoadBalancer: factory(info, hosts),
+		panicLB:      panicFactory(info, &hostSet{allHosts: panicHosts}),
+		info:         info,
+		hosts:        hosts,
+		threshold:    info.HealthyPanicThreshold(),
+		nowFunc:      time.Now,
+	}
+	lb.state.Store(&panicState{})
+	return lb
+}
+
+func (lb *panicLoadBalancer) ChooseHost(context types.LoadBalancerContext) types.Host {
+	if !lb.isInPanic() {
+		return lb.LoadBalancer.ChooseHost(context)
+	}
+	host := lb.panicLB.ChooseHost(context)
+	if ph, ok := host.(*panicHost); ok {
+		return ph.Host
+	}
+	return host
+}
+
+func (lb *panicLoadBalancer) isInPanic() bool {
+	state := lb.state.Load().(*panicState)
+	if lb.nowFunc().Sub(state.updateTime) < healthRefreshInterval {
+		return state.inPanic
+	}
+	return lb.refreshState().inPanic
+}
+
+func (lb *panicLoadBalancer) refreshState() *panicState {
+	lb.mutex.Lock()
+	defer lb.mutex.Unlock()
+	last := lb.state.Load().(*panicState)
+	healthy := countHealthyHosts(lb.hosts)
+	state := &panicState{
+		inPanic:    uint32(healthy*100) < lb.threshold*uint32(lb.hosts.Size()),
+		updateTime: lb.nowFunc(),
+	}
+	if state.inPanic != last.inPanic {
+		if state.inPanic {
+			lb.info.Stats().LBHealthyPanic.Inc(1)
+			log.DefaultLogger.Warnf("[upstream] [panic lb] cluster %s enters panic mode, healthy hosts: %d, total hosts: %d",
+				lb.info.Name(), healthy, lb.hosts.Size())
+		} else if log.DefaultLogger.GetLogLevel() >= log.INFO {
+			log.DefaultLogger.Infof("[upstream] [panic lb] cluster %s exits panic mode, healthy hosts: %d, total hosts: %d",
+				lb.info.Name(), healthy, lb.hosts.Size())
+		}
+	}
+	lb.state.Store(state)
+	return state
+}
@@ -0,0 +1,127 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cluster
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	v2 "mosn.io/mosn/pkg/config/v2"
+	"mosn.io/mosn/pkg/types"
+	"mosn.io/pkg/variable"
+)
+
+func newPanicTestHosts(info types.ClusterInfo, subnet, count int) []types.Host {
+	hosts := make([]types.Host, 0, count)
+	for i := 0; i < count; i++ {
+		hosts = append(hosts, NewSimpleHost(v2.Host{
+			HostConfig: v2.HostConfig{
+				Address: fmt.Sprintf("127.0.%d.%d:8080", subnet, i),
+				Weight:  1,
+			},
+		}, info))
+	}
+	return hosts
+}
+
+func TestPanicLoadBalancerNotConfigured(t *testing.T) {
+	info := NewClusterInfo(v2.Cluster{Name: "panic_not_configured", LbType: v2.LB_ROUNDROBIN})
+	lb := NewLoadBalancer(info, NewHostSet(newPanicTestHosts(info, 240, 3)))
+	_, ok := lb.(*panicLoadBalancer)
+	assert.False(t, ok)
+}
+
+func TestPanicLoadBalancer(t *testing.T) {
+	info := NewClusterInfo(v2.Cluster{
+		Name:                  "panic_lb",
+		LbType:                v2.LB_ROUNDROBIN,
+		HealthyPanicThreshold: 50,
+	})
+	hosts := newPanicTestHosts(info, 241, 10)
+	defer setHostsHealth(hosts, true)
+	lb := NewLoadBalancer(info, NewHostSet(hosts)).(*panicLoadBalancer)
+	now := time.Now()
+	lb.nowFunc = func() time.Time {
+		return now
+	}
+	chooseUnhealthy := func() bool {
+		for i := 0; i < 20; i++ {
+			h := lb.ChooseHost(nil)
+			require.NotNil(t, h)
+			_, wrapped := h.(*panicHost)
+			require.False(t, wrapped)
+			if !h.Health() {
+				return true
+			}
+		}
+		return false
+	}
+	stats := info.Stats()
+	// 50% healthy is not in panic
+	setHostsHealth(hosts[:5], false)
+	now = now.Add(healthRefreshInterval)
+	assert.False(t, chooseUnhealthy())
+	assert.Equal(t, int64(0), stats.LBHealthyPanic.Count())
+	// 40% healthy, enters panic mode
+	setHostsHealth(hosts[:6], false)
+	now = now.Add(healthRefreshInterval)
+	assert.True(t, chooseUnhealthy())
+	assert.Equal(t, int64(1), stats.LBHealthyPanic.Count())
+	// stay in panic mode
+	now = now.Add(healthRefreshInterval)
+	assert.True(t, chooseUnhealthy())
+	assert.Equal(t, int64(1), stats.LBHealthyPanic.Count())
+	// exits panic mode
+	setHostsHealth(hosts[:6], true)
+	now = now.Add(healthRefreshInterval)
+	assert.False(t, lb.isInPanic())
+	// enters again
+	setHostsHealth(hosts, false)
+	now = now.Add(healthRefreshInterval)
+	assert.True(t, chooseUnhealthy())
+	assert.Equal(t, int64(2), stats.LBHealthyPanic.Count())
+}
+
+func TestPanicLoadBalancerTypes(t *testing.T) {
+	for i, lbType := range []v2.LbType{
+		v2.LB_ROUNDROBIN,
+		v2.LB_RANDOM,
+		v2.LB_LEAST_REQUEST,
+		v2.LbType(types.WeightedRoundRobin),
+		v2.LbType(types.LeastActiveConnection),
+		v2.LbType(types.RequestRoundRobin),
+		v2.LbType(types.PeakEwma),
+	} {
+		info := NewClusterInfo(v2.Cluster{
+			Name:                  fmt.Sprintf("panic_lb_%s", lbType),
+			LbType:                lbType,
+			HealthyPanicThreshold: 50,
+		})
+		hosts := newPanicTestHosts(info, 242+i, 4)
+		setHostsHealth(hosts, false)
+		lb := NewLoadBalancer(info, NewHostSet(hosts))
+		h := lb.ChooseHost(newMockLbContextWithCtx(nil, variable.NewVariableContext(context.Background())))
+		assert.NotNil(t, h, "lb type %s", lbType)
+		setHostsHealth(hosts, true)
+	}
+}
@@ -0,0 +1,294 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cluster
+
+import (
+	"fmt"
+	"math/rand"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"mosn.io/api"
+	"mosn.io/mosn/pkg/configmanager"
+	"mosn.io/mosn/pkg/log"
+	"mosn.io/mosn/pkg/metrics"
+	"mosn.io/mosn/pkg/types"
+)
+
+const (
+	defaultOverprovisioningFactor = 140
+	// the load balancer state depends on the hosts health is recalculated at most
+	// once in the interval, so the health changes of the hosts take effect in time.
+	healthRefreshInterval = time.Second
+)
+
+// priorityLevel is a set of hosts with the same priority
+type priorityLevel struct {
+	priority uint32
+	hosts    types.HostSet
+	lb       types.LoadBalancer
+	stats    types.Metrics
+}
+
+// priorityLoad is the load percent of each priority level
+type priorityLoad struct {
+	loads      []uint32
+	healthy    []int
+	updateTime time.Time
+}
+
+// PriorityLevelState is the state of a priority level of a cluster
+type PriorityLevelState struct {
+	Priority     uint32 `json:"priority"`
+	HealthyHosts int    `json:"healthy_hosts"`
+	TotalHosts   int    `json:"total_hosts"`
+	// the percent of the requests dispatched to the priority level
+	Load uint32 `json:"load"`
+}
+
+// priorityLoadBalancer dispatches the requests to the hosts with the highest priority,
+// and fails over to the lower priority levels when the healthy hosts are not enough.
+// Each priority level has its own load balancer created by the cluster's lb type.
+type priorityLoadBalancer struct {
+	levels                 []*priorityLevel
+	overprovisioningFactor uint32
+	total                  int
+	load                   atomic.Value // store *priorityLoad
+	mutex                  sync.Mutex
+	rand                   *rand.Rand
+	nowFunc                func() time.Time
+}
+
+func newPriorityLoadBalancer(info types.ClusterInfo, levels []types.HostSet, factory func(types.ClusterInfo, types.HostSet) types.LoadBalancer) types.LoadBalancer {
+	lb := &priorityLoadBalancer{
+		levels:                 make([]*priorityLevel, 0, len(levels)),
+		overprovisioningFactor: getOverprovisioningFactor(info),
+		rand:                   rand.New(rand.NewSource(time.Now().UnixNano())),
+		nowFunc:                time.Now,
+	}
+	for _, hosts := range levels {
+		level := &priorityLevel{
+			priority: hosts.Get(0).Priority(),
+			hosts:    hosts,
+			lb:       factory(info, hosts),
+		}
+		if info != nil {
+			level.stats = metrics.NewClusterStats(info.Name())
+		}
+		lb.levels = append(lb.levels, level)
+		lb.total += hosts.Size()
+	}
+	lb.refreshLoad()
+	return lb
+}
+
+func (lb *priorityLoadBalancer) ChooseHost(context types.LoadBalancerContext) types.Host {
+	loads := lb.getLoad().loads
+	lb.mutex.Lock()
+	n := uint32(lb.rand.Intn(100))
+	lb.mutex.Unlock()
+	chosen := 0
+	for i, load := range loads {
+		if n < load {
+			chosen = i
+			break
+		}
+		n -= load
+	}
+	if host := lb.levels[chosen].lb.ChooseHost(context); host != nil {
+		return host
+	}
+	// the chosen level have no available hosts, try the other levels by priority
+	for i, level := range lb.levels {
+		if i == chosen {
+			continue
+		}
+		if host := level.lb.ChooseHost(context); host != nil {
+			return host
+		}
+	}
+	return nil
+}
+
+func (lb *priorityLoadBalancer) IsExistsHosts(metadata api.MetadataMatchCriteria) bool {
+	return lb.total > 0
+}
+
+func (lb *priorityLoadBalancer) HostNum(metadata api.MetadataMatchCriteria) int {
+	return lb.total
+}
+
+// levelStates returns the states calculated at the last refresh
+func (lb *priorityLoadBalancer) levelStates() []PriorityLevelState {
+	load := lb.load.Load().(*priorityLoad)
+	states := make([]PriorityLevelState, len(lb.levels))
+	for i, level := range lb.levels {
+		states[i] = PriorityLevelState{
+			Priority:     level.priority,
+			HealthyHosts: load.healthy[i],
+			TotalHosts:   level.hosts.Size(),
+			Load:         load.loads[i],
+		}
+	}
+	return states
+}
+
+func (lb *priorityLoadBalancer) getLoad() *priorityLoad {
+	load := lb.load.Load().(*priorityLoad)
+	if lb.nowFunc().Sub(load.updateTime) < healthRefreshInterval {
+		return load
+	}
+	return lb.refreshLoad()
+}
+
+// refreshLoad calculates the load of each priority level.
+// the load is allocated to the levels by priority according to their health. if the total health
+// is less than 100, the load is normalized by the total health.
+func (lb *priorityLoadBalancer) refreshLoad() *priorityLoad {
+	health := make([]uint32, len(lb.levels))
+	healthyHosts := make([]int, len(lb.levels))
+	totalHealth := uint32(0)
+	for i, level := range lb.levels {
+		healthy := countHealthyHosts(level.hosts)
+		healthyHosts[i] = healthy
+		h := healthPercent(healthy, level.hosts.Size(), lb.overprovisioningFactor)
+		health[i] = h
+		totalHealth += h
+		if level.stats != nil {
+			level.stats.Gauge(fmt.Sprintf("priority_%d_healthy_hosts", level.priority)).Update(int64(healthy))
+			level.stats.Gauge(fmt.Sprintf("priority_%d_total_hosts", level.priority)).Update(int64(level.hosts.Size()))
+		}
+	}
+	loads := make([]uint32, len(lb.levels))
+	if totalHealth == 0 {
+		// no healthy hosts at all, all the load goes to the highest priority
+		loads[0] = 100
+	} else {
+		if totalHealth > 100 {
+			totalHealth = 100
+		}
+		remaining := uint32(100)
+		for i, h := range health {
+			load := h * 100 / totalHealth
+			if load > remaining {
+				load = remaining
+			}
+			loads[i] = load
+			remaining -= load
+		}
+		// the remaining load caused by rounding goes to the first level that have healthy hosts
+		if remaining > 0 {
+			for i, h := range health {
+				if h > 0 {
+					loads[i] += remaining
+					break
+				}
+			}
+		}
+	}
+	load := &priorityLoad{
+		loads:      loads,
+		healthy:    healthyHosts,
+		updateTime: lb.nowFunc(),
+	}
+	lb.load.Store(load)
+	if log.DefaultLogger.GetLogLevel() >= log.DEBUG {
+		log.DefaultLogger.Debugf("[upstream] [priority lb] priority levels: %d, health: %v, load: %v", len(lb.levels), health, loads)
+	}
+	return load
+}
+
+// ClusterState is the runtime state of a cluster in the config dump
+type ClusterState struct {
+	PriorityLevels []PriorityLevelState `json:"priority_levels,omitempty"`
+}
+
+func init() {
+	configmanager.RegisterClusterStates(func() interface{} {
+		if states := GetClusterStates(); len(states) > 0 {
+			return states
+		}
+		return nil
+	})
+}
+
+// GetClusterStates returns the states of the clusters that have multiple priority levels
+func GetClusterStates() map[string]ClusterState {
+	cm := getClusterManager()
+	if cm == nil {
+		return nil
+	}
+	states := map[string]ClusterState{}
+	cm.clustersMap.Range(func(k, v interface{}) bool {
+		if lb, ok := v.(types.Cluster).Snapshot().LoadBalancer().(*priorityLoadBalancer); ok {
+			states[k.(string)] = ClusterState{
+				PriorityLevels: lb.levelStates(),
+			}
+		}
+		return true
+	})
+	return states
+}
+
+// GetPriorityLevelStates returns the states of the priority levels of the cluster,
+// it returns nil if the cluster is not found or the hosts of it have only one priority.
+func GetPriorityLevelStates(clusterName string) []PriorityLevelState {
+	cm := getClusterManager()
+	if cm == nil {
+		return nil
+	}
+	c := cm.getCluster(clusterName)
+	if c == nil {
+		return nil
+	}
+	if lb, ok := c.Snapshot().LoadBalancer().(*priorityLoadBalancer); ok {
+		return lb.levelStates()
+	}
+	return nil
+}
+
+func getOverprovisioningFactor(info types.ClusterInfo) uint32 {
+	if info != nil && info.LbConfig() != nil && info.LbConfig().OverprovisioningFactor > 0 {
+		return info.LbConfig().OverprovisioningFactor
+	}
+	return defaultOverprovisioningFactor
+}
+
+func countHealthyHosts(hosts types.HostSet) int {
+	healthy := 0
+	hosts.Range(func(host types.Host) bool {
+		if host.Health() {
+			healthy++
+		}
+		return true
+	})
+	return healthy
+}
+
+// healthPercent returns the healthy hosts ratio multiply the overprovisioning factor, and not more than 100.
+func healthPercent(healthy, total int, overprovisioningFactor uint32) uint32 {
+	if total == 0 {
+		return 0
+	}
+	h := uint32(healthy) * overprovisioningFactor / uint32(total)
+	if h > 100 {
+		h = 100
+	}
+	return h
+}
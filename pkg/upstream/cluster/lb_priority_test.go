@@ -0,0 +1,215 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cluster
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"mosn.io/api"
+	v2 "mosn.io/mosn/pkg/config/v2"
+	"mosn.io/mosn/pkg/configmanager"
+	"mosn.io/mosn/pkg/types"
+)
+
+// newPriorityMockHosts creates hosts in subnet, counts[i] is the number of hosts with priority i
+func newPriorityMockHosts(subnet int, counts ...int) []types.Host {
+	hosts := []types.Host{}
+	for priority, count := range counts {
+		for i := 0; i < count; i++ {
+			hosts = append(hosts, &mockHost{
+				addr:     fmt.Sprintf("127.%d.%d.%d:8080", subnet, priority, i),
+				priority: uint32(priority),
+			})
+		}
+	}
+	return hosts
+}
+
+func setHostsHealth(hosts []types.Host, healthy bool) {
+	for _, h := range hosts {
+		if healthy {
+			h.ClearHealthFlag(api.FAILED_ACTIVE_HC)
+		} else {
+			h.SetHealthFlag(api.FAILED_ACTIVE_HC)
+		}
+	}
+}
+
+func TestHostSetPriorityLevels(t *testing.T) {
+	hosts := newPriorityMockHosts(210, 2, 0, 3)
+	// out of order
+	hosts[0], hosts[4] = hosts[4], hosts[0]
+	levels := NewHostSet(hosts).PriorityLevels()
+	require.Len(t, levels, 2)
+	assert.Equal(t, 2, levels[0].Size())
+	assert.Equal(t, 3, levels[1].Size())
+	levels[0].Range(func(h types.Host) bool {
+		assert.Equal(t, uint32(0), h.Priority())
+		return true
+	})
+	levels[1].Range(func(h types.Host) bool {
+		assert.Equal(t, uint32(2), h.Priority())
+		return true
+	})
+	// single priority
+	hs := NewHostSet(newPriorityMockHosts(210, 3))
+	levels = hs.PriorityLevels()
+	require.Len(t, levels, 1)
+	assert.Equal(t, hs, levels[0])
+}
+
+func TestNewPriorityLoadBalancer(t *testing.T) {
+	info := NewClusterInfo(v2.Cluster{Name: "priority_new", LbType: v2.LB_RANDOM})
+	lb := NewLoadBalancer(info, NewHostSet(newPriorityMockHosts(211, 3)))
+	_, ok := lb.(*priorityLoadBalancer)
+	assert.False(t, ok)
+	lb = NewLoadBalancer(info, NewHostSet(newPriorityMockHosts(211, 3, 2)))
+	plb, ok := lb.(*priorityLoadBalancer)
+	require.True(t, ok)
+	require.Len(t, plb.levels, 2)
+	_, ok = plb.levels[0].lb.(*randomLoadBalancer)
+	assert.True(t, ok)
+	assert.True(t, lb.IsExistsHosts(nil))
+	assert.Equal(t, 5, lb.HostNum(nil))
+}
+
+func TestPriorityLoadBalancerFailover(t *testing.T) {
+	hosts := newPriorityMockHosts(212, 10, 10)
+	defer setHostsHealth(hosts, true)
+	info := NewClusterInfo(v2.Cluster{Name: "priority_failover", LbType: v2.LB_ROUNDROBIN})
+	lb := NewLoadBalancer(info, NewHostSet(hosts)).(*priorityLoadBalancer)
+	now := time.Now()
+	lb.nowFunc = func() time.Time {
+		return now
+	}
+	countPriority := func() map[uint32]int {
+		counts := map[uint32]int{}
+		for i := 0; i < 1000; i++ {
+			h := lb.ChooseHost(nil)
+			require.NotNil(t, h)
+			counts[h.Priority()]++
+		}
+		return counts
+	}
+	// all healthy
+	assert.Equal(t, []uint32{100, 0}, lb.getLoad().loads)
+	assert.Equal(t, map[uint32]int{0: 1000}, countPriority())
+	// 80% healthy * 1.4 is more than 100%, still no failover
+	setHostsHealth(hosts[:2], false)
+	lb.refreshLoad()
+	assert.Equal(t, []uint32{100, 0}, lb.getLoad().loads)
+	// 50% healthy * 1.4 = 70%
+	setHostsHealth(hosts[:5], false)
+	// the load is not refreshed in the interval
+	assert.Equal(t, []uint32{100, 0}, lb.getLoad().loads)
+	now = now.Add(healthRefreshInterval)
+	assert.Equal(t, []uint32{70, 30}, lb.getLoad().loads)
+	counts := countPriority()
+	assert.True(t, counts[0] > 600 && counts[0] < 800, "unexpected counts %v", counts)
+	// all the hosts in priority 0 are unhealthy
+	setHostsHealth(hosts[:10], false)
+	lb.refreshLoad()
+	assert.Equal(t, []uint32{0, 100}, lb.getLoad().loads)
+	assert.Equal(t, map[uint32]int{1: 1000}, countPriority())
+	// the total health is less than 100%, normalized
+	setHostsHealth(hosts[:5], true)
+	setHostsHealth(hosts[10:18], false)
+	lb.refreshLoad()
+	// health: 70, 28
+	assert.Equal(t, []uint32{72, 28}, lb.getLoad().loads)
+	// no healthy hosts
+	setHostsHealth(hosts, false)
+	lb.refreshLoad()
+	assert.Equal(t, []uint32{100, 0}, lb.getLoad().loads)
+	assert.Nil(t, lb.ChooseHost(nil))
+}
+
+func TestPriorityLoadBalancerOverprovisioningFactor(t *testing.T) {
+	hosts := newPriorityMockHosts(213, 4, 4)
+	defer setHostsHealth(hosts, true)
+	info := NewClusterInfo(v2.Cluster{
+		Name:   "priority_overprovisioning",
+		LbType: v2.LB_ROUNDROBIN,
+		LbConfig: &v2.LbConfig{
+			OverprovisioningFactor: 100,
+		},
+	})
+	setHostsHealth(hosts[:1], false)
+	lb := NewLoadBalancer(info, NewHostSet(hosts)).(*priorityLoadBalancer)
+	assert.Equal(t, []uint32{75, 25}, lb.getLoad().loads)
+	// per priority stats
+	assert.Equal(t, int64(3), lb.levels[0].stats.Gauge("priority_0_healthy_hosts").Value())
+	assert.Equal(t, int64(4), lb.levels[1].stats.Gauge("priority_1_healthy_hosts").Value())
+	assert.Equal(t, int64(4), lb.levels[1].stats.Gauge("priority_1_total_hosts").Value())
+}
+
+func TestPriorityLevelStatesHealthChanged(t *testing.T) {
+	_createClusterManager()
+	cm := clusterManagerInstance
+	var hostsConfig []v2.Host
+	for i := 0; i < 4; i++ {
+		hostsConfig = append(hostsConfig, v2.Host{
+			HostConfig: v2.HostConfig{
+				Address:  fmt.Sprintf("127.0.214.%d:8080", i),
+				Priority: uint32(i % 2),
+			},
+		})
+	}
+	require.Nil(t, cm.AddOrUpdateClusterAndHost(v2.Cluster{
+		Name:   "priority_states",
+		LbType: v2.LB_ROUNDROBIN,
+		OutlierDetection: &v2.OutlierDetection{
+			Consecutive5xx:     1,
+			MaxEjectionPercent: 100,
+		},
+	}, hostsConfig))
+	defer cm.RemovePrimaryCluster("priority_states")
+	assert.Equal(t, []PriorityLevelState{
+		{Priority: 0, HealthyHosts: 2, TotalHosts: 2, Load: 100},
+		{Priority: 1, HealthyHosts: 2, TotalHosts: 2, Load: 0},
+	}, GetPriorityLevelStates("priority_states"))
+	assert.Nil(t, GetPriorityLevelStates("priority_not_found"))
+	// the states are in the config dump
+	assert.Equal(t, GetPriorityLevelStates("priority_states"), GetClusterStates()["priority_states"].PriorityLevels)
+	dump := struct {
+		ClusterStates map[string]ClusterState `json:"cluster_states"`
+	}{}
+	b, err := configmanager.DumpJSON()
+	require.Nil(t, err)
+	require.Nil(t, json.Unmarshal(b, &dump))
+	assert.Equal(t, GetPriorityLevelStates("priority_states"), dump.ClusterStates["priority_states"].PriorityLevels)
+
+	// the states and the gauges are updated when a host is ejected, without choosing a host
+	snapshot := cm.GetClusterSnapshot(context.Background(), "priority_states")
+	lb := snapshot.LoadBalancer().(*priorityLoadBalancer)
+	host := lb.levels[0].hosts.Get(0)
+	snapshot.ClusterInfo().OutlierDetector().PutResult(host, types.Outlier5xx)
+	require.False(t, host.Health())
+	assert.Eventually(t, func() bool {
+		return GetPriorityLevelStates("priority_states")[0].HealthyHosts == 1
+	}, time.Second, 10*time.Millisecond)
+	assert.Equal(t, int64(1), lb.levels[0].stats.Gauge("priority_0_healthy_hosts").Value())
+	assert.Equal(t, uint32(70), GetPriorityLevelStates("priority_states")[0].Load)
+}
@@ -0,0 +1,216 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cluster
+
+import (
+	"math"
+	"sort"
+	"strconv"
+
+	"github.com/dchest/siphash"
+
+	"mosn.io/api"
+	"mosn.io/mosn/pkg/log"
+	"mosn.io/mosn/pkg/types"
+	"mosn.io/pkg/variable"
+)
+
+const (
+	defaultMinimumRingSize uint64 = 1024
+	defaultMaximumRingSize uint64 = 8 * 1024 * 1024
+)
+
+type ringEntry struct {
+	hash      uint64
+	hostIndex int
+}
+
+// ringHashLoadBalancer is a ketama style consistent hash load balancer.
+// Each host is placed on the ring several times in proportion to its weight,
+// a request is routed to the first host on the ring clockwise from its hash.
+// With the hash balance factor configured, a host whose active requests are more than
+// the factor multiply the average is skipped, see "consistent hashing with bounded loads".
+type ringHashLoadBalancer struct {
+	hosts         types.HostSet
+	ring          []ringEntry
+	balanceFactor float64
+}
+
+func newRingHashLoadBalancer(info types.ClusterInfo, set types.HostSet) types.LoadBalancer {
+	minRingSize, maxRingSize := defaultMinimumRingSize, defaultMaximumRingSize
+	lb := &ringHashLoadBalancer{
+		hosts: set,
+	}
+	if info != nil && info.LbConfig() != nil {
+		cfg := info.LbConfig()
+		if cfg.MinimumRingSize > 0 {
+			minRingSize = cfg.MinimumRingSize
+		}
+		if cfg.MaximumRingSize > 0 {
+			maxRingSize = cfg.MaximumRingSize
+		}
+		if cfg.HashBalanceFactor > 100 {
+			lb.balanceFactor = float64(cfg.HashBalanceFactor) / 100
+		}
+	}
+	if minRingSize > maxRingSize {
+		log.DefaultLogger.Errorf("[lb][ringhash] minimum ring size %d is greater than maximum ring size %d", minRingSize, maxRingSize)
+		minRingSize = maxRingSize
+	}
+	lb.ring = buildHashRing(set, minRingSize, maxRingSize)
+	return lb
+}
+
+// buildHashRing places the hosts on the ring, the ring size is scaled so that
+// the host with the min weight has one entry at least.
+func buildHashRing(set types.HostSet, minRingSize, maxRingSize uint64) []ringEntry {
+	if set == nil || set.Size() == 0 {
+		return nil
+	}
+	weights := make([]float64, set.Size())
+	totalWeight := 0.0
+	for i := 0; i < set.Size(); i++ {
+		weights[i] = fixHostWeight(float64(set.Get(i).Weight()))
+		totalWeight += weights[i]
+	}
+	minNormalizedWeight := 1.0
+	for i := range weights {
+		weights[i] /= totalWeight
+		minNormalizedWeight = math.Min(minNormalizedWeight, weights[i])
+	}
+	scale := math.Min(math.Ceil(minNormalizedWeight*float64(minRingSize))/minNormalizedWeight, float64(maxRingSize))
+	ring := make([]ringEntry, 0, uint64(math.Ceil(scale)))
+	currentHashes, targetHashes := 0.0, 0.0
+	for i := range weights {
+		address := set.Get(i).AddressString()
+		targetHashes += scale * weights[i]
+		for n := 0; currentHashes < targetHashes; n++ {
+			ring = append(ring, ringEntry{
+				hash:      siphash.Hash(0xbeefcafebabedead, 0, []byte(address+"_"+strconv.Itoa(n))),
+				hostIndex: i,
+			})
+			currentHashes++
+		}
+	}
+	sort.Slice(ring, func(i, j int) bool {
+		return ring[i].hash < ring[j].hash
+	})
+	if log.DefaultLogger.GetLogLevel() >= log.DEBUG {
+		log.DefaultLogger.Debugf("[lb][ringhash] build ring with %d hosts, ring size: %d", set.Size(), len(ring))
+	}
+	return ring
+}
+
+func (lb *ringHashLoadBalancer) ChooseHost(ctx types.LoadBalancerContext) types.Host {
+	if len(lb.ring) == 0 || ctx == nil {
+		return nil
+	}
+	route := ctx.DownstreamRoute()
+	if route == nil || route.RouteRule() == nil {
+		return nil
+	}
+	hashPolicy := route.RouteRule().Policy().HashPolicy()
+	if hashPolicy == nil {
+		return nil
+	}
+	context := ctx.DownstreamContext()
+	hash := hashPolicy.GenerateHash(context)
+	index := sort.Search(len(lb.ring), func(i int) bool {
+		return lb.ring[i].hash >= hash
+	})
+	// if retry, means request to last chose host failed, do not use it again
+	skip := -1
+	if ind, err := variable.GetString(context, VarProxyUpstreamIndex); err == nil {
+		if i, err := strconv.Atoi(ind); err == nil && i < len(lb.ring) {
+			index = i + 1
+			skip = lb.ring[i].hostIndex
+		}
+	}
+	chosen, chosenIndex := lb.chooseHostFromRing(index, skip)
+	if chosen == nil && skip >= 0 {
+		// no other host is available, such as a ring with only one host, retry the last chose host
+		chosen, chosenIndex = lb.chooseHostFromRing(index, -1)
+	}
+	if chosen == nil {
+		if log.Proxy.GetLogLevel() >= log.INFO {
+			log.Proxy.Infof(context, "[lb][ringhash] hash %d get nil host", hash)
+		}
+		return nil
+	}
+	variable.SetString(context, VarProxyUpstreamIndex, strconv.Itoa(chosenIndex))
+	if log.Proxy.GetLogLevel() >= log.DEBUG {
+		log.Proxy.Debugf(context, "[lb][ringhash] hash %d index %d get host %s", hash, chosenIndex, chosen.AddressString())
+	}
+	return chosen
+}
+
+// chooseHostFromRing walks the ring clockwise from the index to find a healthy host that is not overloaded.
+// If all the healthy hosts are overloaded, the first healthy host is returned.
+func (lb *ringHashLoadBalancer) chooseHostFromRing(index int, skip int) (types.Host, int) {
+	total := lb.hosts.Size()
+	visited := make([]bool, total)
+	if skip >= 0 {
+		visited[skip] = true
+	}
+	var threshold float64
+	if lb.balanceFactor > 0 {
+		threshold = lb.loadThreshold()
+	}
+	var fallback types.Host
+	fallbackIndex := -1
+	for i, checked := 0, 0; i < len(lb.ring) && checked < total; i++ {
+		ind := (index + i) % len(lb.ring)
+		hostIndex := lb.ring[ind].hostIndex
+		if visited[hostIndex] {
+			continue
+		}
+		visited[hostIndex] = true
+		checked++
+		host := lb.hosts.Get(hostIndex)
+		if !host.Health() {
+			continue
+		}
+		if threshold > 0 && float64(host.HostStats().UpstreamRequestActive.Count()+1) > threshold {
+			if fallback == nil {
+				fallback, fallbackIndex = host, ind
+			}
+			continue
+		}
+		return host, ind
+	}
+	return fallback, fallbackIndex
+}
+
+// loadThreshold returns the max active requests a host can hold, which is the balance
+// factor multiply the average active requests including the new request.
+func (lb *ringHashLoadBalancer) loadThreshold() float64 {
+	var active int64
+	lb.hosts.Range(func(host types.Host) bool {
+		active += host.HostStats().UpstreamRequestActive.Count()
+		return true
+	})
+	return math.Ceil(float64(active+1) * lb.balanceFactor / float64(lb.hosts.Size()))
+}
+
+func (lb *ringHashLoadBalancer) IsExistsHosts(metadata api.MetadataMatchCriteria) bool {
+	return lb.HostNum(metadata) > 0
+}
+
+func (lb *ringHashLoadBalancer) HostNum(metadata api.MetadataMatchCriteria) int {
+	return lb.hosts.Size()
+}
@@ -0,0 +1,202 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cluster
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"mosn.io/api"
+	v2 "mosn.io/mosn/pkg/config/v2"
+	"mosn.io/mosn/pkg/types"
+	"mosn.io/pkg/variable"
+)
+
+type ringHashTestPolicy struct {
+	api.HashPolicy
+	hash uint64
+}
+
+func (p *ringHashTestPolicy) GenerateHash(context context.Context) uint64 {
+	return p.hash
+}
+
+func newRingHashTestHosts(cluster string, subnet int, weights ...uint32) []types.Host {
+	hosts := make([]types.Host, 0, len(weights))
+	for i, w := range weights {
+		addr := fmt.Sprintf("127.0.%d.%d:8080", subnet, i)
+		hosts = append(hosts, &mockHost{
+			addr:  addr,
+			w:     w,
+			stats: newHostStats(cluster, addr),
+		})
+	}
+	return hosts
+}
+
+func newRingHashTestContext(hash uint64) (*mockLbContext, *ringHashTestPolicy) {
+	policy := &ringHashTestPolicy{hash: hash}
+	return &mockLbContext{
+		context: variable.NewVariableContext(context.Background()),
+		route: &mockRoute{
+			routeRule: &mockRouteRule{
+				policy: &mockPolicy{hashPolicy: policy},
+			},
+		},
+	}, policy
+}
+
+func TestRingHashBuildRing(t *testing.T) {
+	hosts := newRingHashTestHosts("ringhash_build", 230, 1, 1, 1)
+	lb := newRingHashLoadBalancer(nil, NewHostSet(hosts)).(*ringHashLoadBalancer)
+	// ceil(1024 / 3) * 3
+	assert.Len(t, lb.ring, 1026)
+	for i := 1; i < len(lb.ring); i++ {
+		require.True(t, lb.ring[i-1].hash <= lb.ring[i].hash)
+	}
+	// the entries are in proportion to the weights
+	hosts = newRingHashTestHosts("ringhash_build", 230, 1, 3)
+	info := NewClusterInfo(v2.Cluster{
+		Name:   "ringhash_build",
+		LbType: v2.LB_RING_HASH,
+		LbConfig: &v2.LbConfig{
+			MinimumRingSize: 100,
+			MaximumRingSize: 200,
+		},
+	})
+	lb = NewLoadBalancer(info, NewHostSet(hosts)).(*ringHashLoadBalancer)
+	counts := map[int]int{}
+	for _, entry := range lb.ring {
+		counts[entry.hostIndex]++
+	}
+	assert.Equal(t, map[int]int{0: 25, 1: 75}, counts)
+	// limited by the max ring size
+	info.LbConfig().MinimumRingSize = 1000
+	hosts = newRingHashTestHosts("ringhash_build", 230, 1, 1, 1)
+	lb = NewLoadBalancer(info, NewHostSet(hosts)).(*ringHashLoadBalancer)
+	assert.Len(t, lb.ring, 200)
+	// no hosts
+	lb = NewLoadBalancer(info, NewHostSet(nil)).(*ringHashLoadBalancer)
+	ctx, _ := newRingHashTestContext(0)
+	assert.Nil(t, lb.ChooseHost(ctx))
+	assert.False(t, lb.IsExistsHosts(nil))
+}
+
+func TestRingHashChooseHost(t *testing.T) {
+	hosts := newRingHashTestHosts("ringhash_choose", 231, 1, 1, 1, 1, 1)
+	defer setHostsHealth(hosts, true)
+	lb := newRingHashLoadBalancer(nil, NewHostSet(hosts))
+	// no hash policy
+	assert.Nil(t, lb.ChooseHost(&mockLbContext{
+		context: variable.NewVariableContext(context.Background()),
+		route:   &mockRoute{routeRule: &mockRouteRule{policy: &mockPolicy{}}},
+	}))
+	chosen := map[uint64]types.Host{}
+	for i := uint64(0); i < 1000; i++ {
+		hash := i * 0x9e3779b97f4a7c15
+		ctx, _ := newRingHashTestContext(hash)
+		host := lb.ChooseHost(ctx)
+		require.NotNil(t, host)
+		chosen[hash] = host
+		// the same hash gets the same host
+		ctx, _ = newRingHashTestContext(hash)
+		assert.Equal(t, host, lb.ChooseHost(ctx))
+	}
+	// unhealthy host is skipped, the other keys are not moved
+	setHostsHealth(hosts[3:4], false)
+	for hash, host := range chosen {
+		ctx, _ := newRingHashTestContext(hash)
+		h := lb.ChooseHost(ctx)
+		require.NotNil(t, h)
+		assert.NotEqual(t, hosts[3], h)
+		if host != hosts[3] {
+			assert.Equal(t, host, h)
+		}
+	}
+	setHostsHealth(hosts[3:4], true)
+	// removes a host, most of the keys on the other hosts are not moved
+	removed := hosts[2]
+	lb = newRingHashLoadBalancer(nil, NewHostSet(append(append([]types.Host{}, hosts[:2]...), hosts[3:]...)))
+	kept, total := 0, 0
+	for hash, host := range chosen {
+		ctx, _ := newRingHashTestContext(hash)
+		h := lb.ChooseHost(ctx)
+		assert.NotEqual(t, removed, h)
+		if host != removed {
+			total++
+			if host == h {
+				kept++
+			}
+		}
+	}
+	assert.True(t, float64(kept)/float64(total) > 0.7, "kept %d of %d", kept, total)
+}
+
+func TestRingHashRetry(t *testing.T) {
+	hosts := newRingHashTestHosts("ringhash_retry", 232, 1, 1, 1)
+	lb := newRingHashLoadBalancer(nil, NewHostSet(hosts))
+	ctx, _ := newRingHashTestContext(12345)
+	first := lb.ChooseHost(ctx)
+	require.NotNil(t, first)
+	// the context keeps the chosen index, the retry chooses another host
+	second := lb.ChooseHost(ctx)
+	require.NotNil(t, second)
+	assert.NotEqual(t, first, second)
+}
+
+func TestRingHashRetrySingleHost(t *testing.T) {
+	hosts := newRingHashTestHosts("ringhash_retry_single", 234, 1)
+	lb := newRingHashLoadBalancer(nil, NewHostSet(hosts))
+	ctx, _ := newRingHashTestContext(12345)
+	first := lb.ChooseHost(ctx)
+	require.NotNil(t, first)
+	// no other host can be chosen, the retry chooses the only host
+	assert.Equal(t, first, lb.ChooseHost(ctx))
+}
+
+func TestRingHashBoundedLoad(t *testing.T) {
+	hosts := newRingHashTestHosts("ringhash_bounded", 233, 1, 1, 1, 1)
+	info := NewClusterInfo(v2.Cluster{
+		Name:   "ringhash_bounded",
+		LbType: v2.LB_RING_HASH,
+		LbConfig: &v2.LbConfig{
+			HashBalanceFactor: 150,
+		},
+	})
+	lb := NewLoadBalancer(info, NewHostSet(hosts))
+	ctx, _ := newRingHashTestContext(67890)
+	hot := lb.ChooseHost(ctx)
+	require.NotNil(t, hot)
+	// average is (8 + 1) / 4, the threshold is ceil(2.25 * 1.5) = 4
+	hot.HostStats().UpstreamRequestActive.Inc(8)
+	defer hot.HostStats().UpstreamRequestActive.Dec(8)
+	for i := 0; i < 10; i++ {
+		ctx, _ = newRingHashTestContext(67890)
+		h := lb.ChooseHost(ctx)
+		require.NotNil(t, h)
+		assert.NotEqual(t, hot, h)
+	}
+	// unbounded
+	lb = newRingHashLoadBalancer(nil, NewHostSet(hosts))
+	ctx, _ = newRingHashTestContext(67890)
+	assert.Equal(t, hot, lb.ChooseHost(ctx))
+}
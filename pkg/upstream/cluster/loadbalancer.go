@@ -69,6 +69,7 @@ func init() {
 	RegisterLBType(types.RequestRoundRobin, newReqRoundRobinLoadBalancer)
 	RegisterLBType(types.LeastActiveConnection, newLeastActiveConnectionLoadBalancer)
 	RegisterLBType(types.PeakEwma, newPeakEwmaLoadBalancer)
+	RegisterLBType(types.RingHash, newRingHashLoadBalancer)
 
 	RegisterSlowStartMode(types.ModeDuration, slowStartDurationFactorFunc)
 
@@ -92,11 +93,28 @@ func registerVariables() {
 }
 
 func NewLoadBalancer(info types.ClusterInfo, hosts types.HostSet) types.LoadBalancer {
-	lbType := info.LbType()
-	if f, ok := lbFactories[lbType]; ok {
-		return f(info, hosts)
+	base := rrFactory.newRoundRobinLoadBalancer
+	if f, ok := lbFactories[info.LbType()]; ok {
+		base = f
 	}
-	return rrFactory.newRoundRobinLoadBalancer(info, hosts)
+	factory := base
+	if info.DegradedHostsEnabled() {
+		factory = degradedAwareFactory(factory)
+	}
+	if info.LbConfig() != nil && len(info.LbConfig().LocalityKeys) > 0 {
+		factory = localityAwareFactory(factory)
+	}
+	// in panic mode, the hosts are balanced without locality
+	if info.HealthyPanicThreshold() > 0 {
+		factory = panicAwareFactory(factory, base)
+	}
+	// hosts with different priorities are balanced by the priority load balancer
+	if hosts != nil && hosts.Size() > 0 {
+		if levels := hosts.PriorityLevels(); len(levels) > 1 {
+			return newPriorityLoadBalancer(info, levels, factory)
+		}
+	}
+	return factory(info, hosts)
 }
 
 // LoadBalancer Implementations
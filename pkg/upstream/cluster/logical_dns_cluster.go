@@ -0,0 +1,210 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cluster
+
+import (
+	"context"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	v2 "mosn.io/mosn/pkg/config/v2"
+	"mosn.io/mosn/pkg/log"
+	"mosn.io/mosn/pkg/network"
+	"mosn.io/mosn/pkg/types"
+	"mosn.io/pkg/utils"
+)
+
+func init() {
+	RegisterClusterType(v2.LOGICAL_DNS_CLUSTER, newLogicalDnsCluster)
+}
+
+// logicalDnsCluster keeps a single logical host for the configured address.
+// The address is resolved periodically, and the new connections are created
+// to the latest resolved address, the existing connections are not affected.
+type logicalDnsCluster struct {
+	*simpleCluster
+	dnsResolver     *network.DnsResolver
+	dnsLookupFamily v2.DnsLookupFamily
+	respectDnsTTL   bool
+	dnsRefreshRate  time.Duration
+	mutex           sync.Mutex
+	stop            chan struct{}
+}
+
+// logicalHost is identified by the configured address, the connection pools
+// and the health flags are shared across the resolved addresses.
+type logicalHost struct {
+	*simpleHost
+	dnsAddress string
+	port       string
+	// the latest resolved address, store string
+	resolvedAddress atomic.Value
+}
+
+func newLogicalDnsCluster(clusterConfig v2.Cluster) types.Cluster {
+	cluster := &logicalDnsCluster{
+		simpleCluster:   newSimpleCluster(clusterConfig).(*simpleCluster),
+		dnsLookupFamily: clusterConfig.DnsLookupFamily,
+		respectDnsTTL:   clusterConfig.RespectDnsTTL,
+		dnsResolver:     newClusterDnsResolver(clusterConfig),
+	}
+	if clusterConfig.DnsRefreshRate != nil {
+		cluster.dnsRefreshRate = clusterConfig.DnsRefreshRate.Duration
+	}
+	return cluster
+}
+
+func (ldc *logicalDnsCluster) UpdateHosts(newHosts types.HostSet) {
+	ldc.mutex.Lock()
+	defer ldc.mutex.Unlock()
+	ldc.stopResolve()
+
+	var host types.Host
+	if newHosts != nil {
+		newHosts.Range(func(h types.Host) bool {
+			host = h
+			return false
+		})
+		if newHosts.Size() > 1 {
+			log.DefaultLogger.Errorf("[upstream] [logical dns cluster] cluster %s should have only one host, use the first one: %s",
+				ldc.info.Name(), host.AddressString())
+		}
+	}
+	if host == nil {
+		ldc.simpleCluster.UpdateHosts(NewHostSet(nil))
+		return
+	}
+
+	addr, port := getHostPortFromAddr(host.AddressString())
+	if addr == "" {
+		log.DefaultLogger.Errorf("[upstream] [logical dns cluster] config address format error: %s", host.AddressString())
+		ldc.simpleCluster.UpdateHosts(NewHostSet(nil))
+		return
+	}
+	// default port: 80
+	if port == "" {
+		port = "80"
+	}
+	lh := &logicalHost{
+		simpleHost: NewSimpleHost(host.Config(), ldc.info).(*simpleHost),
+		dnsAddress: addr,
+		port:       port,
+	}
+	ldc.simpleCluster.UpdateHosts(NewHostSet([]types.Host{lh}))
+
+	// if address is already an ip, skip dns resolution
+	if net.ParseIP(addr) != nil {
+		lh.resolvedAddress.Store(net.JoinHostPort(addr, port))
+		return
+	}
+	stop := make(chan struct{})
+	ldc.stop = stop
+	utils.GoWithRecover(func() {
+		ldc.startResolve(lh, stop)
+	}, nil)
+}
+
+func (ldc *logicalDnsCluster) StopHealthChecking() {
+	ldc.mutex.Lock()
+	defer ldc.mutex.Unlock()
+	ldc.stopResolve()
+	ldc.simpleCluster.StopHealthChecking()
+}
+
+// stopResolve should be called with the lock
+func (ldc *logicalDnsCluster) stopResolve() {
+	if ldc.stop != nil {
+		close(ldc.stop)
+		ldc.stop = nil
+	}
+}
+
+func (ldc *logicalDnsCluster) startResolve(lh *logicalHost, stop chan struct{}) {
+	if log.DefaultLogger.GetLogLevel() >= log.DEBUG {
+		log.DefaultLogger.Debugf("[upstream] [logical dns cluster] start resolve dns address: %s", lh.dnsAddress)
+	}
+	for {
+		timer := time.NewTimer(ldc.resolve(lh))
+		select {
+		case <-stop:
+			timer.Stop()
+			if log.DefaultLogger.GetLogLevel() >= log.DEBUG {
+				log.DefaultLogger.Debugf("[upstream] [logical dns cluster] stop resolve dns address: %s", lh.dnsAddress)
+			}
+			return
+		case <-timer.C:
+		}
+	}
+}
+
+// resolve updates the address of the logical host by the first address in the dns response,
+// returns the interval of the next resolve.
+func (ldc *logicalDnsCluster) resolve(lh *logicalHost) time.Duration {
+	dnsResponse := ldc.dnsResolver.DnsResolve(lh.dnsAddress, ldc.dnsLookupFamily)
+	if dnsResponse == nil || len(*dnsResponse) == 0 {
+		if log.DefaultLogger.GetLogLevel() >= log.DEBUG {
+			log.DefaultLogger.Debugf("[upstream] [logical dns cluster] resolve dns address %s failed", lh.dnsAddress)
+		}
+		return nextResolveInterval(ldc.dnsRefreshRate, ldc.respectDnsTTL, 0)
+	}
+	rsp := (*dnsResponse)[0]
+	newAddr := net.JoinHostPort(rsp.Address, lh.port)
+	if lastAddr := lh.ResolvedAddress(); lastAddr != newAddr {
+		lh.resolvedAddress.Store(newAddr)
+		if log.DefaultLogger.GetLogLevel() >= log.INFO {
+			log.DefaultLogger.Infof("[upstream] [logical dns cluster] resolve dns result updated, cluster_name:%s, address:%s, last:%s, new:%s",
+				ldc.info.Name(), lh.dnsAddress, lastAddr, newAddr)
+		}
+	}
+	return nextResolveInterval(ldc.dnsRefreshRate, ldc.respectDnsTTL, rsp.Ttl)
+}
+
+// ResolvedAddress returns the latest resolved address, returns empty if it is not resolved yet
+func (lh *logicalHost) ResolvedAddress() string {
+	addr, _ := lh.resolvedAddress.Load().(string)
+	return addr
+}
+
+// Address returns the latest resolved address, or the configured address if it is not resolved yet
+func (lh *logicalHost) Address() net.Addr {
+	if addr := lh.ResolvedAddress(); addr != "" {
+		return GetOrCreateAddr(addr)
+	}
+	return lh.simpleHost.Address()
+}
+
+func (lh *logicalHost) UDPAddress() net.Addr {
+	if addr := lh.ResolvedAddress(); addr != "" {
+		return GetOrCreateUDPAddr(addr)
+	}
+	return lh.simpleHost.UDPAddress()
+}
+
+func (lh *logicalHost) CreateConnection(context context.Context) types.CreateConnectionData {
+	data := lh.simpleHost.createConnection(context, lh.Address())
+	data.Host = lh
+	return data
+}
+
+func (lh *logicalHost) CreateUDPConnection(context context.Context) types.CreateConnectionData {
+	data := lh.simpleHost.createUDPConnection(lh.UDPAddress())
+	data.Host = lh
+	return data
+}
@@ -0,0 +1,107 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cluster
+
+import (
+	"context"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	monkey "github.com/cch123/supermonkey"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"mosn.io/api"
+	v2 "mosn.io/mosn/pkg/config/v2"
+	"mosn.io/mosn/pkg/network"
+	"mosn.io/mosn/pkg/types"
+)
+
+func getLogicalHost(t *testing.T, c types.Cluster) *logicalHost {
+	hs := c.Snapshot().HostSet()
+	require.Equal(t, 1, hs.Size())
+	var lh *logicalHost
+	hs.Range(func(host types.Host) bool {
+		lh, _ = host.(*logicalHost)
+		return false
+	})
+	require.NotNil(t, lh)
+	return lh
+}
+
+func TestLogicalDnsCluster(t *testing.T) {
+	c := NewCluster(v2.Cluster{
+		Name:           "logical_dns_cluster",
+		LbType:         v2.LB_ROUNDROBIN,
+		ClusterType:    v2.LOGICAL_DNS_CLUSTER,
+		DnsRefreshRate: &api.DurationConfig{Duration: time.Hour},
+	})
+	ldc, ok := c.(*logicalDnsCluster)
+	require.True(t, ok)
+	defer ldc.StopHealthChecking()
+
+	var mutex sync.Mutex
+	resolved := "127.0.0.1"
+	monkey.PatchInstanceMethod(reflect.TypeOf(ldc.dnsResolver), "DnsResolve",
+		func(resolver *network.DnsResolver, dnsAddr string, dnsLookupFamily v2.DnsLookupFamily) *[]network.DnsResponse {
+			mutex.Lock()
+			defer mutex.Unlock()
+			return &[]network.DnsResponse{
+				{Address: resolved, Ttl: 10 * time.Second},
+				{Address: "127.0.0.10", Ttl: 5 * time.Second},
+			}
+		})
+	defer monkey.UnpatchAll()
+
+	newHost := func(addr string) types.Host {
+		return NewSimpleHost(v2.Host{HostConfig: v2.HostConfig{Address: addr}}, c.Snapshot().ClusterInfo())
+	}
+	// only the first host is used
+	c.UpdateHosts(NewHostSet([]types.Host{newHost("logical.dns.test:8080"), newHost("other.dns.test:8080")}))
+	lh := getLogicalHost(t, c)
+	require.Eventually(t, func() bool {
+		return lh.ResolvedAddress() != ""
+	}, time.Second, 10*time.Millisecond)
+	assert.Equal(t, "logical.dns.test:8080", lh.AddressString())
+	assert.Equal(t, "127.0.0.1:8080", lh.ResolvedAddress())
+	assert.Equal(t, "127.0.0.1:8080", lh.Address().String())
+	data := lh.CreateConnection(context.Background())
+	assert.Equal(t, "127.0.0.1:8080", data.Connection.RemoteAddr().String())
+	assert.True(t, data.Host == lh)
+
+	// the address is changed, the host is not changed
+	mutex.Lock()
+	resolved = "127.0.0.2"
+	mutex.Unlock()
+	assert.Equal(t, time.Hour, ldc.resolve(lh))
+	assert.True(t, getLogicalHost(t, c) == lh)
+	assert.Equal(t, "logical.dns.test:8080", lh.AddressString())
+	assert.Equal(t, "127.0.0.2:8080", lh.CreateConnection(context.Background()).Connection.RemoteAddr().String())
+
+	// respect dns ttl
+	ldc.respectDnsTTL = true
+	assert.Equal(t, 11*time.Second, ldc.resolve(lh))
+
+	// ip address is not resolved
+	c.UpdateHosts(NewHostSet([]types.Host{newHost("127.0.0.3:8080")}))
+	lh = getLogicalHost(t, c)
+	assert.Equal(t, "127.0.0.3:8080", lh.ResolvedAddress())
+	assert.Nil(t, ldc.stop)
+}
@@ -55,6 +55,10 @@ func (hs *mockHostSet) Size() int {
 	return len(hs.hosts)
 }
 
+func (hs *mockHostSet) PriorityLevels() []types.HostSet {
+	return []types.HostSet{hs}
+}
+
 func getMockHostSet(count int) *mockHostSet {
 	hosts := []types.Host{}
 	hostCount := count
@@ -77,6 +81,7 @@ type mockHost struct {
 	addr        string
 	meta        api.Metadata
 	w           uint32
+	priority    uint32
 	clusterInfo types.ClusterInfo
 
 	healthFlag *uint64
@@ -110,7 +115,7 @@ func (h *mockHost) Health() bool {
 	if mhs, ok := h.hostSet.(*mockHostSet); ok {
 		mhs.healthCheckVisitedCount++
 	}
-	return atomic.LoadUint64(h.healthFlag) == 0
+	return atomic.LoadUint64(h.healthFlag)&^uint64(types.DEGRADED_ACTIVE_HC) == 0
 }
 
 func (h *mockHost) ClearHealthFlag(flag api.HealthFlag) {
@@ -138,6 +143,10 @@ func (h *mockHost) Weight() uint32 {
 	return h.w
 }
 
+func (h *mockHost) Priority() uint32 {
+	return h.priority
+}
+
 type ipPool struct {
 	idx int
 	ips []string
@@ -0,0 +1,374 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cluster
+
+import (
+	"math"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"mosn.io/api"
+	v2 "mosn.io/mosn/pkg/config/v2"
+	"mosn.io/mosn/pkg/log"
+	"mosn.io/mosn/pkg/types"
+	"mosn.io/pkg/utils"
+)
+
+const (
+	defaultOutlierInterval                 = 10 * time.Second
+	defaultOutlierBaseEjectionTime         = 30 * time.Second
+	defaultOutlierMaxEjectionTime          = 300 * time.Second
+	defaultOutlierMaxEjectionPercent       = 10
+	defaultOutlierSuccessRateMinimumHosts  = 5
+	defaultOutlierSuccessRateRequestVolume = 100
+)
+
+// outlierEjectReason is the reason why a host is ejected
+type outlierEjectReason int
+
+const (
+	ejectConsecutive5xx outlierEjectReason = iota
+	ejectConsecutiveGatewayFailure
+	ejectConsecutiveConnectFailure
+	ejectSuccessRate
+)
+
+// outlierHostMonitor records the results of a host
+type outlierHostMonitor struct {
+	host types.Host
+	// consecutive counters, updated by PutResult
+	consecutive5xx            uint32
+	consecutiveGatewayFailure uint32
+	consecutiveConnectFailure uint32
+	// success rate counters in current interval, updated by PutResult
+	requests  uint64
+	successes uint64
+	// ejection states, protected by the detector's mutex
+	ejected       bool
+	ejectionTime  time.Duration
+	numEjections  uint32
+	lastEjectTime time.Time
+}
+
+func (m *outlierHostMonitor) resetConsecutive() {
+	atomic.StoreUint32(&m.consecutive5xx, 0)
+	atomic.StoreUint32(&m.consecutiveGatewayFailure, 0)
+	atomic.StoreUint32(&m.consecutiveConnectFailure, 0)
+}
+
+// outlierDetector is an implementation of types.OutlierDetector
+type outlierDetector struct {
+	info  types.ClusterInfo
+	stats *types.ClusterStats
+	// config
+	consecutive5xx            uint32
+	consecutiveGatewayFailure uint32
+	consecutiveConnectFailure uint32
+	interval                  time.Duration
+	baseEjectionTime          time.Duration
+	maxEjectionTime           time.Duration
+	maxEjectionPercent        uint32
+	successRateMinimumHosts   uint32
+	successRateRequestVolume  uint64
+	successRateStdevFactor    float64
+	// runtime
+	monitors atomic.Value // store map[string]*outlierHostMonitor
+	mutex    sync.Mutex
+	ejected  int64
+	timer    *utils.Timer
+	stopped  bool
+	// nowFunc for testing
+	nowFunc func() time.Time
+}
+
+func newOutlierDetector(info types.ClusterInfo, cfg *v2.OutlierDetection) *outlierDetector {
+	d := &outlierDetector{
+		info:                      info,
+		stats:                     info.Stats(),
+		consecutive5xx:            cfg.Consecutive5xx,
+		consecutiveGatewayFailure: cfg.ConsecutiveGatewayFailure,
+		consecutiveConnectFailure: cfg.ConsecutiveConnectFailure,
+		interval:                  defaultOutlierInterval,
+		baseEjectionTime:          defaultOutlierBaseEjectionTime,
+		maxEjectionTime:           defaultOutlierMaxEjectionTime,
+		maxEjectionPercent:        defaultOutlierMaxEjectionPercent,
+		successRateMinimumHosts:   defaultOutlierSuccessRateMinimumHosts,
+		successRateRequestVolume:  defaultOutlierSuccessRateRequestVolume,
+		successRateStdevFactor:    cfg.SuccessRateStdevFactor,
+		nowFunc:                   time.Now,
+	}
+	if cfg.Interval != nil && cfg.Interval.Duration > 0 {
+		d.interval = cfg.Interval.Duration
+	}
+	if cfg.BaseEjectionTime != nil && cfg.BaseEjectionTime.Duration > 0 {
+		d.baseEjectionTime = cfg.BaseEjectionTime.Duration
+	}
+	if cfg.MaxEjectionTime != nil && cfg.MaxEjectionTime.Duration > 0 {
+		d.maxEjectionTime = cfg.MaxEjectionTime.Duration
+	}
+	// max ejection time should not be less than base ejection time
+	if d.maxEjectionTime < d.baseEjectionTime {
+		d.maxEjectionTime = d.baseEjectionTime
+	}
+	if cfg.MaxEjectionPercent > 0 {
+		d.maxEjectionPercent = cfg.MaxEjectionPercent
+	}
+	if d.maxEjectionPercent > 100 {
+		d.maxEjectionPercent = 100
+	}
+	if cfg.SuccessRateMinimumHosts > 0 {
+		d.successRateMinimumHosts = cfg.SuccessRateMinimumHosts
+	}
+	if cfg.SuccessRateRequestVolume > 0 {
+		d.successRateRequestVolume = uint64(cfg.SuccessRateRequestVolume)
+	}
+	d.monitors.Store(map[string]*outlierHostMonitor{})
+	return d
+}
+
+func (d *outlierDetector) getMonitor(host types.Host) *outlierHostMonitor {
+	monitors, _ := d.monitors.Load().(map[string]*outlierHostMonitor)
+	return monitors[host.AddressString()]
+}
+
+// PutResult is called by the proxy for each upstream request or connection.
+// A connect failure is treated as a gateway failure, and a gateway failure is treated as a 5xx,
+// so each of them can trigger the consecutive 5xx ejection.
+func (d *outlierDetector) PutResult(host types.Host, result types.OutlierResult) {
+	m := d.getMonitor(host)
+	if m == nil {
+		return
+	}
+	atomic.AddUint64(&m.requests, 1)
+	if result == types.OutlierSuccess {
+		atomic.AddUint64(&m.successes, 1)
+		m.resetConsecutive()
+		return
+	}
+	if result == types.OutlierConnectFailure {
+		if n := atomic.AddUint32(&m.consecutiveConnectFailure, 1); d.consecutiveConnectFailure > 0 && n >= d.consecutiveConnectFailure {
+			d.ejectHost(m, ejectConsecutiveConnectFailure)
+			return
+		}
+	} else {
+		atomic.StoreUint32(&m.consecutiveConnectFailure, 0)
+	}
+	if result == types.OutlierGatewayFailure || result == types.OutlierConnectFailure {
+		if n := atomic.AddUint32(&m.consecutiveGatewayFailure, 1); d.consecutiveGatewayFailure > 0 && n >= d.consecutiveGatewayFailure {
+			d.ejectHost(m, ejectConsecutiveGatewayFailure)
+			return
+		}
+	} else {
+		atomic.StoreUint32(&m.consecutiveGatewayFailure, 0)
+	}
+	if n := atomic.AddUint32(&m.consecutive5xx, 1); d.consecutive5xx > 0 && n >= d.consecutive5xx {
+		d.ejectHost(m, ejectConsecutive5xx)
+	}
+}
+
+// SetHostSet is called when the cluster's hosts changed.
+// The monitors of the existing hosts are kept, and the removed hosts are restored.
+func (d *outlierDetector) SetHostSet(hostSet types.HostSet) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	if d.stopped {
+		return
+	}
+	oldMonitors, _ := d.monitors.Load().(map[string]*outlierHostMonitor)
+	monitors := make(map[string]*outlierHostMonitor, hostSet.Size())
+	hostSet.Range(func(host types.Host) bool {
+		addr := host.AddressString()
+		if m, ok := oldMonitors[addr]; ok {
+			m.host = host
+			monitors[addr] = m
+		} else {
+			monitors[addr] = &outlierHostMonitor{
+				host: host,
+			}
+		}
+		return true
+	})
+	for addr, m := range oldMonitors {
+		if _, ok := monitors[addr]; !ok && m.ejected {
+			d.unejectHost(m)
+		}
+	}
+	d.monitors.Store(monitors)
+	if d.timer == nil {
+		d.timer = utils.NewTimer(d.interval, d.onInterval)
+	}
+}
+
+// Stop stops the interval timer and restores all the ejected hosts.
+// The cluster is always replaced by a new one when the cluster config is updated,
+// so the hosts will be checked by the new cluster's detector from a clean state.
+func (d *outlierDetector) Stop() {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	if d.stopped {
+		return
+	}
+	d.stopped = true
+	if d.timer != nil {
+		d.timer.Stop()
+	}
+	monitors, _ := d.monitors.Load().(map[string]*outlierHostMonitor)
+	for _, m := range monitors {
+		if m.ejected {
+			d.unejectHost(m)
+		}
+	}
+}
+
+func (d *outlierDetector) onInterval() {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	if d.stopped {
+		return
+	}
+	d.checkEjections()
+	d.checkSuccessRate()
+	d.timer = utils.NewTimer(d.interval, d.onInterval)
+}
+
+// checkEjections restores the hosts whose ejection time is expired.
+// The ejection time of a host grows with the times it is ejected, and
+// decreases when the host is not ejected in an interval.
+func (d *outlierDetector) checkEjections() {
+	now := d.nowFunc()
+	monitors, _ := d.monitors.Load().(map[string]*outlierHostMonitor)
+	for _, m := range monitors {
+		if m.ejected {
+			if now.Sub(m.lastEjectTime) >= m.ejectionTime {
+				d.unejectHost(m)
+			}
+			continue
+		}
+		if m.numEjections > 0 && now.Sub(m.lastEjectTime) >= m.ejectionTime+d.interval {
+			m.numEjections--
+		}
+	}
+}
+
+// checkSuccessRate ejects the hosts whose success rate is less than
+// mean - (stdev * success_rate_stdev_factor) in current interval.
+func (d *outlierDetector) checkSuccessRate() {
+	monitors, _ := d.monitors.Load().(map[string]*outlierHostMonitor)
+	rates := make(map[*outlierHostMonitor]float64, len(monitors))
+	for _, m := range monitors {
+		requests := atomic.SwapUint64(&m.requests, 0)
+		successes := atomic.SwapUint64(&m.successes, 0)
+		if m.ejected || requests < d.successRateRequestVolume {
+			continue
+		}
+		rates[m] = float64(successes) * 100 / float64(requests)
+	}
+	if d.successRateStdevFactor <= 0 || len(rates) == 0 || uint32(len(rates)) < d.successRateMinimumHosts {
+		return
+	}
+	var sum float64
+	for _, rate := range rates {
+		sum += rate
+	}
+	mean := sum / float64(len(rates))
+	var variance float64
+	for _, rate := range rates {
+		variance += (rate - mean) * (rate - mean)
+	}
+	stdev := math.Sqrt(variance / float64(len(rates)))
+	threshold := mean - stdev*d.successRateStdevFactor
+	for m, rate := range rates {
+		if rate < threshold {
+			d.ejectHostLocked(m, ejectSuccessRate)
+		}
+	}
+}
+
+func (d *outlierDetector) ejectHost(m *outlierHostMonitor, reason outlierEjectReason) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	if d.stopped {
+		return
+	}
+	d.ejectHostLocked(m, reason)
+}
+
+func (d *outlierDetector) ejectHostLocked(m *outlierHostMonitor, reason outlierEjectReason) {
+	m.resetConsecutive()
+	if m.ejected {
+		return
+	}
+	monitors, _ := d.monitors.Load().(map[string]*outlierHostMonitor)
+	// the host may be removed already
+	if monitors[m.host.AddressString()] != m {
+		return
+	}
+	// at least one host can be ejected
+	if d.ejected > 0 && (d.ejected+1)*100 > int64(len(monitors))*int64(d.maxEjectionPercent) {
+		d.stats.OutlierEjectionsOverflow.Inc(1)
+		if log.DefaultLogger.GetLogLevel() >= log.INFO {
+			log.DefaultLogger.Infof("[upstream] [outlier detection] cluster %s reaches max ejection percent, host %s is not ejected",
+				d.info.Name(), m.host.AddressString())
+		}
+		return
+	}
+	m.ejected = true
+	m.numEjections++
+	m.lastEjectTime = d.nowFunc()
+	ejectionTime := d.baseEjectionTime * time.Duration(m.numEjections)
+	if ejectionTime > d.maxEjectionTime {
+		ejectionTime = d.maxEjectionTime
+		// keep the ejection multiplier from growing endlessly
+		m.numEjections--
+	}
+	m.ejectionTime = ejectionTime
+	m.host.SetHealthFlag(api.FAILED_OUTLIER_CHECK)
+	m.host.HostStats().UpstreamRequestFailureEject.Inc(1)
+	onClusterHealthChanged(d.info.Name())
+
+	d.ejected++
+	d.stats.OutlierEjectionsActive.Update(d.ejected)
+	d.stats.OutlierEjectionsTotal.Inc(1)
+	switch reason {
+	case ejectConsecutive5xx:
+		d.stats.OutlierEjectionsConsecutive5xx.Inc(1)
+	case ejectConsecutiveGatewayFailure:
+		d.stats.OutlierEjectionsConsecutiveGatewayFailure.Inc(1)
+	case ejectConsecutiveConnectFailure:
+		d.stats.OutlierEjectionsConsecutiveConnectFailure.Inc(1)
+	case ejectSuccessRate:
+		d.stats.OutlierEjectionsSuccessRate.Inc(1)
+	}
+	if log.DefaultLogger.GetLogLevel() >= log.INFO {
+		log.DefaultLogger.Infof("[upstream] [outlier detection] cluster %s eject host %s for %s, reason: %d",
+			d.info.Name(), m.host.AddressString(), ejectionTime, reason)
+	}
+}
+
+func (d *outlierDetector) unejectHost(m *outlierHostMonitor) {
+	m.ejected = false
+	m.resetConsecutive()
+	m.host.ClearHealthFlag(api.FAILED_OUTLIER_CHECK)
+	onClusterHealthChanged(d.info.Name())
+	d.ejected--
+	d.stats.OutlierEjectionsActive.Update(d.ejected)
+	if log.DefaultLogger.GetLogLevel() >= log.INFO {
+		log.DefaultLogger.Infof("[upstream] [outlier detection] cluster %s uneject host %s", d.info.Name(), m.host.AddressString())
+	}
+}
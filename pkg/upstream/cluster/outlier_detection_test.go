@@ -0,0 +1,196 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cluster
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"mosn.io/api"
+	v2 "mosn.io/mosn/pkg/config/v2"
+	"mosn.io/mosn/pkg/types"
+)
+
+func newOutlierTestCluster(name string, subnet, count int, cfg *v2.OutlierDetection) (types.Cluster, []types.Host) {
+	c := NewCluster(v2.Cluster{
+		Name:             name,
+		ClusterType:      v2.SIMPLE_CLUSTER,
+		LbType:           v2.LB_ROUNDROBIN,
+		OutlierDetection: cfg,
+	})
+	info := c.Snapshot().ClusterInfo()
+	hosts := make([]types.Host, 0, count)
+	for i := 0; i < count; i++ {
+		hosts = append(hosts, NewSimpleHost(v2.Host{
+			HostConfig: v2.HostConfig{
+				Address: fmt.Sprintf("127.0.%d.%d:8080", subnet, i),
+			},
+		}, info))
+	}
+	c.UpdateHosts(NewHostSet(hosts))
+	return c, hosts
+}
+
+func TestOutlierDetectionNotConfigured(t *testing.T) {
+	info := NewClusterInfo(v2.Cluster{Name: "outlier_not_configured"})
+	assert.Nil(t, info.OutlierDetector())
+}
+
+func TestOutlierDetectionConsecutiveErrors(t *testing.T) {
+	c, hosts := newOutlierTestCluster("outlier_consecutive", 201, 10, &v2.OutlierDetection{
+		Consecutive5xx:            3,
+		ConsecutiveConnectFailure: 2,
+		MaxEjectionPercent:        100,
+	})
+	defer c.StopHealthChecking()
+	od := c.Snapshot().ClusterInfo().OutlierDetector()
+	require.NotNil(t, od)
+	stats := c.Snapshot().ClusterInfo().Stats()
+	// success resets the consecutive counter
+	od.PutResult(hosts[0], types.Outlier5xx)
+	od.PutResult(hosts[0], types.Outlier5xx)
+	od.PutResult(hosts[0], types.OutlierSuccess)
+	od.PutResult(hosts[0], types.Outlier5xx)
+	assert.True(t, hosts[0].Health())
+	od.PutResult(hosts[0], types.OutlierGatewayFailure)
+	od.PutResult(hosts[0], types.Outlier5xx)
+	assert.False(t, hosts[0].Health())
+	assert.True(t, hosts[0].ContainHealthFlag(api.FAILED_OUTLIER_CHECK))
+	assert.Equal(t, int64(1), stats.OutlierEjectionsConsecutive5xx.Count())
+	// connect failure
+	od.PutResult(hosts[1], types.OutlierConnectFailure)
+	assert.True(t, hosts[1].Health())
+	od.PutResult(hosts[1], types.OutlierConnectFailure)
+	assert.False(t, hosts[1].Health())
+	assert.Equal(t, int64(1), stats.OutlierEjectionsConsecutiveConnectFailure.Count())
+	assert.Equal(t, int64(2), stats.OutlierEjectionsTotal.Count())
+	assert.Equal(t, int64(2), stats.OutlierEjectionsActive.Value())
+	// the load balancer skips the ejected hosts
+	lb := c.Snapshot().LoadBalancer()
+	for i := 0; i < 20; i++ {
+		h := lb.ChooseHost(nil)
+		assert.NotEqual(t, hosts[0].AddressString(), h.AddressString())
+		assert.NotEqual(t, hosts[1].AddressString(), h.AddressString())
+	}
+}
+
+func TestOutlierDetectionMaxEjectionPercent(t *testing.T) {
+	c, hosts := newOutlierTestCluster("outlier_max_percent", 202, 4, &v2.OutlierDetection{
+		ConsecutiveGatewayFailure: 1,
+		MaxEjectionPercent:        50,
+	})
+	defer c.StopHealthChecking()
+	od := c.Snapshot().ClusterInfo().OutlierDetector()
+	for _, h := range hosts {
+		od.PutResult(h, types.OutlierGatewayFailure)
+	}
+	ejected := 0
+	for _, h := range hosts {
+		if !h.Health() {
+			ejected++
+		}
+	}
+	assert.Equal(t, 2, ejected)
+	stats := c.Snapshot().ClusterInfo().Stats()
+	assert.Equal(t, int64(2), stats.OutlierEjectionsOverflow.Count())
+}
+
+func TestOutlierDetectionUneject(t *testing.T) {
+	c, hosts := newOutlierTestCluster("outlier_uneject", 203, 2, &v2.OutlierDetection{
+		Consecutive5xx:     1,
+		MaxEjectionPercent: 100,
+		BaseEjectionTime:   &api.DurationConfig{Duration: time.Second},
+		MaxEjectionTime:    &api.DurationConfig{Duration: 3 * time.Second},
+		Interval:           &api.DurationConfig{Duration: time.Hour},
+	})
+	defer c.StopHealthChecking()
+	od := c.Snapshot().ClusterInfo().OutlierDetector().(*outlierDetector)
+	now := time.Now()
+	od.nowFunc = func() time.Time {
+		return now
+	}
+	host := hosts[0]
+	m := od.getMonitor(host)
+	// the ejection time grows with the ejection times, and not more than the max ejection time
+	for i, expected := range []time.Duration{time.Second, 2 * time.Second, 3 * time.Second, 3 * time.Second} {
+		od.PutResult(host, types.Outlier5xx)
+		require.False(t, host.Health(), "round %d", i)
+		require.Equal(t, expected, m.ejectionTime, "round %d", i)
+		now = now.Add(expected - time.Millisecond)
+		od.onInterval()
+		require.False(t, host.Health(), "round %d", i)
+		now = now.Add(time.Millisecond)
+		od.onInterval()
+		require.True(t, host.Health(), "round %d", i)
+	}
+	// the ejection multiplier decreases when the host is healthy
+	now = now.Add(time.Hour)
+	od.onInterval()
+	od.onInterval()
+	od.PutResult(host, types.Outlier5xx)
+	assert.Equal(t, 2*time.Second, m.ejectionTime)
+	// removed host is restored
+	c.UpdateHosts(NewHostSet(hosts[1:]))
+	assert.True(t, host.Health())
+	assert.Nil(t, od.getMonitor(host))
+	// stop restores all the ejected hosts
+	od.PutResult(hosts[1], types.Outlier5xx)
+	assert.False(t, hosts[1].Health())
+	c.StopHealthChecking()
+	assert.True(t, hosts[1].Health())
+	assert.Equal(t, int64(0), c.Snapshot().ClusterInfo().Stats().OutlierEjectionsActive.Value())
+}
+
+func TestOutlierDetectionSuccessRate(t *testing.T) {
+	c, hosts := newOutlierTestCluster("outlier_success_rate", 204, 6, &v2.OutlierDetection{
+		SuccessRateStdevFactor:   1.9,
+		SuccessRateMinimumHosts:  5,
+		SuccessRateRequestVolume: 10,
+		MaxEjectionPercent:       100,
+		Interval:                 &api.DurationConfig{Duration: time.Hour},
+	})
+	defer c.StopHealthChecking()
+	od := c.Snapshot().ClusterInfo().OutlierDetector().(*outlierDetector)
+	for i, h := range hosts {
+		for j := 0; j < 100; j++ {
+			result := types.OutlierSuccess
+			// host 0 fails half of the requests, others fail 1%
+			if (i == 0 && j%2 == 0) || (i != 0 && j == 0) {
+				result = types.Outlier5xx
+			}
+			od.PutResult(h, result)
+		}
+	}
+	od.onInterval()
+	assert.False(t, hosts[0].Health())
+	for _, h := range hosts[1:] {
+		assert.True(t, h.Health())
+	}
+	assert.Equal(t, int64(1), c.Snapshot().ClusterInfo().Stats().OutlierEjectionsSuccessRate.Count())
+	// not enough request volume, no ejection
+	for _, h := range hosts[1:] {
+		od.PutResult(h, types.Outlier5xx)
+	}
+	od.onInterval()
+	for _, h := range hosts[1:] {
+		assert.True(t, h.Health())
+	}
+}
@@ -0,0 +1,145 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cluster
+
+import (
+	"context"
+	gotls "crypto/tls"
+	"fmt"
+	"net"
+
+	"mosn.io/api"
+	v2 "mosn.io/mosn/pkg/config/v2"
+	"mosn.io/mosn/pkg/mtls/crypto/tls"
+	"mosn.io/mosn/pkg/network"
+	"mosn.io/mosn/pkg/types"
+	"mosn.io/pkg/variable"
+)
+
+// checkProxyProtocolConfig checks the version and the TLVs of the PROXY protocol config
+func checkProxyProtocolConfig(config *v2.ProxyProtocolConfig) error {
+	if config == nil {
+		return nil
+	}
+	switch config.Version {
+	case "", v2.ProxyProtocolV1:
+		if len(config.TLVs) > 0 {
+			return fmt.Errorf("proxy protocol TLVs %v are only supported in version %s", config.TLVs, v2.ProxyProtocolV2)
+		}
+	case v2.ProxyProtocolV2:
+		for _, tlv := range config.TLVs {
+			switch tlv {
+			case v2.ProxyProtocolTLVAlpn, v2.ProxyProtocolTLVSni, v2.ProxyProtocolTLVAuthority:
+			default:
+				return fmt.Errorf("unsupported proxy protocol TLV %s", tlv)
+			}
+		}
+	default:
+		return fmt.Errorf("unsupported proxy protocol version %s", config.Version)
+	}
+	return nil
+}
+
+// getDownstreamConnection returns the downstream connection in the context, returns nil if it is not found
+func getDownstreamConnection(ctx context.Context) api.Connection {
+	if ctx == nil {
+		return nil
+	}
+	if v, err := variable.Get(ctx, types.VariableConnection); err == nil && v != nil {
+		if conn, ok := v.(api.Connection); ok {
+			return conn
+		}
+	}
+	return nil
+}
+
+// newProxyProtocolHeader builds the PROXY protocol header with the downstream connection in the context.
+// The header has no addresses if the downstream connection is not found, such as the prewarmed connections.
+func newProxyProtocolHeader(ctx context.Context, config *v2.ProxyProtocolConfig) *network.ProxyProtocolHeader {
+	header := &network.ProxyProtocolHeader{
+		Version: config.Version,
+	}
+	conn := getDownstreamConnection(ctx)
+	if conn == nil {
+		return header
+	}
+	header.SourceAddr = conn.RemoteAddr()
+	header.DestinationAddr = conn.LocalAddr()
+	if config.Version != v2.ProxyProtocolV2 || len(config.TLVs) == 0 {
+		return header
+	}
+	var authority string
+	for _, tlv := range config.TLVs {
+		switch tlv {
+		case v2.ProxyProtocolTLVAlpn:
+			if alpn := negotiatedProtocol(conn.RawConn()); alpn != "" {
+				header.TLVs = append(header.TLVs, network.ProxyProtocolTLV{
+					Type:  network.PP2TypeALPN,
+					Value: []byte(alpn),
+				})
+			}
+		case v2.ProxyProtocolTLVSni:
+			// the server name is set by the tls inspector if the tls is not terminated
+			if serverName, err := variable.GetString(ctx, types.VariableRequestedServerName); err == nil && authority == "" {
+				authority = serverName
+			}
+		case v2.ProxyProtocolTLVAuthority:
+			if requestAuthority := getRequestAuthority(ctx); requestAuthority != "" {
+				authority = requestAuthority
+			}
+		}
+	}
+	if authority != "" {
+		header.TLVs = append(header.TLVs, network.ProxyProtocolTLV{
+			Type:  network.PP2TypeAuthority,
+			Value: []byte(authority),
+		})
+	}
+	return header
+}
+
+// negotiatedProtocol returns the ALPN negotiated by the downstream tls connection
+func negotiatedProtocol(conn net.Conn) string {
+	// the mtls connection returns the connection state of the standard library
+	switch c := conn.(type) {
+	case interface{ ConnectionState() tls.ConnectionState }:
+		return c.ConnectionState().NegotiatedProtocol
+	case interface{ ConnectionState() gotls.ConnectionState }:
+		return c.ConnectionState().NegotiatedProtocol
+	}
+	return ""
+}
+
+// getRequestAuthority returns the authority of the downstream request, it is empty in the tcp proxy
+func getRequestAuthority(ctx context.Context) string {
+	v, err := variable.Get(ctx, types.VariableDownStreamReqHeaders)
+	if err != nil || v == nil {
+		return ""
+	}
+	headers, ok := v.(api.HeaderMap)
+	if !ok {
+		return ""
+	}
+	if host, ok := headers.Get("Host"); ok && host != "" {
+		return host
+	}
+	if authority, ok := headers.Get(":authority"); ok {
+		return authority
+	}
+	return ""
+}
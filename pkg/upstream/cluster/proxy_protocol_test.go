@@ -0,0 +1,304 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cluster
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"net"
+	"sync"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"mosn.io/api"
+	v2 "mosn.io/mosn/pkg/config/v2"
+	"mosn.io/mosn/pkg/mock"
+	"mosn.io/mosn/pkg/mtls"
+	"mosn.io/mosn/pkg/mtls/certtool"
+	"mosn.io/mosn/pkg/network"
+	"mosn.io/mosn/pkg/protocol"
+	"mosn.io/mosn/pkg/types"
+	"mosn.io/pkg/variable"
+)
+
+// newDownstreamTLSConn returns the server side of a mtls connection, the client sends the sni and negotiates h2
+func newDownstreamTLSConn(t *testing.T) net.Conn {
+	priv, err := certtool.GeneratePrivateKey("RSA")
+	require.Nil(t, err)
+	tmpl, err := certtool.CreateTemplate("sni.example.com", false, []string{"sni.example.com"})
+	require.Nil(t, err)
+	cert, err := certtool.SignCertificate(tmpl, priv)
+	require.Nil(t, err)
+	mng, err := mtls.NewTLSServerContextManager(&v2.Listener{
+		ListenerConfig: v2.ListenerConfig{
+			FilterChains: []v2.FilterChain{
+				{
+					TLSContexts: []v2.TLSConfig{
+						{
+							Status:     true,
+							CertChain:  cert.CertPem,
+							PrivateKey: cert.KeyPem,
+							ALPN:       "h2",
+						},
+					},
+				},
+			},
+		},
+	})
+	require.Nil(t, err)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.Nil(t, err)
+	defer ln.Close()
+	clientConn := make(chan net.Conn, 1)
+	go func() {
+		c, err := tls.Dial("tcp", ln.Addr().String(), &tls.Config{
+			ServerName:         "sni.example.com",
+			NextProtos:         []string{"h2"},
+			InsecureSkipVerify: true,
+		})
+		if err != nil {
+			c = nil
+		}
+		clientConn <- c
+	}()
+	raw, err := ln.Accept()
+	require.Nil(t, err)
+	conn, err := mng.Conn(raw)
+	require.Nil(t, err)
+	tlsConn, ok := conn.(*mtls.TLSConn)
+	require.True(t, ok)
+	require.Nil(t, tlsConn.Handshake())
+	c := <-clientConn
+	require.NotNil(t, c)
+	t.Cleanup(func() {
+		c.Close()
+		conn.Close()
+	})
+	return conn
+}
+
+func newProxyProtocolTestContext(t *testing.T, ctrl *gomock.Controller, id uint64) (context.Context, *mock.MockConnection) {
+	src, _ := net.ResolveTCPAddr("tcp", "192.168.0.1:56324")
+	dst, _ := net.ResolveTCPAddr("tcp", "192.168.0.11:443")
+	conn := mock.NewMockConnection(ctrl)
+	conn.EXPECT().ID().Return(id).AnyTimes()
+	conn.EXPECT().RemoteAddr().Return(src).AnyTimes()
+	conn.EXPECT().LocalAddr().Return(dst).AnyTimes()
+	conn.EXPECT().RawConn().Return(newDownstreamTLSConn(t)).AnyTimes()
+	ctx := variable.NewVariableContext(context.Background())
+	_ = variable.Set(ctx, types.VariableConnection, conn)
+	return ctx, conn
+}
+
+func TestNewProxyProtocolHeader(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	ctx, _ := newProxyProtocolTestContext(t, ctrl, 1)
+
+	header := newProxyProtocolHeader(ctx, &v2.ProxyProtocolConfig{Version: v2.ProxyProtocolV1})
+	assert.Equal(t, "192.168.0.1:56324", header.SourceAddr.String())
+	assert.Equal(t, "192.168.0.11:443", header.DestinationAddr.String())
+	assert.Empty(t, header.TLVs)
+
+	config := &v2.ProxyProtocolConfig{
+		Version: v2.ProxyProtocolV2,
+		TLVs:    []string{v2.ProxyProtocolTLVAlpn, v2.ProxyProtocolTLVSni, v2.ProxyProtocolTLVAuthority},
+	}
+	// the sni is sent without the request
+	header = newProxyProtocolHeader(ctx, config)
+	assert.Equal(t, []network.ProxyProtocolTLV{
+		{Type: network.PP2TypeALPN, Value: []byte("h2")},
+		{Type: network.PP2TypeAuthority, Value: []byte("sni.example.com")},
+	}, header.TLVs)
+	// the request authority is preferred
+	_ = variable.Set(ctx, types.VariableDownStreamReqHeaders, protocol.CommonHeader{"Host": "www.example.com"})
+	header = newProxyProtocolHeader(ctx, config)
+	assert.Equal(t, []network.ProxyProtocolTLV{
+		{Type: network.PP2TypeALPN, Value: []byte("h2")},
+		{Type: network.PP2TypeAuthority, Value: []byte("www.example.com")},
+	}, header.TLVs)
+
+	// no downstream connection
+	header = newProxyProtocolHeader(context.Background(), config)
+	assert.Nil(t, header.SourceAddr)
+	assert.Empty(t, header.TLVs)
+
+	// the tls passthrough, the server name is set by the tls inspector
+	plain := mock.NewMockConnection(ctrl)
+	plain.EXPECT().RemoteAddr().Return(&net.TCPAddr{IP: net.ParseIP("192.168.0.1"), Port: 56324}).AnyTimes()
+	plain.EXPECT().LocalAddr().Return(&net.TCPAddr{IP: net.ParseIP("192.168.0.11"), Port: 443}).AnyTimes()
+	plain.EXPECT().RawConn().Return(&net.TCPConn{}).AnyTimes()
+	ctx = variable.NewVariableContext(context.Background())
+	_ = variable.Set(ctx, types.VariableConnection, plain)
+	_ = variable.SetString(ctx, types.VariableRequestedServerName, "passthrough.example.com")
+	header = newProxyProtocolHeader(ctx, config)
+	assert.Equal(t, []network.ProxyProtocolTLV{
+		{Type: network.PP2TypeAuthority, Value: []byte("passthrough.example.com")},
+	}, header.TLVs)
+}
+
+func TestCheckProxyProtocolConfig(t *testing.T) {
+	for _, tc := range []struct {
+		config *v2.ProxyProtocolConfig
+		valid  bool
+	}{
+		{nil, true},
+		{&v2.ProxyProtocolConfig{}, true},
+		{&v2.ProxyProtocolConfig{Version: v2.ProxyProtocolV1}, true},
+		{&v2.ProxyProtocolConfig{Version: v2.ProxyProtocolV2, TLVs: []string{v2.ProxyProtocolTLVAlpn, v2.ProxyProtocolTLVSni, v2.ProxyProtocolTLVAuthority}}, true},
+		{&v2.ProxyProtocolConfig{Version: "V2"}, false},
+		{&v2.ProxyProtocolConfig{Version: v2.ProxyProtocolV1, TLVs: []string{v2.ProxyProtocolTLVAlpn}}, false},
+		{&v2.ProxyProtocolConfig{Version: v2.ProxyProtocolV2, TLVs: []string{"snl"}}, false},
+	} {
+		err := checkProxyProtocolConfig(tc.config)
+		assert.Equal(t, tc.valid, err == nil, "%+v", tc.config)
+	}
+	// the cluster with invalid config is rejected
+	assert.Nil(t, NewCluster(v2.Cluster{
+		Name:          "invalid_proxy_protocol",
+		ProxyProtocol: &v2.ProxyProtocolConfig{Version: "v3"},
+	}))
+}
+
+func TestHostCreateConnectionWithProxyProtocol(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	ctx, _ := newProxyProtocolTestContext(t, ctrl, 1)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.Nil(t, err)
+	defer ln.Close()
+	received := make(chan string, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		line, _ := bufio.NewReader(conn).ReadString('\n')
+		received <- line
+	}()
+
+	info := NewClusterInfo(v2.Cluster{
+		Name:          "proxy_protocol",
+		ProxyProtocol: &v2.ProxyProtocolConfig{Version: v2.ProxyProtocolV1},
+	})
+	host := NewSimpleHost(v2.Host{HostConfig: v2.HostConfig{Address: ln.Addr().String()}}, info)
+	data := host.CreateConnection(ctx)
+	require.Nil(t, data.Connection.Connect())
+	defer data.Connection.Close(api.NoFlush, api.LocalClose)
+	assert.Equal(t, "PROXY TCP4 192.168.0.1 192.168.0.11 56324 443\r\n", <-received)
+}
+
+func TestConnPoolWithProxyProtocol(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	cm := &clusterManager{}
+	factory, ok := protocol.GetNewPoolFactory(mockPrewarmProtocol)
+	require.True(t, ok)
+	connectionPool := &sync.Map{}
+
+	info := NewClusterInfo(v2.Cluster{
+		Name:          "proxy_protocol",
+		ProxyProtocol: &v2.ProxyProtocolConfig{Version: v2.ProxyProtocolV2},
+	})
+	host := NewSimpleHost(v2.Host{HostConfig: v2.HostConfig{Address: "127.0.0.1:8080"}}, info)
+
+	// the connection pools are not shared by the downstream connections
+	var listeners []api.ConnectionEventListener
+	ctx1, conn1 := newProxyProtocolTestContext(t, ctrl, 1)
+	ctx2, conn2 := newProxyProtocolTestContext(t, ctrl, 2)
+	for _, conn := range []*mock.MockConnection{conn1, conn2} {
+		conn.EXPECT().AddConnectionEventListener(gomock.Any()).Do(func(listener api.ConnectionEventListener) {
+			listeners = append(listeners, listener)
+		}).Times(1)
+	}
+	pool1, loaded := cm.loadOrStoreConnPool(ctx1, factory, connectionPool, host)
+	assert.False(t, loaded)
+	pool, loaded := cm.loadOrStoreConnPool(ctx1, factory, connectionPool, host)
+	assert.True(t, loaded)
+	assert.Same(t, pool1, pool)
+	pool2, loaded := cm.loadOrStoreConnPool(ctx2, factory, connectionPool, host)
+	assert.False(t, loaded)
+	assert.NotSame(t, pool1, pool2)
+	// the pool without the downstream connection
+	_, loaded = cm.loadOrStoreConnPool(context.Background(), factory, connectionPool, host)
+	assert.False(t, loaded)
+
+	// the pool is removed when the downstream connection is closed
+	require.Len(t, listeners, 2)
+	listeners[0].OnEvent(api.Connected)
+	_, ok = connectionPool.Load("127.0.0.1:8080#1")
+	assert.True(t, ok)
+	listeners[0].OnEvent(api.RemoteClose)
+	_, ok = connectionPool.Load("127.0.0.1:8080#1")
+	assert.False(t, ok)
+	_, ok = connectionPool.Load("127.0.0.1:8080#2")
+	assert.True(t, ok)
+}
+
+func TestProxyProtocolConnPoolShutdown(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	_createClusterManager()
+	cm := clusterManagerInstance
+	clusterConfig := v2.Cluster{
+		Name:          "proxy_protocol",
+		LbType:        v2.LB_ROUNDROBIN,
+		ProxyProtocol: &v2.ProxyProtocolConfig{Version: v2.ProxyProtocolV1},
+		Prewarm: &v2.PrewarmConfig{
+			Protocols: []api.ProtocolName{mockPrewarmProtocol},
+		},
+	}
+	require.Nil(t, cm.AddOrUpdateClusterAndHost(clusterConfig, newAggregateTestHosts("127.0.0.1:8080", "127.0.0.1:80")))
+	defer cm.RemovePrimaryCluster("proxy_protocol")
+	// the prewarmed pool is never used
+	_, ok := cm.prewarmers.Load("proxy_protocol")
+	assert.False(t, ok)
+
+	snapshot := cm.GetClusterSnapshot(context.Background(), "proxy_protocol")
+	connectionPool, ok := cm.protocolConnPool.load(mockPrewarmProtocol, snapshot)
+	require.True(t, ok)
+	factory, ok := protocol.GetNewPoolFactory(mockPrewarmProtocol)
+	require.True(t, ok)
+	var hosts []types.Host
+	snapshot.HostSet().Range(func(host types.Host) bool {
+		hosts = append(hosts, host)
+		return true
+	})
+	require.Len(t, hosts, 2)
+	for i := uint64(1); i <= 2; i++ {
+		ctx, conn := newProxyProtocolTestContext(t, ctrl, i)
+		conn.EXPECT().AddConnectionEventListener(gomock.Any()).AnyTimes()
+		for _, host := range hosts {
+			cm.loadOrStoreConnPool(ctx, factory, connectionPool, host)
+		}
+	}
+	cm.ShutdownConnectionPool(mockPrewarmProtocol, "127.0.0.1:8080")
+	var keys []string
+	connectionPool.Range(func(key, _ interface{}) bool {
+		keys = append(keys, key.(string))
+		return true
+	})
+	assert.ElementsMatch(t, []string{"127.0.0.1:80#1", "127.0.0.1:80#2"}, keys)
+}
@@ -41,6 +41,8 @@ func newHostStats(clustername string, addr string) *types.HostStats {
 		UpstreamConnectionLocalCloseWithActiveRequest:  s.Counter(metrics.UpstreamConnectionLocalCloseWithActiveRequest),
 		UpstreamConnectionRemoteCloseWithActiveRequest: s.Counter(metrics.UpstreamConnectionRemoteCloseWithActiveRequest),
 		UpstreamConnectionCloseNotify:                  s.Counter(metrics.UpstreamConnectionCloseNotify),
+		UpstreamConnectionPrewarm:                      s.Counter(metrics.UpstreamConnectionPrewarm),
+		UpstreamConnectionOnDemand:                     s.Counter(metrics.UpstreamConnectionOnDemand),
 		UpstreamRequestTotal:                           s.Counter(metrics.UpstreamRequestTotal),
 		UpstreamRequestActive:                          s.Counter(metrics.UpstreamRequestActive),
 		UpstreamRequestLocalReset:                      s.Counter(metrics.UpstreamRequestLocalReset),
@@ -72,6 +74,8 @@ func newClusterStats(clustername string) *types.ClusterStats {
 		UpstreamConnectionLocalCloseWithActiveRequest:  s.Counter(metrics.UpstreamConnectionLocalCloseWithActiveRequest),
 		UpstreamConnectionRemoteCloseWithActiveRequest: s.Counter(metrics.UpstreamConnectionRemoteCloseWithActiveRequest),
 		UpstreamConnectionCloseNotify:                  s.Counter(metrics.UpstreamConnectionCloseNotify),
+		UpstreamConnectionPrewarm:                      s.Counter(metrics.UpstreamConnectionPrewarm),
+		UpstreamConnectionOnDemand:                     s.Counter(metrics.UpstreamConnectionOnDemand),
 		UpstreamBytesReadTotal:                         s.Counter(metrics.UpstreamBytesReadTotal),
 		UpstreamBytesWriteTotal:                        s.Counter(metrics.UpstreamBytesWriteTotal),
 		UpstreamRequestTotal:                           s.Counter(metrics.UpstreamRequestTotal),
@@ -80,6 +84,9 @@ func newClusterStats(clustername string) *types.ClusterStats {
 		UpstreamRequestRemoteReset:                     s.Counter(metrics.UpstreamRequestRemoteReset),
 		UpstreamRequestRetry:                           s.Counter(metrics.UpstreamRequestRetry),
 		UpstreamRequestRetryOverflow:                   s.Counter(metrics.UpstreamRequestRetryOverflow),
+		UpstreamRequestHedge:                           s.Counter(metrics.UpstreamRequestHedge),
+		UpstreamRequestHedgeWin:                        s.Counter(metrics.UpstreamRequestHedgeWin),
+		UpstreamRequestHedgeCancel:                     s.Counter(metrics.UpstreamRequestHedgeCancel),
 		UpstreamRequestTimeout:                         s.Counter(metrics.UpstreamRequestTimeout),
 		UpstreamRequestFailureEject:                    s.Counter(metrics.UpstreamRequestFailureEject),
 		UpstreamRequestPendingOverflow:                 s.Counter(metrics.UpstreamRequestPendingOverflow),
@@ -90,6 +97,17 @@ func newClusterStats(clustername string) *types.ClusterStats {
 		UpstreamResponseFailed:                         s.Counter(metrics.UpstreamResponseFailed),
 		LBSubSetsFallBack:                              s.Counter(metrics.UpstreamLBSubSetsFallBack),
 		LBSubsetsCreated:                               s.Gauge(metrics.UpstreamLBSubsetsCreated),
+		OutlierEjectionsTotal:                          s.Counter(metrics.UpstreamOutlierEjectionsTotal),
+		OutlierEjectionsActive:                         s.Gauge(metrics.UpstreamOutlierEjectionsActive),
+		OutlierEjectionsOverflow:                       s.Counter(metrics.UpstreamOutlierEjectionsOverflow),
+		OutlierEjectionsConsecutive5xx:                 s.Counter(metrics.UpstreamOutlierEjectionsConsecutive5xx),
+		OutlierEjectionsConsecutiveGatewayFailure:      s.Counter(metrics.UpstreamOutlierEjectionsConsecutiveGatewayFailure),
+		OutlierEjectionsConsecutiveConnectFailure:      s.Counter(metrics.UpstreamOutlierEjectionsConsecutiveConnectFailure),
+		OutlierEjectionsSuccessRate:                    s.Counter(metrics.UpstreamOutlierEjectionsSuccessRate),
+		LBZoneRoutingAllDirectly:                       s.Counter(metrics.UpstreamLBZoneRoutingAllDirectly),
+		LBZoneRoutingSampled:                           s.Counter(metrics.UpstreamLBZoneRoutingSampled),
+		LBZoneRoutingCrossZone:                         s.Counter(metrics.UpstreamLBZoneRoutingCrossZone),
+		LBHealthyPanic:                                 s.Counter(metrics.UpstreamLBHealthyPanic),
 	}
 }
 
@@ -84,16 +84,19 @@ func newStrictDnsCluster(clusterConfig v2.Cluster) types.Cluster {
 		cluster.dnsRefreshRate = clusterConfig.DnsRefreshRate.Duration
 	}
 
-	// set resolve server
-	if clusterConfig.DnsResolverConfig.Servers != nil {
-		cluster.dnsResolver = network.NewDnsResolver(&clusterConfig.DnsResolverConfig)
-	} else {
-		cluster.dnsResolver = network.NewDnsResolverFromFile(clusterConfig.DnsResolverFile, clusterConfig.DnsResolverPort)
-	}
+	cluster.dnsResolver = newClusterDnsResolver(clusterConfig)
 
 	return cluster
 }
 
+// newClusterDnsResolver creates the dns resolver by the resolve servers, or the resolver file
+func newClusterDnsResolver(clusterConfig v2.Cluster) *network.DnsResolver {
+	if clusterConfig.DnsResolverConfig.Servers != nil {
+		return network.NewDnsResolver(&clusterConfig.DnsResolverConfig)
+	}
+	return network.NewDnsResolverFromFile(clusterConfig.DnsResolverFile, clusterConfig.DnsResolverPort)
+}
+
 // supported formats including {aaa.com:80, aaa.com}
 func getHostPortFromAddr(addr string) (string, string) {
 	s := strings.Split(addr, ":")
@@ -177,11 +180,15 @@ func (sdc *strictDnsCluster) stopResolve() {
 // 2. if dnsRefreshRate configured, use dnsRefreshRate
 // 3. use DefaultRefreshInterval(5s)
 func (sdc *strictDnsCluster) calculateNextResolveInterval(minTtl time.Duration) time.Duration {
-	dnsRefreshRate := sdc.dnsRefreshRate
-	if sdc.respectDnsTTL && minTtl > 0 {
+	return nextResolveInterval(sdc.dnsRefreshRate, sdc.respectDnsTTL, minTtl)
+}
+
+func nextResolveInterval(refreshRate time.Duration, respectDnsTTL bool, minTtl time.Duration) time.Duration {
+	dnsRefreshRate := refreshRate
+	if respectDnsTTL && minTtl > 0 {
 		// increase minTtl by 1 in case we will get dns response with ttl 0
 		dnsRefreshRate = minTtl + time.Second
-	} else if sdc.dnsRefreshRate == 0 {
+	} else if refreshRate == 0 {
 		dnsRefreshRate = DefaultRefreshInterval
 	}
 	return dnsRefreshRate
@@ -331,6 +338,7 @@ func (rt *ResolveTarget) OnResolve() {
 			metaData:      rt.config.MetaData,
 			tlsDisable:    rt.config.TLSDisable,
 			weight:        rt.config.Weight,
+			priority:      rt.config.Priority,
 			healthFlags:   GetHealthFlagPointer(newAddr),
 		}
 		host.clusterInfo.Store(sdc.info)
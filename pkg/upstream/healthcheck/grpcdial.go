@@ -0,0 +1,205 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package healthcheck
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"reflect"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+
+	"mosn.io/api"
+	"mosn.io/mosn/pkg/log"
+	"mosn.io/mosn/pkg/types"
+)
+
+const (
+	GRPCCheckConfigKey = "grpc_check_config"
+	// GRPC is the protocol of the health check config that uses the grpc health checking protocol
+	GRPC types.ProtocolName = "grpc"
+)
+
+var errSessionClosed = errors.New("health check session is closed")
+
+func init() {
+	grpcDialSessionFactory := &GRPCDialSessionFactory{}
+	RegisterSessionFactory(GRPC, grpcDialSessionFactory)
+}
+
+// GRPCCheckConfig is the config of the grpc.health.v1.Health/Check
+type GRPCCheckConfig struct {
+	Port    int                `json:"port,omitempty"`
+	Timeout api.DurationConfig `json:"timeout,omitempty"`
+	// ServiceName is the service name in the health check request, empty means the server's overall health
+	ServiceName string `json:"service_name,omitempty"`
+	// Authority is the :authority header in the health check request, default is the host address
+	Authority string `json:"authority,omitempty"`
+}
+
+// GRPCDialSession checks the host by the grpc health checking protocol,
+// the host is healthy only if the response status is SERVING.
+type GRPCDialSession struct {
+	addr        string
+	timeout     time.Duration
+	serviceName string
+	authority   string
+	// the detail of the last check, store string
+	detail atomic.Value
+	// the connection is reused by the checks
+	mutex  sync.Mutex
+	conn   *grpc.ClientConn
+	closed bool
+}
+
+type GRPCDialSessionFactory struct{}
+
+func (f *GRPCDialSessionFactory) NewSession(cfg map[string]interface{}, host types.Host) types.HealthCheckSession {
+	// checks the server's overall health on the host address if no config
+	v, ok := cfg[GRPCCheckConfigKey]
+	if !ok {
+		v = &GRPCCheckConfig{}
+	}
+
+	grpcCheckConfig, ok := v.(*GRPCCheckConfig)
+	if !ok {
+		grpcCheckConfigBytes, err := json.Marshal(v)
+		if err != nil {
+			log.DefaultLogger.Errorf("[upstream] [health check] [grpcdial session] grpcCheckConfig covert %+v error %+v %+v", reflect.TypeOf(v), v, err)
+			return nil
+		}
+		grpcCheckConfig = &GRPCCheckConfig{}
+		if err := json.Unmarshal(grpcCheckConfigBytes, grpcCheckConfig); err != nil {
+			log.DefaultLogger.Errorf("[upstream] [health check] [grpcdial session] grpcCheckConfig Unmarshal %+v error %+v %+v", reflect.TypeOf(v), v, err)
+			return nil
+		}
+	}
+
+	hostIp, _, err := net.SplitHostPort(host.AddressString())
+	if err != nil {
+		log.DefaultLogger.Errorf("[upstream] [health check] [grpcdial session] host=%s parse error %+v", host.AddressString(), err)
+		return nil
+	}
+
+	grpcDial := &GRPCDialSession{
+		addr:        host.AddressString(),
+		timeout:     defaultTimeout.Duration,
+		serviceName: grpcCheckConfig.ServiceName,
+		authority:   grpcCheckConfig.Authority,
+	}
+	if grpcCheckConfig.Port > 0 && grpcCheckConfig.Port < 65535 {
+		// re-config grpc check port
+		grpcDial.addr = net.JoinHostPort(hostIp, strconv.Itoa(grpcCheckConfig.Port))
+	}
+	if grpcCheckConfig.Timeout.Duration > 0 {
+		grpcDial.timeout = grpcCheckConfig.Timeout.Duration
+	}
+
+	log.DefaultLogger.Infof("[upstream] [health check] [grpcdial session]  create a health check success for %s, service: %s", grpcDial.addr, grpcDial.serviceName)
+	return grpcDial
+}
+
+func (s *GRPCDialSession) CheckHealth() bool {
+	code, servingStatus := s.check()
+	s.detail.Store("grpc_status:" + strconv.Itoa(int(code)) + ",serving_status:" + servingStatus.String())
+	if code != codes.OK {
+		return false
+	}
+	if servingStatus != healthpb.HealthCheckResponse_SERVING {
+		log.DefaultLogger.Errorf("[upstream] [health check] [grpcdial session] grpc check for host %s failed, service: %s, status: %s", s.addr, s.serviceName, servingStatus)
+		return false
+	}
+	if log.DefaultLogger.GetLogLevel() >= log.DEBUG {
+		log.DefaultLogger.Debugf("[upstream] [health check] [grpcdial session] grpc check for host %s succeed", s.addr)
+	}
+	return true
+}
+
+// check sends a health check request, returns the grpc status code and the serving status in the response
+func (s *GRPCDialSession) check() (codes.Code, healthpb.HealthCheckResponse_ServingStatus) {
+	conn, err := s.clientConn()
+	if err != nil {
+		log.DefaultLogger.Errorf("[upstream] [health check] [grpcdial session] dial grpc for host %s error: %v", s.addr, err)
+		return status.Code(err), healthpb.HealthCheckResponse_UNKNOWN
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+	defer cancel()
+	resp, err := healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{
+		Service: s.serviceName,
+	})
+	if err != nil {
+		log.DefaultLogger.Errorf("[upstream] [health check] [grpcdial session] grpc check for host %s error: %v", s.addr, err)
+		return status.Code(err), healthpb.HealthCheckResponse_UNKNOWN
+	}
+	return codes.OK, resp.GetStatus()
+}
+
+// clientConn returns the connection of the session, the connection is created at the first check
+// and reconnects automatically, so it is reused by the following checks.
+func (s *GRPCDialSession) clientConn() (*grpc.ClientConn, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.closed {
+		return nil, errSessionClosed
+	}
+	if s.conn != nil {
+		return s.conn, nil
+	}
+	opts := []grpc.DialOption{grpc.WithTransportCredentials(insecure.NewCredentials())}
+	if s.authority != "" {
+		opts = append(opts, grpc.WithAuthority(s.authority))
+	}
+	conn, err := grpc.Dial(s.addr, opts...)
+	if err != nil {
+		return nil, err
+	}
+	s.conn = conn
+	return conn, nil
+}
+
+// Close closes the connection when the health check of the host is stopped
+func (s *GRPCDialSession) Close() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.closed = true
+	if s.conn != nil {
+		s.conn.Close()
+		s.conn = nil
+	}
+}
+
+func (s *GRPCDialSession) OnTimeout() {
+	log.DefaultLogger.Errorf("[upstream] [health check] [grpcdial session] grpc check for host %s timeout", s.addr)
+}
+
+// LastCheckDetail returns the grpc status code and the serving status of the last check
+func (s *GRPCDialSession) LastCheckDetail() string {
+	detail, _ := s.detail.Load().(string)
+	return detail
+}
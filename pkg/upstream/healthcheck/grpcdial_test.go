@@ -0,0 +1,168 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package healthcheck
+
+import (
+	"context"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+
+	"mosn.io/api"
+	"mosn.io/mosn/pkg/protocol"
+	"mosn.io/mosn/pkg/types"
+)
+
+func TestGRPCDialNewSession(t *testing.T) {
+	f := &GRPCDialSessionFactory{}
+	h := &mockHost{addr: "127.0.0.1:22222"}
+	cfg := make(map[string]interface{})
+	// checks the server's overall health on the host address
+	gs := f.NewSession(cfg, h).(*GRPCDialSession)
+	assert.Equal(t, "127.0.0.1:22222", gs.addr)
+	assert.Equal(t, "", gs.serviceName)
+
+	cfg[GRPCCheckConfigKey] = "xx"
+	assert.Nil(t, f.NewSession(cfg, h))
+
+	cfg[GRPCCheckConfigKey] = &GRPCCheckConfig{
+		ServiceName: "test.service",
+		Authority:   "test.authority",
+	}
+	gs = f.NewSession(cfg, h).(*GRPCDialSession)
+	assert.Equal(t, "127.0.0.1:22222", gs.addr)
+	assert.Equal(t, defaultTimeout.Duration, gs.timeout)
+	assert.Equal(t, "test.service", gs.serviceName)
+	assert.Equal(t, "test.authority", gs.authority)
+
+	// config from json map
+	cfg[GRPCCheckConfigKey] = map[string]interface{}{
+		"port":         33333,
+		"timeout":      "1s",
+		"service_name": "test.service",
+	}
+	gs = f.NewSession(cfg, h).(*GRPCDialSession)
+	assert.Equal(t, "127.0.0.1:33333", gs.addr)
+	assert.Equal(t, time.Second, gs.timeout)
+
+	// registered for grpc only, the http2 health check is not changed
+	assert.Equal(t, reflect.TypeOf(f), reflect.TypeOf(sessionFactories[GRPC]))
+	_, ok := sessionFactories[protocol.HTTP2]
+	assert.False(t, ok)
+}
+
+func TestGRPCDialCheckHealth(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.Nil(t, err)
+	authority := make(chan string, 10)
+	server := grpc.NewServer(grpc.UnaryInterceptor(func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if md, ok := metadata.FromIncomingContext(ctx); ok && len(md[":authority"]) > 0 {
+			authority <- md[":authority"][0]
+		}
+		return handler(ctx, req)
+	}))
+	hs := health.NewServer()
+	hs.SetServingStatus("serving.service", healthpb.HealthCheckResponse_SERVING)
+	hs.SetServingStatus("not.serving.service", healthpb.HealthCheckResponse_NOT_SERVING)
+	healthpb.RegisterHealthServer(server, hs)
+	go server.Serve(ln)
+	defer server.Stop()
+
+	_, port, _ := net.SplitHostPort(ln.Addr().String())
+	newSession := func(serviceName string) *GRPCDialSession {
+		return (&GRPCDialSessionFactory{}).NewSession(map[string]interface{}{
+			GRPCCheckConfigKey: &GRPCCheckConfig{
+				Timeout:     api.DurationConfig{Duration: time.Second},
+				ServiceName: serviceName,
+				Authority:   "test.authority",
+			},
+		}, &mockHost{addr: "127.0.0.1:" + port}).(*GRPCDialSession)
+	}
+	testcases := []struct {
+		service  string
+		expected bool
+		detail   string
+	}{
+		// the server's overall health
+		{"", true, "grpc_status:0,serving_status:SERVING"},
+		{"serving.service", true, "grpc_status:0,serving_status:SERVING"},
+		{"not.serving.service", false, "grpc_status:0,serving_status:NOT_SERVING"},
+		// NotFound
+		{"unknown.service", false, "grpc_status:5,serving_status:UNKNOWN"},
+	}
+	for _, tc := range testcases {
+		s := newSession(tc.service)
+		assert.Equal(t, tc.expected, s.CheckHealth(), "service %s", tc.service)
+		assert.Equal(t, tc.detail, s.LastCheckDetail(), "service %s", tc.service)
+		assert.Equal(t, "test.authority", <-authority)
+		s.Close()
+	}
+
+	// the connection is reused by the checks
+	s := newSession("serving.service")
+	assert.True(t, s.CheckHealth())
+	conn := s.conn
+	require.NotNil(t, conn)
+	assert.True(t, s.CheckHealth())
+	assert.Same(t, conn, s.conn)
+	// the session is closed when the health check is stopped
+	s.Close()
+	assert.Nil(t, s.conn)
+	assert.False(t, s.CheckHealth())
+	assert.Nil(t, s.conn)
+
+	// the server is closed
+	server.Stop()
+	s = newSession("")
+	assert.False(t, s.CheckHealth())
+	// Unavailable
+	assert.Equal(t, "grpc_status:14,serving_status:UNKNOWN", s.LastCheckDetail())
+}
+
+type mockDetailLog struct {
+	details []string
+}
+
+func (l *mockDetailLog) Log(host types.Host, changed bool, healthy bool) {
+	l.details = append(l.details, "")
+}
+
+func (l *mockDetailLog) LogDetail(host types.Host, changed bool, healthy bool, detail string) {
+	l.details = append(l.details, detail)
+}
+
+func TestHealthCheckLogDetail(t *testing.T) {
+	l := &mockDetailLog{}
+	hc := &healthChecker{logger: l}
+	h := &mockHost{addr: "127.0.0.1:22222"}
+	c := newChecker(&TCPDialSession{}, h, hc)
+	hc.log(h, true, false, c.checkDetail())
+	s := &GRPCDialSession{}
+	s.detail.Store("grpc_status:0,serving_status:SERVING")
+	c = newChecker(s, h, hc)
+	hc.log(h, true, false, c.checkDetail())
+	assert.Equal(t, []string{"", "grpc_status:0,serving_status:SERVING"}, l.details)
+}
@@ -282,9 +282,13 @@ func (hc *healthChecker) decHealthy(host types.Host, reason types.FailureType, c
 
 }
 
-func (hc *healthChecker) log(host types.Host, current_status, changed bool) {
+func (hc *healthChecker) log(host types.Host, current_status, changed bool, detail string) {
 	if hc.logger == nil {
 		return
 	}
+	if l, ok := hc.logger.(types.HealthCheckDetailLog); ok && detail != "" {
+		l.LogDetail(host, current_status, changed, detail)
+		return
+	}
 	hc.logger.Log(host, current_status, changed)
 }
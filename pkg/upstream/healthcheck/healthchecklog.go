@@ -64,6 +64,11 @@ type defaultHealthCheckLogger struct {
 
 // default format:time host health_status current_result status_changed
 func (l *defaultHealthCheckLogger) Log(host types.Host, current_status, changed bool) {
+	l.LogDetail(host, current_status, changed, "")
+}
+
+// LogDetail appends the check detail to the default format
+func (l *defaultHealthCheckLogger) LogDetail(host types.Host, current_status, changed bool, detail string) {
 	if l.logger == nil {
 		return
 	}
@@ -79,6 +84,9 @@ func (l *defaultHealthCheckLogger) Log(host types.Host, current_status, changed
 	buf.WriteString(strconv.Itoa(boolToInt(current_status)) + ",")
 	buf.WriteString("status_changed:")
 	buf.WriteString(strconv.Itoa(boolToInt(changed)))
+	if detail != "" {
+		buf.WriteString("," + detail)
+	}
 	buf.WriteString("\n")
 
 	l.logger.Print(buf, true)
@@ -18,16 +18,21 @@
 package healthcheck
 
 import (
+	"bytes"
 	"encoding/json"
+	"io"
+	"io/ioutil"
 	"net"
 	"net/http"
 	"net/url"
 	"reflect"
+	"regexp"
 	"strconv"
 	"strings"
 	"time"
 
 	"mosn.io/api"
+	v2 "mosn.io/mosn/pkg/config/v2"
 	"mosn.io/mosn/pkg/log"
 	"mosn.io/mosn/pkg/protocol"
 	"mosn.io/mosn/pkg/types"
@@ -57,13 +62,45 @@ type HttpCheckConfig struct {
 	Scheme  string             `json:"scheme,omitempty"`
 	Domain  string             `json:"domain,omitempty"`
 	Codes   []CodeRange        `json:"codes,omitempty"`
+	// Headers are added to the health check request
+	Headers map[string]string `json:"headers,omitempty"`
+	// ResponseBody checks the response body if the status code is matched
+	ResponseBody *BodyMatcher `json:"response_body,omitempty"`
+	// DegradedHeader marks the host degraded if the response contains the header
+	DegradedHeader string `json:"degraded_header,omitempty"`
 }
 
+// BodyMatcher matches the response body of the http health check,
+// all of the configured conditions should be matched.
+type BodyMatcher struct {
+	// Contains matches the body contains the substring
+	Contains string `json:"contains,omitempty"`
+	// Regex matches the body by the regular expression
+	Regex string `json:"regex,omitempty"`
+	// JSONPath is a dot separated path in the json body, such as "status" or "$.data.status",
+	// the value at the path should be equal to Equals
+	JSONPath string `json:"json_path,omitempty"`
+	Equals   string `json:"equals,omitempty"`
+}
+
+type bodyMatcher struct {
+	contains string
+	regex    *regexp.Regexp
+	jsonPath []string
+	equals   string
+}
+
+// the max size of the response body to match
+const maxCheckBodySize = 64 * 1024
+
 type HTTPDialSession struct {
-	client  *http.Client
-	timeout time.Duration
-	request *http.Request
-	Codes   []CodeRange
+	client         *http.Client
+	timeout        time.Duration
+	request        *http.Request
+	Codes          []CodeRange
+	host           types.Host
+	bodyMatcher    *bodyMatcher
+	degradedHeader string
 }
 
 type HTTPDialSessionFactory struct{}
@@ -78,18 +115,9 @@ func (f *HTTPDialSessionFactory) NewSession(cfg map[string]interface{}, host typ
 		return tcpDialSessionFactory.NewSession(cfg, host)
 	}
 
-	httpCheckConfig, ok := v.(*HttpCheckConfig)
-	if !ok {
-		httpCheckConfigBytes, err := json.Marshal(v)
-		if err != nil {
-			log.DefaultLogger.Errorf("[upstream] [health check] [httpdial session] httpCheckConfig covert %+v error %+v %+v", reflect.TypeOf(v), v, err)
-			return nil
-		}
-		httpCheckConfig = &HttpCheckConfig{}
-		if err := json.Unmarshal(httpCheckConfigBytes, httpCheckConfig); err != nil {
-			log.DefaultLogger.Errorf("[upstream] [health check] [httpdial session] httpCheckConfig Unmarshal %+v error %+v %+v", reflect.TypeOf(v), v, err)
-			return nil
-		}
+	httpCheckConfig := parseHttpCheckConfig(v)
+	if httpCheckConfig == nil {
+		return nil
 	}
 
 	uri := &url.URL{}
@@ -143,13 +171,125 @@ func (f *HTTPDialSessionFactory) NewSession(cfg map[string]interface{}, host typ
 	if httpCheckConfig.Domain != "" {
 		httpDial.request.Host = httpCheckConfig.Domain
 	}
+	for k, v := range httpCheckConfig.Headers {
+		httpDial.request.Header.Set(k, v)
+	}
 
 	httpDial.Codes = httpCheckConfig.Codes
 
+	if httpCheckConfig.ResponseBody != nil {
+		httpDial.bodyMatcher, err = newBodyMatcher(httpCheckConfig.ResponseBody)
+		if err != nil {
+			log.DefaultLogger.Errorf("[upstream] [health check] [httpdial session]  create response body matcher failed, %v", err)
+			return nil
+		}
+	}
+	httpDial.host = host
+	httpDial.degradedHeader = httpCheckConfig.DegradedHeader
+
 	log.DefaultLogger.Infof("[upstream] [health check] [httpdial session]  create a health check success for %s", uri.String())
 	return httpDial
 }
 
+func parseHttpCheckConfig(v interface{}) *HttpCheckConfig {
+	httpCheckConfig, ok := v.(*HttpCheckConfig)
+	if !ok {
+		httpCheckConfigBytes, err := json.Marshal(v)
+		if err != nil {
+			log.DefaultLogger.Errorf("[upstream] [health check] [httpdial session] httpCheckConfig covert %+v error %+v %+v", reflect.TypeOf(v), v, err)
+			return nil
+		}
+		httpCheckConfig = &HttpCheckConfig{}
+		if err := json.Unmarshal(httpCheckConfigBytes, httpCheckConfig); err != nil {
+			log.DefaultLogger.Errorf("[upstream] [health check] [httpdial session] httpCheckConfig Unmarshal %+v error %+v %+v", reflect.TypeOf(v), v, err)
+			return nil
+		}
+	}
+	return httpCheckConfig
+}
+
+// DegradedEnabled returns true if the health check marks the hosts degraded by the http response header
+func DegradedEnabled(cfg v2.HealthCheck) bool {
+	v, ok := cfg.SessionConfig[HTTPCheckConfigKey]
+	if !ok {
+		return false
+	}
+	httpCheckConfig := parseHttpCheckConfig(v)
+	return httpCheckConfig != nil && httpCheckConfig.DegradedHeader != ""
+}
+
+func newBodyMatcher(cfg *BodyMatcher) (*bodyMatcher, error) {
+	m := &bodyMatcher{
+		contains: cfg.Contains,
+		equals:   cfg.Equals,
+	}
+	if cfg.Regex != "" {
+		regex, err := regexp.Compile(cfg.Regex)
+		if err != nil {
+			return nil, err
+		}
+		m.regex = regex
+	}
+	if path := strings.TrimPrefix(strings.TrimPrefix(cfg.JSONPath, "$"), "."); path != "" {
+		m.jsonPath = strings.Split(path, ".")
+	}
+	return m, nil
+}
+
+func (m *bodyMatcher) match(body []byte) bool {
+	if m.contains != "" && !bytes.Contains(body, []byte(m.contains)) {
+		return false
+	}
+	if m.regex != nil && !m.regex.Match(body) {
+		return false
+	}
+	if len(m.jsonPath) > 0 {
+		var data interface{}
+		if err := json.Unmarshal(body, &data); err != nil {
+			return false
+		}
+		value, ok := LookupJSONPath(data, m.jsonPath)
+		if !ok {
+			return false
+		}
+		return jsonValueString(value) == m.equals
+	}
+	return true
+}
+
+// LookupJSONPath returns the value at the path in the decoded json data,
+// the array elements are indexed by the number in the path, such as "items.0.status"
+func LookupJSONPath(data interface{}, path []string) (interface{}, bool) {
+	for _, key := range path {
+		switch v := data.(type) {
+		case map[string]interface{}:
+			value, ok := v[key]
+			if !ok {
+				return nil, false
+			}
+			data = value
+		case []interface{}:
+			idx, err := strconv.Atoi(key)
+			if err != nil || idx < 0 || idx >= len(v) {
+				return nil, false
+			}
+			data = v[idx]
+		default:
+			return nil, false
+		}
+	}
+	return data, true
+}
+
+// jsonValueString returns the string as it is, and the json encoding of the other values
+func jsonValueString(value interface{}) string {
+	if str, ok := value.(string); ok {
+		return str
+	}
+	b, _ := json.Marshal(value)
+	return string(b)
+}
+
 func (s *HTTPDialSession) verifyCode(code int) bool {
 	// default: [200, 200]
 	if len(s.Codes) == 0 {
@@ -172,9 +312,14 @@ func (s *HTTPDialSession) CheckHealth() bool {
 	}
 	defer resp.Body.Close()
 
+	s.updateDegraded(resp)
+
 	result := s.verifyCode(resp.StatusCode)
 	if !result {
 		log.DefaultLogger.Errorf("[upstream] [health check] [httpdial session] http check for host %s failed, statuscode: %+v", s.request.URL.String(), resp.StatusCode)
+	} else if !s.verifyBody(resp.Body) {
+		result = false
+		log.DefaultLogger.Errorf("[upstream] [health check] [httpdial session] http check for host %s failed, response body is not matched", s.request.URL.String())
 	} else {
 		if log.DefaultLogger.GetLogLevel() > log.DEBUG {
 			log.DefaultLogger.Debugf("[upstream] [health check] [httpdial session] http check for host %s succeed", s.request.URL.String())
@@ -184,6 +329,36 @@ func (s *HTTPDialSession) CheckHealth() bool {
 	return result
 }
 
+func (s *HTTPDialSession) verifyBody(body io.Reader) bool {
+	if s.bodyMatcher == nil {
+		return true
+	}
+	b, err := ioutil.ReadAll(io.LimitReader(body, maxCheckBodySize))
+	if err != nil {
+		log.DefaultLogger.Errorf("[upstream] [health check] [httpdial session] http check for host %s read body error: %v", s.request.URL.String(), err)
+		return false
+	}
+	return s.bodyMatcher.match(b)
+}
+
+// updateDegraded sets or clears the degraded flag of the host by the response header
+func (s *HTTPDialSession) updateDegraded(resp *http.Response) {
+	if s.degradedHeader == "" || s.host == nil {
+		return
+	}
+	degraded := resp.Header.Get(s.degradedHeader) != ""
+	if degraded == s.host.ContainHealthFlag(types.DEGRADED_ACTIVE_HC) {
+		return
+	}
+	if degraded {
+		s.host.SetHealthFlag(types.DEGRADED_ACTIVE_HC)
+		log.DefaultLogger.Warnf("[upstream] [health check] [httpdial session] host %s is degraded", s.host.AddressString())
+	} else {
+		s.host.ClearHealthFlag(types.DEGRADED_ACTIVE_HC)
+		log.DefaultLogger.Infof("[upstream] [health check] [httpdial session] host %s is not degraded", s.host.AddressString())
+	}
+}
+
 func (s *HTTPDialSession) OnTimeout() {
 	log.DefaultLogger.Errorf("[upstream] [health check] [httpdial session] http check for host %s timeout", s.request.URL.String())
 }
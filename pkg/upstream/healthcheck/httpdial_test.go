@@ -18,12 +18,19 @@
 package healthcheck
 
 import (
+	"net"
 	"net/http"
+	"net/http/httptest"
 	"reflect"
 	"testing"
 	"time"
 
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
 	"mosn.io/api"
+	v2 "mosn.io/mosn/pkg/config/v2"
+	"mosn.io/mosn/pkg/types"
 )
 
 func Test_NewSession(t *testing.T) {
@@ -309,3 +316,108 @@ func Test_CheckL7Health(t *testing.T) {
 		}
 	}
 }
+
+func TestHTTPCheckBodyMatcher(t *testing.T) {
+	body := `{"status":"UP","data":{"items":[{"ready":true},{"count":2}]}}`
+	testCases := []struct {
+		matcher BodyMatcher
+		expect  bool
+	}{
+		{BodyMatcher{}, true},
+		{BodyMatcher{Contains: `"status":"UP"`}, true},
+		{BodyMatcher{Contains: `"status":"DOWN"`}, false},
+		{BodyMatcher{Regex: `"status":\s*"UP"`}, true},
+		{BodyMatcher{Regex: `^UP$`}, false},
+		{BodyMatcher{JSONPath: "status", Equals: "UP"}, true},
+		{BodyMatcher{JSONPath: "$.status", Equals: "DOWN"}, false},
+		{BodyMatcher{JSONPath: "data.items.0.ready", Equals: "true"}, true},
+		{BodyMatcher{JSONPath: "data.items.1.count", Equals: "2"}, true},
+		{BodyMatcher{JSONPath: "data.items.2.count", Equals: "2"}, false},
+		{BodyMatcher{JSONPath: "data.unknown", Equals: ""}, false},
+		// all of the conditions should be matched
+		{BodyMatcher{Contains: "items", JSONPath: "status", Equals: "DOWN"}, false},
+	}
+	for i, tc := range testCases {
+		m, err := newBodyMatcher(&tc.matcher)
+		require.Nil(t, err)
+		assert.Equal(t, tc.expect, m.match([]byte(body)), "case %d", i)
+	}
+	// not a json body
+	m, _ := newBodyMatcher(&BodyMatcher{JSONPath: "status", Equals: "UP"})
+	assert.False(t, m.match([]byte("UP")))
+	// invalid regex
+	_, err := newBodyMatcher(&BodyMatcher{Regex: "("})
+	assert.NotNil(t, err)
+}
+
+func TestHTTPCheckBodyAndHeaders(t *testing.T) {
+	status := "UP"
+	degraded := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("x-check-token") != "token" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		if degraded {
+			w.Header().Set("x-health-degraded", "1")
+		}
+		w.Write([]byte(`{"status":"` + status + `"}`))
+	}))
+	defer server.Close()
+	_, port, _ := net.SplitHostPort(server.Listener.Addr().String())
+
+	host := &mockHost{addr: "127.0.0.1:" + port}
+	s := (&HTTPDialSessionFactory{}).NewSession(map[string]interface{}{
+		HTTPCheckConfigKey: map[string]interface{}{
+			"headers": map[string]string{
+				"x-check-token": "token",
+			},
+			"response_body": map[string]interface{}{
+				"json_path": "status",
+				"equals":    "UP",
+			},
+			"degraded_header": "x-health-degraded",
+		},
+	}, host).(*HTTPDialSession)
+
+	assert.True(t, s.CheckHealth())
+	assert.False(t, host.ContainHealthFlag(types.DEGRADED_ACTIVE_HC))
+	status = "DOWN"
+	assert.False(t, s.CheckHealth())
+	// a degraded host is still healthy
+	status = "UP"
+	degraded = true
+	assert.True(t, s.CheckHealth())
+	assert.True(t, host.ContainHealthFlag(types.DEGRADED_ACTIVE_HC))
+	degraded = false
+	assert.True(t, s.CheckHealth())
+	assert.False(t, host.ContainHealthFlag(types.DEGRADED_ACTIVE_HC))
+	// the request headers are not matched
+	delete(s.request.Header, "X-Check-Token")
+	assert.False(t, s.CheckHealth())
+
+	// invalid body matcher
+	assert.Nil(t, (&HTTPDialSessionFactory{}).NewSession(map[string]interface{}{
+		HTTPCheckConfigKey: &HttpCheckConfig{
+			ResponseBody: &BodyMatcher{Regex: "("},
+		},
+	}, host))
+}
+
+func TestDegradedEnabled(t *testing.T) {
+	assert.False(t, DegradedEnabled(v2.HealthCheck{}))
+	assert.False(t, DegradedEnabled(v2.HealthCheck{
+		HealthCheckConfig: v2.HealthCheckConfig{
+			SessionConfig: map[string]interface{}{
+				HTTPCheckConfigKey: map[string]interface{}{"path": "/health"},
+			},
+		},
+	}))
+	assert.True(t, DegradedEnabled(v2.HealthCheck{
+		HealthCheckConfig: v2.HealthCheckConfig{
+			SessionConfig: map[string]interface{}{
+				HTTPCheckConfigKey: map[string]interface{}{"degraded_header": "x-health-degraded"},
+			},
+		},
+	}))
+}
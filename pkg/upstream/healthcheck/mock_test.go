@@ -82,6 +82,10 @@ func (hs *mockHostSet) Range(f func(types.Host) bool) {
 	}
 }
 
+func (hs *mockHostSet) PriorityLevels() []types.HostSet {
+	return []types.HostSet{hs}
+}
+
 type mockHost struct {
 	types.Host
 	addr string
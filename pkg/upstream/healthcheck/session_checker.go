@@ -71,6 +71,9 @@ func (c *sessionChecker) Start() {
 		// stop all the timer when start is finished
 		c.checkTimer.Stop()
 		c.checkTimeout.Stop()
+		if s, ok := c.Session.(types.HealthCheckSessionCloser); ok {
+			s.Close()
+		}
 	}()
 	c.checkTimer = utils.NewTimer(c.HealthChecker.initialDelay, c.OnCheck)
 	for {
@@ -131,7 +134,7 @@ func (c *sessionChecker) HandleSuccess() {
 			c.Host.ClearHealthFlag(api.FAILED_ACTIVE_HC)
 		}
 	}
-	c.HealthChecker.log(c.Host, true, changed)
+	c.HealthChecker.log(c.Host, true, changed, c.checkDetail())
 	c.HealthChecker.incHealthy(c.Host, changed)
 }
 
@@ -147,7 +150,14 @@ func (c *sessionChecker) HandleFailure(reason types.FailureType) {
 		}
 	}
 	c.HealthChecker.decHealthy(c.Host, reason, changed)
-	c.HealthChecker.log(c.Host, false, changed)
+	c.HealthChecker.log(c.Host, false, changed, c.checkDetail())
+}
+
+func (c *sessionChecker) checkDetail() string {
+	if s, ok := c.Session.(types.HealthCheckSessionDetail); ok {
+		return s.LastCheckDetail()
+	}
+	return ""
 }
 
 func (c *sessionChecker) OnCheck() {
@@ -0,0 +1,198 @@
+package integrate
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	v2 "mosn.io/mosn/pkg/config/v2"
+	"mosn.io/mosn/pkg/protocol"
+	"mosn.io/mosn/pkg/protocol/xprotocol/bolt"
+	"mosn.io/mosn/pkg/types"
+	"mosn.io/mosn/test/util"
+	"mosn.io/mosn/test/util/mosn"
+)
+
+// the slow server responds after the per try timeout, the request is hedged to the closed server
+// and no more retry is left, the response of the slow server should still be received.
+const (
+	hedgeTryTimeout    = 100 * time.Millisecond
+	hedgeResponseDelay = 400 * time.Millisecond
+)
+
+func createHedgeProxyMesh(addr string, hosts []string, chain func(routers []v2.Router) v2.FilterChain) *v2.MOSNConfig {
+	clusterName := "hedgeCluster"
+	cmconfig := v2.ClusterManagerConfig{
+		Clusters: []v2.Cluster{
+			util.NewBasicCluster(clusterName, hosts),
+		},
+	}
+	routers := []v2.Router{
+		util.NewPrefixRouter(clusterName, "/"),
+		util.NewHeaderRouter(clusterName, ".*"),
+	}
+	for i := range routers {
+		routers[i].Route.RetryPolicy = &v2.RetryPolicy{
+			RetryPolicyConfig: v2.RetryPolicyConfig{
+				RetryOn:              true,
+				NumRetries:           2,
+				HedgeOnPerTryTimeout: true,
+			},
+			RetryTimeout: hedgeTryTimeout,
+		}
+	}
+	listener := util.NewListener("hedgeListener", addr, []v2.FilterChain{chain(routers)})
+	return util.NewMOSNConfig([]v2.Listener{listener}, cmconfig)
+}
+
+type SlowHTTPHandler struct{}
+
+func (h *SlowHTTPHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	time.Sleep(hedgeResponseDelay)
+	w.Header().Set("Content-Type", "text/plain")
+	fmt.Fprintf(w, "\nRequestId:%s\n", r.Header.Get("Requestid"))
+}
+
+func ServeSlowBoltV1(t *testing.T, conn net.Conn) {
+	proto := (&bolt.XCodec{}).NewXProtocol(context.Background())
+	response := func(iobuf types.IoBuffer) ([]byte, bool) {
+		cmd, _ := proto.Decode(nil, iobuf)
+		if cmd == nil {
+			return nil, false
+		}
+		if req, ok := cmd.(*bolt.Request); ok {
+			time.Sleep(hedgeResponseDelay)
+			resp := bolt.NewRpcResponse(req.RequestId, bolt.ResponseStatusSuccess, nil, nil)
+			iobufresp, err := proto.Encode(nil, resp)
+			if err != nil {
+				t.Errorf("Build response error: %v\n", err)
+				return nil, true
+			}
+			return iobufresp.Bytes(), true
+		}
+		return nil, true
+	}
+	util.ServeRPC(t, conn, response)
+}
+
+type HedgeCase struct {
+	*TestCase
+	SlowServer util.UpstreamServer
+}
+
+func NewHedgeCase(t *testing.T, proto types.ProtocolName) *HedgeCase {
+	var slow util.UpstreamServer
+	switch proto {
+	case protocol.HTTP1:
+		slow = util.NewHTTPServer(t, &SlowHTTPHandler{})
+	case protocol.HTTP2:
+		slow = util.NewUpstreamHTTP2(t, "127.0.0.1:8080", &SlowHTTPHandler{})
+	}
+	tc := NewTestCase(t, proto, proto, util.NewRPCServer(t, "", bolt.ProtocolName)) // Empty RPC server for get rpc client
+	return &HedgeCase{
+		TestCase:   tc,
+		SlowServer: slow,
+	}
+}
+
+func (c *HedgeCase) StartProxy() {
+	c.SlowServer.GoServe()
+	// nothing listens on the closed address
+	closedAddr := util.CurrentMeshAddr()
+	clientMeshAddr := util.CurrentMeshAddr()
+	c.ClientMeshAddr = clientMeshAddr
+	cfg := createHedgeProxyMesh(clientMeshAddr, []string{c.SlowServer.Addr(), closedAddr}, func(routers []v2.Router) v2.FilterChain {
+		return util.NewFilterChain("hedgeVirtualHost", c.AppProtocol, c.AppProtocol, routers)
+	})
+	mesh := mosn.NewMosn(cfg)
+	go mesh.Start()
+	go func() {
+		<-c.Finish
+		c.SlowServer.Close()
+		mesh.Close()
+		c.Finish <- true
+	}()
+	time.Sleep(5 * time.Second) //wait server and mesh start
+}
+
+type XHedgeCase struct {
+	*XTestCase
+	SlowServer util.UpstreamServer
+}
+
+func NewXHedgeCase(t *testing.T, subProtocol types.ProtocolName) *XHedgeCase {
+	addr := "127.0.0.1:8080"
+	slow := &util.RPCServer{
+		Client:         util.NewRPCClient(t, "rpcClient", subProtocol),
+		Name:           addr,
+		UpstreamServer: util.NewUpstreamServer(t, addr, ServeSlowBoltV1),
+	}
+	return &XHedgeCase{
+		XTestCase:  NewXTestCase(t, subProtocol, slow),
+		SlowServer: slow,
+	}
+}
+
+func (c *XHedgeCase) StartProxy() {
+	c.SlowServer.GoServe()
+	// nothing listens on the closed address
+	closedAddr := util.CurrentMeshAddr()
+	clientMeshAddr := util.CurrentMeshAddr()
+	c.ClientMeshAddr = clientMeshAddr
+	cfg := createHedgeProxyMesh(clientMeshAddr, []string{c.SlowServer.Addr(), closedAddr}, func(routers []v2.Router) v2.FilterChain {
+		return util.NewXProtocolFilterChain("hedgeVirtualHost", c.SubProtocol, routers)
+	})
+	mesh := mosn.NewMosn(cfg)
+	go mesh.Start()
+	go func() {
+		<-c.Finish
+		c.SlowServer.Close()
+		mesh.Close()
+		c.Finish <- true
+	}()
+	time.Sleep(5 * time.Second) //wait server and mesh start
+}
+
+func TestHedgeProxy(t *testing.T) {
+	testCases := []*HedgeCase{
+		NewHedgeCase(t, protocol.HTTP1),
+		NewHedgeCase(t, protocol.HTTP2),
+	}
+	for i, tc := range testCases {
+		t.Logf("start case #%d\n", i)
+		tc.StartProxy()
+		go tc.RunCase(5, 0)
+		select {
+		case err := <-tc.C:
+			if err != nil {
+				t.Errorf("[ERROR MESSAGE] #%d %v to mesh %v test failed, error: %v\n", i, tc.AppProtocol, tc.MeshProtocol, err)
+			}
+		case <-time.After(15 * time.Second):
+			t.Errorf("[ERROR MESSAGE] #%d %v to mesh %v hang\n", i, tc.AppProtocol, tc.MeshProtocol)
+		}
+		tc.FinishCase()
+	}
+}
+
+func TestXHedgeProxy(t *testing.T) {
+	testCases := []*XHedgeCase{
+		NewXHedgeCase(t, bolt.ProtocolName),
+	}
+	for i, tc := range testCases {
+		t.Logf("start case #%d\n", i)
+		tc.StartProxy()
+		go tc.RunCase(5, 0)
+		select {
+		case err := <-tc.C:
+			if err != nil {
+				t.Errorf("[ERROR MESSAGE] #%d %v to mesh %v test failed, error: %v\n", i, tc.SubProtocol, tc.MeshProtocol, err)
+			}
+		case <-time.After(15 * time.Second):
+			t.Errorf("[ERROR MESSAGE] #%d %v to mesh %v hang\n", i, tc.SubProtocol, tc.MeshProtocol)
+		}
+		tc.FinishCase()
+	}
+}